@@ -0,0 +1,151 @@
+package bfd
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPacket(t *testing.T) {
+	p := &Packet{
+		Diag:          DiagDetectExpired,
+		State:         StateUp,
+		Poll:          true,
+		DetectMult:    3,
+		MyDisc:        1,
+		YourDisc:      2,
+		DesiredMinTx:  10000,
+		RequiredMinRx: 20000,
+	}
+	b := p.Encode(make([]byte, PacketLen))
+	got, err := DecodePacket(b)
+	if err != nil {
+		t.Fatalf("DecodePacket: %s", err)
+	}
+	if *got != *p {
+		t.Errorf("got %+v want %+v", got, p)
+	}
+
+	bad := []func(b []byte){
+		func(b []byte) { b[0] = 2 << 5 },    // version
+		func(b []byte) { b[1] |= flagAuth }, // authentication
+		func(b []byte) { b[1] |= 0x01 },     // multipoint
+		func(b []byte) { b[2] = 0 },         // detect multiplier
+		func(b []byte) { b[3] = 40 },        // length
+		func(b []byte) { b[7] = 0 },         // my discriminator
+		func(b []byte) { b[11] = 0 },        // your discriminator in Up
+	}
+	for i, f := range bad {
+		b := p.Encode(make([]byte, PacketLen))
+		f(b)
+		if _, err := DecodePacket(b); err == nil {
+			t.Errorf("bad packet %d accepted", i)
+		}
+	}
+	if _, err := DecodePacket(b[:PacketLen-1]); err == nil {
+		t.Errorf("short packet accepted")
+	}
+}
+
+type testEvent struct {
+	name   string
+	state  State
+	diag   Diag
+	failed bool
+}
+
+// testPair returns two sessions sending to each other while connected is
+// non-zero.
+func testPair(events chan<- testEvent, connected *int32) (*Session, *Session) {
+	var a, b *Session
+	sendTo := func(dst **Session) func([]byte) error {
+		return func(buf []byte) error {
+			if atomic.LoadInt32(connected) == 0 {
+				return nil
+			}
+			p, err := DecodePacket(buf)
+			if err != nil {
+				return err
+			}
+			(*dst).input(p)
+			return nil
+		}
+	}
+	cfg := Config{Multiplier: 3, DesiredMinTx: 10 * time.Millisecond, RequiredMinRx: 10 * time.Millisecond}
+	a = newSession("eth0", net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), 1, cfg, sendTo(&b))
+	b = newSession("eth0", net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.1"), 2, cfg, sendTo(&a))
+	for name, s := range map[string]*Session{"a": a, "b": b} {
+		name := name
+		s.clients[&Client{s: s, stateF: func(state State, diag Diag, failed bool) {
+			events <- testEvent{name, state, diag, failed}
+		}}] = true
+	}
+	return a, b
+}
+
+// waitState waits for both sessions to reach state.
+func waitState(t *testing.T, events <-chan testEvent, state State, diag Diag) {
+	t.Helper()
+	seen := make(map[string]bool)
+	timeout := time.After(5 * time.Second)
+	for len(seen) != 2 {
+		select {
+		case ev := <-events:
+			if ev.state == state {
+				if ev.diag != diag || ev.failed != (state == StateDown) {
+					t.Errorf("%s: %s diag %s failed %v want %s", ev.name, ev.state, ev.diag, ev.failed, diag)
+				}
+				seen[ev.name] = true
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %s", state)
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	defer func(d time.Duration) { slowTxInterval = d }(slowTxInterval)
+	slowTxInterval = 50 * time.Millisecond
+
+	events := make(chan testEvent, 100)
+	connected := int32(1)
+	a, b := testPair(events, &connected)
+	go a.run()
+	go b.run()
+
+	waitState(t, events, StateUp, DiagNone)
+	// The poll sequence ends slow start.
+	time.Sleep(100 * time.Millisecond)
+	ai, bi := &SessionInfo{}, &SessionInfo{}
+	a.do(func() { ai = a.info() })
+	b.do(func() { bi = b.info() })
+	if ai.RemoteDisc != 2 || bi.RemoteDisc != 1 || ai.TxInterval != 10000 ||
+		ai.DetectTime != 30000 || ai.RemoteState != StateUp {
+		t.Errorf("bad info %+v %+v", ai, bi)
+	}
+
+	// Loss of packets brings the sessions down.
+	atomic.StoreInt32(&connected, 0)
+	waitState(t, events, StateDown, DiagDetectExpired)
+
+	// They come back up and an administratively removed session brings
+	// the other one down.
+	atomic.StoreInt32(&connected, 1)
+	waitState(t, events, StateUp, DiagNone)
+	close(a.quit)
+	select {
+	case ev := <-events:
+		if ev.name != "b" || ev.state != StateDown || ev.diag != DiagNeighborDown || ev.failed {
+			t.Errorf("bad event %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for neighbor down")
+	}
+	close(b.quit)
+	<-a.done
+	<-b.done
+	if a.do(func() {}) {
+		t.Errorf("closed session ran rpc")
+	}
+}
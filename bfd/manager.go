@@ -0,0 +1,283 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package bfd implements single-hop Bidirectional Forwarding Detection
+// (RFC5880, RFC5881) sessions used to detect the failure of adjacencies.
+// This file contains the session manager and the UDP transport.
+package bfd
+
+import (
+	"fmt"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"math/rand"
+	"net"
+)
+
+// Port is the UDP destination port of single-hop BFD Control packets
+// (RFC5881 section 4).
+const Port = 3784
+
+// The source port range of the Control packets (RFC5881 section 4).
+const (
+	srcPortMin   = 49152
+	srcPortCount = 65536 - srcPortMin
+)
+
+// ttl is the TTL or hop limit of the Control packets, received packets with
+// another value are dropped (RFC5881 section 5).
+const ttl = 255
+
+type peerKey struct {
+	addr    string
+	ifindex int
+}
+
+type rxPacket struct {
+	p       *Packet
+	src     net.IP
+	ifindex int
+}
+
+// Manager owns the BFD sessions and demultiplexes the received packets to
+// them. Its state is owned by the manager go routine.
+type Manager struct {
+	rxC      chan rxPacket
+	rpC      chan func()
+	byDisc   map[uint32]*Session
+	byPeer   map[peerKey]*Session
+	nextDisc uint32
+	nextPort int
+}
+
+// NewManager returns a new session manager, Listen starts it.
+func NewManager() *Manager {
+	return &Manager{
+		rxC:      make(chan rxPacket, 10),
+		rpC:      make(chan func()),
+		byDisc:   make(map[uint32]*Session),
+		byPeer:   make(map[peerKey]*Session),
+		nextDisc: rand.Uint32(),
+		nextPort: rand.Intn(srcPortCount),
+	}
+}
+
+func (m *Manager) do(f func()) {
+	c := make(chan bool)
+	m.rpC <- func() { f(); close(c) }
+	<-c
+}
+
+// Listen opens the sockets receiving the Control packets and starts the
+// manager. It fails only if neither IPv4 nor IPv6 could be used.
+func (m *Manager) Listen(quit <-chan bool) error {
+	var conns []*net.UDPConn
+	conn4, err4 := net.ListenUDP("udp4", &net.UDPAddr{Port: Port})
+	if err4 == nil {
+		pc := ipv4.NewPacketConn(conn4)
+		if err4 = pc.SetControlMessage(ipv4.FlagTTL|ipv4.FlagInterface, true); err4 == nil {
+			conns = append(conns, conn4)
+			go m.readIPv4(pc)
+		} else {
+			conn4.Close() // nolint
+		}
+	}
+	conn6, err6 := net.ListenUDP("udp6", &net.UDPAddr{Port: Port})
+	if err6 == nil {
+		pc := ipv6.NewPacketConn(conn6)
+		if err6 = pc.SetControlMessage(ipv6.FlagHopLimit|ipv6.FlagInterface, true); err6 == nil {
+			conns = append(conns, conn6)
+			go m.readIPv6(pc)
+		} else {
+			conn6.Close() // nolint
+		}
+	}
+	if len(conns) == 0 {
+		return fmt.Errorf("BFD: ipv4: %s ipv6: %s", err4, err6)
+	}
+	if err4 != nil {
+		Info("BFD: no IPv4 sessions: %s", err4)
+	}
+	if err6 != nil {
+		Info("BFD: no IPv6 sessions: %s", err6)
+	}
+	go func() {
+		<-quit
+		for _, conn := range conns {
+			conn.Close() // nolint
+		}
+	}()
+	go m.run(quit)
+	return nil
+}
+
+func (m *Manager) readIPv4(pc *ipv4.PacketConn) {
+	b := make([]byte, 1500)
+	for {
+		n, cm, src, err := pc.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		if cm == nil || cm.TTL != ttl {
+			continue
+		}
+		m.input(b[:n], src.(*net.UDPAddr).IP, cm.IfIndex)
+	}
+}
+
+func (m *Manager) readIPv6(pc *ipv6.PacketConn) {
+	b := make([]byte, 1500)
+	for {
+		n, cm, src, err := pc.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		if cm == nil || cm.HopLimit != ttl {
+			continue
+		}
+		m.input(b[:n], src.(*net.UDPAddr).IP, cm.IfIndex)
+	}
+}
+
+func (m *Manager) input(b []byte, src net.IP, ifindex int) {
+	p, err := DecodePacket(b)
+	if err != nil {
+		Debug(DbgFPkt, "BFD: %s from %s", err, src)
+		return
+	}
+	m.rxC <- rxPacket{p, append(net.IP(nil), src...), ifindex}
+}
+
+func (m *Manager) run(quit <-chan bool) {
+	for {
+		select {
+		case in := <-m.rxC:
+			var s *Session
+			if in.p.YourDisc != 0 {
+				s = m.byDisc[in.p.YourDisc]
+				if s != nil && !s.Remote.Equal(in.src) {
+					s = nil
+				}
+			} else {
+				s = m.byPeer[peerKey{in.src.String(), in.ifindex}]
+			}
+			if s == nil {
+				Debug(DbgFPkt, "BFD: no session for packet from %s", in.src)
+				continue
+			}
+			s.input(in.p)
+		case f := <-m.rpC:
+			f()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// allocDisc returns an unused non-zero local discriminator.
+func (m *Manager) allocDisc() uint32 {
+	for {
+		m.nextDisc++
+		if m.nextDisc != 0 && m.byDisc[m.nextDisc] == nil {
+			return m.nextDisc
+		}
+	}
+}
+
+// listenTx returns a socket to send the Control packets of a session from the
+// local address.
+func (m *Manager) listenTx(intf string, local net.IP) (*net.UDPConn, error) {
+	laddr := &net.UDPAddr{IP: local}
+	if local.IsLinkLocalUnicast() {
+		laddr.Zone = intf
+	}
+	var err error
+	for i := 0; i < srcPortCount; i++ {
+		laddr.Port = srcPortMin + m.nextPort
+		m.nextPort = (m.nextPort + 1) % srcPortCount
+		var conn *net.UDPConn
+		if conn, err = net.ListenUDP("udp", laddr); err != nil {
+			continue
+		}
+		if local.To4() != nil {
+			err = ipv4.NewConn(conn).SetTTL(ttl)
+		} else {
+			err = ipv6.NewConn(conn).SetHopLimit(ttl)
+		}
+		if err != nil {
+			conn.Close() // nolint
+			return nil, err
+		}
+		return conn, nil
+	}
+	return nil, err
+}
+
+// Open returns a client of the session to remote from local on interface
+// intf, the session is created if needed. stateF is called on the changes of
+// the session state.
+func (m *Manager) Open(intf string, local, remote net.IP, cfg Config, stateF StateFunc) (*Client, error) {
+	ifi, err := net.InterfaceByName(intf)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{m: m, stateF: stateF}
+	m.do(func() {
+		key := peerKey{remote.String(), ifi.Index}
+		s := m.byPeer[key]
+		if s == nil {
+			var conn *net.UDPConn
+			if conn, err = m.listenTx(intf, local); err != nil {
+				return
+			}
+			raddr := &net.UDPAddr{IP: remote, Port: Port}
+			if remote.IsLinkLocalUnicast() {
+				raddr.Zone = intf
+			}
+			send := func(b []byte) error {
+				_, err := conn.WriteToUDP(b, raddr)
+				return err
+			}
+			s = newSession(intf, local, remote, m.allocDisc(), cfg, send)
+			m.byPeer[key] = s
+			m.byDisc[s.localDisc] = s
+			go func() {
+				s.run()
+				conn.Close() // nolint
+			}()
+			Debug(DbgFAdj, "%s: created", s)
+		}
+		c.s = s
+		s.do(func() { s.clients[c] = true })
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close removes the client from its session, the session is deleted with its
+// last client.
+func (c *Client) Close() {
+	m, s := c.m, c.s
+	m.do(func() {
+		last := false
+		s.do(func() {
+			delete(s.clients, c)
+			last = len(s.clients) == 0
+		})
+		if !last {
+			return
+		}
+		Debug(DbgFAdj, "%s: deleted", s)
+		for key, ks := range m.byPeer {
+			if ks == s {
+				delete(m.byPeer, key)
+			}
+		}
+		delete(m.byDisc, s.localDisc)
+		close(s.quit)
+	})
+}
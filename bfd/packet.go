@@ -0,0 +1,185 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package bfd implements single-hop Bidirectional Forwarding Detection
+// (RFC5880, RFC5881) sessions used to detect the failure of adjacencies.
+// This file contains the BFD Control packet.
+package bfd
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Version is the BFD protocol version.
+const Version = 1
+
+// PacketLen is the length of a BFD Control packet without authentication.
+const PacketLen = 24
+
+// State is a BFD session state (RFC5880 section 4.1).
+type State uint8
+
+// State values.
+const (
+	StateAdminDown State = iota
+	StateDown
+	StateInit
+	StateUp
+)
+
+// The yang names of the states (RFC9314).
+var stateStrings = map[State]string{
+	StateAdminDown: "adminDown",
+	StateDown:      "down",
+	StateInit:      "init",
+	StateUp:        "up",
+}
+
+func (s State) String() string {
+	ss, ok := stateStrings[s]
+	if !ok {
+		return fmt.Sprintf("Unknown State(%d)", s)
+	}
+	return ss
+}
+
+// MarshalText converts the state to text (yang value).
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Diag is a BFD diagnostic code (RFC5880 section 4.1).
+type Diag uint8
+
+// Diag values.
+const (
+	DiagNone Diag = iota
+	DiagDetectExpired
+	DiagEchoFailed
+	DiagNeighborDown
+	DiagForwardingReset
+	DiagPathDown
+	DiagConcatPathDown
+	DiagAdminDown
+	DiagRevConcatPathDown
+)
+
+// The yang names of the diagnostic codes (RFC9314).
+var diagStrings = map[Diag]string{
+	DiagNone:              "none",
+	DiagDetectExpired:     "control-expiry",
+	DiagEchoFailed:        "echo-failed",
+	DiagNeighborDown:      "neighbor-down",
+	DiagForwardingReset:   "forwarding-reset",
+	DiagPathDown:          "path-down",
+	DiagConcatPathDown:    "concatenated-path-down",
+	DiagAdminDown:         "admin-down",
+	DiagRevConcatPathDown: "reverse-concatenated-path-down",
+}
+
+func (d Diag) String() string {
+	ds, ok := diagStrings[d]
+	if !ok {
+		return fmt.Sprintf("Unknown Diag(%d)", d)
+	}
+	return ds
+}
+
+// MarshalText converts the diagnostic code to text (yang value).
+func (d Diag) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Control packet flags.
+const (
+	flagPoll       = 0x20
+	flagFinal      = 0x10
+	flagAuth       = 0x04
+	flagDemand     = 0x02
+	flagMultipoint = 0x01
+)
+
+// Packet is a BFD Control packet, the intervals are in microseconds.
+type Packet struct {
+	Diag           Diag
+	State          State
+	Poll           bool
+	Final          bool
+	Demand         bool
+	DetectMult     uint8
+	MyDisc         uint32
+	YourDisc       uint32
+	DesiredMinTx   uint32
+	RequiredMinRx  uint32
+	RequiredEchoRx uint32
+}
+
+// Encode encodes the packet in b which must have room for PacketLen bytes.
+func (p *Packet) Encode(b []byte) []byte {
+	b = b[:PacketLen]
+	b[0] = Version<<5 | byte(p.Diag)&0x1f
+	b[1] = byte(p.State) << 6
+	if p.Poll {
+		b[1] |= flagPoll
+	}
+	if p.Final {
+		b[1] |= flagFinal
+	}
+	if p.Demand {
+		b[1] |= flagDemand
+	}
+	b[2] = p.DetectMult
+	b[3] = PacketLen
+	binary.BigEndian.PutUint32(b[4:], p.MyDisc)
+	binary.BigEndian.PutUint32(b[8:], p.YourDisc)
+	binary.BigEndian.PutUint32(b[12:], p.DesiredMinTx)
+	binary.BigEndian.PutUint32(b[16:], p.RequiredMinRx)
+	binary.BigEndian.PutUint32(b[20:], p.RequiredEchoRx)
+	return b
+}
+
+// DecodePacket decodes and validates a received Control packet (RFC5880
+// section 6.8.6). Authentication is not supported, authenticated packets are
+// rejected.
+func DecodePacket(b []byte) (*Packet, error) {
+	if len(b) < PacketLen {
+		return nil, fmt.Errorf("short BFD packet length %d", len(b))
+	}
+	if v := b[0] >> 5; v != Version {
+		return nil, fmt.Errorf("unsupported BFD version %d", v)
+	}
+	if l := int(b[3]); l < PacketLen || l > len(b) {
+		return nil, fmt.Errorf("invalid BFD length %d", l)
+	}
+	if b[1]&flagAuth != 0 {
+		return nil, fmt.Errorf("BFD authentication not supported")
+	}
+	if b[1]&flagMultipoint != 0 {
+		return nil, fmt.Errorf("BFD multipoint set")
+	}
+	p := &Packet{
+		Diag:           Diag(b[0] & 0x1f),
+		State:          State(b[1] >> 6),
+		Poll:           b[1]&flagPoll != 0,
+		Final:          b[1]&flagFinal != 0,
+		Demand:         b[1]&flagDemand != 0,
+		DetectMult:     b[2],
+		MyDisc:         binary.BigEndian.Uint32(b[4:]),
+		YourDisc:       binary.BigEndian.Uint32(b[8:]),
+		DesiredMinTx:   binary.BigEndian.Uint32(b[12:]),
+		RequiredMinRx:  binary.BigEndian.Uint32(b[16:]),
+		RequiredEchoRx: binary.BigEndian.Uint32(b[20:]),
+	}
+	if p.DetectMult == 0 {
+		return nil, fmt.Errorf("BFD detect multiplier zero")
+	}
+	if p.MyDisc == 0 {
+		return nil, fmt.Errorf("BFD my discriminator zero")
+	}
+	if p.YourDisc == 0 && p.State != StateDown && p.State != StateAdminDown {
+		return nil, fmt.Errorf("BFD your discriminator zero in state %s", p.State)
+	}
+	return p, nil
+}
@@ -0,0 +1,361 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package bfd implements single-hop Bidirectional Forwarding Detection
+// (RFC5880, RFC5881) sessions used to detect the failure of adjacencies.
+// This file contains the session state machine.
+package bfd
+
+import (
+	"fmt"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"math/rand"
+	"net"
+	"time"
+)
+
+// Session defaults (RFC9314).
+const (
+	DefMultiplier    = 3
+	DefDesiredMinTx  = time.Second
+	DefRequiredMinRx = time.Second
+)
+
+// slowTxInterval is the minimum transmit interval while the session is not
+// Up (RFC5880 section 6.8.3).
+var slowTxInterval = time.Second
+
+// Config holds the parameters of a session.
+type Config struct {
+	Multiplier    uint8
+	DesiredMinTx  time.Duration
+	RequiredMinRx time.Duration
+}
+
+func (cfg *Config) setDefaults() {
+	if cfg.Multiplier == 0 {
+		cfg.Multiplier = DefMultiplier
+	}
+	if cfg.DesiredMinTx == 0 {
+		cfg.DesiredMinTx = DefDesiredMinTx
+	}
+	if cfg.RequiredMinRx == 0 {
+		cfg.RequiredMinRx = DefRequiredMinRx
+	}
+}
+
+// StateFunc is called from the session go routine when the session state
+// changes. Failed is true if an Up session went down for another reason than
+// the remote system administratively disabling it (RFC5882 section 3.2).
+type StateFunc func(state State, diag Diag, failed bool)
+
+// Client is a user of a session, the session is shared by the clients with
+// the same interface and addresses, e.g., the adjacencies of both levels.
+type Client struct {
+	m      *Manager
+	s      *Session
+	stateF StateFunc
+}
+
+// SessionInfo is the state of a session for the yang model, the intervals are
+// in microseconds.
+type SessionInfo struct {
+	Interface   string `json:"interface"`
+	Local       net.IP `json:"source-addr"`
+	Remote      net.IP `json:"dest-addr"`
+	LocalState  State  `json:"local-state"`
+	RemoteState State  `json:"remote-state"`
+	LocalDiag   Diag   `json:"local-diagnostic"`
+	RemoteDiag  Diag   `json:"remote-diagnostic"`
+	LocalDisc   uint32 `json:"local-discriminator"`
+	RemoteDisc  uint32 `json:"remote-discriminator"`
+	Multiplier  uint8  `json:"local-multiplier"`
+	RemoteMult  uint8  `json:"remote-multiplier"`
+	TxInterval  uint32 `json:"negotiated-tx-interval"`
+	RxInterval  uint32 `json:"negotiated-rx-interval"`
+	DetectTime  uint32 `json:"detection-time"`
+}
+
+// Session is a BFD session to a neighbor on a directly connected interface.
+// The session state is owned by the session go routine.
+type Session struct {
+	Intf   string
+	Local  net.IP
+	Remote net.IP
+
+	cfg     Config
+	send    func([]byte) error
+	clients map[*Client]bool
+	rxC     chan *Packet
+	rpC     chan func()
+	quit    chan bool
+	done    chan bool
+
+	state        State
+	diag         Diag
+	localDisc    uint32
+	desiredMinTx time.Duration // current, slow while not Up
+	poll         bool          // poll sequence in progress
+	detect       *time.Timer
+
+	// Remote state.
+	heard       bool
+	remoteState State
+	remoteDiag  Diag
+	remoteDisc  uint32
+	remoteMult  uint8
+	remoteMinRx time.Duration
+	remoteMinTx time.Duration
+}
+
+func (s *Session) String() string {
+	return fmt.Sprintf("BFDSession(%s %s->%s %s)", s.Intf, s.Local, s.Remote, s.state)
+}
+
+// newSession returns a new session in the Down state, run starts it.
+func newSession(intf string, local, remote net.IP, disc uint32, cfg Config, send func([]byte) error) *Session {
+	cfg.setDefaults()
+	s := &Session{
+		Intf:        intf,
+		Local:       local,
+		Remote:      remote,
+		cfg:         cfg,
+		send:        send,
+		clients:     make(map[*Client]bool),
+		rxC:         make(chan *Packet, 10),
+		rpC:         make(chan func()),
+		quit:        make(chan bool),
+		done:        make(chan bool),
+		state:       StateDown,
+		localDisc:   disc,
+		remoteState: StateDown,
+		remoteMinRx: time.Microsecond,
+	}
+	s.desiredMinTx = s.slowTx()
+	return s
+}
+
+func usec(d time.Duration) uint32 {
+	return uint32(d / time.Microsecond)
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// slowTx returns the transmit interval used while the session is not Up.
+func (s *Session) slowTx() time.Duration {
+	return maxDuration(s.cfg.DesiredMinTx, slowTxInterval)
+}
+
+// txInterval returns the negotiated transmit interval.
+func (s *Session) txInterval() time.Duration {
+	return maxDuration(s.desiredMinTx, s.remoteMinRx)
+}
+
+// detectTime returns the detection time of the session (RFC5880 section
+// 6.8.4).
+func (s *Session) detectTime() time.Duration {
+	return time.Duration(s.remoteMult) * maxDuration(s.cfg.RequiredMinRx, s.remoteMinTx)
+}
+
+// jitter returns the transmit interval reduced by 0 to 25 percent, at least 10
+// percent with a detect multiplier of 1 (RFC5880 section 6.8.7).
+func (s *Session) jitter(ival time.Duration) time.Duration {
+	pct := 75 + rand.Intn(26)
+	if s.cfg.Multiplier == 1 {
+		pct = 75 + rand.Intn(16)
+	}
+	return ival * time.Duration(pct) / 100
+}
+
+// transmit sends a Control packet, final answers a poll.
+func (s *Session) transmit(final bool) {
+	// The remote system doesn't want any periodic packets.
+	if s.heard && s.remoteMinRx == 0 && !final {
+		return
+	}
+	p := &Packet{
+		Diag:          s.diag,
+		State:         s.state,
+		Poll:          s.poll && !final,
+		Final:         final,
+		DetectMult:    s.cfg.Multiplier,
+		MyDisc:        s.localDisc,
+		YourDisc:      s.remoteDisc,
+		DesiredMinTx:  usec(s.desiredMinTx),
+		RequiredMinRx: usec(s.cfg.RequiredMinRx),
+	}
+	if err := s.send(p.Encode(make([]byte, PacketLen))); err != nil {
+		Debug(DbgFAdj, "%s: error sending: %s", s, err)
+	}
+}
+
+// setState changes the session state and notifies the owner.
+func (s *Session) setState(state State, diag Diag) {
+	Debug(DbgFAdj, "%s: state change to %s diag %s", s, state, diag)
+	failed := s.state == StateUp && state == StateDown && s.remoteState != StateAdminDown
+	s.state, s.diag = state, diag
+	if state == StateUp {
+		// Leave slow start with a poll sequence (RFC5880 section 6.8.3).
+		if s.desiredMinTx != s.cfg.DesiredMinTx {
+			s.desiredMinTx = s.cfg.DesiredMinTx
+			s.poll = true
+		}
+	} else {
+		s.desiredMinTx = s.slowTx()
+		s.poll = false
+	}
+	s.transmit(false)
+	for c := range s.clients {
+		c.stateF(state, diag, failed)
+	}
+}
+
+// receive processes a received Control packet (RFC5880 section 6.8.6).
+func (s *Session) receive(p *Packet) {
+	if p.YourDisc != 0 && p.YourDisc != s.localDisc {
+		return
+	}
+	s.heard = true
+	s.remoteDisc = p.MyDisc
+	s.remoteState = p.State
+	s.remoteDiag = p.Diag
+	s.remoteMult = p.DetectMult
+	s.remoteMinRx = time.Duration(p.RequiredMinRx) * time.Microsecond
+	s.remoteMinTx = time.Duration(p.DesiredMinTx) * time.Microsecond
+	if p.Final {
+		s.poll = false
+	}
+	if s.state == StateAdminDown {
+		return
+	}
+
+	switch {
+	case p.State == StateAdminDown:
+		if s.state != StateDown {
+			s.setState(StateDown, DiagNeighborDown)
+		}
+	case s.state == StateDown:
+		if p.State == StateDown {
+			s.setState(StateInit, DiagNone)
+		} else if p.State == StateInit {
+			s.setState(StateUp, DiagNone)
+		}
+	case s.state == StateInit:
+		if p.State == StateInit || p.State == StateUp {
+			s.setState(StateUp, DiagNone)
+		}
+	case s.state == StateUp:
+		if p.State == StateDown {
+			s.setState(StateDown, DiagNeighborDown)
+		}
+	}
+	if p.Poll {
+		s.transmit(true)
+	}
+
+	if s.detect != nil {
+		s.detect.Stop()
+	}
+	s.detect = time.NewTimer(s.detectTime())
+}
+
+// expire handles the expiration of the detection time.
+func (s *Session) expire() {
+	s.detect = nil
+	if s.state != StateInit && s.state != StateUp {
+		return
+	}
+	s.remoteDisc = 0
+	s.remoteState = StateDown
+	s.setState(StateDown, DiagDetectExpired)
+}
+
+func (s *Session) info() *SessionInfo {
+	si := &SessionInfo{
+		Interface:   s.Intf,
+		Local:       s.Local,
+		Remote:      s.Remote,
+		LocalState:  s.state,
+		RemoteState: s.remoteState,
+		LocalDiag:   s.diag,
+		RemoteDiag:  s.remoteDiag,
+		LocalDisc:   s.localDisc,
+		RemoteDisc:  s.remoteDisc,
+		Multiplier:  s.cfg.Multiplier,
+		RemoteMult:  s.remoteMult,
+		TxInterval:  usec(s.txInterval()),
+	}
+	if s.heard {
+		si.RxInterval = usec(maxDuration(s.cfg.RequiredMinRx, s.remoteMinTx))
+		si.DetectTime = usec(s.detectTime())
+	}
+	return si
+}
+
+// run is the session go routine.
+func (s *Session) run() {
+	defer close(s.done)
+
+	tx := time.NewTimer(0)
+	defer tx.Stop()
+	for {
+		var detectC <-chan time.Time
+		if s.detect != nil {
+			detectC = s.detect.C
+		}
+		select {
+		case p := <-s.rxC:
+			s.receive(p)
+		case <-tx.C:
+			s.transmit(false)
+			tx.Reset(s.jitter(s.txInterval()))
+		case <-detectC:
+			s.expire()
+		case f := <-s.rpC:
+			f()
+		case <-s.quit:
+			// Let the remote system know the session is going away.
+			s.state, s.diag = StateAdminDown, DiagAdminDown
+			s.transmit(false)
+			if s.detect != nil {
+				s.detect.Stop()
+			}
+			return
+		}
+	}
+}
+
+// input queues a received packet, it is dropped if the session is busy or
+// closed.
+func (s *Session) input(p *Packet) {
+	select {
+	case s.rxC <- p:
+	default:
+	}
+}
+
+// do runs f in the session go routine, it returns false if the session is
+// closed.
+func (s *Session) do(f func()) bool {
+	c := make(chan bool)
+	select {
+	case s.rpC <- func() { f(); close(c) }:
+		<-c
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// Info returns the state of the session of the client, nil if closed.
+func (c *Client) Info() *SessionInfo {
+	var si *SessionInfo
+	c.s.do(func() { si = c.s.info() })
+	return si
+}
@@ -0,0 +1,223 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+package main
+
+import (
+	"fmt"
+	"github.com/choppsv1/goisis/bfd"
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"time"
+)
+
+// BFDConfig is the BFD configuration of an interface, the intervals are in
+// microseconds. MinInterval sets both the transmit and receive intervals.
+type BFDConfig struct {
+	Enable        bool   `json:"enable"`
+	Multiplier    uint8  `json:"local-multiplier,omitempty"`
+	DesiredMinTx  uint32 `json:"desired-min-tx-interval,omitempty"`
+	RequiredMinRx uint32 `json:"required-min-rx-interval,omitempty"`
+	MinInterval   uint32 `json:"min-interval,omitempty"`
+}
+
+// GlbBFD is the BFD session manager, nil if BFD isn't running.
+var GlbBFD *bfd.Manager
+
+// validate checks that only one of the interval configuration types is used.
+func (bc *BFDConfig) validate() error {
+	if bc.MinInterval != 0 && (bc.DesiredMinTx != 0 || bc.RequiredMinRx != 0) {
+		return fmt.Errorf("bfd min-interval with tx-rx intervals")
+	}
+	return nil
+}
+
+// sessionConfig returns the session parameters.
+func (bc *BFDConfig) sessionConfig() bfd.Config {
+	tx, rx := bc.DesiredMinTx, bc.RequiredMinRx
+	if bc.MinInterval != 0 {
+		tx, rx = bc.MinInterval, bc.MinInterval
+	}
+	return bfd.Config{
+		Multiplier:    bc.Multiplier,
+		DesiredMinTx:  time.Duration(tx) * time.Microsecond,
+		RequiredMinRx: time.Duration(rx) * time.Microsecond,
+	}
+}
+
+// StartBFD starts the BFD session manager if an interface has BFD enabled.
+func StartBFD(config *Config) {
+	enabled := false
+	for _, ic := range config.Interfaces {
+		if ic.BFD != nil && ic.BFD.Enable {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return
+	}
+	m := bfd.NewManager()
+	if err := m.Listen(GlbQuit); err != nil {
+		Info("BFD disabled: %s", err)
+		return
+	}
+	GlbBFD = m
+}
+
+// bfdConfig returns the BFD configuration of the circuit, nil if BFD isn't
+// enabled.
+func (cb *CircuitBase) bfdConfig() *BFDConfig {
+	if GlbBFD == nil || cb.config.BFD == nil || !cb.config.BFD.Enable {
+		return nil
+	}
+	return cb.config.BFD
+}
+
+// bfdEnabled returns the BFD-enabled TLV entries (RFC6213) for our IIH, a
+// topology and address family pair for which we have an address.
+func (cb *CircuitBase) bfdEnabled() []tlv.BFDEnabled {
+	if cb.bfdConfig() == nil {
+		return nil
+	}
+	mtids := cb.Topologies()
+	if mtids == nil {
+		mtids = []uint16{tlv.MTIDStandard}
+	}
+	var ents []tlv.BFDEnabled
+	for _, mtid := range mtids {
+		if len(cb.v4addrs) != 0 && mtid != tlv.MTIDIPv6 {
+			ents = append(ents, tlv.BFDEnabled{MTID: mtid, NLPID: clns.NLPIDIPv4})
+		}
+		if len(cb.v6lladdrs) != 0 && (mtid == tlv.MTIDStandard || mtid == tlv.MTIDIPv6) {
+			ents = append(ents, tlv.BFDEnabled{MTID: mtid, NLPID: clns.NLPIDIPv6})
+		}
+	}
+	return ents
+}
+
+// decodeBFDEnabled returns the BFD-Enabled TLV entries of the IIH TLVs.
+func decodeBFDEnabled(tlvs []tlv.Data) []tlv.BFDEnabled {
+	var ents []tlv.BFDEnabled
+	for _, t := range tlvs {
+		decoded, err := t.BFDEnabledDecode()
+		if err != nil {
+			Info("ERROR: processing BFD-Enabled TLV: %s", err)
+			continue
+		}
+		ents = append(ents, decoded...)
+	}
+	return ents
+}
+
+// commonBFD returns the network layer protocols of the topology and protocol
+// pairs for which BFD is enabled on both sides.
+func commonBFD(ours, theirs []tlv.BFDEnabled) map[clns.NLPID]bool {
+	nlpids := make(map[clns.NLPID]bool)
+	for _, o := range ours {
+		for _, t := range theirs {
+			if o == t {
+				nlpids[o.NLPID] = true
+			}
+		}
+	}
+	return nlpids
+}
+
+// BFDHold returns true if the adjacency must not come Up yet. BFD is enabled
+// for a common topology and protocol on both sides and the BFD sessions of
+// those protocols aren't all Up (RFC6213 section 3.2). The sessions are opened
+// while the adjacency is held.
+func (link *LinkLAN) BFDHold(a *Adj) bool {
+	nlpids := commonBFD(link.circuit.bfdEnabled(), a.bfdEnabled)
+	if len(nlpids) == 0 {
+		return false
+	}
+	link.startBFD(a)
+	held := false
+	for _, si := range a.bfdInfo() {
+		var nlpid clns.NLPID = clns.NLPIDIPv6
+		if si.Remote.To4() != nil {
+			nlpid = clns.NLPIDIPv4
+		}
+		if nlpids[nlpid] && si.LocalState != bfd.StateUp {
+			held = true
+		}
+	}
+	a.bfdHeld = held
+	return held
+}
+
+// bfdUp brings up an adjacency held for BFD once its sessions are Up, returns
+// true if DIS election should be re-run.
+func (link *LinkLAN) bfdUp(a *Adj) bool {
+	if link.srcidMap[a.sysid] != a || !a.bfdHeld || link.BFDHold(a) {
+		return false
+	}
+	a.state = AdjStateUp
+	a.lastUpTime = time.Now()
+	Trap("TRAP: AdjacencyStateChange: Up: %s", a)
+	a.allocSIDs()
+	link.updb.AdjChange(link.circuit, true, a.neighbor())
+	return true
+}
+
+// startBFD opens the BFD sessions of an adjacency, one for each address family
+// both sides have an interface address for, if not already open. The
+// adjacency, not a later one with the same neighbor, is expired if a session
+// fails.
+func (link *LinkLAN) startBFD(a *Adj) {
+	bc := link.circuit.bfdConfig()
+	if bc == nil || a.bfdClients != nil {
+		return
+	}
+	cb := link.circuit.CircuitBase
+	type pair struct{ local, remote net.IP }
+	var pairs []pair
+	if len(cb.v4addrs) != 0 && len(a.v4addrs) != 0 {
+		pairs = append(pairs, pair{cb.v4addrs[0].IP, a.v4addrs[0]})
+	}
+	if len(cb.v6lladdrs) != 0 && len(a.v6addrs) != 0 {
+		pairs = append(pairs, pair{cb.v6lladdrs[0].IP, a.v6addrs[0]})
+	}
+	sysid := a.sysid
+	stateF := func(state bfd.State, diag bfd.Diag, failed bool) {
+		if state == bfd.StateUp {
+			// Don't block the session, stopBFD waits on it.
+			go func() { link.bfdUpC <- a }()
+		} else if failed {
+			Trap("TRAP: BFD session down (%s): %s on %s", diag, sysid, link)
+			// Don't block the session, stopBFD waits on it.
+			go link.ExpireAdj(a)
+		}
+	}
+	for _, p := range pairs {
+		c, err := GlbBFD.Open(cb.intf.Name, p.local, p.remote, bc.sessionConfig(), stateF)
+		if err != nil {
+			Info("%s: error opening BFD session to %s: %s", a, p.remote, err)
+			continue
+		}
+		a.bfdClients = append(a.bfdClients, c)
+	}
+}
+
+// stopBFD closes the BFD sessions of an adjacency.
+func (a *Adj) stopBFD() {
+	for _, c := range a.bfdClients {
+		c.Close()
+	}
+	a.bfdClients = nil
+}
+
+// bfdInfo returns the state of the BFD sessions of an adjacency.
+func (a *Adj) bfdInfo() []*bfd.SessionInfo {
+	var infos []*bfd.SessionInfo
+	for _, c := range a.bfdClients {
+		if si := c.Info(); si != nil {
+			infos = append(infos, si)
+		}
+	}
+	return infos
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/tlv"
+	"testing"
+)
+
+func TestCommonBFD(t *testing.T) {
+	v4 := tlv.BFDEnabled{MTID: tlv.MTIDStandard, NLPID: clns.NLPIDIPv4}
+	v6 := tlv.BFDEnabled{MTID: tlv.MTIDStandard, NLPID: clns.NLPIDIPv6}
+	mtv6 := tlv.BFDEnabled{MTID: tlv.MTIDIPv6, NLPID: clns.NLPIDIPv6}
+	tests := []struct {
+		name           string
+		ours, theirs   []tlv.BFDEnabled
+		wantV4, wantV6 bool
+	}{
+		{"none", nil, nil, false, false},
+		{"ours only", []tlv.BFDEnabled{v4, v6}, nil, false, false},
+		{"theirs only", nil, []tlv.BFDEnabled{v4}, false, false},
+		{"ipv4", []tlv.BFDEnabled{v4, v6}, []tlv.BFDEnabled{v4}, true, false},
+		{"both", []tlv.BFDEnabled{v4, v6}, []tlv.BFDEnabled{v6, v4}, true, true},
+		{"topology mismatch", []tlv.BFDEnabled{v6}, []tlv.BFDEnabled{mtv6}, false, false},
+	}
+	for _, test := range tests {
+		nlpids := commonBFD(test.ours, test.theirs)
+		if nlpids[clns.NLPIDIPv4] != test.wantV4 || nlpids[clns.NLPIDIPv6] != test.wantV6 {
+			t.Errorf("%s: got %v", test.name, nlpids)
+		}
+	}
+}
@@ -82,6 +82,7 @@ type CircuitBase struct {
 	v4addrs   []net.IPNet
 	v6addrs   []net.IPNet
 	v6lladdrs []net.IPNet
+	config    *InterfaceConfig
 	outpkt    chan []byte
 	quit      <-chan bool
 }
@@ -108,6 +109,7 @@ func NewCircuitBase(ifname string, lf clns.LevelFlag, cdb *CircuitDB, updb [2]*u
 	if err != nil {
 		return nil, err
 	}
+	cb.config = GlbConfig.intfConfig(ifname)
 
 	// Get the L3 addrs for this circuit
 	var addrs []net.Addr
@@ -117,6 +119,10 @@ func NewCircuitBase(ifname string, lf clns.LevelFlag, cdb *CircuitDB, updb [2]*u
 	}
 	for _, addr := range addrs {
 		ipnet := addr.(*net.IPNet)
+		if ipnet.IP.IsLoopback() {
+			// Host local, e.g., 127.0.0.1 and ::1 on the loopback.
+			continue
+		}
 		ipv4 := ipnet.IP.To4()
 		if ipv4 != nil {
 			ipnet.IP = ipv4
@@ -130,14 +136,6 @@ func NewCircuitBase(ifname string, lf clns.LevelFlag, cdb *CircuitDB, updb [2]*u
 		}
 	}
 
-	// Get raw socket connection for interface send/receive
-
-	cb.sock, err = raw.NewInterfaceSocket(cb.intf.Name)
-	if err != nil {
-		fmt.Printf("Error creating interface: %s\n", err)
-		return nil, err
-	}
-
 	return cb, nil
 }
 
@@ -191,6 +189,18 @@ func NewCircuitLAN(cb *CircuitBase, lf clns.LevelFlag) (*CircuitLAN, error) {
 		CircuitBase: cb,
 	}
 
+	if len(cb.intf.HardwareAddr) != len(ether.MAC{}) {
+		return nil, fmt.Errorf("%s has no MAC address, it can only be passive", cb.intf.Name)
+	}
+
+	// Get raw socket connection for interface send/receive
+
+	cb.sock, err = raw.NewInterfaceSocket(cb.intf.Name)
+	if err != nil {
+		fmt.Printf("Error creating interface: %s\n", err)
+		return nil, err
+	}
+
 	// IS-IS LAN BPF filter
 	filter, err := bpf.Assemble([]bpf.Instruction{
 		// 0: Load 2 bytes from offset 12 (ethertype)
@@ -314,13 +324,13 @@ func (c *CircuitLAN) ChgFlag(flag update.SxxFlag, lspid *clns.LSPID, set bool, l
 	}
 }
 
-func (c *CircuitLAN) Addrs(v4, linklocal bool) []net.IPNet {
+func (cb *CircuitBase) Addrs(v4, linklocal bool) []net.IPNet {
 	if v4 {
-		return c.v4addrs
+		return cb.v4addrs
 	} else if linklocal {
-		return c.v6lladdrs
+		return cb.v6lladdrs
 	} else {
-		return c.v6addrs
+		return cb.v6addrs
 	}
 }
 
@@ -332,8 +342,13 @@ func (c *CircuitLAN) IsP2P() bool {
 	return false
 }
 
-func (c *CircuitLAN) MTU() uint {
-	return uint(c.intf.MTU)
+// IsPassive returns true if the circuit only advertises its prefixes.
+func (c *CircuitLAN) IsPassive() bool {
+	return false
+}
+
+func (cb *CircuitBase) MTU() uint {
+	return uint(cb.intf.MTU)
 }
 
 func (c *CircuitLAN) Send(pdu []byte, li clns.Lindex) {
@@ -353,28 +368,103 @@ func (c *CircuitLAN) Adjacencies(C chan<- interface{}, li clns.Lindex, forPN boo
 
 // IPReach arranges for tlvb.IPInfo to be sent on the provided change for all
 // IPv4 reachability associated with this circuit.
-func (c *CircuitLAN) IPReach(ipv4 bool, C chan<- interface{}, li clns.Lindex) {
+func (cb *CircuitBase) IPReach(ipv4 bool, C chan<- interface{}, li clns.Lindex) {
 	// XXX a circuit probably needs it's own interface address go routine.
 	// For now just spawn a go routine to act like one, we don't support
 	// dynamic address changes yet.
 	go func() {
-		for _, a := range c.Addrs(ipv4, false) {
+		for _, a := range cb.Addrs(ipv4, false) {
 			C <- tlv.IPInfo{
 				Metric: clns.DefExtIPMetric,
 				Ipnet:  a,
+				Node:   cb.isNodePrefix(&a),
+				Tags:   cb.config.Tags,
+				Tags64: cb.config.Tags64,
+				SIDs:   GlbConfig.SR.prefixSIDs(&a, cb.isNodePrefix(&a)),
 			}
 		}
 		C <- tlv.Done{}
 	}()
 }
 
+// linkTE returns the traffic engineering attributes of the circuit or nil if
+// not configured. There is no neighbor address on a LAN as the link is to the
+// pseudo-node (RFC5305 section 3.3).
+func (cb *CircuitBase) linkTE() *tlv.LinkTE {
+	tc := cb.config.TE
+	if tc == nil {
+		return nil
+	}
+	te := &tlv.LinkTE{
+		AdminGroup:    tc.AdminGroup,
+		ExtAdminGroup: tc.ExtAdminGroup,
+		MaxBW:         tc.MaxBW,
+		MaxResvBW:     tc.MaxResvBW,
+		UnresvBW:      tc.UnresvBW,
+		TEMetric:      tc.TEMetric,
+	}
+	if len(te.UnresvBW) == 0 && te.MaxResvBW != 0 {
+		te.UnresvBW = make([]float32, tlv.TEPriorities)
+		for i := range te.UnresvBW {
+			te.UnresvBW[i] = te.MaxResvBW
+		}
+	}
+	if len(cb.v4addrs) != 0 {
+		te.LocalAddr = cb.v4addrs[0].IP
+	}
+	if len(cb.v6addrs) != 0 {
+		te.LocalAddr6 = cb.v6addrs[0].IP
+	}
+	return te
+}
+
+// srlg returns the shared risk link groups of the link to nodeid for each
+// address family of the circuit, without an IPv4 or IPv6 address the IPv4 SRLG
+// is for an unnumbered link.
+func (cb *CircuitBase) srlg(nodeid clns.NodeID) []tlv.SRLG {
+	values := cb.config.SRLG
+	if len(values) == 0 {
+		return nil
+	}
+	var srlgs []tlv.SRLG
+	if len(cb.v4addrs) != 0 || len(cb.v6addrs) == 0 {
+		s := tlv.SRLG{Nodeid: nodeid, Values: values}
+		if len(cb.v4addrs) != 0 {
+			s.Numbered = true
+			s.LocalAddr = cb.v4addrs[0].IP
+		}
+		srlgs = append(srlgs, s)
+	}
+	if len(cb.v6addrs) != 0 {
+		srlgs = append(srlgs, tlv.SRLG{Nodeid: nodeid, LocalAddr: cb.v6addrs[0].IP, Values: values})
+	}
+	return srlgs
+}
+
+// isNodePrefix returns true if the prefix identifies this router, a host prefix
+// on a loopback interface or one with the node flag configured.
+func (cb *CircuitBase) isNodePrefix(ipnet *net.IPNet) bool {
+	if ones, bits := ipnet.Mask.Size(); ones != bits {
+		return false
+	}
+	return cb.intf.Flags&net.FlagLoopback != 0 || cb.config.NodeFlag
+}
+
 // YangData returns the yang data for this circuit, it is called within the
 // circuit DB go routine.
 func (c *CircuitLAN) YangData() (*YangInterface, error) {
 	yd := &YangInterface{
 		Name:      c.intf.Name,
 		LevelType: c.lf,
+		Tags:      c.config.Tags,
+		Tags64:    c.config.Tags64,
+		NodeFlag:  c.config.NodeFlag,
+		TE:        c.config.TE,
+		SRLG:      c.config.SRLG,
 	}
+	yd.Topologies = c.Topologies()
+	yd.FRR = c.config.FRR
+	yd.BFD = c.config.BFD
 	for _, levlink := range c.levlink {
 		if levlink == nil {
 			continue
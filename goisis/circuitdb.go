@@ -12,16 +12,22 @@ import (
 	// . "github.com/choppsv1/goisis/logging" // nolint
 )
 
+// DBCircuit is a circuit of the circuit DB, an IS-IS or a passive circuit.
+type DBCircuit interface {
+	Name() string
+	YangData() (*YangInterface, error)
+}
+
 // CircuitDB is a database of circuits we run on.
 type CircuitDB struct {
-	circuits map[string]Circuit
+	circuits map[string]DBCircuit
 	rpC      chan RPC
 }
 
 // NewCircuitDB allocate and initialize a new circuit database.
 func NewCircuitDB() *CircuitDB {
 	cdb := &CircuitDB{
-		circuits: make(map[string]Circuit),
+		circuits: make(map[string]DBCircuit),
 		rpC:      make(chan RPC),
 	}
 
@@ -30,8 +36,9 @@ func NewCircuitDB() *CircuitDB {
 	return cdb
 }
 
-// NewCircuit creates a circuit enabled for the given levels.
-func (cdb *CircuitDB) NewCircuit(ifname string, lf clns.LevelFlag, updb [2]*update.DB) (*CircuitLAN, error) {
+// NewCircuit creates a circuit enabled for the given levels, a passive circuit
+// for passive and loopback interfaces.
+func (cdb *CircuitDB) NewCircuit(ifname string, lf clns.LevelFlag, updb [2]*update.DB) (DBCircuit, error) {
 	ifname, err := resolveIfname(ifname)
 	if err != nil {
 		return nil, err
@@ -44,11 +51,19 @@ func (cdb *CircuitDB) NewCircuit(ifname string, lf clns.LevelFlag, updb [2]*upda
 	if err != nil {
 		return nil, err
 	}
+	if cb.isPassive() {
+		cp := NewCircuitPassive(cb, lf)
+		cdb.circuits[ifname] = cp
+		return cp, nil
+	}
 	// Check interface type and allocate LAN or P2P
 	cll, err := NewCircuitLAN(cb, lf)
+	if err != nil {
+		return nil, err
+	}
 	cdb.circuits[ifname] = cll
 
-	return cll, err
+	return cll, nil
 }
 
 // func (cdb *CircuitDB) yangData(name string) interface{} {
@@ -77,6 +92,15 @@ func (cdb *CircuitDB) yangData(name string) interface{} {
 	return append(ifdata, yd)
 }
 
+// IsCircuit returns true if the interface is one of our circuits.
+func (cdb *CircuitDB) IsCircuit(ifname string) bool {
+	i, _ := DoRPC(cdb.rpC, func() interface{} {
+		_, ok := cdb.circuits[ifname]
+		return ok
+	})
+	return i.(bool)
+}
+
 // YangData returns the yang data for an interface
 func (cdb *CircuitDB) YangData(key string) ([]*YangInterface, error) {
 	i, err := DoRPC(cdb.rpC, func() interface{} { return cdb.yangData(key) })
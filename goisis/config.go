@@ -0,0 +1,514 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/goisis/update"
+	"github.com/choppsv1/goisis/policy"
+	"github.com/choppsv1/goisis/tlv"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+// Config is the instance configuration read from the file given with the
+// -config option. The JSON follows the naming of the IS-IS yang model where
+// possible. Values not present use the defaults.
+type Config struct {
+	LSPGen      LevLSPGenConfig      `json:"lsp-generation"`
+	SPFDelay    LevSPFDelayConfig    `json:"spf-delay"`
+	ULoop       *ULoopConfig         `json:"microloop-avoidance,omitempty"`
+	Overload    OverloadConfig       `json:"overload"`
+	AttachedBit AttachedBitConfig    `json:"attached-bit"`
+	Summaries   []SummaryConfig      `json:"summary-address,omitempty"`
+	PrefixLists []*policy.PrefixList `json:"prefix-lists,omitempty"`
+	RouteLeak   *RouteLeakConfig     `json:"route-leaking,omitempty"`
+	Redist      []RedistConfig       `json:"redistribute,omitempty"`
+	Policies    []*policy.Policy     `json:"policies,omitempty"`
+	ApplyPolicy ApplyPolicyConfig    `json:"apply-policy"`
+	Interfaces  []*InterfaceConfig   `json:"interfaces,omitempty"`
+	RouterID    RouterIDConfig       `json:"te-rid"`
+	Measure     *MeasureConfig       `json:"link-measurement,omitempty"`
+	RouterCap   *RouterCapConfig     `json:"router-capability,omitempty"`
+	SR          *SRConfig            `json:"segment-routing,omitempty"`
+	SRv6        *SRv6Config          `json:"srv6,omitempty"`
+	FlexAlgos   []FlexAlgoConfig     `json:"flex-algo,omitempty"`
+	Topologies  []string             `json:"topologies,omitempty"`
+}
+
+// RouterCapConfig holds the Router Capability (RFC7981) values we advertise.
+// With DomainWide set other levels receive the capabilities as well.
+type RouterCapConfig struct {
+	DomainWide bool      `json:"domain-wide,omitempty"`
+	NodeTags   []uint32  `json:"node-tags,omitempty"`
+	NodeMSD    []tlv.MSD `json:"node-msd,omitempty"`
+}
+
+// RouterIDConfig holds the router IDs used for TE and as the source router ID
+// of our prefixes.
+type RouterIDConfig struct {
+	IPv4 string `json:"ipv4-router-id,omitempty"`
+	IPv6 string `json:"ipv6-router-id,omitempty"`
+}
+
+// InterfaceConfig is the configuration of an interface given on the command
+// line. The hello interval is in milliseconds, with HelloMinimal the IIH hold
+// time is 1 second and the hellos are sent hello-multiplier times a second. A
+// passive interface only has its prefixes advertised.
+type InterfaceConfig struct {
+	Name         string     `json:"name"`
+	Tags         []uint32   `json:"tag,omitempty"`
+	Tags64       []uint64   `json:"tag64,omitempty"`
+	NodeFlag     bool       `json:"node-flag,omitempty"`
+	TE           *TEConfig  `json:"te,omitempty"`
+	SRLG         []uint32   `json:"srlg,omitempty"`
+	Topologies   []string   `json:"topologies,omitempty"`
+	FRR          *FRRConfig `json:"fast-reroute,omitempty"`
+	BFD          *BFDConfig `json:"bfd,omitempty"`
+	HelloInt     uint       `json:"hello-interval,omitempty"`
+	HelloMult    uint       `json:"hello-multiplier,omitempty"`
+	HelloMinimal bool       `json:"hello-minimal,omitempty"`
+	Passive      bool       `json:"passive,omitempty"`
+}
+
+// TEConfig holds the traffic engineering link attributes of an interface,
+// bandwidths are in bytes per second. The unreserved bandwidth for all
+// priorities defaults to the maximum reservable bandwidth. The first word of
+// the extended admin group must be the admin group if both are given.
+type TEConfig struct {
+	AdminGroup    *uint32   `json:"admin-group,omitempty"`
+	ExtAdminGroup []uint32  `json:"extended-admin-group,omitempty"`
+	MaxBW         float32   `json:"max-bandwidth,omitempty"`
+	MaxResvBW     float32   `json:"max-reservable-bandwidth,omitempty"`
+	UnresvBW      []float32 `json:"unreserved-bandwidth,omitempty"`
+	TEMetric      *uint32   `json:"te-metric,omitempty"`
+}
+
+// LSPGenConfig holds the LSP generation back-off timer values (milliseconds)
+type LSPGenConfig struct {
+	InitialWait   uint `json:"initial-wait,omitempty"`
+	SecondaryWait uint `json:"secondary-wait,omitempty"`
+	MaximumWait   uint `json:"maximum-wait,omitempty"`
+}
+
+// LevLSPGenConfig is the level specific LSP generation config.
+type LevLSPGenConfig struct {
+	*LSPGenConfig
+	Level1 *LSPGenConfig `json:"level-1,omitempty"`
+	Level2 *LSPGenConfig `json:"level-2,omitempty"`
+}
+
+// SPFDelayConfig holds the SPF back-off (RFC8405) timer values (milliseconds)
+type SPFDelayConfig struct {
+	InitialDelay uint `json:"initial-delay,omitempty"`
+	ShortDelay   uint `json:"short-delay,omitempty"`
+	LongDelay    uint `json:"long-delay,omitempty"`
+	HoldDown     uint `json:"hold-down,omitempty"`
+	TimeToLearn  uint `json:"time-to-learn,omitempty"`
+}
+
+// LevSPFDelayConfig is the level specific SPF back-off config.
+type LevSPFDelayConfig struct {
+	*SPFDelayConfig
+	Level1 *SPFDelayConfig `json:"level-1,omitempty"`
+	Level2 *SPFDelayConfig `json:"level-2,omitempty"`
+}
+
+// ULoopConfig holds the local microloop avoidance (RFC8333) configuration,
+// the delay of the forwarding updates after a local link down is in
+// milliseconds. It applies to the segment routing forwarding entries only.
+type ULoopConfig struct {
+	Enable bool `json:"enable"`
+	Delay  uint `json:"rib-update-delay,omitempty"`
+}
+
+// OverloadConfig holds the overload bit configuration.
+type OverloadConfig struct {
+	Status          bool `json:"status"`
+	OnStartup       uint `json:"on-startup,omitempty"` // seconds
+	WaitAdjacencies bool `json:"wait-for-adjacencies,omitempty"`
+	WaitTimeout     uint `json:"wait-for-adjacencies-timeout,omitempty"` // seconds
+}
+
+// AttachedBitConfig holds the attached bit configuration.
+type AttachedBitConfig struct {
+	Suppress bool `json:"suppress,omitempty"`        // don't set in our L1 LSP
+	Ignore   bool `json:"ignore-received,omitempty"` // no default route from ATT
+}
+
+// SummaryConfig is a summary address range used when propagating level 1
+// reachability into level 2. A zero metric uses the lowest covered metric.
+type SummaryConfig struct {
+	Prefix string `json:"prefix"`
+	Metric uint32 `json:"metric,omitempty"`
+}
+
+// RouteLeakConfig selects the level 2 prefixes leaked into level 1 by prefix
+// list name or by administrative tag, the named policy is then applied.
+type RouteLeakConfig struct {
+	PrefixList string   `json:"prefix-list,omitempty"`
+	Tags       []uint32 `json:"tags,omitempty"`
+	Policy     string   `json:"policy,omitempty"`
+}
+
+// ApplyPolicyConfig names the policies applied to the interface prefixes and
+// the level 1 prefixes propagated into level 2.
+type ApplyPolicyConfig struct {
+	Interface string `json:"interface,omitempty"`
+	Propagate string `json:"propagate,omitempty"`
+}
+
+// GlbConfig is the instance configuration.
+var GlbConfig = &Config{}
+
+// LoadConfig reads the configuration from the JSON file 'path'.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+	if path == "" {
+		return config, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, config); err != nil {
+		return nil, err
+	}
+	if ul := config.ULoop; ul != nil && ul.Enable &&
+		(config.SR == nil || !config.SR.Enable) && (config.SRv6 == nil || !config.SRv6.Enable) {
+		return nil, fmt.Errorf("microloop-avoidance requires segment-routing or srv6")
+	}
+	for _, s := range config.Summaries {
+		if _, _, err = net.ParseCIDR(s.Prefix); err != nil {
+			return nil, err
+		}
+	}
+	for i, pl := range config.PrefixLists {
+		if err = pl.Validate(); err != nil {
+			return nil, err
+		}
+		if config.prefixList(pl.Name) != config.PrefixLists[i] {
+			return nil, fmt.Errorf("duplicate prefix-list %s", pl.Name)
+		}
+	}
+	if rl := config.RouteLeak; rl != nil && rl.PrefixList != "" {
+		if config.prefixList(rl.PrefixList) == nil {
+			return nil, fmt.Errorf("route-leaking: unknown prefix-list %s", rl.PrefixList)
+		}
+	}
+	for i, p := range config.Policies {
+		if err = p.Resolve(config.PrefixLists); err != nil {
+			return nil, err
+		}
+		if config.policy(p.Name) != config.Policies[i] {
+			return nil, fmt.Errorf("duplicate policy %s", p.Name)
+		}
+	}
+	refs := []string{config.ApplyPolicy.Interface, config.ApplyPolicy.Propagate}
+	if config.RouteLeak != nil {
+		refs = append(refs, config.RouteLeak.Policy)
+	}
+	if rid := config.RouterID.IPv4; rid != "" && net.ParseIP(rid).To4() == nil {
+		return nil, fmt.Errorf("invalid ipv4-router-id %s", rid)
+	}
+	if rid := config.RouterID.IPv6; rid != "" && (net.ParseIP(rid) == nil || net.ParseIP(rid).To4() != nil) {
+		return nil, fmt.Errorf("invalid ipv6-router-id %s", rid)
+	}
+	for _, ic := range config.Interfaces {
+		if len(ic.Tags) > tlv.MaxPrefixTags || len(ic.Tags64) > tlv.MaxPrefixTags64 {
+			return nil, fmt.Errorf("interface %s: more than %d tag or %d tag64 values", ic.Name, tlv.MaxPrefixTags, tlv.MaxPrefixTags64)
+		}
+		if te := ic.TE; te != nil && len(te.UnresvBW) > tlv.TEPriorities {
+			return nil, fmt.Errorf("interface %s: more than %d unreserved-bandwidth values", ic.Name, tlv.TEPriorities)
+		}
+		if te := ic.TE; te != nil && te.TEMetric != nil && *te.TEMetric > update.MaxLinkMetric {
+			return nil, fmt.Errorf("interface %s: te-metric %d too large", ic.Name, *te.TEMetric)
+		}
+		if te := ic.TE; te != nil && len(te.ExtAdminGroup) > tlv.MaxExtAdminGroup {
+			return nil, fmt.Errorf("interface %s: more than %d extended-admin-group words", ic.Name, tlv.MaxExtAdminGroup)
+		}
+		if te := ic.TE; te != nil && te.AdminGroup != nil && len(te.ExtAdminGroup) != 0 && te.ExtAdminGroup[0] != *te.AdminGroup {
+			return nil, fmt.Errorf("interface %s: admin-group differs from extended-admin-group", ic.Name)
+		}
+		if ic.FRR != nil {
+			if err = ic.FRR.validate(); err != nil {
+				return nil, fmt.Errorf("interface %s: %s", ic.Name, err)
+			}
+		}
+		if ic.BFD != nil {
+			if err = ic.BFD.validate(); err != nil {
+				return nil, fmt.Errorf("interface %s: %s", ic.Name, err)
+			}
+		}
+		if err = ic.validateHello(); err != nil {
+			return nil, fmt.Errorf("interface %s: %s", ic.Name, err)
+		}
+	}
+	if err = config.validateTopologies(); err != nil {
+		return nil, err
+	}
+	if config.Measure != nil {
+		if err = config.Measure.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if config.SR != nil {
+		if err = config.SR.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if config.SRv6 != nil {
+		if err = config.SRv6.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err = validateFlexAlgos(config.FlexAlgos); err != nil {
+		return nil, err
+	}
+	if config.SR != nil {
+		for _, ps := range config.SR.PrefixSIDs {
+			if ps.Algo != tlv.SRAlgoSPF && config.flexAlgo(ps.Algo) == nil {
+				return nil, fmt.Errorf("segment-routing: prefix %s unknown flex-algo %d", ps.Prefix, ps.Algo)
+			}
+		}
+	}
+	for i := range config.Redist {
+		if err = config.Redist[i].validate(); err != nil {
+			return nil, err
+		}
+		refs = append(refs, config.Redist[i].Policy)
+	}
+	for _, name := range refs {
+		if name != "" && config.policy(name) == nil {
+			return nil, fmt.Errorf("unknown policy %s", name)
+		}
+	}
+	return config, nil
+}
+
+// intfConfig returns the configuration for the interface 'name', the default
+// configuration if there is none.
+func (config *Config) intfConfig(name string) *InterfaceConfig {
+	for _, ic := range config.Interfaces {
+		if ic.Name == name {
+			return ic
+		}
+	}
+	return &InterfaceConfig{Name: name}
+}
+
+// policy returns the policy named 'name' or nil.
+func (config *Config) policy(name string) *policy.Policy {
+	for _, p := range config.Policies {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// prefixList returns the prefix list named 'name' or nil.
+func (config *Config) prefixList(name string) *policy.PrefixList {
+	for _, pl := range config.PrefixLists {
+		if pl.Name == name {
+			return pl
+		}
+	}
+	return nil
+}
+
+// flexAlgo returns the configuration of flexible algorithm algo or nil.
+func (config *Config) flexAlgo(algo uint8) *FlexAlgoConfig {
+	for i := range config.FlexAlgos {
+		if config.FlexAlgos[i].Algo == uint32(algo) {
+			return &config.FlexAlgos[i]
+		}
+	}
+	return nil
+}
+
+// levConfig returns the common values overridden by the level specific values
+// that are set, nil if neither is given.
+func (lc *LevLSPGenConfig) levConfig(li clns.Lindex) *LSPGenConfig {
+	lev := lc.Level1
+	if li == 1 {
+		lev = lc.Level2
+	}
+	if lev == nil || lc.LSPGenConfig == nil {
+		if lev != nil {
+			return lev
+		}
+		return lc.LSPGenConfig
+	}
+	c := *lc.LSPGenConfig
+	setIfNonZero(&c.InitialWait, lev.InitialWait)
+	setIfNonZero(&c.SecondaryWait, lev.SecondaryWait)
+	setIfNonZero(&c.MaximumWait, lev.MaximumWait)
+	return &c
+}
+
+// levConfig returns the common values overridden by the level specific values
+// that are set, nil if neither is given.
+func (lc *LevSPFDelayConfig) levConfig(li clns.Lindex) *SPFDelayConfig {
+	lev := lc.Level1
+	if li == 1 {
+		lev = lc.Level2
+	}
+	if lev == nil || lc.SPFDelayConfig == nil {
+		if lev != nil {
+			return lev
+		}
+		return lc.SPFDelayConfig
+	}
+	c := *lc.SPFDelayConfig
+	setIfNonZero(&c.InitialDelay, lev.InitialDelay)
+	setIfNonZero(&c.ShortDelay, lev.ShortDelay)
+	setIfNonZero(&c.LongDelay, lev.LongDelay)
+	setIfNonZero(&c.HoldDown, lev.HoldDown)
+	setIfNonZero(&c.TimeToLearn, lev.TimeToLearn)
+	return &c
+}
+
+func setIfNonZero(v *uint, lev uint) {
+	if lev != 0 {
+		*v = lev
+	}
+}
+
+func msecOrDefault(msec uint, def time.Duration) time.Duration {
+	if msec == 0 {
+		return def
+	}
+	return time.Duration(msec) * time.Millisecond
+}
+
+// ApplyUpdate configures the level specific update process values.
+func (config *Config) ApplyUpdate(li clns.Lindex, db *update.DB) {
+	initial := update.DefLSPGenInitialWait
+	secondary := update.DefLSPGenSecondaryWait
+	max := update.DefLSPGenMaximumWait
+	if lc := config.LSPGen.levConfig(li); lc != nil {
+		initial = msecOrDefault(lc.InitialWait, initial)
+		secondary = msecOrDefault(lc.SecondaryWait, secondary)
+		max = msecOrDefault(lc.MaximumWait, max)
+	}
+	db.SetLSPGenTimers(initial, secondary, max)
+
+	sinitial := update.DefSPFInitialDelay
+	short := update.DefSPFShortDelay
+	long := update.DefSPFLongDelay
+	holddown := update.DefSPFHoldDown
+	learn := update.DefSPFTimeToLearn
+	if sc := config.SPFDelay.levConfig(li); sc != nil {
+		sinitial = msecOrDefault(sc.InitialDelay, sinitial)
+		short = msecOrDefault(sc.ShortDelay, short)
+		long = msecOrDefault(sc.LongDelay, long)
+		holddown = msecOrDefault(sc.HoldDown, holddown)
+		learn = msecOrDefault(sc.TimeToLearn, learn)
+	}
+	db.SetSPFDelayTimers(sinitial, short, long, holddown, learn)
+
+	if ul := config.ULoop; ul != nil && ul.Enable {
+		db.SetMicroloopAvoidance(msecOrDefault(ul.Delay, update.DefULoopDelay))
+	}
+
+	if rid := &config.RouterID; rid.IPv4 != "" || rid.IPv6 != "" {
+		db.SetRouterID(net.ParseIP(rid.IPv4), net.ParseIP(rid.IPv6))
+	}
+
+	ol := &config.Overload
+	if ol.OnStartup != 0 || ol.WaitAdjacencies {
+		var waitAdj time.Duration
+		if ol.WaitAdjacencies {
+			waitAdj = update.DefWaitAdjTimeout
+			if ol.WaitTimeout != 0 {
+				waitAdj = time.Duration(ol.WaitTimeout) * time.Second
+			}
+		}
+		db.SetOverloadOnStartup(time.Duration(ol.OnStartup)*time.Second, waitAdj)
+	}
+	if ol.Status {
+		db.SetOverload(true)
+	}
+
+	att := &config.AttachedBit
+	if att.Suppress || att.Ignore {
+		db.SetAttachedBit(att.Suppress, att.Ignore)
+	}
+
+	if li == 1 && len(config.Summaries) > 0 {
+		var summaries []update.Summary
+		for _, s := range config.Summaries {
+			_, ipnet, _ := net.ParseCIDR(s.Prefix)
+			if ip := ipnet.IP.To4(); ip != nil {
+				ipnet.IP = ip
+			}
+			summaries = append(summaries, update.Summary{Prefix: *ipnet, Metric: s.Metric})
+		}
+		db.SetSummaries(summaries)
+	}
+
+	if rl := config.RouteLeak; li == 0 && rl != nil {
+		db.SetLeakPolicy(&update.LeakPolicy{
+			PrefixList: config.prefixList(rl.PrefixList),
+			Tags:       rl.Tags,
+		})
+		if rl.Policy != "" {
+			db.SetPolicy(update.PolicyLeak, config.policy(rl.Policy))
+		}
+	}
+
+	if ap := &config.ApplyPolicy; ap.Interface != "" {
+		db.SetPolicy(update.PolicyInterface, config.policy(ap.Interface))
+	}
+	if ap := &config.ApplyPolicy; li == 1 && ap.Propagate != "" {
+		db.SetPolicy(update.PolicyPropagate, config.policy(ap.Propagate))
+	}
+
+	if rc := config.routerCap(); rc != nil {
+		db.SetRouterCap(rc)
+	}
+	if sc := config.SRv6; sc != nil && sc.Enable {
+		db.SetSRv6Locators(sc.locators())
+	}
+	if mtids := config.mtids(); mtids != nil {
+		db.SetTopologies(mtids)
+	}
+}
+
+// routerCap returns the Router Capability to advertise or nil if none is
+// configured. Segment routing adds its capabilities and the flexible
+// algorithms their participation and definitions.
+func (config *Config) routerCap() *tlv.RouterCap {
+	rcc := config.RouterCap
+	sc := config.SR
+	if sc != nil && !sc.Enable {
+		sc = nil
+	}
+	v6 := config.SRv6 != nil && config.SRv6.Enable
+	if rcc == nil && sc == nil && !v6 && len(config.FlexAlgos) == 0 {
+		return nil
+	}
+	rc := &tlv.RouterCap{}
+	if rcc != nil {
+		rc.Flood = rcc.DomainWide
+		rc.NodeTags = rcc.NodeTags
+		rc.NodeMSD = rcc.NodeMSD
+	}
+	if sc != nil {
+		rc.SR = sc.srCap()
+		rc.SRAlgos = []uint8{tlv.SRAlgoSPF}
+		rc.SRLB = sc.srlb().sidRange()
+	}
+	if v6 {
+		rc.SRv6 = &tlv.SRv6Cap{}
+		rc.SRAlgos = []uint8{tlv.SRAlgoSPF}
+	}
+	flexAlgoCap(rc, config.FlexAlgos)
+	return rc
+}
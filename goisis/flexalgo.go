@@ -0,0 +1,104 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+package main
+
+import (
+	"fmt"
+	"github.com/choppsv1/goisis/tlv"
+	"sort"
+)
+
+// Flexible algorithm metric type names.
+const (
+	FlexAlgoMetricIGP      = "igp"
+	FlexAlgoMetricMinDelay = "min-delay"
+	FlexAlgoMetricTE       = "te"
+)
+
+// FlexAlgoConfig is a flexible algorithm (RFC9350) we participate in. The
+// admin groups are bit masks matched against the first word of the interface
+// extended-admin-group, or its admin-group. The definition is advertised
+// unless NoAdvertise is set, the definition used is the one of highest
+// priority advertised in the area.
+type FlexAlgoConfig struct {
+	Algo        uint32   `json:"algorithm"`
+	MetricType  string   `json:"metric-type,omitempty"`
+	CalcType    uint8    `json:"calculation-type,omitempty"`
+	Priority    uint8    `json:"priority,omitempty"`
+	ExcludeAny  uint32   `json:"exclude-any,omitempty"`
+	IncludeAny  uint32   `json:"include-any,omitempty"`
+	IncludeAll  uint32   `json:"include-all,omitempty"`
+	ExcludeSRLG []uint32 `json:"exclude-srlg,omitempty"`
+	NoAdvertise bool     `json:"no-advertise-definition,omitempty"`
+}
+
+var flexAlgoMetricTypes = map[string]uint8{
+	"":                     tlv.FlexAlgoMetricIGP,
+	FlexAlgoMetricIGP:      tlv.FlexAlgoMetricIGP,
+	FlexAlgoMetricMinDelay: tlv.FlexAlgoMetricDelay,
+	FlexAlgoMetricTE:       tlv.FlexAlgoMetricTE,
+}
+
+// validateFlexAlgos checks the flexible algorithm configuration.
+func validateFlexAlgos(fas []FlexAlgoConfig) error {
+	seen := make(map[uint32]bool)
+	for _, fa := range fas {
+		if fa.Algo < tlv.FlexAlgoMin || fa.Algo > tlv.FlexAlgoMax {
+			return fmt.Errorf("flex-algo: algorithm %d not between %d and %d", fa.Algo, tlv.FlexAlgoMin, tlv.FlexAlgoMax)
+		}
+		if seen[fa.Algo] {
+			return fmt.Errorf("flex-algo: duplicate algorithm %d", fa.Algo)
+		}
+		seen[fa.Algo] = true
+		if _, ok := flexAlgoMetricTypes[fa.MetricType]; !ok {
+			return fmt.Errorf("flex-algo %d: unknown metric-type %q", fa.Algo, fa.MetricType)
+		}
+		if fa.CalcType != tlv.SRAlgoSPF && fa.CalcType != tlv.SRAlgoStrictSPF {
+			return fmt.Errorf("flex-algo %d: unsupported calculation-type %d", fa.Algo, fa.CalcType)
+		}
+	}
+	return nil
+}
+
+// adminGroups returns the extended admin group for an admin group bit mask.
+func adminGroups(ag uint32) []uint32 {
+	if ag == 0 {
+		return nil
+	}
+	return []uint32{ag}
+}
+
+// fad returns the Flexible Algorithm Definition to advertise.
+func (fa *FlexAlgoConfig) fad() tlv.FAD {
+	return tlv.FAD{
+		Algo:        uint8(fa.Algo),
+		MetricType:  flexAlgoMetricTypes[fa.MetricType],
+		CalcType:    fa.CalcType,
+		Priority:    fa.Priority,
+		ExcludeAny:  adminGroups(fa.ExcludeAny),
+		IncludeAny:  adminGroups(fa.IncludeAny),
+		IncludeAll:  adminGroups(fa.IncludeAll),
+		ExcludeSRLG: fa.ExcludeSRLG,
+	}
+}
+
+// flexAlgoCap adds the flexible algorithms we participate in and their
+// definitions to the capabilities we advertise.
+func flexAlgoCap(rc *tlv.RouterCap, fas []FlexAlgoConfig) {
+	if len(fas) == 0 {
+		return
+	}
+	algos := []uint8{tlv.SRAlgoSPF}
+	var fads []tlv.FAD
+	for i := range fas {
+		algos = append(algos, uint8(fas[i].Algo))
+		if !fas[i].NoAdvertise {
+			fads = append(fads, fas[i].fad())
+		}
+	}
+	sort.Slice(algos, func(i, j int) bool { return algos[i] < algos[j] })
+	rc.SRAlgos = algos
+	rc.FADs = fads
+}
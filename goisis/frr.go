@@ -0,0 +1,98 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+package main
+
+import (
+	"fmt"
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/goisis/update"
+)
+
+// FRRConfig is the fast reroute configuration of an interface.
+type FRRConfig struct {
+	LFA   LevLFAConfig `json:"lfa"`
+	TILFA *TILFAConfig `json:"ti-lfa,omitempty"`
+}
+
+// TILFAConfig enables the Topology Independent LFA repair paths of an
+// interface, they require segment routing.
+type TILFAConfig struct {
+	Enable bool `json:"enable,omitempty"`
+}
+
+// LFAConfig holds the loop-free alternate (RFC5286) values of an interface.
+// With CandidateEnable (default true) the interface may be used as the backup
+// of other interfaces, with Enable its primary next hops are protected.
+type LFAConfig struct {
+	CandidateEnable *bool            `json:"candidate-enable,omitempty"`
+	Enable          bool             `json:"enable,omitempty"`
+	RemoteLFA       *RemoteLFAConfig `json:"remote-lfa,omitempty"`
+}
+
+// RemoteLFAConfig holds the remote LFA (RFC7490) values of an interface,
+// PQSelection is the PQ node selection policy, "lowest-metric" by default or
+// "closest".
+type RemoteLFAConfig struct {
+	Enable      bool   `json:"enable,omitempty"`
+	PQSelection string `json:"pq-selection,omitempty"`
+}
+
+// LevLFAConfig is the level specific LFA config.
+type LevLFAConfig struct {
+	*LFAConfig
+	Level1 *LFAConfig `json:"level-1,omitempty"`
+	Level2 *LFAConfig `json:"level-2,omitempty"`
+}
+
+// levConfig returns the level specific value if set, otherwise the common
+// value, otherwise nil.
+func (lc *LevLFAConfig) levConfig(li clns.Lindex) *LFAConfig {
+	if li == 0 && lc.Level1 != nil {
+		return lc.Level1
+	} else if li == 1 && lc.Level2 != nil {
+		return lc.Level2
+	}
+	return lc.LFAConfig
+}
+
+// validate checks the remote LFA PQ node selection policies.
+func (fc *FRRConfig) validate() error {
+	for _, lc := range []*LFAConfig{fc.LFA.LFAConfig, fc.LFA.Level1, fc.LFA.Level2} {
+		if lc == nil || lc.RemoteLFA == nil {
+			continue
+		}
+		switch lc.RemoteLFA.PQSelection {
+		case "", update.PQSelectLowestMetric, update.PQSelectClosest:
+		default:
+			return fmt.Errorf("unknown pq-selection %s", lc.RemoteLFA.PQSelection)
+		}
+	}
+	return nil
+}
+
+// FRR returns the fast reroute policy of the circuit at level li.
+func (cb *CircuitBase) FRR(li clns.Lindex) update.FRRPolicy {
+	p := update.FRRPolicy{Candidate: true, PQSelection: update.PQSelectLowestMetric}
+	fc := cb.config.FRR
+	if fc == nil {
+		return p
+	}
+	if lc := fc.LFA.levConfig(li); lc != nil {
+		if lc.CandidateEnable != nil {
+			p.Candidate = *lc.CandidateEnable
+		}
+		p.LFA = lc.Enable
+		if rc := lc.RemoteLFA; rc != nil {
+			p.RemoteLFA = rc.Enable
+			if rc.PQSelection != "" {
+				p.PQSelection = rc.PQSelection
+			}
+		}
+	}
+	if fc.TILFA != nil {
+		p.TILFA = fc.TILFA.Enable
+	}
+	return p
+}
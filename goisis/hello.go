@@ -10,11 +10,14 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"github.com/choppsv1/goisis/bfd"
 	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/goisis/update"
 	. "github.com/choppsv1/goisis/logging" // nolint
 	"github.com/choppsv1/goisis/pkt"
 	xtime "github.com/choppsv1/goisis/time"
 	"github.com/choppsv1/goisis/tlv"
+	"math"
 	"net"
 	"time"
 )
@@ -79,8 +82,17 @@ type Adj struct {
 	ctype      clns.LevelFlag
 	state      AdjState
 	areas      []clns.Area
+	v4addrs    []net.IP
+	v6addrs    []net.IP
 	holdTimer  *xtime.HoldTimer
 	lastUpTime time.Time
+	adjSIDs    []tlv.AdjSID
+	endXSIDs   []tlv.SRv6EndXSID
+	srv6Func   uint32
+	mtids      []uint16
+	bfdClients []*bfd.Client
+	bfdEnabled []tlv.BFDEnabled // RFC6213
+	bfdHeld    bool             // Up once the BFD sessions are
 
 	// LAN state
 	lanID    clns.NodeID
@@ -91,6 +103,41 @@ func (a *Adj) String() string {
 	return fmt.Sprintf("Adj(%s,%s,%s)", clns.ISOString(a.sysid[:], false), a.link, a.state)
 }
 
+// neighbor returns the adjacency information used by the decision process.
+func (a *Adj) neighbor() update.Neighbor {
+	return update.Neighbor{
+		Sysid:   a.sysid,
+		SNPA:    a.snpa,
+		V4Addrs: a.v4addrs,
+		V6Addrs: a.v6addrs,
+		AdjSIDs: a.adjSIDs,
+		EndXSID: a.endXSIDs,
+		MTIDs:   a.mtids,
+	}
+}
+
+// decodeAddrs returns a copy of the interface addresses from the IIH TLVs.
+func decodeAddrs(tlvs []tlv.Data, v4 bool) []net.IP {
+	var addrs []net.IP
+	for _, t := range tlvs {
+		var decoded []net.IP
+		var err error
+		if v4 {
+			decoded, err = t.IntfIPv4AddrsDecode()
+		} else {
+			decoded, err = t.IntfIPv6AddrsDecode()
+		}
+		if err != nil {
+			Info("ERROR: processing interface address TLV: %s", err)
+			continue
+		}
+		for _, addr := range decoded {
+			addrs = append(addrs, append(net.IP(nil), addr...))
+		}
+	}
+	return addrs
+}
+
 type getAdj struct {
 	c     chan<- interface{}
 	forPN bool
@@ -100,17 +147,75 @@ type getAdj struct {
 // Hello Process
 // =============
 
+// validateHello checks the hello values of an interface, the hold time must
+// fit in the IIH.
+func (ic *InterfaceConfig) validateHello() error {
+	if ic.HelloMinimal && ic.HelloInt != 0 {
+		return fmt.Errorf("hello-minimal with hello-interval")
+	}
+	ival, mult, _ := ic.helloValues()
+	if mult > math.MaxUint16 || ival*time.Duration(mult) > math.MaxUint16*time.Second {
+		return fmt.Errorf("hold time too large")
+	}
+	return nil
+}
+
+// helloValues returns the hello interval and multiplier of the interface and
+// whether the minimal hold time is used.
+func (ic *InterfaceConfig) helloValues() (time.Duration, uint, bool) {
+	mult := ic.HelloMult
+	if mult == 0 {
+		mult = clns.DefHelloMult
+	}
+	if ic.HelloMinimal {
+		return time.Second / time.Duration(mult), mult, true
+	}
+	return msecOrDefault(ic.HelloInt, time.Second*clns.DefHelloInt), mult, false
+}
+
+// holdTime returns the hold time of our IIHs in seconds, the hello interval
+// times the multiplier rounded up, 1 second with the minimal hold time.
+func (link *LinkLAN) holdTime() uint16 {
+	if link.minimal {
+		return 1
+	}
+	hold := (link.helloInt*time.Duration(link.holdMult) + time.Second - 1) / time.Second
+	if hold < 1 {
+		return 1
+	}
+	return uint16(hold)
+}
+
+// helloIval returns the interval between our hellos, the DIS sends them 3
+// times as often (ISO10589 section 8.4.5).
+func (link *LinkLAN) helloIval() time.Duration {
+	if link.disElected {
+		return link.helloInt / 3
+	}
+	return link.helloInt
+}
+
+// resetHelloTimer restarts the hello timer with a jittered interval.
+func (link *LinkLAN) resetHelloTimer() {
+	if !link.helloTimer.Stop() {
+		select {
+		case <-link.helloTimer.C:
+		default:
+		}
+	}
+	link.helloTimer.Reset(xtime.Jitter(link.helloIval(), xtime.DefJitter))
+}
+
 // StartHelloProcess starts a go routine to send and receive hellos, manage
 // adjacencies and elect DIS on LANs
 func StartHelloProcess(link *LinkLAN, quit <-chan bool) {
-	Debug(DbgFPkt, "Sending hellos on %s with interval %d", link, link.helloInt)
-	ival := time.Second * time.Duration(link.helloInt)
-	link.ticker = time.NewTicker(ival) // XXX replace with jittered timer.
+	Debug(DbgFPkt, "Sending hellos on %s with interval %s", link, link.helloInt)
+	link.helloTimer = time.NewTimer(xtime.Jitter(link.helloIval(), xtime.DefJitter))
 
 	go helloProcess(link, quit)
 }
 
-// sendLANHellos is a go routine that sends hellos based using a ticker
+// sendLANHellos is a go routine that sends hellos based using a jittered timer
 // It also processes DIS update events.
 // nolint: gocyclo
 func helloProcess(link *LinkLAN, quit <-chan bool) {
@@ -132,25 +237,36 @@ func helloProcess(link *LinkLAN, quit <-chan bool) {
 			link.getAdjacencies(ga)
 		case pdu := <-link.iihpkt:
 			rundis = pdu.link.RecvHello(pdu)
-		case srcid := <-link.expireC:
-			Debug(DbgFAdj, "Adj for %s on %s expiring.", srcid, link)
-			a := link.srcidMap[srcid]
-			if a == nil {
-				Debug(DbgFAdj, "Adj for %s on %s is already gone.", srcid, link)
+		case a := <-link.bfdUpC:
+			rundis = link.bfdUp(a)
+		case a := <-link.expireC:
+			Debug(DbgFAdj, "Adj for %s on %s expiring.", a.sysid, link)
+			if link.srcidMap[a.sysid] != a {
+				Debug(DbgFAdj, "Adj for %s on %s is already gone.", a.sysid, link)
 				break
 			}
+			// The hold timer may still be running if BFD expired it.
+			if a.holdTimer != nil {
+				a.holdTimer.Stop()
+			}
 			// If the adjacency was up then we need to rerun DIS election.
 			rundis = a.state == AdjStateUp
+			if a.state == AdjStateUp {
+				link.updb.AdjChange(link.circuit, false, a.neighbor())
+				a.freeSIDs()
+			}
+			a.stopBFD()
 			delete(link.snpaMap, a.snpa)
 			delete(link.srcidMap, a.sysid)
 		case <-link.disTimer.C:
 			Debug(DbgFDIS, "INFO: DIS timer fires %s", link)
 			disWaiting = false
 			rundis = true
-		case <-link.ticker.C:
+		case <-link.helloTimer.C:
 			if err := sendLANHello(link); err != nil {
 				Trap("%s: error sending LAN hello: %s", link, err)
 			}
+			link.helloTimer.Reset(xtime.Jitter(link.helloIval(), xtime.DefJitter))
 		case in := <-link.rpC:
 			in.Result <- in.F()
 		}
@@ -170,9 +286,21 @@ func helloProcess(link *LinkLAN, quit <-chan bool) {
 func (link *LinkLAN) getAdjacencies(in getAdj) {
 	if !in.forPN {
 		Debug(DbgFPkt, "Sending LANID %s on channel", link.lanID)
+		var sids []tlv.AdjSID
+		var xsids []tlv.SRv6EndXSID
+		for _, a := range link.srcidMap {
+			if a.state == AdjStateUp {
+				sids = append(sids, a.adjSIDs...)
+				xsids = append(xsids, a.endXSIDs...)
+			}
+		}
 		in.c <- tlv.AdjInfo{
-			Metric: clns.DefExtISMetric,
-			Nodeid: link.lanID,
+			Metric:  clns.DefExtISMetric,
+			Nodeid:  link.lanID,
+			TE:      mergeMeasured(link.circuit.linkTE(), link.measured),
+			SRLG:    link.circuit.srlg(link.lanID),
+			AdjSIDs: sids,
+			EndXSID: xsids,
 		}
 		in.c <- tlv.Done{}
 		return
@@ -196,11 +324,15 @@ func (a *Adj) yangData() *YangAdj {
 	yd := &YangAdj{
 		Istype:   a.ctype,
 		HoldTime: a.holdTimer.Until(),
+		HoldMsec: uint32(a.holdTimer.Remaining() / time.Millisecond),
 		Sysid:    a.sysid,
 		Snpa:     a.snpa,
 		State:    a.state,
 		Priority: a.priority,
 		Usage:    a.usage,
+		AdjSIDs:  a.adjSIDs,
+		EndXSIDs: a.endXSIDs,
+		BFD:      a.bfdInfo(),
 	}
 	yd.LastUpTime = uint32(a.lastUpTime.Sub(GlbStartTime) / (time.Second / time.Duration(100)))
 	return yd
@@ -209,18 +341,26 @@ func (a *Adj) yangData() *YangAdj {
 // yangData returns level specific interface yang data
 func (link *LinkLAN) yangData(yd *YangInterface) error {
 	if link.l == 1 {
-		yd.HelloInt.Level1 = &Value{Value: link.helloInt}
+		yd.HelloInt.Level1 = &Value{Value: uint(link.helloInt / time.Millisecond)}
 		yd.HelloMult.Level1 = &Value{Value: link.holdMult}
 		yd.Priority.Level1 = &Value{Value: uint(link.priority)}
 		yd.Metric.Level1 = &Value{Value: uint(link.metric)}
 	} else {
-		yd.HelloInt.Level2 = &Value{Value: link.helloInt}
+		yd.HelloInt.Level2 = &Value{Value: uint(link.helloInt / time.Millisecond)}
 		yd.HelloMult.Level2 = &Value{Value: link.holdMult}
 		yd.Priority.Level2 = &Value{Value: uint(link.priority)}
 		yd.Metric.Level2 = &Value{Value: uint(link.metric)}
 	}
+	mtids := link.circuit.Topologies()
 	for _, a := range link.srcidMap {
-		yd.Adjcencies.Adj = append(yd.Adjcencies.Adj, a.yangData())
+		ya := a.yangData()
+		if mtids != nil {
+			ya.Topologies = commonTopologies(mtids, a.mtids)
+		}
+		yd.Adjcencies.Adj = append(yd.Adjcencies.Adj, ya)
+	}
+	if link.measured != nil {
+		yd.Measured = link.measured
 	}
 	return nil
 }
@@ -264,8 +404,7 @@ func sendLANHello(link *LinkLAN) error {
 
 	iihp[clns.HdrIIHLANCircType] = uint8(link.l)
 	copy(iihp[clns.HdrIIHLANSrcID:], GlbSystemID[:])
-	pkt.PutUInt16(iihp[clns.HdrIIHLANHoldTime:],
-		uint16(link.helloInt*link.holdMult))
+	pkt.PutUInt16(iihp[clns.HdrIIHLANHoldTime:], link.holdTime())
 	iihp[clns.HdrIIHLANPriority] = link.priority & 0x7F
 	copy(iihp[clns.HdrIIHLANLANID:], link.lanID[:])
 
@@ -285,6 +424,14 @@ func sendLANHello(link *LinkLAN) error {
 		return err
 	}
 
+	if err = bt.AddMT(mts(link.circuit.Topologies())); err != nil {
+		return err
+	}
+
+	if err = bt.AddBFDEnabled(link.circuit.bfdEnabled()); err != nil {
+		return err
+	}
+
 	if err = bt.AddIntfAddrs(link.circuit.v4addrs); err != nil {
 		return err
 	}
@@ -344,6 +491,14 @@ func (a *Adj) UpdateAdj(pdu *RecvPDU) bool {
 	oldstate := a.state
 	a.state = AdjStateInit
 
+	a.v4addrs = decodeAddrs(pdu.tlvs[tlv.TypeIPv4IntfAddrs], true)
+	a.v6addrs = decodeAddrs(pdu.tlvs[tlv.TypeIPv6IntfAddrs], false)
+	// RFC5120: LAN adjacencies are formed regardless of the topologies, they
+	// only determine which topologies the adjacency is used in.
+	a.mtids = decodeMTIDs(pdu.tlvs[tlv.TypeMT])
+	a.bfdEnabled = decodeBFDEnabled(pdu.tlvs[tlv.TypeBFDEnabled])
+	a.bfdHeld = false
+
 	if a.link.IsP2P() {
 		// XXX writeme
 	} else {
@@ -372,6 +527,11 @@ func (a *Adj) UpdateAdj(pdu *RecvPDU) bool {
 				}
 			}
 		}
+		// RFC6213 section 3.2: a new adjacency waits for BFD.
+		if a.state == AdjStateUp && oldstate != AdjStateUp && a.link.BFDHold(a) {
+			Debug(DbgFAdj, "%s: held for BFD", a)
+			a.state = AdjStateInit
+		}
 	}
 
 	if a.state != oldstate {
@@ -390,8 +550,7 @@ func (a *Adj) UpdateAdj(pdu *RecvPDU) bool {
 	holdtime := pkt.GetUInt16(iihp[clns.HdrIIHHoldTime:])
 	if a.holdTimer == nil {
 		a.holdTimer = xtime.NewHoldTimer(holdtime, func() {
-			sysid := a.sysid
-			a.link.ExpireAdj(sysid)
+			a.link.ExpireAdj(a)
 		})
 	} else {
 		a.holdTimer.Reset(holdtime)
@@ -482,7 +641,26 @@ func (link *LinkLAN) RecvHello(pdu *RecvPDU) bool {
 		// If the system ID changed ignore and let timeout.
 		rundis = false
 	} else {
+		oldstate := a.state
+		oldmtids := a.mtids
 		rundis = a.UpdateAdj(pdu)
+		if oldstate == AdjStateUp && a.state == AdjStateUp && !equalMTIDs(oldmtids, a.mtids) {
+			// Topologies changed, bounce the adjacency in the decision
+			// process.
+			link.updb.AdjChange(link.circuit, false, a.neighbor())
+			link.updb.AdjChange(link.circuit, true, a.neighbor())
+		} else if (oldstate == AdjStateUp) != (a.state == AdjStateUp) {
+			up := a.state == AdjStateUp
+			if up {
+				a.allocSIDs()
+				link.startBFD(a)
+			}
+			link.updb.AdjChange(link.circuit, up, a.neighbor())
+			if !up {
+				a.freeSIDs()
+				a.stopBFD()
+			}
+		}
 	}
 	return rundis
 }
@@ -536,10 +714,7 @@ func (link *LinkLAN) disSelfElect() {
 		return
 	}
 	link.disElected = true
-
-	ival := time.Second * time.Duration(link.helloInt) / 3
-	link.ticker.Stop()
-	link.ticker = time.NewTicker(ival) // XXX replace with jittered timer.
+	link.resetHelloTimer()
 }
 
 func (link *LinkLAN) disSelfResign() {
@@ -547,10 +722,7 @@ func (link *LinkLAN) disSelfResign() {
 		return
 	}
 	link.disElected = false
-
-	ival := time.Second * time.Duration(link.helloInt)
-	link.ticker.Stop()
-	link.ticker = time.NewTicker(ival) // XXX replace with jittered timer.
+	link.resetHelloTimer()
 }
 
 func (link *LinkLAN) disElect(firstRun bool) {
@@ -588,7 +760,7 @@ func (link *LinkLAN) disElect(firstRun bool) {
 	link.lanID = newLANID
 
 	// Always let the update process know.
-	link.updb.ChangeDIS(link.circuit, link.lanID[clns.SysIDLen])
+	link.updb.ChangeDIS(link.circuit, link.lanID)
 
 	if !electUs {
 		link.disSelfResign()
@@ -32,7 +32,8 @@ type Link interface {
 	// SetFlag(update.SxxFlag, *clns.LSPID) // No-lock uses channels
 	RecvHello(*RecvPDU) bool
 	GetOurSNPA() net.HardwareAddr
-	ExpireAdj(clns.SystemID)
+	ExpireAdj(*Adj)
+	BFDHold(*Adj) bool
 }
 
 // =====
@@ -53,8 +54,9 @@ type LinkLAN struct {
 	li      clns.Lindex // level - 1 for array indexing
 
 	// Hello Process
-	helloInt  uint
+	helloInt  time.Duration
 	holdMult  uint
+	minimal   bool // minimal hold time
 	priority  uint8
 	metric    uint32
 	lclCircID uint8
@@ -62,8 +64,9 @@ type LinkLAN struct {
 	ourlanID  clns.NodeID
 
 	// Hello Process
-	ticker     *time.Ticker
-	expireC    chan clns.SystemID
+	helloTimer *time.Timer
+	expireC    chan *Adj
+	bfdUpC     chan *Adj
 	iihpkt     chan *RecvPDU
 	getAdjC    chan getAdj
 	rpC        chan RPC
@@ -71,6 +74,7 @@ type LinkLAN struct {
 	disElected bool
 	snpaMap    map[clns.SNPA]*Adj
 	srcidMap   map[clns.SystemID]*Adj
+	measured   *tlv.LinkTE // RFC8570 link attributes
 
 	// Update Process
 	updb   *update.DB
@@ -92,10 +96,9 @@ func NewLinkLAN(c *CircuitLAN, li clns.Lindex, updb *update.DB, quit <-chan bool
 		li:       li,
 		updb:     updb,
 		priority: 67, // clns.DefHelloPri,
-		helloInt: clns.DefHelloInt,
-		holdMult: clns.DefHelloMult,
 		metric:   clns.DefExtISMetric,
-		expireC:  make(chan clns.SystemID, 10),
+		expireC:  make(chan *Adj, 10),
+		bfdUpC:   make(chan *Adj, 10),
 		getAdjC:  make(chan getAdj, 10),
 		rpC:      make(chan RPC),
 		iihpkt:   make(chan *RecvPDU, 3),
@@ -104,6 +107,7 @@ func NewLinkLAN(c *CircuitLAN, li clns.Lindex, updb *update.DB, quit <-chan bool
 		flagsC:   make(chan chgSxxFlag, 10),
 		flags:    [2]update.FlagSet{make(update.FlagSet), make(update.FlagSet)},
 	}
+	link.helloInt, link.holdMult, link.minimal = c.config.helloValues()
 	lanLinkCircuitIDs[li]++
 	link.lclCircID = lanLinkCircuitIDs[li]
 
@@ -118,8 +122,7 @@ func NewLinkLAN(c *CircuitLAN, li clns.Lindex, updb *update.DB, quit <-chan bool
 	ourSNPA[ether.MACKey(c.CircuitBase.intf.HardwareAddr)] = true
 
 	// Start DIS election routine
-	dur := time.Second * time.Duration(link.helloInt*2)
-	link.disTimer = time.NewTimer(dur)
+	link.disTimer = time.NewTimer(link.helloInt * 2)
 
 	// Start Sending Hellos
 	StartHelloProcess(link, quit)
@@ -139,9 +142,10 @@ func (link *LinkLAN) GetOurSNPA() net.HardwareAddr {
 	return link.circuit.CircuitBase.intf.HardwareAddr
 }
 
-// ExpireAdj cause the adjacency to expire.
-func (link *LinkLAN) ExpireAdj(sysid clns.SystemID) {
-	link.expireC <- sysid
+// ExpireAdj cause the adjacency to expire, nothing happens if it has already
+// been replaced by a new adjacency with the neighbor.
+func (link *LinkLAN) ExpireAdj(a *Adj) {
+	link.expireC <- a
 }
 
 // -------------------
@@ -76,12 +76,13 @@ func main() {
 	iflistPtr := flag.String("iflist", "", "Space separated list of interfaces to run on")
 	playPtr := flag.Bool("play", false, "run the playground")
 	areaIDPtr := flag.String("area", "00", "area of this instance")
+	configPtr := flag.String("config", "", "JSON configuration file")
 	debugPtr := flag.String("debug", "",
-		"strsep list of debug flags: all or adj,dis,flags,packet,update")
+		"strsep list of debug flags: all or adj,dis,flags,packet,spf,update")
 	isTypePtr := flag.String("istype", "l-1", "l-1, l-1-2, l-2-only")
 	sysIDPtr := flag.String("sysid", "0000.0000.0001", "system id of this instance")
 	tracePtr := flag.String("trace", "",
-		"strsep list of debug flags: all or adj,dis,flags,packet,update")
+		"strsep list of debug flags: all or adj,dis,flags,packet,spf,update")
 	flag.Parse()
 
 	if *playPtr {
@@ -109,6 +110,10 @@ func main() {
 	}
 	fmt.Printf("IS-IS %s router\n", GlbISType)
 
+	if GlbConfig, err = LoadConfig(*configPtr); err != nil {
+		Panicf("Error loading config %s: %s", *configPtr, err)
+	}
+
 	// Initialize System and AreaIDs
 
 	sysid, err := clns.ISOEncode(*sysIDPtr)
@@ -148,8 +153,16 @@ func main() {
 		if GlbISType.IsLevelEnabled(l) {
 			li := l.ToIndex()
 			updb[li] = update.NewDB(GlbSystemID, GlbISType, l, GlbAreaIDs, GlbNLPID)
+			GlbConfig.ApplyUpdate(li, updb[li])
 		}
 	}
+	if updb[0] != nil && updb[1] != nil {
+		update.LinkLevels(updb[0], updb[1])
+	}
+
+	StartSegmentRouting(GlbConfig, updb)
+	StartSRv6(GlbConfig, updb)
+	StartBFD(GlbConfig)
 
 	// Initialize Circuit DB
 
@@ -157,14 +170,22 @@ func main() {
 
 	// Add interfaces
 	fmt.Printf("%v: %q\n", iflistPtr, *iflistPtr)
+	var circuits []*CircuitLAN
 	for _, ifname := range splitArg(iflistPtr) {
-		fmt.Printf("Adding LAN link: %q\n", ifname)
-		_, err = cdb.NewCircuit(ifname, GlbISType, updb)
+		fmt.Printf("Adding link: %q\n", ifname)
+		c, err := cdb.NewCircuit(ifname, GlbISType, updb)
 		if err != nil {
 			Panicf("Error creating circuit: %s\n", err)
 		}
+		if cll, ok := c.(*CircuitLAN); ok {
+			circuits = append(circuits, cll)
+		}
 	}
 
+	StartLinkMeasurement(GlbConfig, circuits)
+
+	StartRedistribution(GlbConfig, cdb, updb)
+
 	SetupManagement(cdb, updb)
 
 	ticker := time.NewTicker(time.Second * 120)
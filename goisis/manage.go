@@ -8,8 +8,10 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/choppsv1/goisis/bfd"
 	"github.com/choppsv1/goisis/clns"
 	"github.com/choppsv1/goisis/goisis/update"
+	"github.com/choppsv1/goisis/tlv"
 	"github.com/gorilla/mux"
 	"io"
 	"net/http"
@@ -31,14 +33,117 @@ type LSPList struct {
 	Lsp []*update.YangLSP `json:"lsp,omitempty"`
 }
 
+// LSPLogList is a yang list of LSP log entries
+type LSPLogList struct {
+	Event []*update.YangLSPLog `json:"event,omitempty"`
+}
+
+// SPFLogList is a yang list of SPF log entries
+type SPFLogList struct {
+	Event []*update.YangSPFLog `json:"event,omitempty"`
+}
+
+// SPFDelayList is a yang list of the level SPF back-off state.
+type SPFDelayList struct {
+	Level []*update.YangSPFDelay `json:"level,omitempty"`
+}
+
+// OverloadList is a yang list of the level overload state.
+type OverloadList struct {
+	Level []*update.YangOverload `json:"level,omitempty"`
+}
+
+// AttachedList is a yang list of the level attached bit state.
+type AttachedList struct {
+	Level []*update.YangAttached `json:"level,omitempty"`
+}
+
+// RouteList is a yang list of routes.
+type RouteList struct {
+	Route []*update.Route `json:"route,omitempty"`
+}
+
+// SummaryList is a yang list of summary addresses.
+type SummaryList struct {
+	Summary []*update.YangSummary `json:"summary,omitempty"`
+}
+
+// LeakedList is a yang list of level 2 prefixes leaked into level 1.
+type LeakedList struct {
+	Prefix []*update.YangLeaked `json:"prefix,omitempty"`
+}
+
+// RedistList is a yang list of redistributed prefixes.
+type RedistList struct {
+	Prefix []*update.YangRedist `json:"prefix,omitempty"`
+}
+
+// RouterCapList is a yang list of the capabilities of the routers.
+type RouterCapList struct {
+	Router []*update.YangRouterCap `json:"router,omitempty"`
+}
+
+// LFIBList is a yang list of label forwarding table entries.
+type LFIBList struct {
+	Entry []*update.LabelRoute `json:"entry,omitempty"`
+}
+
+// SIDConflictList is a yang list of prefix SID conflicts.
+type SIDConflictList struct {
+	Conflict []*update.YangSIDConflict `json:"conflict,omitempty"`
+}
+
+// SRv6State is the SRv6 state of the levels.
+type SRv6State struct {
+	Locators  []*update.Route   `json:"locator-routes"`
+	LocalSIDs []*update.SRv6SID `json:"local-sids"`
+}
+
+// SRState is the segment routing state.
+type SRState struct {
+	LFIB      LFIBList        `json:"label-forwarding-table"`
+	Conflicts SIDConflictList `json:"sid-conflicts"`
+	SRv6      SRv6State       `json:"srv6"`
+}
+
+// FRRList is a yang list of the fast reroute coverage of the levels.
+type FRRList struct {
+	Coverage []*update.YangFRRCoverage `json:"protection-statistics,omitempty"`
+}
+
+// ULoopList is a yang list of the level microloop avoidance state.
+type ULoopList struct {
+	Level []*update.YangULoop `json:"level,omitempty"`
+}
+
+// FlexAlgoList is a yang list of the flexible algorithm topologies.
+type FlexAlgoList struct {
+	Algorithm []*update.YangFlexAlgo `json:"algorithm,omitempty"`
+}
+
 // YangRoot is the root of the yang module.
 type YangRoot struct {
-	Enable    bool           `json:"enable"`
-	LevelType clns.LevelFlag `json:"level-type"`
-	SystemID  clns.SystemID  `json:"system-id"`
+	Enable    bool            `json:"enable"`
+	LevelType clns.LevelFlag  `json:"level-type"`
+	SystemID  clns.SystemID   `json:"system-id"`
+	LSPGen    LevLSPGenConfig `json:"lsp-generation"`
+	SPFDelay  SPFDelayList    `json:"spf-delay"`
+	Overload  OverloadList    `json:"overload"`
+	Attached  AttachedList    `json:"attached-bit"`
+	Summaries SummaryList     `json:"summary-address"`
+	Leaked    LeakedList      `json:"leaked-prefixes"`
+	Redist    RedistList      `json:"redistributed"`
+	RouterCap RouterCapList   `json:"router-capabilities"`
+	SR        SRState         `json:"segment-routing"`
+	FlexAlgo  FlexAlgoList    `json:"flex-algo"`
+	FRR       FRRList         `json:"fast-reroute"`
+	ULoop     ULoopList       `json:"microloop-avoidance"`
 	//...
-	Interfaces IFList  `json:"interfaces,omitempty"`
-	DB         LSPList `json:"db,omitempty"`
+	Interfaces IFList     `json:"interfaces,omitempty"`
+	DB         LSPList    `json:"db,omitempty"`
+	LSPLog     LSPLogList `json:"lsp-log,omitempty"`
+	SPFLog     SPFLogList `json:"spf-log,omitempty"`
+	LocalRIB   RouteList  `json:"local-rib,omitempty"`
 }
 
 func errToHTTP(w http.ResponseWriter, err error) {
@@ -54,12 +159,115 @@ func muxRoot(w http.ResponseWriter, r *http.Request, cdb *CircuitDB, updb [2]*up
 		return
 	}
 
+	lsplog, err := lspLogData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	spflog, err := spfLogData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	spfdelay, err := spfDelayData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	overload, err := overloadData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	attached, err := attachedData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	routes, err := ribData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	var summaries []*update.YangSummary
+	if updb[1] != nil {
+		if summaries, err = updb[1].Summaries(); err != nil {
+			errToHTTP(w, err)
+			return
+		}
+	}
+
+	var leaked []*update.YangLeaked
+	if updb[0] != nil {
+		if leaked, err = updb[0].Leaked(); err != nil {
+			errToHTTP(w, err)
+			return
+		}
+	}
+
+	redist, err := redistData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	caps, err := routerCapData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	sr, err := srData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	flexAlgos, err := flexAlgoData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	frr, err := frrData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	uloop, err := uloopData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
 	root := YangRoot{
 		Enable:     true,
 		LevelType:  GlbISType,
 		SystemID:   GlbSystemID,
+		LSPGen:     GlbConfig.LSPGen,
+		SPFDelay:   SPFDelayList{spfdelay},
+		Overload:   OverloadList{overload},
+		Attached:   AttachedList{attached},
+		Summaries:  SummaryList{summaries},
+		Leaked:     LeakedList{leaked},
+		Redist:     RedistList{redist},
+		RouterCap:  RouterCapList{caps},
+		SR:         *sr,
+		FlexAlgo:   FlexAlgoList{flexAlgos},
+		FRR:        FRRList{frr},
+		ULoop:      ULoopList{uloop},
 		Interfaces: IFList{interfaceData(w, "", cdb)},
 		DB:         LSPList{lsplist},
+		LSPLog:     LSPLogList{lsplog},
+		SPFLog:     SPFLogList{spflog},
+		LocalRIB:   RouteList{routes},
 	}
 
 	jvars, err := json.Marshal(root)
@@ -196,15 +404,20 @@ func muxRoot(w http.ResponseWriter, r *http.Request, cdb *CircuitDB, updb [2]*up
 
 // YangAdj is the adjacency data for the yang model
 type YangAdj struct {
-	Istype     clns.LevelFlag `json:"neighbor-sys-type"`
-	Sysid      clns.SystemID  `json:"neighbor-sysid"`
-	Snpa       clns.SNPA      `json:"neighbor-snpa,omitempty"`
-	State      AdjState       `json:"state"`
-	Priority   uint8          `json:"neighbor-priority"`
-	Usage      clns.LevelFlag `json:"usage"`
-	HoldTime   uint16         `json:"hold-timer"`
-	ExtCID     uint32         `json:"neighbor-extended-circuit-id,omitempty"`
-	LastUpTime uint32         `json:"lastuptime"`
+	Istype     clns.LevelFlag     `json:"neighbor-sys-type"`
+	Sysid      clns.SystemID      `json:"neighbor-sysid"`
+	Snpa       clns.SNPA          `json:"neighbor-snpa,omitempty"`
+	State      AdjState           `json:"state"`
+	Priority   uint8              `json:"neighbor-priority"`
+	Usage      clns.LevelFlag     `json:"usage"`
+	HoldTime   uint16             `json:"hold-timer"`
+	HoldMsec   uint32             `json:"hold-timer-msec"`
+	ExtCID     uint32             `json:"neighbor-extended-circuit-id,omitempty"`
+	LastUpTime uint32             `json:"lastuptime"`
+	AdjSIDs    []tlv.AdjSID       `json:"adj-sids,omitempty"`
+	EndXSIDs   []tlv.SRv6EndXSID  `json:"srv6-endx-sids,omitempty"`
+	Topologies []uint16           `json:"topologies,omitempty"`
+	BFD        []*bfd.SessionInfo `json:"bfd,omitempty"`
 }
 
 // Value is a level specific value.
@@ -223,10 +436,20 @@ type LevValue struct {
 type YangInterface struct {
 	Name       string         `json:"name"`
 	LevelType  clns.LevelFlag `json:"level-type"`
-	HelloInt   LevValue       `json:"hello-interval"`
+	Passive    bool           `json:"passive,omitempty"`
+	HelloInt   LevValue       `json:"hello-interval"` // milliseconds
 	HelloMult  LevValue       `json:"hello-multiplier"`
 	Priority   LevValue       `json:"priority"`
 	Metric     LevValue       `json:"metric"`
+	Tags       []uint32       `json:"tag,omitempty"`
+	Tags64     []uint64       `json:"tag64,omitempty"`
+	NodeFlag   bool           `json:"node-flag,omitempty"`
+	TE         *TEConfig      `json:"te,omitempty"`
+	SRLG       []uint32       `json:"srlg,omitempty"`
+	Measured   *tlv.LinkTE    `json:"measured-link-attributes,omitempty"`
+	Topologies []uint16       `json:"topologies,omitempty"`
+	FRR        *FRRConfig     `json:"fast-reroute,omitempty"`
+	BFD        *BFDConfig     `json:"bfd,omitempty"`
 	Adjcencies struct {
 		Adj []*YangAdj `json:"adjacency"`
 	} `json:"adjcencies"`
@@ -308,6 +531,446 @@ func muxDB(w http.ResponseWriter, r *http.Request, updb [2]*update.DB) {
 	}
 }
 
+func lspLogData(updb [2]*update.DB) ([]*update.YangLSPLog, error) {
+	var alldata []*update.YangLSPLog
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		logdata, err := db.LSPLog()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, logdata...)
+	}
+	return alldata, nil
+}
+
+func muxLSPLog(w http.ResponseWriter, r *http.Request, updb [2]*update.DB) {
+	w.WriteHeader(http.StatusOK)
+
+	logdata, err := lspLogData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	jvars, err := json.Marshal(LSPLogList{logdata})
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	_, err = io.WriteString(w, string(jvars))
+	if err != nil {
+		errToHTTP(w, err)
+	}
+}
+
+func spfLogData(updb [2]*update.DB) ([]*update.YangSPFLog, error) {
+	var alldata []*update.YangSPFLog
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		logdata, err := db.SPFLog()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, logdata...)
+	}
+	return alldata, nil
+}
+
+func muxSPFLog(w http.ResponseWriter, r *http.Request, updb [2]*update.DB) {
+	w.WriteHeader(http.StatusOK)
+
+	logdata, err := spfLogData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	jvars, err := json.Marshal(SPFLogList{logdata})
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	_, err = io.WriteString(w, string(jvars))
+	if err != nil {
+		errToHTTP(w, err)
+	}
+}
+
+func spfDelayData(updb [2]*update.DB) ([]*update.YangSPFDelay, error) {
+	var alldata []*update.YangSPFDelay
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		data, err := db.SPFDelay()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, data)
+	}
+	return alldata, nil
+}
+
+func muxSPFDelay(w http.ResponseWriter, r *http.Request, updb [2]*update.DB) {
+	w.WriteHeader(http.StatusOK)
+
+	data, err := spfDelayData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	jvars, err := json.Marshal(SPFDelayList{data})
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	_, err = io.WriteString(w, string(jvars))
+	if err != nil {
+		errToHTTP(w, err)
+	}
+}
+
+func overloadData(updb [2]*update.DB) ([]*update.YangOverload, error) {
+	var alldata []*update.YangOverload
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		oldata, err := db.Overload()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, oldata)
+	}
+	return alldata, nil
+}
+
+// muxOverload returns the overload state, a PUT of {"status": bool} sets or
+// clears the administrative overload bit.
+func muxOverload(w http.ResponseWriter, r *http.Request, updb [2]*update.DB) {
+	if r.Method == http.MethodPut {
+		var in struct {
+			Status bool `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, db := range updb {
+			if db != nil {
+				db.SetOverload(in.Status)
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+
+	oldata, err := overloadData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	jvars, err := json.Marshal(OverloadList{oldata})
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	_, err = io.WriteString(w, string(jvars))
+	if err != nil {
+		errToHTTP(w, err)
+	}
+}
+
+func attachedData(updb [2]*update.DB) ([]*update.YangAttached, error) {
+	var alldata []*update.YangAttached
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		attdata, err := db.AttachedBit()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, attdata)
+	}
+	return alldata, nil
+}
+
+func redistData(updb [2]*update.DB) ([]*update.YangRedist, error) {
+	var alldata []*update.YangRedist
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		rdata, err := db.Redistributed()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, rdata...)
+	}
+	return alldata, nil
+}
+
+func routerCapData(updb [2]*update.DB) ([]*update.YangRouterCap, error) {
+	var alldata []*update.YangRouterCap
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		cdata, err := db.RouterCaps()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, cdata...)
+	}
+	return alldata, nil
+}
+
+// sidConflictData returns the prefix SID conflicts of the levels.
+func sidConflictData(updb [2]*update.DB) ([]*update.YangSIDConflict, error) {
+	var alldata []*update.YangSIDConflict
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		cdata, err := db.SIDConflicts()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, cdata...)
+	}
+	return alldata, nil
+}
+
+// lfibData returns the label forwarding tables of the levels.
+func lfibData(updb [2]*update.DB) ([]*update.LabelRoute, error) {
+	var alldata []*update.LabelRoute
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		ldata, err := db.LFIB()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, ldata...)
+	}
+	return alldata, nil
+}
+
+func srData(updb [2]*update.DB) (*SRState, error) {
+	lfib, err := lfibData(updb)
+	if err != nil {
+		return nil, err
+	}
+	conflicts, err := sidConflictData(updb)
+	if err != nil {
+		return nil, err
+	}
+	srv6, err := srv6Data(updb)
+	if err != nil {
+		return nil, err
+	}
+	return &SRState{LFIBList{lfib}, SIDConflictList{conflicts}, *srv6}, nil
+}
+
+// srv6Data returns the SRv6 locator routes and local SIDs of the levels.
+func srv6Data(updb [2]*update.DB) (*SRv6State, error) {
+	state := &SRv6State{}
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		ys, err := db.SRv6()
+		if err != nil {
+			return nil, err
+		}
+		state.Locators = append(state.Locators, ys.Locators...)
+		state.LocalSIDs = append(state.LocalSIDs, ys.LocalSIDs...)
+	}
+	return state, nil
+}
+
+// flexAlgoData returns the flexible algorithm topologies of the levels.
+func flexAlgoData(updb [2]*update.DB) ([]*update.YangFlexAlgo, error) {
+	var alldata []*update.YangFlexAlgo
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		fdata, err := db.FlexAlgos()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, fdata...)
+	}
+	return alldata, nil
+}
+
+// frrData returns the fast reroute coverage of the levels.
+func frrData(updb [2]*update.DB) ([]*update.YangFRRCoverage, error) {
+	var alldata []*update.YangFRRCoverage
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		cov, err := db.FRRCoverage()
+		if err != nil {
+			return nil, err
+		}
+		if cov != nil {
+			alldata = append(alldata, cov)
+		}
+	}
+	return alldata, nil
+}
+
+// uloopData returns the microloop avoidance state of the levels.
+func uloopData(updb [2]*update.DB) ([]*update.YangULoop, error) {
+	var alldata []*update.YangULoop
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		data, err := db.MicroloopAvoidance()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, data)
+	}
+	return alldata, nil
+}
+
+func ribData(updb [2]*update.DB) ([]*update.Route, error) {
+	var alldata []*update.Route
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		routes, err := db.LocalRIB()
+		if err != nil {
+			return nil, err
+		}
+		alldata = append(alldata, routes...)
+	}
+	return alldata, nil
+}
+
+func muxRIB(w http.ResponseWriter, r *http.Request, updb [2]*update.DB) {
+	w.WriteHeader(http.StatusOK)
+
+	routes, err := ribData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	jvars, err := json.Marshal(RouteList{routes})
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	_, err = io.WriteString(w, string(jvars))
+	if err != nil {
+		errToHTTP(w, err)
+	}
+}
+
+func muxSR(w http.ResponseWriter, r *http.Request, updb [2]*update.DB) {
+	w.WriteHeader(http.StatusOK)
+
+	sr, err := srData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	jvars, err := json.Marshal(sr)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	_, err = io.WriteString(w, string(jvars))
+	if err != nil {
+		errToHTTP(w, err)
+	}
+}
+
+func muxFlexAlgo(w http.ResponseWriter, r *http.Request, updb [2]*update.DB) {
+	w.WriteHeader(http.StatusOK)
+
+	flexAlgos, err := flexAlgoData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	jvars, err := json.Marshal(FlexAlgoList{flexAlgos})
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	_, err = io.WriteString(w, string(jvars))
+	if err != nil {
+		errToHTTP(w, err)
+	}
+}
+
+func muxFRR(w http.ResponseWriter, r *http.Request, updb [2]*update.DB) {
+	w.WriteHeader(http.StatusOK)
+
+	frr, err := frrData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	jvars, err := json.Marshal(FRRList{frr})
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	_, err = io.WriteString(w, string(jvars))
+	if err != nil {
+		errToHTTP(w, err)
+	}
+}
+
+func muxULoop(w http.ResponseWriter, r *http.Request, updb [2]*update.DB) {
+	w.WriteHeader(http.StatusOK)
+
+	uloop, err := uloopData(updb)
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	jvars, err := json.Marshal(ULoopList{uloop})
+	if err != nil {
+		errToHTTP(w, err)
+		return
+	}
+
+	_, err = io.WriteString(w, string(jvars))
+	if err != nil {
+		errToHTTP(w, err)
+	}
+}
+
 // SetupManagement initializes the management interface.
 func SetupManagement(cdb *CircuitDB, updb [2]*update.DB) error {
 	rootF := func(w http.ResponseWriter, r *http.Request) {
@@ -322,6 +985,42 @@ func SetupManagement(cdb *CircuitDB, updb [2]*update.DB) error {
 		fmt.Printf("calling muxDB")
 		muxDB(w, r, updb)
 	}
+	logF := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("calling muxLSPLog")
+		muxLSPLog(w, r, updb)
+	}
+	spfLogF := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("calling muxSPFLog")
+		muxSPFLog(w, r, updb)
+	}
+	sdF := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("calling muxSPFDelay")
+		muxSPFDelay(w, r, updb)
+	}
+	olF := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("calling muxOverload")
+		muxOverload(w, r, updb)
+	}
+	ribF := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("calling muxRIB")
+		muxRIB(w, r, updb)
+	}
+	srF := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("calling muxSR")
+		muxSR(w, r, updb)
+	}
+	faF := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("calling muxFlexAlgo")
+		muxFlexAlgo(w, r, updb)
+	}
+	frrF := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("calling muxFRR")
+		muxFRR(w, r, updb)
+	}
+	ulF := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("calling muxULoop")
+		muxULoop(w, r, updb)
+	}
 	r := mux.NewRouter()
 	r.HandleFunc("/isis", rootF)
 	r.HandleFunc("/isis/interfaces", intfF)
@@ -330,6 +1029,15 @@ func SetupManagement(cdb *CircuitDB, updb [2]*update.DB) error {
 	r.HandleFunc("/isis/db", updF)
 	r.HandleFunc("/isis/db={level}", updF)
 	r.HandleFunc("/isis/db={level}/{lspid}", updF)
+	r.HandleFunc("/isis/lsp-log", logF)
+	r.HandleFunc("/isis/spf-log", spfLogF)
+	r.HandleFunc("/isis/spf-delay", sdF)
+	r.HandleFunc("/isis/overload", olF).Methods("GET", "PUT")
+	r.HandleFunc("/isis/local-rib", ribF)
+	r.HandleFunc("/isis/segment-routing", srF)
+	r.HandleFunc("/isis/flex-algo", faF)
+	r.HandleFunc("/isis/fast-reroute", frrF)
+	r.HandleFunc("/isis/microloop-avoidance", ulF)
 
 	return http.ListenAndServe("localhost:8080", r)
 }
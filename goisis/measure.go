@@ -0,0 +1,565 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/choppsv1/goisis/goisis/update"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"io/ioutil"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Link measurement defaults.
+const (
+	DefMeasurePort          = 8862
+	DefMeasureProbeInterval = time.Second
+	DefMeasureInterval      = 30 * time.Second
+	DefMeasureMinAdvertise  = 30 * time.Second
+	DefMeasureChange        = 10.0 // percent
+)
+
+// MeasureConfig configures the active measurement of the RFC8570 link
+// attributes of our circuits. Intervals are in milliseconds, delay thresholds
+// in microseconds, loss and change thresholds in percent. A zero threshold
+// never sets the anomalous flag, a zero reuse threshold is the threshold.
+type MeasureConfig struct {
+	Enable          bool    `json:"enable"`
+	Port            int     `json:"port,omitempty"`
+	ProbeInterval   uint    `json:"probe-interval,omitempty"`
+	Interval        uint    `json:"measurement-interval,omitempty"`
+	MinAdvertise    uint    `json:"min-advertisement-interval,omitempty"`
+	ChangeThreshold float64 `json:"change-threshold,omitempty"`
+	DelayThreshold  uint32  `json:"delay-threshold,omitempty"`
+	DelayReuse      uint32  `json:"delay-reuse-threshold,omitempty"`
+	LossThreshold   float64 `json:"loss-threshold,omitempty"`
+	LossReuse       float64 `json:"loss-reuse-threshold,omitempty"`
+}
+
+// validate checks the link measurement configuration.
+func (mc *MeasureConfig) validate() error {
+	if mc.Port < 0 || mc.Port > 65535 {
+		return fmt.Errorf("link-measurement: invalid port %d", mc.Port)
+	}
+	if mc.DelayReuse > mc.DelayThreshold {
+		return fmt.Errorf("link-measurement: delay-reuse-threshold above delay-threshold")
+	}
+	if mc.LossReuse > mc.LossThreshold || mc.LossThreshold > 100 {
+		return fmt.Errorf("link-measurement: invalid loss thresholds")
+	}
+	return nil
+}
+
+func (mc *MeasureConfig) port() int {
+	if mc.Port == 0 {
+		return DefMeasurePort
+	}
+	return mc.Port
+}
+
+// -----------------------------------------------------------------
+// Probe packet
+//
+// A probe is a timestamped UDP packet echoed by the responder of the
+// neighbor, the timestamps are nanoseconds.
+//
+//    magic (4) | seqno (4) | t1 sent (8) | t2 received (8) | t3 echoed (8)
+// -----------------------------------------------------------------
+
+const (
+	probeMagic = 0x49534c4d // "ISLM"
+	probeLen   = 32
+)
+
+type probe struct {
+	seqno      uint32
+	t1, t2, t3 int64
+}
+
+func (p *probe) encode(b []byte) []byte {
+	b = b[:probeLen]
+	binary.BigEndian.PutUint32(b, probeMagic)
+	binary.BigEndian.PutUint32(b[4:], p.seqno)
+	binary.BigEndian.PutUint64(b[8:], uint64(p.t1))
+	binary.BigEndian.PutUint64(b[16:], uint64(p.t2))
+	binary.BigEndian.PutUint64(b[24:], uint64(p.t3))
+	return b
+}
+
+func decodeProbe(b []byte) (*probe, error) {
+	if len(b) < probeLen || binary.BigEndian.Uint32(b) != probeMagic {
+		return nil, fmt.Errorf("invalid probe")
+	}
+	return &probe{
+		seqno: binary.BigEndian.Uint32(b[4:]),
+		t1:    int64(binary.BigEndian.Uint64(b[8:])),
+		t2:    int64(binary.BigEndian.Uint64(b[16:])),
+		t3:    int64(binary.BigEndian.Uint64(b[24:])),
+	}, nil
+}
+
+// rtt returns the round trip time of the probe echoed at t2 and t3 and
+// received back at t4, the time spent in the responder removed. It returns
+// false if the timestamps are inconsistent.
+func (p *probe) rtt(t4 int64) (time.Duration, bool) {
+	rtt := (t4 - p.t1) - (p.t3 - p.t2)
+	if rtt < 0 {
+		return 0, false
+	}
+	return time.Duration(rtt), true
+}
+
+// probeReply is the round trip time measured with a probe.
+type probeReply struct {
+	seqno uint32
+	rtt   time.Duration
+}
+
+// upAdjAddrs returns the interface addresses of the Up adjacencies of the
+// circuit on all levels, only the preferred one of each adjacency unless 'all'.
+func (c *CircuitLAN) upAdjAddrs(all bool) []net.IP {
+	var addrs []net.IP
+	for _, link := range c.levlink {
+		if link == nil {
+			continue
+		}
+		i, err := DoRPC(link.rpC, func() interface{} {
+			var ips []net.IP
+			for _, a := range link.srcidMap {
+				if a.state != AdjStateUp {
+					continue
+				}
+				if all {
+					ips = append(ips, a.v4addrs...)
+					ips = append(ips, a.v6addrs...)
+				} else if len(a.v4addrs) != 0 {
+					ips = append(ips, a.v4addrs[0])
+				} else if len(a.v6addrs) != 0 {
+					ips = append(ips, a.v6addrs[0])
+				}
+			}
+			return ips
+		})
+		if err != nil {
+			continue
+		}
+		addrs = append(addrs, i.([]net.IP)...)
+	}
+	return addrs
+}
+
+// isNeighbor returns true if the probe source is an interface address of an Up
+// adjacency of the circuits, a link-local source only of the circuit of its
+// zone.
+func isNeighbor(circuits []*CircuitLAN, src *net.UDPAddr) bool {
+	for _, c := range circuits {
+		if src.Zone != "" && src.Zone != c.intf.Name {
+			continue
+		}
+		for _, ip := range c.upAdjAddrs(true) {
+			if ip.Equal(src.IP) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StartMeasureResponder starts echoing the probes of our neighbors on the
+// circuits, the probes from other sources are dropped.
+func StartMeasureResponder(mc *MeasureConfig, circuits []*CircuitLAN) error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: mc.port()})
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-GlbQuit
+		conn.Close()
+	}()
+	go func() {
+		b := make([]byte, probeLen)
+		for {
+			n, addr, err := conn.ReadFromUDP(b)
+			if err != nil {
+				return
+			}
+			t2 := time.Now().UnixNano()
+			p, err := decodeProbe(b[:n])
+			if err != nil {
+				Debug(DbgFPkt, "Measure: %s from %s", err, addr)
+				continue
+			}
+			if !isNeighbor(circuits, addr) {
+				Debug(DbgFPkt, "Measure: probe from non-neighbor %s", addr)
+				continue
+			}
+			p.t2 = t2
+			p.t3 = time.Now().UnixNano()
+			if _, err = conn.WriteToUDP(p.encode(b), addr); err != nil {
+				Debug(DbgFPkt, "Measure: error echoing to %s: %s", addr, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// -----------------------------------------------------------------
+// Measurement
+// -----------------------------------------------------------------
+
+// measurer probes the neighbors on a circuit and advertises the measured link
+// attributes on each level. On a LAN the samples to all neighbors are
+// combined into the attributes of the link to the pseudo-node.
+type measurer struct {
+	mc     *MeasureConfig
+	c      *CircuitLAN
+	conn   *net.UDPConn
+	replyC chan probeReply
+
+	// Current measurement window.
+	seqno    uint32
+	winSeqno uint32
+	sent     int
+	delays   []uint32 // microseconds
+	txBytes  uint64
+	txTime   time.Time
+
+	// Advertised state.
+	delayAnom bool
+	lossAnom  bool
+	adv       *tlv.LinkTE
+	advTime   time.Time
+}
+
+// StartMeasurement starts measuring the link attributes of the circuit.
+func StartMeasurement(mc *MeasureConfig, c *CircuitLAN) error {
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return err
+	}
+	m := &measurer{
+		mc:     mc,
+		c:      c,
+		conn:   conn,
+		replyC: make(chan probeReply, 10),
+	}
+	go m.read()
+	go m.run()
+	return nil
+}
+
+func (m *measurer) read() {
+	b := make([]byte, probeLen)
+	for {
+		n, _, err := m.conn.ReadFromUDP(b)
+		if err != nil {
+			close(m.replyC)
+			return
+		}
+		t4 := time.Now().UnixNano()
+		p, err := decodeProbe(b[:n])
+		if err != nil {
+			continue
+		}
+		rtt, ok := p.rtt(t4)
+		if !ok {
+			continue
+		}
+		select {
+		case m.replyC <- probeReply{p.seqno, rtt}:
+		case <-m.c.quit:
+			return
+		}
+	}
+}
+
+func (m *measurer) run() {
+	defer m.conn.Close()
+
+	probeTicker := time.NewTicker(msecOrDefault(m.mc.ProbeInterval, DefMeasureProbeInterval))
+	defer probeTicker.Stop()
+	winTicker := time.NewTicker(msecOrDefault(m.mc.Interval, DefMeasureInterval))
+	defer winTicker.Stop()
+
+	m.startWindow()
+	for {
+		select {
+		case <-probeTicker.C:
+			m.probe()
+		case r, ok := <-m.replyC:
+			if !ok {
+				return
+			}
+			// Drop replies to probes sent in earlier windows.
+			if r.seqno-m.winSeqno < uint32(m.sent) {
+				m.delays = append(m.delays, uint32(r.rtt/2/time.Microsecond))
+			}
+		case <-winTicker.C:
+			m.endWindow()
+			m.startWindow()
+		case <-m.c.quit:
+			return
+		}
+	}
+}
+
+// neighbors returns the addresses of the Up adjacencies on all levels.
+func (m *measurer) neighbors() []*net.UDPAddr {
+	var addrs []*net.UDPAddr
+	seen := make(map[string]bool)
+	for _, ip := range m.c.upAdjAddrs(false) {
+		if seen[ip.String()] {
+			continue
+		}
+		seen[ip.String()] = true
+		addr := &net.UDPAddr{IP: ip, Port: m.mc.port()}
+		if ip.IsLinkLocalUnicast() {
+			addr.Zone = m.c.intf.Name
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// probe sends a probe to each neighbor.
+func (m *measurer) probe() {
+	b := make([]byte, probeLen)
+	for _, addr := range m.neighbors() {
+		p := &probe{seqno: m.seqno, t1: time.Now().UnixNano()}
+		m.seqno++
+		m.sent++
+		if _, err := m.conn.WriteToUDP(p.encode(b), addr); err != nil {
+			Debug(DbgFPkt, "%s: error sending probe to %s: %s", m.c, addr, err)
+		}
+	}
+}
+
+// txCounter returns the number of bytes sent on the interface.
+func (m *measurer) txCounter() (uint64, error) {
+	path := "/sys/class/net/" + m.c.intf.Name + "/statistics/tx_bytes"
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+func (m *measurer) startWindow() {
+	m.winSeqno = m.seqno
+	m.sent = 0
+	m.delays = m.delays[:0]
+	m.txTime = time.Now()
+	m.txBytes, _ = m.txCounter()
+}
+
+// anomalous returns the anomalous flag for the value, once set the flag
+// remains set until the value drops to the reuse threshold.
+func anomalous(set bool, value, threshold, reuse float64) bool {
+	if threshold == 0 {
+		return false
+	}
+	if reuse == 0 {
+		reuse = threshold
+	}
+	if value >= threshold {
+		return true
+	}
+	if value < reuse {
+		return false
+	}
+	return set
+}
+
+// lossValue converts a loss percentage to the RFC8570 units of 0.000003%.
+func lossValue(percent float64) uint32 {
+	v := percent / 0.000003
+	if v > tlv.TEMaxValue {
+		return tlv.TEMaxValue
+	}
+	return uint32(v)
+}
+
+// endWindow computes the attributes measured in the window and advertises them
+// if required.
+func (m *measurer) endWindow() {
+	te := &tlv.LinkTE{}
+	if n := len(m.delays); n != 0 {
+		var sum, dvsum uint64
+		min, max := m.delays[0], m.delays[0]
+		for i, d := range m.delays {
+			sum += uint64(d)
+			if d < min {
+				min = d
+			}
+			if d > max {
+				max = d
+			}
+			if i > 0 {
+				dv := int64(d) - int64(m.delays[i-1])
+				if dv < 0 {
+					dv = -dv
+				}
+				dvsum += uint64(dv)
+			}
+		}
+		avg := uint32(sum / uint64(n))
+		m.delayAnom = anomalous(m.delayAnom, float64(avg), float64(m.mc.DelayThreshold), float64(m.mc.DelayReuse))
+		te.Delay = &tlv.TEValue{Anomalous: m.delayAnom, Value: avg}
+		te.MinMaxDelay = &tlv.TEMinMax{Anomalous: m.delayAnom, Min: min, Max: max}
+		var dv uint32
+		if n > 1 {
+			dv = uint32(dvsum / uint64(n-1))
+		}
+		te.DelayVar = &dv
+	}
+	if m.sent != 0 {
+		lost := m.sent - len(m.delays)
+		if lost < 0 {
+			lost = 0
+		}
+		percent := float64(lost) * 100 / float64(m.sent)
+		m.lossAnom = anomalous(m.lossAnom, percent, m.mc.LossThreshold, m.mc.LossReuse)
+		te.Loss = &tlv.TEValue{Anomalous: m.lossAnom, Value: lossValue(percent)}
+	}
+	if tx, err := m.txCounter(); err == nil && tx >= m.txBytes {
+		utilized := float32(float64(tx-m.txBytes) / time.Since(m.txTime).Seconds())
+		te.UtilizedBW = &utilized
+		// Without RSVP-TE nothing is reserved so residual is the maximum.
+		if tc := m.c.config.TE; tc != nil && tc.MaxBW != 0 {
+			residual := tc.MaxBW
+			available := float32(math.Max(float64(residual-utilized), 0))
+			te.ResidualBW = &residual
+			te.AvailableBW = &available
+		}
+	}
+
+	if !m.significant(te) {
+		return
+	}
+	m.adv = te
+	m.advTime = time.Now()
+	m.advertise(te)
+}
+
+// changed returns true if the value changed by at least the change threshold.
+func (m *measurer) changed(old, new float64) bool {
+	if old == new {
+		return false
+	}
+	if old == 0 {
+		return true
+	}
+	threshold := m.mc.ChangeThreshold
+	if threshold == 0 {
+		threshold = DefMeasureChange
+	}
+	return math.Abs(new-old)*100/old >= threshold
+}
+
+func (m *measurer) changedValue(old, new *tlv.TEValue) bool {
+	if old == nil || new == nil {
+		return old != new
+	}
+	return old.Anomalous != new.Anomalous || m.changed(float64(old.Value), float64(new.Value))
+}
+
+func (m *measurer) changedFloat(old, new *float32) bool {
+	if old == nil || new == nil {
+		return old != new
+	}
+	return m.changed(float64(*old), float64(*new))
+}
+
+// significant returns true if the measured attributes should be advertised. A
+// change of an anomalous flag is advertised right away, other changes are
+// damped by the change threshold and the minimum advertisement interval.
+func (m *measurer) significant(te *tlv.LinkTE) bool {
+	old := m.adv
+	if old == nil {
+		return true
+	}
+	if (old.Delay == nil) != (te.Delay == nil) || (old.Loss == nil) != (te.Loss == nil) {
+		return true
+	}
+	if old.Delay != nil && old.Delay.Anomalous != te.Delay.Anomalous {
+		return true
+	}
+	if old.Loss != nil && old.Loss.Anomalous != te.Loss.Anomalous {
+		return true
+	}
+	if time.Since(m.advTime) < msecOrDefault(m.mc.MinAdvertise, DefMeasureMinAdvertise) {
+		return false
+	}
+	if m.changedValue(old.Delay, te.Delay) || m.changedValue(old.Loss, te.Loss) {
+		return true
+	}
+	if old.MinMaxDelay != nil && (m.changed(float64(old.MinMaxDelay.Min), float64(te.MinMaxDelay.Min)) ||
+		m.changed(float64(old.MinMaxDelay.Max), float64(te.MinMaxDelay.Max))) {
+		return true
+	}
+	if old.DelayVar != nil && m.changed(float64(*old.DelayVar), float64(*te.DelayVar)) {
+		return true
+	}
+	return m.changedFloat(old.UtilizedBW, te.UtilizedBW) || m.changedFloat(old.AvailableBW, te.AvailableBW)
+}
+
+// advertise stores the measured attributes with the level links and updates
+// our LSPs.
+func (m *measurer) advertise(te *tlv.LinkTE) {
+	Debug(DbgFUpd, "%s: advertising measured link attributes %+v", m.c, te)
+	for li, link := range m.c.levlink {
+		if link == nil {
+			continue
+		}
+		_, _ = DoRPC(link.rpC, func() interface{} { // nolint
+			link.measured = te
+			return nil
+		})
+		m.c.updb[li].SomethingChanged(nil, update.GenReasonLink)
+	}
+}
+
+// mergeMeasured returns the configured link attributes with the measured
+// attributes added.
+func mergeMeasured(te, measured *tlv.LinkTE) *tlv.LinkTE {
+	if measured == nil {
+		return te
+	}
+	if te == nil {
+		te = &tlv.LinkTE{}
+	}
+	te.Delay = measured.Delay
+	te.MinMaxDelay = measured.MinMaxDelay
+	te.DelayVar = measured.DelayVar
+	te.Loss = measured.Loss
+	te.ResidualBW = measured.ResidualBW
+	te.AvailableBW = measured.AvailableBW
+	te.UtilizedBW = measured.UtilizedBW
+	return te
+}
+
+// StartLinkMeasurement starts the responder and measures the link attributes
+// of the circuits if enabled.
+func StartLinkMeasurement(config *Config, circuits []*CircuitLAN) {
+	mc := config.Measure
+	if mc == nil || !mc.Enable {
+		return
+	}
+	if err := StartMeasureResponder(mc, circuits); err != nil {
+		Info("Link measurement responder: %s", err)
+		return
+	}
+	for _, c := range circuits {
+		if err := StartMeasurement(mc, c); err != nil {
+			Info("%s: link measurement: %s", c, err)
+		}
+	}
+}
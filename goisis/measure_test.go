@@ -0,0 +1,172 @@
+package main
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	p := &probe{seqno: 7, t1: 1000, t2: 5000, t3: 5200}
+	b := p.encode(make([]byte, probeLen+8))
+	if len(b) != probeLen {
+		t.Fatalf("Bad probe length %d", len(b))
+	}
+	dp, err := decodeProbe(b)
+	if err != nil || *dp != *p {
+		t.Errorf("Decoded %+v want %+v: %v", dp, p, err)
+	}
+	if _, err = decodeProbe(b[:probeLen-1]); err == nil {
+		t.Errorf("Short probe decoded")
+	}
+	b[0]++
+	if _, err = decodeProbe(b); err == nil {
+		t.Errorf("Probe with bad magic decoded")
+	}
+
+	// The 200ns spent in the responder aren't part of the round trip time.
+	if rtt, ok := p.rtt(2000); !ok || rtt != 800 {
+		t.Errorf("Bad round trip time %s %v", rtt, ok)
+	}
+	if _, ok := p.rtt(1100); ok {
+		t.Errorf("Negative round trip time accepted")
+	}
+	if p.t1 != 1000 {
+		t.Errorf("Probe changed %+v", p)
+	}
+}
+
+func TestAnomalous(t *testing.T) {
+	tests := []struct {
+		set                     bool
+		value, threshold, reuse float64
+		want                    bool
+	}{
+		{false, 100, 0, 0, false},
+		{true, 100, 0, 0, false},
+		{false, 10, 10, 0, true},
+		{true, 9, 10, 0, false},
+		{false, 10, 10, 5, true},
+		// Between the reuse threshold and the threshold the flag is kept.
+		{true, 7, 10, 5, true},
+		{false, 7, 10, 5, false},
+		{true, 5, 10, 5, true},
+		{true, 4, 10, 5, false},
+	}
+	for _, test := range tests {
+		if got := anomalous(test.set, test.value, test.threshold, test.reuse); got != test.want {
+			t.Errorf("anomalous(%v, %v, %v, %v) = %v want %v", test.set, test.value, test.threshold,
+				test.reuse, got, test.want)
+		}
+	}
+}
+
+func TestLossValue(t *testing.T) {
+	tests := []struct {
+		percent float64
+		want    uint32
+	}{
+		{0, 0},
+		{0.000003, 1},
+		{3, 1000000},
+		{50.331645, tlv.TEMaxValue},
+		{100, tlv.TEMaxValue},
+	}
+	for _, test := range tests {
+		if got := lossValue(test.percent); got != test.want {
+			t.Errorf("lossValue(%v) = %d want %d", test.percent, got, test.want)
+		}
+	}
+}
+
+// measuredTE returns measured attributes with the average delay and loss.
+func measuredTE(delay, loss uint32, anomalous bool) *tlv.LinkTE {
+	dv := uint32(10)
+	return &tlv.LinkTE{
+		Delay:       &tlv.TEValue{Anomalous: anomalous, Value: delay},
+		MinMaxDelay: &tlv.TEMinMax{Anomalous: anomalous, Min: delay, Max: delay},
+		DelayVar:    &dv,
+		Loss:        &tlv.TEValue{Value: loss},
+	}
+}
+
+func TestSignificant(t *testing.T) {
+	adv := measuredTE(1000, 100, false)
+	tests := []struct {
+		name   string
+		age    time.Duration // since the last advertisement
+		te     *tlv.LinkTE
+		change float64
+		want   bool
+	}{
+		{"unchanged", time.Minute, measuredTE(1000, 100, false), 0, false},
+		{"below threshold", time.Minute, measuredTE(1090, 100, false), 0, false},
+		{"threshold", time.Minute, measuredTE(1100, 100, false), 0, true},
+		{"configured threshold", time.Minute, measuredTE(1100, 100, false), 20, false},
+		{"loss", time.Minute, measuredTE(1000, 50, false), 0, true},
+		{"damped", time.Second, measuredTE(2000, 100, false), 0, false},
+		{"anomalous", time.Second, measuredTE(1000, 100, true), 0, true},
+		{"no delay", time.Second, &tlv.LinkTE{Loss: adv.Loss}, 0, true},
+	}
+	for _, test := range tests {
+		m := &measurer{
+			mc:      &MeasureConfig{ChangeThreshold: test.change},
+			adv:     adv,
+			advTime: time.Now().Add(-test.age),
+		}
+		if got := m.significant(test.te); got != test.want {
+			t.Errorf("%s: significant %v want %v", test.name, got, test.want)
+		}
+	}
+	m := &measurer{mc: &MeasureConfig{}}
+	if !m.significant(adv) {
+		t.Errorf("First measurement not significant")
+	}
+}
+
+func TestIsNeighbor(t *testing.T) {
+	link := &LinkLAN{rpC: make(chan RPC), srcidMap: make(map[clns.SystemID]*Adj)}
+	link.srcidMap[clns.SystemID{1}] = &Adj{
+		state:   AdjStateUp,
+		v4addrs: []net.IP{net.ParseIP("10.0.0.2")},
+		v6addrs: []net.IP{net.ParseIP("fe80::2")},
+	}
+	link.srcidMap[clns.SystemID{2}] = &Adj{
+		state:   AdjStateInit,
+		v4addrs: []net.IP{net.ParseIP("10.0.0.3")},
+	}
+	quit := make(chan bool)
+	defer close(quit)
+	go func() {
+		for {
+			select {
+			case in := <-link.rpC:
+				in.Result <- in.F()
+			case <-quit:
+				return
+			}
+		}
+	}()
+	c := &CircuitLAN{CircuitBase: &CircuitBase{intf: &net.Interface{Name: "eth0"}}}
+	c.levlink[1] = link
+
+	tests := []struct {
+		ip   string
+		zone string
+		want bool
+	}{
+		{"10.0.0.2", "", true},
+		{"10.0.0.3", "", false},
+		{"10.0.0.4", "", false},
+		{"fe80::2", "eth0", true},
+		{"fe80::2", "eth1", false},
+	}
+	for _, test := range tests {
+		src := &net.UDPAddr{IP: net.ParseIP(test.ip), Zone: test.zone, Port: 1000}
+		if got := isNeighbor([]*CircuitLAN{c}, src); got != test.want {
+			t.Errorf("%s%%%s: neighbor %v want %v", test.ip, test.zone, got, test.want)
+		}
+	}
+}
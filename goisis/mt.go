@@ -0,0 +1,154 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+package main
+
+import (
+	"fmt"
+	"github.com/choppsv1/goisis/tlv"
+	"sort"
+)
+
+// Topology names, the names of the ribs of the yang model.
+const (
+	TopologyStandard = "standard"
+	TopologyIPv6     = "ipv6-unicast"
+)
+
+var topologyIDs = map[string]uint16{
+	TopologyStandard: tlv.MTIDStandard,
+	TopologyIPv6:     tlv.MTIDIPv6,
+}
+
+// validateTopologies checks the instance and interface topologies, the
+// interfaces may only use topologies of the instance.
+func (config *Config) validateTopologies() error {
+	for _, name := range config.Topologies {
+		if _, ok := topologyIDs[name]; !ok {
+			return fmt.Errorf("topologies: unknown topology %s", name)
+		}
+	}
+	for _, ic := range config.Interfaces {
+		if len(ic.Topologies) != 0 && len(config.Topologies) == 0 {
+			return fmt.Errorf("interface %s: topologies without multi-topology", ic.Name)
+		}
+		for _, name := range ic.Topologies {
+			if _, ok := topologyIDs[name]; !ok {
+				return fmt.Errorf("interface %s: unknown topology %s", ic.Name, name)
+			}
+			if name != TopologyStandard && !config.hasTopology(name) {
+				return fmt.Errorf("interface %s: topology %s not enabled", ic.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (config *Config) hasTopology(name string) bool {
+	for _, n := range config.Topologies {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// topologyIDList returns the sorted IDs of the named topologies.
+func topologyIDList(names []string) []uint16 {
+	var mtids []uint16
+	seen := make(map[uint16]bool)
+	for _, name := range names {
+		mtid := topologyIDs[name]
+		if !seen[mtid] {
+			seen[mtid] = true
+			mtids = append(mtids, mtid)
+		}
+	}
+	sort.Slice(mtids, func(i, j int) bool { return mtids[i] < mtids[j] })
+	return mtids
+}
+
+// mtids returns the topologies of the instance or nil if not multi-topology.
+// The standard topology is always included.
+func (config *Config) mtids() []uint16 {
+	if len(config.Topologies) == 0 {
+		return nil
+	}
+	return topologyIDList(append([]string{TopologyStandard}, config.Topologies...))
+}
+
+// intfMTIDs returns the topologies of the interface, all the topologies of
+// the instance by default, nil if not multi-topology.
+func (config *Config) intfMTIDs(ic *InterfaceConfig) []uint16 {
+	if len(config.Topologies) == 0 {
+		return nil
+	}
+	if len(ic.Topologies) == 0 {
+		return config.mtids()
+	}
+	return topologyIDList(ic.Topologies)
+}
+
+// Topologies returns the topologies the circuit is in, nil if not
+// multi-topology.
+func (cb *CircuitBase) Topologies() []uint16 {
+	return GlbConfig.intfMTIDs(cb.config)
+}
+
+// mts returns the topologies for the Multi-Topology TLV of our IIH.
+func mts(mtids []uint16) []tlv.MT {
+	var mts []tlv.MT
+	for _, mtid := range mtids {
+		mts = append(mts, tlv.MT{ID: mtid})
+	}
+	return mts
+}
+
+// decodeMTIDs returns the topologies of the neighbor's IIH, nil if it has no
+// Multi-Topology TLV.
+func decodeMTIDs(tlvs []tlv.Data) []uint16 {
+	var mtids []uint16
+	for _, t := range tlvs {
+		mts, err := t.MTDecode()
+		if err != nil {
+			continue
+		}
+		for _, mt := range mts {
+			mtids = append(mtids, mt.ID)
+		}
+	}
+	return mtids
+}
+
+// commonTopologies returns the topologies in both lists, a nil list is the
+// standard topology only.
+func commonTopologies(ours, theirs []uint16) []uint16 {
+	if len(ours) == 0 {
+		ours = []uint16{tlv.MTIDStandard}
+	}
+	if len(theirs) == 0 {
+		theirs = []uint16{tlv.MTIDStandard}
+	}
+	var common []uint16
+	for _, a := range ours {
+		for _, b := range theirs {
+			if a == b {
+				common = append(common, a)
+			}
+		}
+	}
+	return common
+}
+
+func equalMTIDs(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+package main
+
+import (
+	"fmt"
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/goisis/update"
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+)
+
+// CircuitPassive is a circuit that only advertises the prefixes of its
+// interface, e.g., a loopback or stub network. There is no socket, hellos or
+// flooding on it.
+type CircuitPassive struct {
+	*CircuitBase
+}
+
+func (c *CircuitPassive) String() string {
+	return fmt.Sprintf("CircuitPassive(%s)", c.CircuitBase)
+}
+
+// NewCircuitPassive creates a passive circuit for all levels.
+func NewCircuitPassive(cb *CircuitBase, lf clns.LevelFlag) *CircuitPassive {
+	c := &CircuitPassive{
+		CircuitBase: cb,
+	}
+	for l := clns.Level(1); l <= 2; l++ {
+		if lf.IsLevelEnabled(l) {
+			cb.updb[l.ToIndex()].AddCircuit(c)
+		}
+	}
+	return c
+}
+
+// isPassive returns true if the interface doesn't run IS-IS, because it is
+// configured passive or is a loopback.
+func (cb *CircuitBase) isPassive() bool {
+	return cb.config.Passive || cb.intf.Flags&net.FlagLoopback != 0
+}
+
+// Name returns the name of the interface.
+func (c *CircuitPassive) Name() string {
+	return c.intf.Name
+}
+
+// Adjacencies sends the end marker, there are no adjacencies.
+func (c *CircuitPassive) Adjacencies(C chan<- interface{}, li clns.Lindex, forPN bool) {
+	go func() { C <- tlv.Done{} }()
+}
+
+// ChgFlag does nothing, LSPs aren't flooded on the circuit.
+func (c *CircuitPassive) ChgFlag(flag update.SxxFlag, lspid *clns.LSPID, set bool, li clns.Lindex) {
+}
+
+// CID returns 0, the circuit has no pseudo-node.
+func (c *CircuitPassive) CID(li clns.Lindex) uint8 {
+	return 0
+}
+
+// IsP2P returns false.
+func (c *CircuitPassive) IsP2P() bool {
+	return false
+}
+
+// IsPassive returns true.
+func (c *CircuitPassive) IsPassive() bool {
+	return true
+}
+
+// Send drops the PDU.
+func (c *CircuitPassive) Send(pdu []byte, li clns.Lindex) {
+}
+
+// YangData returns the yang data for this circuit, it is called within the
+// circuit DB go routine.
+func (c *CircuitPassive) YangData() (*YangInterface, error) {
+	return &YangInterface{
+		Name:       c.intf.Name,
+		LevelType:  c.lf,
+		Passive:    true,
+		Tags:       c.config.Tags,
+		Tags64:     c.config.Tags64,
+		NodeFlag:   c.config.NodeFlag,
+		Topologies: c.Topologies(),
+	}, nil
+}
@@ -0,0 +1,229 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+package main
+
+import (
+	"fmt"
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/goisis/update"
+	"github.com/choppsv1/goisis/kernel"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/policy"
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"time"
+)
+
+// Redistribution sources.
+const (
+	RedistStatic    = "static"
+	RedistConnected = "connected"
+	RedistKernel    = "kernel"
+)
+
+// RedistInterval is how often the redistribution sources are re-read in
+// addition to reading them on kernel change notifications.
+const RedistInterval = 30 * time.Second
+
+// RedistConfig configures a source of prefixes redistributed into IS-IS. A
+// missing level redistributes into all enabled levels.
+type RedistConfig struct {
+	Source     string         `json:"source"`
+	Prefixes   []string       `json:"prefixes,omitempty"`   // static
+	Interfaces []string       `json:"interfaces,omitempty"` // connected, empty for all
+	Table      int            `json:"table,omitempty"`      // kernel
+	Protocol   int            `json:"protocol,omitempty"`   // kernel
+	Metric     uint32         `json:"metric,omitempty"`
+	Tag        uint32         `json:"tag,omitempty"`
+	Level      clns.LevelFlag `json:"level,omitempty"`
+	Policy     string         `json:"policy,omitempty"`
+}
+
+// validate checks the redistribution source configuration.
+func (rc *RedistConfig) validate() error {
+	switch rc.Source {
+	case RedistStatic:
+		for _, p := range rc.Prefixes {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				return err
+			}
+		}
+	case RedistConnected:
+	case RedistKernel:
+		if rc.Protocol == kernel.ProtoISIS {
+			return fmt.Errorf("redistribute kernel protocol %d is our own", rc.Protocol)
+		}
+	default:
+		return fmt.Errorf("unknown redistribute source %q", rc.Source)
+	}
+	if rc.Metric > tlv.ExtIPMaxMetric {
+		return fmt.Errorf("redistribute %s metric %d too large", rc.Source, rc.Metric)
+	}
+	return nil
+}
+
+// normPrefix returns the network of the address with IPv4 in 4 byte form.
+func normPrefix(ipnet *net.IPNet) net.IPNet {
+	n := net.IPNet{IP: ipnet.IP.Mask(ipnet.Mask), Mask: ipnet.Mask}
+	if ip := n.IP.To4(); ip != nil && len(n.Mask) == net.IPv4len {
+		n.IP = ip
+	}
+	return n
+}
+
+func redistSkip(ipnet *net.IPNet) bool {
+	return ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast()
+}
+
+func hasString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// prefixes returns the prefixes currently provided by the source.
+func (rc *RedistConfig) prefixes(cdb *CircuitDB) ([]net.IPNet, error) {
+	var out []net.IPNet
+	switch rc.Source {
+	case RedistStatic:
+		for _, p := range rc.Prefixes {
+			_, ipnet, _ := net.ParseCIDR(p)
+			out = append(out, normPrefix(ipnet))
+		}
+	case RedistConnected:
+		intfs, err := net.Interfaces()
+		if err != nil {
+			return nil, err
+		}
+		for _, intf := range intfs {
+			if intf.Flags&net.FlagUp == 0 || cdb.IsCircuit(intf.Name) {
+				continue
+			}
+			if len(rc.Interfaces) != 0 && !hasString(rc.Interfaces, intf.Name) {
+				continue
+			}
+			addrs, err := intf.Addrs()
+			if err != nil {
+				return nil, err
+			}
+			for _, addr := range addrs {
+				ipnet, ok := addr.(*net.IPNet)
+				if !ok || redistSkip(ipnet) {
+					continue
+				}
+				out = append(out, normPrefix(ipnet))
+			}
+		}
+	case RedistKernel:
+		routes, err := kernel.Routes(rc.Table, rc.Protocol)
+		if err != nil {
+			return nil, err
+		}
+		for i := range routes {
+			if !redistSkip(&routes[i]) {
+				out = append(out, normPrefix(&routes[i]))
+			}
+		}
+	}
+	return out, nil
+}
+
+// redistributor reads the redistribution sources and updates the update
+// process of each level.
+type redistributor struct {
+	config   []RedistConfig
+	policies []*policy.Policy
+	cdb      *CircuitDB
+	updb     [2]*update.DB
+}
+
+// StartRedistribution starts redistributing the configured sources into the
+// update processes.
+func StartRedistribution(config *Config, cdb *CircuitDB, updb [2]*update.DB) {
+	if len(config.Redist) == 0 {
+		return
+	}
+	r := &redistributor{
+		config:   config.Redist,
+		policies: make([]*policy.Policy, len(config.Redist)),
+		cdb:      cdb,
+		updb:     updb,
+	}
+	for i := range config.Redist {
+		r.policies[i] = config.policy(config.Redist[i].Policy)
+	}
+	go r.run()
+}
+
+// update reads all the sources and sends the result to each level.
+func (r *redistributor) update() {
+	var rs [2][]update.Redist
+	for i := range r.config {
+		rc := &r.config[i]
+		prefixes, err := rc.prefixes(r.cdb)
+		if err != nil {
+			Info("Redistribute %s: %s", rc.Source, err)
+			continue
+		}
+		metric := rc.Metric
+		if metric == 0 {
+			metric = clns.DefExtIPMetric
+		}
+		var tags []uint32
+		if rc.Tag != 0 {
+			tags = []uint32{rc.Tag}
+		}
+		for li := clns.Lindex(0); li < 2; li++ {
+			if r.updb[li] == nil || (rc.Level != 0 && !rc.Level.IsLindexEnabled(li)) {
+				continue
+			}
+			for _, p := range prefixes {
+				rs[li] = append(rs[li], update.Redist{
+					Source: rc.Source,
+					Policy: r.policies[i],
+					IPInfo: tlv.IPInfo{Metric: metric, Ipnet: p, Tags: tags},
+				})
+			}
+		}
+	}
+	for li, db := range r.updb {
+		if db != nil {
+			db.SetRedistributed(rs[li])
+		}
+	}
+}
+
+func (r *redistributor) run() {
+	done := make(chan struct{})
+	defer close(done)
+
+	var changeC <-chan bool
+	for _, rc := range r.config {
+		if rc.Source == RedistStatic {
+			continue
+		}
+		var err error
+		if changeC, err = kernel.Subscribe(done); err != nil {
+			Info("Redistribute: no kernel change notification: %s", err)
+		}
+		break
+	}
+
+	ticker := time.NewTicker(RedistInterval)
+	defer ticker.Stop()
+	for {
+		r.update()
+		select {
+		case <-changeC:
+			Debug(DbgFUpd, "Redistribute: kernel change")
+		case <-ticker.C:
+		case <-GlbQuit:
+			return
+		}
+	}
+}
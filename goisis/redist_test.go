@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/choppsv1/goisis/kernel"
+	"testing"
+)
+
+func TestRedistValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rc    RedistConfig
+		valid bool
+	}{
+		{"static", RedistConfig{Source: RedistStatic, Prefixes: []string{"10.1.0.0/16", "2001:db8::/32"}}, true},
+		{"bad prefix", RedistConfig{Source: RedistStatic, Prefixes: []string{"10.1.0.0"}}, false},
+		{"connected", RedistConfig{Source: RedistConnected, Interfaces: []string{"eth0"}}, true},
+		{"kernel", RedistConfig{Source: RedistKernel, Table: 254, Protocol: 4}, true},
+		{"kernel isis", RedistConfig{Source: RedistKernel, Protocol: kernel.ProtoISIS}, false},
+		{"unknown source", RedistConfig{Source: "bgp"}, false},
+		{"metric", RedistConfig{Source: RedistKernel, Metric: 0xFE000001}, false},
+	}
+	for _, test := range tests {
+		if err := test.rc.validate(); (err == nil) != test.valid {
+			t.Errorf("%s: valid %v: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestRedistStatic(t *testing.T) {
+	rc := RedistConfig{Source: RedistStatic, Prefixes: []string{"10.1.2.3/16", "2001:db8::1/32"}}
+	prefixes, err := rc.prefixes(nil)
+	if err != nil || len(prefixes) != 2 {
+		t.Fatalf("Bad static prefixes %v: %v", prefixes, err)
+	}
+	// The prefixes are the networks with IPv4 in 4 byte form.
+	if prefixes[0].String() != "10.1.0.0/16" || len(prefixes[0].IP) != 4 {
+		t.Errorf("Bad IPv4 prefix %v", prefixes[0])
+	}
+	if prefixes[1].String() != "2001:db8::/32" {
+		t.Errorf("Bad IPv6 prefix %v", prefixes[1])
+	}
+}
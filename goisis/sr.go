@@ -0,0 +1,346 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+package main
+
+import (
+	"fmt"
+	"github.com/choppsv1/goisis/goisis/update"
+	"github.com/choppsv1/goisis/kernel"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"time"
+)
+
+// Segment routing defaults.
+var (
+	DefSRGB = LabelBlock{LowerBound: 16000, UpperBound: 23999}
+	DefSRLB = LabelBlock{LowerBound: 15000, UpperBound: 15999}
+)
+
+// LFIBInterval is how often the label forwarding table is reinstalled in
+// addition to after each SPF.
+const LFIBInterval = 60 * time.Second
+
+// Prefix SID last hop behaviors.
+const (
+	LastHopPHP          = "php"
+	LastHopNoPHP        = "no-php"
+	LastHopExplicitNull = "explicit-null"
+)
+
+// minSRLabel is the first label not reserved (RFC3032).
+const minSRLabel = 16
+
+// LabelBlock is an inclusive range of MPLS labels.
+type LabelBlock struct {
+	LowerBound uint32 `json:"lower-bound"`
+	UpperBound uint32 `json:"upper-bound"`
+}
+
+func (lb *LabelBlock) size() uint32 {
+	return lb.UpperBound - lb.LowerBound + 1
+}
+
+func (lb *LabelBlock) overlaps(o *LabelBlock) bool {
+	return lb.LowerBound <= o.UpperBound && o.LowerBound <= lb.UpperBound
+}
+
+func (lb *LabelBlock) sidRange() []tlv.SIDRange {
+	return []tlv.SIDRange{{Range: lb.size(), Start: lb.LowerBound}}
+}
+
+// PrefixSIDConfig assigns an SRGB index to one of our interface prefixes for
+// the SPF algorithm or a flexible algorithm.
+type PrefixSIDConfig struct {
+	Prefix  string `json:"prefix"`
+	Index   uint32 `json:"index"`
+	Algo    uint8  `json:"algorithm,omitempty"`
+	LastHop string `json:"last-hop-behavior,omitempty"`
+}
+
+// SRConfig is the segment routing MPLS (RFC8667) configuration. The adjacency
+// SIDs are allocated from the SRLB. The label forwarding table is installed in
+// the network namespace at Netns, the current one if empty, unless NoInstall
+// is set.
+type SRConfig struct {
+	Enable     bool              `json:"enable"`
+	SRGB       *LabelBlock       `json:"srgb,omitempty"`
+	SRLB       *LabelBlock       `json:"srlb,omitempty"`
+	PrefixSIDs []PrefixSIDConfig `json:"prefix-sid-map,omitempty"`
+	Netns      string            `json:"netns,omitempty"`
+	NoInstall  bool              `json:"no-install,omitempty"`
+}
+
+func (sc *SRConfig) srgb() *LabelBlock {
+	if sc.SRGB == nil {
+		return &DefSRGB
+	}
+	return sc.SRGB
+}
+
+func (sc *SRConfig) srlb() *LabelBlock {
+	if sc.SRLB == nil {
+		return &DefSRLB
+	}
+	return sc.SRLB
+}
+
+// validate checks the segment routing configuration.
+func (sc *SRConfig) validate() error {
+	for _, lb := range []*LabelBlock{sc.srgb(), sc.srlb()} {
+		if lb.LowerBound < minSRLabel || lb.UpperBound > tlv.LabelMax || lb.LowerBound > lb.UpperBound {
+			return fmt.Errorf("segment-routing: invalid label block %d-%d", lb.LowerBound, lb.UpperBound)
+		}
+	}
+	if sc.srgb().overlaps(sc.srlb()) {
+		return fmt.Errorf("segment-routing: srgb and srlb overlap")
+	}
+	indices := make(map[uint32]string)
+	algos := make(map[string]bool)
+	for _, ps := range sc.PrefixSIDs {
+		if _, _, err := net.ParseCIDR(ps.Prefix); err != nil {
+			return fmt.Errorf("segment-routing: %s", err)
+		}
+		if ps.Index >= sc.srgb().size() {
+			return fmt.Errorf("segment-routing: prefix %s index %d outside srgb", ps.Prefix, ps.Index)
+		}
+		if p, ok := indices[ps.Index]; ok {
+			return fmt.Errorf("segment-routing: index %d used for %s and %s", ps.Index, p, ps.Prefix)
+		}
+		indices[ps.Index] = ps.Prefix
+		if ps.Algo != tlv.SRAlgoSPF && ps.Algo < tlv.FlexAlgoMin {
+			return fmt.Errorf("segment-routing: prefix %s algorithm %d not supported", ps.Prefix, ps.Algo)
+		}
+		key := fmt.Sprintf("%s/%d", ps.Prefix, ps.Algo)
+		if algos[key] {
+			return fmt.Errorf("segment-routing: prefix %s has more than one algorithm %d index", ps.Prefix, ps.Algo)
+		}
+		algos[key] = true
+		switch ps.LastHop {
+		case "", LastHopPHP, LastHopNoPHP, LastHopExplicitNull:
+		default:
+			return fmt.Errorf("segment-routing: unknown last-hop-behavior %q", ps.LastHop)
+		}
+	}
+	return nil
+}
+
+// srCap returns the SR-Capabilities to advertise.
+func (sc *SRConfig) srCap() *tlv.SRCap {
+	return &tlv.SRCap{MPLSIPv4: true, MPLSIPv6: true, SRGB: sc.srgb().sidRange()}
+}
+
+// prefixSIDs returns the Prefix-SIDs configured for one of our prefixes, one
+// for each algorithm.
+func (sc *SRConfig) prefixSIDs(ipnet *net.IPNet, node bool) []tlv.PrefixSID {
+	if sc == nil || !sc.Enable {
+		return nil
+	}
+	var sids []tlv.PrefixSID
+	for _, psc := range sc.PrefixSIDs {
+		_, pfx, _ := net.ParseCIDR(psc.Prefix)
+		if !pfx.IP.Equal(ipnet.IP.Mask(ipnet.Mask)) || pfx.Mask.String() != ipnet.Mask.String() {
+			continue
+		}
+		sids = append(sids, tlv.PrefixSID{
+			Node:         node,
+			NoPHP:        psc.LastHop == LastHopNoPHP || psc.LastHop == LastHopExplicitNull,
+			ExplicitNull: psc.LastHop == LastHopExplicitNull,
+			Algo:         psc.Algo,
+			SID:          psc.Index,
+		})
+	}
+	return sids
+}
+
+// ---------------------------------------------------------------
+// Label allocation
+//
+// Adjacency SIDs are allocated from the SRLB by a go routine of its
+// own as they are allocated from the hello processes of all links.
+// ---------------------------------------------------------------
+
+// labelAllocator allocates labels from a label block.
+type labelAllocator struct {
+	block LabelBlock
+	used  map[uint32]bool
+	next  uint32
+	rpC   chan RPC
+}
+
+// srLabels is the SRLB allocator, nil if segment routing is not enabled.
+var srLabels *labelAllocator
+
+func newLabelAllocator(block LabelBlock) *labelAllocator {
+	la := &labelAllocator{
+		block: block,
+		used:  make(map[uint32]bool),
+		next:  block.LowerBound,
+		rpC:   make(chan RPC),
+	}
+	go la.run()
+	return la
+}
+
+func (la *labelAllocator) run() {
+	for {
+		select {
+		case in := <-la.rpC:
+			in.Result <- in.F()
+		}
+	}
+}
+
+// Alloc returns an unused label.
+func (la *labelAllocator) Alloc() (uint32, error) {
+	i, err := DoRPC(la.rpC, func() interface{} {
+		for n := uint32(0); n < la.block.size(); n++ {
+			label := la.next
+			if la.next++; la.next > la.block.UpperBound {
+				la.next = la.block.LowerBound
+			}
+			if !la.used[label] {
+				la.used[label] = true
+				return label
+			}
+		}
+		return fmt.Errorf("no free labels in %d-%d", la.block.LowerBound, la.block.UpperBound)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return i.(uint32), nil
+}
+
+// Free returns labels to the allocator.
+func (la *labelAllocator) Free(labels ...uint32) {
+	_, _ = DoRPC(la.rpC, func() interface{} { // nolint
+		for _, label := range labels {
+			delete(la.used, label)
+		}
+		return nil
+	})
+}
+
+// allocSIDs allocates an Adj-SID for each address family of the adjacency
+// and its SRv6 End.X SID.
+func (a *Adj) allocSIDs() {
+	a.allocSRv6SIDs()
+	if srLabels == nil {
+		return
+	}
+	for _, v6 := range []bool{false, true} {
+		if (!v6 && len(a.v4addrs) == 0) || (v6 && len(a.v6addrs) == 0) {
+			continue
+		}
+		label, err := srLabels.Alloc()
+		if err != nil {
+			Info("%s: Adj-SID: %s", a, err)
+			continue
+		}
+		as := tlv.AdjSID{IPv6: v6, Value: true, Local: true, SID: label}
+		if !a.link.IsP2P() {
+			sysid := a.sysid
+			as.Nbr = &sysid
+		}
+		a.adjSIDs = append(a.adjSIDs, as)
+	}
+}
+
+// freeSIDs releases the Adj-SIDs and SRv6 End.X SID of the adjacency.
+func (a *Adj) freeSIDs() {
+	a.freeSRv6SIDs()
+	if srLabels == nil || len(a.adjSIDs) == 0 {
+		return
+	}
+	var labels []uint32
+	for _, as := range a.adjSIDs {
+		labels = append(labels, as.SID)
+	}
+	srLabels.Free(labels...)
+	a.adjSIDs = nil
+}
+
+// ----------------------------------
+// Label forwarding table programming
+// ----------------------------------
+
+// lfibRoutes returns the merged label forwarding table of the levels, level
+// 1 entries are preferred.
+func lfibRoutes(updb [2]*update.DB) ([]kernel.MPLSRoute, error) {
+	var routes []kernel.MPLSRoute
+	seen := make(map[uint32]bool)
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		lrs, err := db.LFIB()
+		if err != nil {
+			return nil, err
+		}
+		for _, lr := range lrs {
+			if seen[lr.Label] {
+				continue
+			}
+			seen[lr.Label] = true
+			r := kernel.MPLSRoute{Label: lr.Label}
+			for _, nh := range lr.NextHops {
+				r.NextHops = append(r.NextHops, kernel.MPLSNextHop{
+					Intf:    nh.Intf,
+					Gateway: nh.Addr,
+					Labels:  nh.Labels,
+				})
+			}
+			routes = append(routes, r)
+		}
+	}
+	return routes, nil
+}
+
+// StartSegmentRouting starts the label allocator and the installation of the
+// label forwarding tables of the levels into the kernel.
+func StartSegmentRouting(config *Config, updb [2]*update.DB) {
+	sc := config.SR
+	if sc == nil || !sc.Enable {
+		return
+	}
+	srLabels = newLabelAllocator(*sc.srlb())
+	if sc.NoInstall {
+		return
+	}
+	lfib, err := kernel.NewLFIB(sc.Netns)
+	if err != nil {
+		Info("Segment routing: not installing LFIB: %s", err)
+		return
+	}
+	changeC := make(chan bool, 1)
+	for _, db := range updb {
+		if db != nil {
+			db.SetLFIBNotify(changeC)
+		}
+	}
+	go func() {
+		defer lfib.Close()
+		ticker := time.NewTicker(LFIBInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-changeC:
+			case <-ticker.C:
+			case <-GlbQuit:
+				return
+			}
+			routes, err := lfibRoutes(updb)
+			if err == nil {
+				err = lfib.Update(routes)
+			}
+			if err != nil {
+				Info("Segment routing: LFIB update: %s", err)
+			}
+			Debug(DbgFSPF, "Segment routing: %d LFIB entries", len(routes))
+		}
+	}()
+}
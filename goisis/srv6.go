@@ -0,0 +1,281 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+package main
+
+import (
+	"fmt"
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/goisis/update"
+	"github.com/choppsv1/goisis/kernel"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"time"
+)
+
+// SRv6 defaults.
+const (
+	DefSRv6FunctionLen = 16
+	DefSRv6BlockLen    = 32
+	SRv6Interval       = 60 * time.Second
+)
+
+// srv6EndFunction is the function of the End SID of each locator, the End.X
+// SID functions are allocated above it.
+const srv6EndFunction = 1
+
+// SRv6LocatorConfig is an SRv6 locator we advertise. BlockLen is the length
+// of the locator block part of the locator for the SID structure.
+type SRv6LocatorConfig struct {
+	Name     string `json:"name"`
+	Prefix   string `json:"prefix"`
+	Algo     uint8  `json:"algorithm,omitempty"`
+	Metric   uint32 `json:"metric,omitempty"`
+	Anycast  bool   `json:"anycast,omitempty"`
+	BlockLen uint8  `json:"block-length,omitempty"`
+}
+
+// SRv6Config is the segment routing over IPv6 (RFC9352) configuration. Each
+// locator has an End SID, each adjacency an End.X SID from the first SPF
+// algorithm locator. The remote locator routes and our SIDs are installed in
+// the network namespace at Netns, the current one if empty, unless NoInstall
+// is set.
+type SRv6Config struct {
+	Enable      bool                `json:"enable"`
+	Locators    []SRv6LocatorConfig `json:"locators,omitempty"`
+	FunctionLen uint8               `json:"function-length,omitempty"`
+	Netns       string              `json:"netns,omitempty"`
+	NoInstall   bool                `json:"no-install,omitempty"`
+}
+
+func (sc *SRv6Config) functionLen() uint8 {
+	if sc.FunctionLen == 0 {
+		return DefSRv6FunctionLen
+	}
+	return sc.FunctionLen
+}
+
+// prefix returns the locator prefix.
+func (lc *SRv6LocatorConfig) prefix() *net.IPNet {
+	_, ipnet, _ := net.ParseCIDR(lc.Prefix)
+	return ipnet
+}
+
+// validate checks the SRv6 configuration.
+func (sc *SRv6Config) validate() error {
+	if sc.functionLen() < 8 || sc.functionLen() > 32 {
+		return fmt.Errorf("srv6: function-length %d not between 8 and 32", sc.functionLen())
+	}
+	names := make(map[string]bool)
+	for _, lc := range sc.Locators {
+		ip, ipnet, err := net.ParseCIDR(lc.Prefix)
+		if err != nil {
+			return fmt.Errorf("srv6: locator %s: %s", lc.Name, err)
+		}
+		if ip.To4() != nil {
+			return fmt.Errorf("srv6: locator %s: %s not IPv6", lc.Name, lc.Prefix)
+		}
+		if plen, _ := ipnet.Mask.Size(); plen+int(sc.functionLen()) > 128 {
+			return fmt.Errorf("srv6: locator %s: no room for %d bit functions", lc.Name, sc.functionLen())
+		}
+		if names[lc.Name] {
+			return fmt.Errorf("srv6: duplicate locator %s", lc.Name)
+		}
+		names[lc.Name] = true
+	}
+	return nil
+}
+
+// sidStructure returns the structure of the SIDs of the locator.
+func (sc *SRv6Config) sidStructure(lc *SRv6LocatorConfig) *tlv.SRv6SIDStructure {
+	plen, _ := lc.prefix().Mask.Size()
+	block := uint8(DefSRv6BlockLen)
+	if lc.BlockLen != 0 {
+		block = lc.BlockLen
+	}
+	if int(block) > plen {
+		block = uint8(plen)
+	}
+	return &tlv.SRv6SIDStructure{LBLen: block, LNLen: uint8(plen) - block, FunLen: sc.functionLen()}
+}
+
+// sid returns the SID of the function 'fn' of the locator.
+func (sc *SRv6Config) sid(lc *SRv6LocatorConfig, fn uint32) net.IP {
+	ipnet := lc.prefix()
+	plen, _ := ipnet.Mask.Size()
+	sid := make(net.IP, net.IPv6len)
+	copy(sid, ipnet.IP.To16())
+	flen := int(sc.functionLen())
+	for i := 0; i < flen; i++ {
+		if fn>>uint(flen-1-i)&1 != 0 {
+			bit := plen + i
+			sid[bit/8] |= 0x80 >> uint(bit%8)
+		}
+	}
+	return sid
+}
+
+// locators returns the locators to advertise with their End SIDs.
+func (sc *SRv6Config) locators() []tlv.SRv6Locator {
+	var locs []tlv.SRv6Locator
+	for i := range sc.Locators {
+		lc := &sc.Locators[i]
+		metric := lc.Metric
+		if metric == 0 {
+			metric = clns.DefExtIPMetric
+		}
+		locs = append(locs, tlv.SRv6Locator{
+			Metric:  metric,
+			Anycast: lc.Anycast,
+			Algo:    lc.Algo,
+			Locator: tlv.IPPrefix(*lc.prefix()),
+			EndSIDs: []tlv.SRv6EndSID{{
+				Behavior:  tlv.SRv6BehaviorEnd,
+				SID:       sc.sid(lc, srv6EndFunction),
+				Structure: sc.sidStructure(lc),
+			}},
+		})
+	}
+	return locs
+}
+
+// adjLocator returns the locator of the End.X SIDs, nil if none.
+func (sc *SRv6Config) adjLocator() *SRv6LocatorConfig {
+	if sc == nil || !sc.Enable {
+		return nil
+	}
+	for i := range sc.Locators {
+		if sc.Locators[i].Algo == tlv.SRAlgoSPF {
+			return &sc.Locators[i]
+		}
+	}
+	return nil
+}
+
+// srv6Funcs is the End.X SID function allocator, nil if SRv6 is not enabled.
+var srv6Funcs *labelAllocator
+
+// allocSRv6SIDs allocates an End.X SID for an adjacency with IPv6 addresses.
+func (a *Adj) allocSRv6SIDs() {
+	sc := GlbConfig.SRv6
+	lc := sc.adjLocator()
+	if srv6Funcs == nil || lc == nil || len(a.v6addrs) == 0 {
+		return
+	}
+	fn, err := srv6Funcs.Alloc()
+	if err != nil {
+		Info("%s: End.X SID: %s", a, err)
+		return
+	}
+	xs := tlv.SRv6EndXSID{
+		Behavior:  tlv.SRv6BehaviorEndX,
+		SID:       sc.sid(lc, fn),
+		Structure: sc.sidStructure(lc),
+	}
+	if !a.link.IsP2P() {
+		sysid := a.sysid
+		xs.Nbr = &sysid
+	}
+	a.srv6Func = fn
+	a.endXSIDs = []tlv.SRv6EndXSID{xs}
+}
+
+// freeSRv6SIDs releases the End.X SID of the adjacency.
+func (a *Adj) freeSRv6SIDs() {
+	if srv6Funcs == nil || a.srv6Func == 0 {
+		return
+	}
+	srv6Funcs.Free(a.srv6Func)
+	a.srv6Func = 0
+	a.endXSIDs = nil
+}
+
+// srv6Kernel returns the merged locator routes and local SIDs of the levels,
+// level 1 entries are preferred.
+func srv6Kernel(updb [2]*update.DB) ([]kernel.IPRoute, []kernel.SRv6LocalSID, error) {
+	var routes []kernel.IPRoute
+	var sids []kernel.SRv6LocalSID
+	seen := make(map[string]bool)
+	for _, db := range updb {
+		if db == nil {
+			continue
+		}
+		ys, err := db.SRv6()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range ys.Locators {
+			key := (*net.IPNet)(&r.Prefix).String()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			kr := kernel.IPRoute{Prefix: net.IPNet(r.Prefix)}
+			for _, nh := range r.NextHops {
+				kr.NextHops = append(kr.NextHops, kernel.IPNextHop{Intf: nh.Intf, Gateway: nh.Addr})
+			}
+			routes = append(routes, kr)
+		}
+		for _, s := range ys.LocalSIDs {
+			key := s.SID.String()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			ks := kernel.SRv6LocalSID{SID: s.SID, Behavior: s.Behavior}
+			if s.NextHop != nil {
+				ks.Intf, ks.Gateway = s.NextHop.Intf, s.NextHop.Addr
+			}
+			sids = append(sids, ks)
+		}
+	}
+	return routes, sids, nil
+}
+
+// StartSRv6 starts the End.X SID allocator and the installation of the SRv6
+// locator routes and local SIDs of the levels into the kernel.
+func StartSRv6(config *Config, updb [2]*update.DB) {
+	sc := config.SRv6
+	if sc == nil || !sc.Enable {
+		return
+	}
+	max := uint32(uint64(1)<<sc.functionLen() - 1)
+	srv6Funcs = newLabelAllocator(LabelBlock{LowerBound: srv6EndFunction + 1, UpperBound: max})
+	if sc.NoInstall {
+		return
+	}
+	fib, err := kernel.NewSRv6FIB(sc.Netns)
+	if err != nil {
+		Info("SRv6: not installing routes: %s", err)
+		return
+	}
+	changeC := make(chan bool, 1)
+	for _, db := range updb {
+		if db != nil {
+			db.SetSRv6Notify(changeC)
+		}
+	}
+	go func() {
+		defer fib.Close()
+		ticker := time.NewTicker(SRv6Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-changeC:
+			case <-ticker.C:
+			case <-GlbQuit:
+				return
+			}
+			routes, sids, err := srv6Kernel(updb)
+			if err == nil {
+				err = fib.Update(routes, sids)
+			}
+			if err != nil {
+				Info("SRv6: kernel update: %s", err)
+			}
+			Debug(DbgFSPF, "SRv6: %d locator routes %d local SIDs", len(routes), len(sids))
+		}
+	}()
+}
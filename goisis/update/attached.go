@@ -0,0 +1,240 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the attached (ATT) bit handling and the communication
+// between the level 1 and level 2 update processes.
+package update
+
+import (
+	"bytes"
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"net"
+	"reflect"
+	"sync"
+)
+
+// GenReasonAttached is the LSP log reason for attached bit changes.
+const GenReasonAttached = "attached-change"
+
+// attachedBit tracks the attached bit state for a level.
+type attachedBit struct {
+	attached bool // level 2 connectivity to another area.
+	suppress bool // don't set the attached bit in our level 1 LSP.
+	ignore   bool // ignore the attached bit set by others.
+	nearest  []clns.SystemID
+}
+
+// YangAttached is the attached bit state for the yang model.
+type YangAttached struct {
+	Level    clns.Level      `json:"level"`
+	Attached bool            `json:"attached"`
+	Set      bool            `json:"set"`
+	Suppress bool            `json:"suppress"`
+	Ignore   bool            `json:"ignore-received"`
+	Nearest  []clns.SystemID `json:"nearest-attached,omitempty"`
+}
+
+// levelAttached is sent from the level 2 to the level 1 update process when
+// the level 2 connectivity to other areas changes.
+type levelAttached bool
+
+// LinkLevels connects the level 1 and level 2 update processes of an L1/L2
+// router.
+func LinkLevels(l1, l2 *DB) {
+	_, _ = DoRPC(l1.rpC, func() interface{} { l1.other = l2; return nil }) // nolint
+	_, _ = DoRPC(l2.rpC, func() interface{} { l2.other = l1; return nil }) // nolint
+}
+
+// levelQueue holds the messages from the other level's update process not yet
+// handled. Each message carries the full state of its kind so only the latest
+// of a kind is kept, and the sender never blocks waiting for the receiver
+// which may be sending to it at the same time.
+type levelQueue struct {
+	sync.Mutex
+	msgs []interface{}
+}
+
+// put queues the message replacing a queued message of the same kind.
+func (q *levelQueue) put(msg interface{}) {
+	q.Lock()
+	defer q.Unlock()
+	for i := range q.msgs {
+		if reflect.TypeOf(q.msgs[i]) == reflect.TypeOf(msg) {
+			q.msgs[i] = msg
+			return
+		}
+	}
+	q.msgs = append(q.msgs, msg)
+}
+
+// take removes and returns the queued messages.
+func (q *levelQueue) take() []interface{} {
+	q.Lock()
+	defer q.Unlock()
+	msgs := q.msgs
+	q.msgs = nil
+	return msgs
+}
+
+// sendOther sends a message to the other level's update process.
+func (db *DB) sendOther(msg interface{}) {
+	if db.other == nil {
+		return
+	}
+	db.other.levelQ.put(msg)
+	select {
+	case db.other.levelC <- true:
+	default:
+	}
+}
+
+// handleLevelC handles the queued messages from the other level's update
+// process.
+func (db *DB) handleLevelC() {
+	for _, msg := range db.levelQ.take() {
+		db.handleLevelMsg(msg)
+	}
+}
+
+// handleLevelMsg handles a message from the other level's update process.
+func (db *DB) handleLevelMsg(msg interface{}) {
+	switch in := msg.(type) {
+	case levelAttached:
+		if db.att.attached == bool(in) {
+			return
+		}
+		Debug(DbgFSPF, "%s: level-2 attached changed to %v", db, in)
+		db.att.attached = bool(in)
+		if !db.att.suppress {
+			db.handleChgLSPC(chgLSP{reason: GenReasonAttached})
+		}
+	case levelPrefixes:
+		db.handleLevelPrefixes(in)
+	case levelCaps:
+		db.handleLevelCaps(in)
+	default:
+		Debug(DbgFUpd, "%s: unexpected level message %v", db, msg)
+	}
+}
+
+// setAttached returns true if we should set the attached bit in our LSP.
+func (db *DB) setAttached() bool {
+	return db.li == 0 && db.att.attached && !db.att.suppress
+}
+
+// hasArea returns true if any of the areas is one of ours.
+func (db *DB) hasArea(areas []clns.Area) bool {
+	for _, a := range areas {
+		for _, our := range db.areas {
+			if bytes.Equal(a, our) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// spfComplete is called after SPF to update state derived from the results.
+func (db *DB) spfComplete(root *spfNode, nodes map[clns.NodeID]*spfNode) {
+	db.propagateReach(root, nodes)
+	db.propagateCaps(root, nodes)
+	if db.li != 1 {
+		return
+	}
+	// We are attached if we can reach a level 2 IS in another area.
+	attached := false
+	for _, n := range nodes {
+		if n == root || n.isPN() || !n.reached() {
+			continue
+		}
+		if !db.hasArea(n.areas()) {
+			attached = true
+			break
+		}
+	}
+	if attached != db.att.attached {
+		Debug(DbgFSPF, "%s: attached changed to %v", db, attached)
+		db.att.attached = attached
+		db.sendOther(levelAttached(attached))
+	}
+}
+
+var (
+	defaultIPv4 = net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+	defaultIPv6 = net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+)
+
+// nearestAttached returns the nearest reachable routers setting the attached
+// bit.
+func nearestAttached(root *spfNode, nodes map[clns.NodeID]*spfNode) []*spfNode {
+	var nearest []*spfNode
+	best := maxDist
+	for _, n := range nodes {
+		if n == root || n.isPN() || !n.reached() || len(n.nexthops) == 0 {
+			continue
+		}
+		if n.flags&clns.LSPFMetDef == 0 || n.flags&clns.LSPFOverload != 0 {
+			continue
+		}
+		if n.dist < best {
+			best = n.dist
+			nearest = []*spfNode{n}
+		} else if n.dist == best {
+			nearest = append(nearest, n)
+		}
+	}
+	return nearest
+}
+
+// addDefaultRoutes adds default routes to the nearest attached L1/L2 routers
+// when we are a level 1 only router. Like the other IP routes of the RIB they
+// aren't installed in the kernel.
+func (db *DB) addDefaultRoutes(rib map[string]*Route, root *spfNode, nodes map[clns.NodeID]*spfNode) {
+	db.att.nearest = nil
+	if db.istype != clns.L1Flag || db.att.ignore {
+		return
+	}
+	for _, n := range nearestAttached(root, nodes) {
+		db.att.nearest = append(db.att.nearest, n.sysid())
+		db.addRoute(rib, &defaultIPv4, n.dist, true, n)
+		db.addRoute(rib, &defaultIPv6, n.dist, true, n)
+	}
+}
+
+// SetAttachedBit configures suppressing the setting of the attached bit in our
+// level 1 LSP and ignoring the attached bit set by others.
+func (db *DB) SetAttachedBit(suppress, ignore bool) {
+	changed, _ := DoRPC(db.rpC, func() interface{} {
+		was := db.setAttached()
+		db.att.suppress = suppress
+		if db.att.ignore != ignore {
+			db.att.ignore = ignore
+			db.scheduleSPF()
+		}
+		return was != db.setAttached()
+	})
+	if changed.(bool) {
+		db.SomethingChanged(nil, GenReasonAttached)
+	}
+}
+
+// AttachedBit arranges for the attached bit state to be returned.
+func (db *DB) AttachedBit() (*YangAttached, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		return &YangAttached{
+			Level:    db.li.ToLevel(),
+			Attached: db.att.attached,
+			Set:      db.setAttached(),
+			Suppress: db.att.suppress,
+			Ignore:   db.att.ignore,
+			Nearest:  db.att.nearest,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.(*YangAttached), nil
+}
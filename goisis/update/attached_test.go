@@ -0,0 +1,34 @@
+package update
+
+import (
+	"github.com/choppsv1/goisis/tlv"
+	"testing"
+)
+
+func TestSendOther(t *testing.T) {
+	// Neither update process is reading, sending doesn't block and only the
+	// latest message of a kind is kept.
+	l1 := &DB{li: 0, levelC: make(chan bool, 1)}
+	l2 := &DB{li: 1, levelC: make(chan bool, 1)}
+	l1.other, l2.other = l2, l1
+	for i := 0; i < 20; i++ {
+		l1.sendOther(levelPrefixes(make([]tlv.IPInfo, i)))
+		l2.sendOther(levelAttached(i%2 == 0))
+	}
+	l2.sendOther(levelCaps(nil))
+
+	msgs := l2.levelQ.take()
+	if len(msgs) != 1 || len(msgs[0].(levelPrefixes)) != 19 {
+		t.Errorf("Bad level 2 messages %v", msgs)
+	}
+	msgs = l1.levelQ.take()
+	if len(msgs) != 2 || bool(msgs[0].(levelAttached)) {
+		t.Fatalf("Bad level 1 messages %v", msgs)
+	}
+	if _, ok := msgs[1].(levelCaps); !ok {
+		t.Errorf("Bad level 1 messages %v", msgs)
+	}
+	if len(l1.levelC) != 1 || len(l1.levelQ.take()) != 0 {
+		t.Errorf("Bad level 1 queue")
+	}
+}
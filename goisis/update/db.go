@@ -71,6 +71,7 @@ func (db *DB) newLSPSegment(payload []byte, tlvs tlv.Map) *lspSegment {
 		payload: payload,
 		hdr:     hdr,
 		tlvs:    tlvs,
+		caps:    decodeRouterCaps(tlvs),
 	}
 	copy(lsp.lspid[:], hdr[clns.HdrLSPLSPID:])
 
@@ -121,6 +122,7 @@ func (db *DB) updateLSPSegment(lsp *lspSegment, payload []byte, tlvs tlv.Map) {
 	lsp.payload = payload
 	lsp.hdr = Slicer(payload, clns.HdrCLNSSize, clns.HdrLSPSize)
 	lsp.tlvs = tlvs
+	lsp.caps = decodeRouterCaps(tlvs)
 
 	db.cacheUpdate(lsp.hdr)
 
@@ -235,6 +237,7 @@ func (db *DB) initiatePurgeLSP(lsp *lspSegment, fromTimer bool) {
 	// Update the CSNP cache
 	db.cacheUpdate(lsp.hdr)
 
+	db.scheduleSPF()
 }
 
 // deleteLSP removes the LSP from the DB.
@@ -244,6 +247,7 @@ func (db *DB) deleteLSP(lsp *lspSegment) {
 
 	Debug(DbgFUpd, "Deleting LSP %s", lsp)
 	db.db.Delete(lsp.lspid[:])
+	db.scheduleSPF()
 }
 
 // Increment the sequence number for one of our own LSP segments, fixup the
@@ -412,12 +416,16 @@ func (db *DB) receiveLSP(c Circuit, payload []byte, tlvs tlv.Map) {
 	}
 
 	if result == NEWER {
+		var ohdr []byte
+		var otlvs tlv.Map
 		if lsp != nil {
 			if c != nil {
 				Debug(DbgFUpd, "%s: Updating LSP from %s", db, c)
 			} else {
 				Debug(DbgFUpd, "%s: Updating Own LSP", db)
 			}
+			ohdr = append([]byte(nil), lsp.hdr...)
+			otlvs = lsp.tlvs
 			db.updateLSPSegment(lsp, payload, tlvs)
 		} else {
 			if c != nil {
@@ -433,6 +441,8 @@ func (db *DB) receiveLSP(c Circuit, payload []byte, tlvs tlv.Map) {
 			lsp = db.newLSPSegment(payload, tlvs)
 		}
 
+		db.scheduleSPFType(lsp.lspChange(ohdr, otlvs), lsp)
+
 		db.setAllFlag(SRM, lsp.lspid, c)
 		db.clearFlag(SRM, lsp.lspid, c)
 		if c != nil && c.IsP2P() {
@@ -539,6 +549,11 @@ func (db *DB) receiveSNP(c Circuit, complete bool, payload []byte, tlvs tlv.Map)
 		return
 	}
 
+	if !db.csnpSeen[c.Name()] {
+		db.csnpSeen[c.Name()] = true
+		db.checkWaitAdj()
+	}
+
 	Debug(DbgFUpd, "%s: CSNP: Look for we have, they don'ts", db)
 
 	// 7.3.15.2.c Set SRM for all LSP we have that were not mentioned.
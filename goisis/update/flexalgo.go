@@ -0,0 +1,311 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the Flexible Algorithm (RFC9350) part of the decision
+// process, the selection of the algorithm definitions and the constrained SPF
+// run for each algorithm over the shared LSP DB.
+package update
+
+import (
+	"bytes"
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"sort"
+)
+
+// YangFlexAlgoNode is a node reachable in a flexible algorithm topology.
+type YangFlexAlgoNode struct {
+	Nodeid   clns.NodeID `json:"node-id"`
+	Metric   uint32      `json:"metric"`
+	NextHops []NextHop   `json:"next-hops,omitempty"`
+}
+
+// YangFlexAlgo is the result of the SPF of a flexible algorithm for the yang
+// model. The definition used is the winning one advertised by Source.
+type YangFlexAlgo struct {
+	Level         clns.Level          `json:"level"`
+	Algo          uint8               `json:"algorithm"`
+	FAD           *tlv.FAD            `json:"definition,omitempty"`
+	Source        *clns.SystemID      `json:"definition-source,omitempty"`
+	Participating bool                `json:"participating"`
+	Nodes         []*YangFlexAlgoNode `json:"nodes,omitempty"`
+	Routes        []*Route            `json:"routes,omitempty"`
+}
+
+// localFlexAlgos returns the flexible algorithms we advertise participation
+// in.
+func (db *DB) localFlexAlgos() []uint8 {
+	var algos []uint8
+	if db.routerCap == nil {
+		return nil
+	}
+	for _, algo := range db.routerCap.SRAlgos {
+		if algo >= tlv.FlexAlgoMin {
+			algos = append(algos, algo)
+		}
+	}
+	sort.Slice(algos, func(i, j int) bool { return algos[i] < algos[j] })
+	return algos
+}
+
+// hasAlgo returns true if the node advertises participation in algo.
+// Capabilities leaked from level 2 (down) are another router's.
+func (n *spfNode) hasAlgo(algo uint8) bool {
+	for _, rc := range n.routerCaps() {
+		if rc.Down {
+			continue
+		}
+		for _, a := range rc.SRAlgos {
+			if a == algo {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectFAD returns the definition of algo to use and the router advertising
+// it. Of the definitions advertised by the reachable routers the one with the
+// highest priority then highest system ID is used (RFC9350 section 5.3).
+func selectFAD(root *spfNode, nodes map[clns.NodeID]*spfNode, algo uint8) (*tlv.FAD, clns.SystemID) {
+	var best *tlv.FAD
+	var bestid clns.SystemID
+	for _, n := range nodes {
+		if n.isPN() || (n != root && !n.reached()) {
+			continue
+		}
+		sysid := n.sysid()
+		for _, rc := range n.routerCaps() {
+			if rc.Down {
+				continue
+			}
+			for i := range rc.FADs {
+				fad := &rc.FADs[i]
+				if fad.Algo != algo {
+					continue
+				}
+				if best == nil || fad.Priority > best.Priority ||
+					(fad.Priority == best.Priority && bytes.Compare(sysid[:], bestid[:]) > 0) {
+					best, bestid = fad, sysid
+				}
+			}
+		}
+	}
+	return best, bestid
+}
+
+// fadSupported returns true if we support the calculation and metric types of
+// the definition, otherwise we must not participate (RFC9350 section 5.3).
+func fadSupported(fad *tlv.FAD) bool {
+	if fad.CalcType != tlv.SRAlgoSPF && fad.CalcType != tlv.SRAlgoStrictSPF {
+		return false
+	}
+	switch fad.MetricType {
+	case tlv.FlexAlgoMetricIGP, tlv.FlexAlgoMetricDelay, tlv.FlexAlgoMetricTE:
+		return true
+	}
+	return false
+}
+
+// agWord returns word i of the extended admin group, 0 past the end.
+func agWord(ag []uint32, i int) uint32 {
+	if i < len(ag) {
+		return ag[i]
+	}
+	return 0
+}
+
+// agAny returns true if any of the bits of groups are set in ag.
+func agAny(ag, groups []uint32) bool {
+	for i, w := range groups {
+		if agWord(ag, i)&w != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// agAll returns true if all of the bits of groups are set in ag.
+func agAll(ag, groups []uint32) bool {
+	for i, w := range groups {
+		if agWord(ag, i)&w != w {
+			return false
+		}
+	}
+	return true
+}
+
+// linkAdminGroup returns the extended admin group (RFC7308) of the link, the
+// RFC5305 admin group is its first word if only that is advertised.
+func linkAdminGroup(te *tlv.LinkTE) []uint32 {
+	switch {
+	case te == nil:
+		return nil
+	case len(te.ExtAdminGroup) != 0:
+		return te.ExtAdminGroup
+	case te.AdminGroup != nil:
+		return []uint32{*te.AdminGroup}
+	}
+	return nil
+}
+
+// fadMetric returns the metric of the edge for the definition, ok is false if
+// the edge is pruned by the constraints or doesn't have the metric.
+func fadMetric(fad *tlv.FAD, e *spfEdge) (uint32, bool) {
+	ag := linkAdminGroup(e.te)
+	if agAny(ag, fad.ExcludeAny) {
+		return 0, false
+	}
+	if len(fad.IncludeAny) != 0 && !agAny(ag, fad.IncludeAny) {
+		return 0, false
+	}
+	if !agAll(ag, fad.IncludeAll) {
+		return 0, false
+	}
+	for _, v := range fad.ExcludeSRLG {
+		if hasSRLG(e.srlg, v) {
+			return 0, false
+		}
+	}
+	switch fad.MetricType {
+	case tlv.FlexAlgoMetricIGP:
+		return e.metric, true
+	case tlv.FlexAlgoMetricDelay:
+		if e.te != nil && e.te.MinMaxDelay != nil {
+			return e.te.MinMaxDelay.Min, true
+		}
+	case tlv.FlexAlgoMetricTE:
+		if e.te != nil && e.te.TEMetric != nil {
+			return *e.te.TEMetric, true
+		}
+	}
+	return 0, false
+}
+
+// flexAlgoGraph returns a copy of the SPF graph with only the routers
+// participating in the algorithm and the edges that satisfy its definition
+// using the definition's metric. The edges from pseudo-nodes have no
+// attributes and are kept.
+func flexAlgoGraph(root *spfNode, nodes map[clns.NodeID]*spfNode, fad *tlv.FAD) map[clns.NodeID]*spfNode {
+	graph := make(map[clns.NodeID]*spfNode)
+	for id, n := range nodes {
+		if n != root && !n.isPN() && !n.hasAlgo(fad.Algo) {
+			continue
+		}
+		fn := &spfNode{
+			nodeid: n.nodeid,
+			segs:   n.segs,
+			flags:  n.flags,
+			dist:   maxDist,
+			index:  -1,
+		}
+		for _, e := range n.edges {
+			if !n.isPN() {
+				m, ok := fadMetric(fad, &e)
+				if !ok {
+					continue
+				}
+				e.metric = m
+			}
+			fn.edges = append(fn.edges, e)
+		}
+		graph[id] = fn
+	}
+	return graph
+}
+
+func hasAlgoSID(sids []tlv.PrefixSID, algo uint8) bool {
+	for _, ps := range sids {
+		if ps.Algo == algo {
+			return true
+		}
+	}
+	return false
+}
+
+// flexAlgoRoutes computes the routes of the algorithm, only prefixes with a
+// Prefix-SID of the algorithm use it (RFC9350 section 13).
+func (db *DB) flexAlgoRoutes(root *spfNode, nodes map[clns.NodeID]*spfNode, algo uint8) map[string]*Route {
+	rib := make(map[string]*Route)
+	for _, n := range nodes {
+		if n == root || n.isPN() || !n.reached() {
+			continue
+		}
+		n.nodePrefixInfo(func(pfx *tlv.IPPrefixCommon) {
+			if pfx.Metric > tlv.ExtIPMaxMetric || !hasAlgoSID(pfx.SIDs, algo) {
+				return
+			}
+			db.addRoute(rib, (*net.IPNet)(&pfx.Prefix), n.dist+pfx.Metric, pfx.Updown, n)
+		})
+	}
+	return rib
+}
+
+// flexAlgoNodes returns the routers reachable in the algorithm's topology.
+func flexAlgoNodes(root *spfNode, nodes map[clns.NodeID]*spfNode) []*YangFlexAlgoNode {
+	var ys []*YangFlexAlgoNode
+	for _, n := range nodes {
+		if n == root || n.isPN() || !n.reached() {
+			continue
+		}
+		y := &YangFlexAlgoNode{Nodeid: n.nodeid, Metric: n.dist}
+		for _, nh := range n.nexthops {
+			y.NextHops = append(y.NextHops, NextHop{Intf: nh.c.Name(), Sysid: nh.sysid})
+		}
+		ys = append(ys, y)
+	}
+	sort.Slice(ys, func(i, j int) bool { return bytes.Compare(ys[i].Nodeid[:], ys[j].Nodeid[:]) < 0 })
+	return ys
+}
+
+// flexAlgoSPF runs the constrained SPF of each flexible algorithm we
+// participate in. The algorithm Prefix-SIDs are added to the label forwarding
+// table and their conflicts to the prefix SID conflicts.
+func (db *DB) flexAlgoSPF(root *spfNode, nodes map[clns.NodeID]*spfNode) []*YangFlexAlgo {
+	var yfas []*YangFlexAlgo
+	for _, algo := range db.localFlexAlgos() {
+		yfa := &YangFlexAlgo{Level: db.li.ToLevel(), Algo: algo}
+		yfas = append(yfas, yfa)
+		fad, src := selectFAD(root, nodes, algo)
+		if fad == nil {
+			Debug(DbgFSPF, "%s: flex-algo %d: no definition", db, algo)
+			continue
+		}
+		yfa.FAD, yfa.Source = fad, &src
+		if !fadSupported(fad) {
+			Debug(DbgFSPF, "%s: flex-algo %d: unsupported definition from %s", db, algo, src)
+			continue
+		}
+		yfa.Participating = true
+
+		graph := flexAlgoGraph(root, nodes, fad)
+		froot := graph[root.nodeid]
+		db.dijkstra(froot, graph, tlv.MTIDStandard)
+		rib := db.flexAlgoRoutes(froot, graph, algo)
+		if db.srgb() != nil {
+			conflicts := db.prefixLabels(db.lfib, froot, graph, rib, algo)
+			for _, c := range conflicts {
+				Debug(DbgFSPF, "%s: flex-algo %d prefix SID %s %d from %s: %s", db, algo,
+					(*net.IPNet)(&c.Prefix), c.SID, c.Sysid, c.Type)
+			}
+			db.conflicts = append(db.conflicts, conflicts...)
+		}
+		yfa.Nodes = flexAlgoNodes(froot, graph)
+		yfa.Routes = sortedRoutes(rib)
+	}
+	return yfas
+}
+
+// FlexAlgos arranges for the per flexible algorithm SPF results to be
+// returned.
+func (db *DB) FlexAlgos() ([]*YangFlexAlgo, error) {
+	i, err := DoRPC(db.rpC, func() interface{} { return db.flexAlgos })
+	if err != nil {
+		return nil, err
+	}
+	return i.([]*YangFlexAlgo), nil
+}
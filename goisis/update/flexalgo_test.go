@@ -0,0 +1,162 @@
+package update
+
+import (
+	"github.com/choppsv1/goisis/tlv"
+	"testing"
+)
+
+func TestFADMetric(t *testing.T) {
+	ag, tem := uint32(0x1), uint32(30)
+	agte := &tlv.LinkTE{AdminGroup: &ag}
+	eagte := &tlv.LinkTE{AdminGroup: &ag, ExtAdminGroup: []uint32{0x1, 0x2}, TEMetric: &tem,
+		MinMaxDelay: &tlv.TEMinMax{Min: 200, Max: 300}}
+	tests := []struct {
+		name   string
+		fad    tlv.FAD
+		te     *tlv.LinkTE
+		srlg   []uint32
+		metric uint32
+		ok     bool
+	}{
+		{"igp", tlv.FAD{}, nil, nil, 10, true},
+		{"exclude", tlv.FAD{ExcludeAny: []uint32{0x1}}, agte, nil, 0, false},
+		{"exclude no groups", tlv.FAD{ExcludeAny: []uint32{0x1}}, nil, nil, 10, true},
+		{"exclude second word", tlv.FAD{ExcludeAny: []uint32{0, 0x2}}, agte, nil, 10, true},
+		{"exclude extended", tlv.FAD{ExcludeAny: []uint32{0, 0x2}}, eagte, nil, 0, false},
+		{"include-any extended", tlv.FAD{IncludeAny: []uint32{0, 0x6}}, eagte, nil, 10, true},
+		{"include-any second word", tlv.FAD{IncludeAny: []uint32{0, 0x6}}, agte, nil, 0, false},
+		{"include-all extended", tlv.FAD{IncludeAll: []uint32{0x1, 0x2}}, eagte, nil, 10, true},
+		{"include-all missing", tlv.FAD{IncludeAll: []uint32{0x1, 0x3}}, eagte, nil, 0, false},
+		{"include-all past end", tlv.FAD{IncludeAll: []uint32{0x1, 0, 0x1}}, eagte, nil, 0, false},
+		{"exclude srlg", tlv.FAD{ExcludeSRLG: []uint32{7}}, nil, []uint32{5, 7}, 0, false},
+		{"other srlg", tlv.FAD{ExcludeSRLG: []uint32{8}}, nil, []uint32{5, 7}, 10, true},
+		{"delay", tlv.FAD{MetricType: tlv.FlexAlgoMetricDelay}, eagte, nil, 200, true},
+		{"no delay", tlv.FAD{MetricType: tlv.FlexAlgoMetricDelay}, agte, nil, 0, false},
+		{"te", tlv.FAD{MetricType: tlv.FlexAlgoMetricTE}, eagte, nil, 30, true},
+		{"no te", tlv.FAD{MetricType: tlv.FlexAlgoMetricTE}, nil, nil, 0, false},
+	}
+	for _, test := range tests {
+		e := &spfEdge{nodeid: testNode(2), metric: 10, srlg: test.srlg, te: test.te}
+		if m, ok := fadMetric(&test.fad, e); m != test.metric || ok != test.ok {
+			t.Errorf("%s: got %d %v want %d %v", test.name, m, ok, test.metric, test.ok)
+		}
+	}
+}
+
+// fadCap returns the capability of router n participating in algorithm 128,
+// with its definition if fad isn't nil.
+func fadCap(n byte, fad *tlv.FAD) *tlv.RouterCap {
+	rc := srCap(n, tlv.SRAlgoSPF, 128)
+	if fad != nil {
+		rc.FADs = []tlv.FAD{*fad}
+	}
+	return rc
+}
+
+func TestSelectFAD(t *testing.T) {
+	links := []testLink{{1, 2, 1}, {1, 3, 1}, {1, 4, 1}}
+	down := fadCap(4, &tlv.FAD{Algo: 128, Priority: 200})
+	down.Down = true
+	db := newTestDB(links, map[byte][][]byte{
+		2: {capTLVs(fadCap(2, &tlv.FAD{Algo: 128, Priority: 100}))},
+		3: {capTLVs(fadCap(3, &tlv.FAD{Algo: 128, Priority: 100, MetricType: tlv.FlexAlgoMetricTE}))},
+		4: {capTLVs(down)},
+	})
+	// Router 5 isn't reachable.
+	addLSP(db, testNode(5), 0, capTLVs(fadCap(5, &tlv.FAD{Algo: 128, Priority: 255})))
+	db.runSPF()
+	root := db.spfNodes[testNode(1)]
+
+	// The highest system ID of the highest priority.
+	fad, src := selectFAD(root, db.spfNodes, 128)
+	if fad == nil || src != testSysID(3) || fad.MetricType != tlv.FlexAlgoMetricTE {
+		t.Errorf("Bad definition %+v from %s", fad, src)
+	}
+	if fad, _ := selectFAD(root, db.spfNodes, 129); fad != nil {
+		t.Errorf("Definition of unknown algorithm %+v", fad)
+	}
+	setLSP(db, 2, isReach(1, 1), capTLVs(fadCap(2, &tlv.FAD{Algo: 128, Priority: 101})))
+	db.runSPF()
+	if fad, src := selectFAD(root, db.spfNodes, 128); fad == nil || src != testSysID(2) || fad.Priority != 101 {
+		t.Errorf("Bad definition %+v from %s", fad, src)
+	}
+
+	tests := []struct {
+		fad tlv.FAD
+		ok  bool
+	}{
+		{tlv.FAD{}, true},
+		{tlv.FAD{CalcType: tlv.SRAlgoStrictSPF, MetricType: tlv.FlexAlgoMetricDelay}, true},
+		{tlv.FAD{CalcType: 2}, false},
+		{tlv.FAD{MetricType: 3}, false},
+	}
+	for _, test := range tests {
+		if ok := fadSupported(&test.fad); ok != test.ok {
+			t.Errorf("Definition %+v supported %v", test.fad, ok)
+		}
+	}
+}
+
+func TestFlexAlgoSPF(t *testing.T) {
+	// Router 4 is reached through 2 with metric 2 or 3 with metric 10. Our
+	// link to 2 has a bit of the second word of its extended admin group.
+	links := []testLink{{1, 2, 1}, {2, 4, 1}, {1, 3, 5}, {3, 4, 5}}
+	eag := &tlv.LinkTE{ExtAdminGroup: []uint32{0, 0x2}}
+	tests := []struct {
+		name    string
+		fad     tlv.FAD
+		algo2   bool // router 2 participates
+		via     byte
+		metric  uint32
+		unknown bool
+	}{
+		{"unconstrained", tlv.FAD{Algo: 128}, true, 2, 2, false},
+		{"exclude", tlv.FAD{Algo: 128, ExcludeAny: []uint32{0, 0x2}}, true, 3, 10, false},
+		{"exclude first word", tlv.FAD{Algo: 128, ExcludeAny: []uint32{0x2}}, true, 2, 2, false},
+		{"not participating", tlv.FAD{Algo: 128}, false, 3, 10, false},
+		{"unsupported", tlv.FAD{Algo: 128, CalcType: 2}, true, 0, 0, true},
+	}
+	for _, test := range tests {
+		cap2 := srCap(2, tlv.SRAlgoSPF)
+		if test.algo2 {
+			cap2 = fadCap(2, nil)
+		}
+		db := newTestDB(links, map[byte][][]byte{
+			2: {capTLVs(cap2)},
+			3: {capTLVs(fadCap(3, nil))},
+			4: {capTLVs(fadCap(4, nil))},
+		})
+		fad := test.fad
+		db.routerCap = fadCap(1, &fad)
+		addLSP(db, testNode(1), 0, isReach(2, 1, eag.Encode()...), isReach(3, 5), capTLVs(db.routerCap))
+		db.runSPF()
+		if len(db.flexAlgos) != 1 {
+			t.Fatalf("%s: bad flex-algos %+v", test.name, db.flexAlgos)
+		}
+		yfa := db.flexAlgos[0]
+		if yfa.Algo != 128 || yfa.FAD == nil || *yfa.Source != testSysID(1) || yfa.Participating == test.unknown {
+			t.Errorf("%s: bad flex-algo %+v", test.name, yfa)
+			continue
+		}
+		if test.unknown {
+			continue
+		}
+		var found bool
+		for _, n := range yfa.Nodes {
+			if n.Nodeid != testNode(4) {
+				continue
+			}
+			found = true
+			if n.Metric != test.metric || len(n.NextHops) != 1 || n.NextHops[0].Intf != testIntf(test.via) {
+				t.Errorf("%s: bad node %+v", test.name, n)
+			}
+		}
+		if !found {
+			t.Errorf("%s: router 4 not reached %+v", test.name, yfa.Nodes)
+		}
+		// The normal SPF is unconstrained.
+		if n := db.spfNodes[testNode(4)]; n.dist != 2 {
+			t.Errorf("%s: SPF distance %d", test.name, n.dist)
+		}
+	}
+}
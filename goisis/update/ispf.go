@@ -0,0 +1,172 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the classification of the LSP DB changes and the partial
+// and incremental SPF calculations they allow.
+package update
+
+import (
+	"bytes"
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/pkt"
+	"github.com/choppsv1/goisis/tlv"
+)
+
+// prefixTLVs only change the routes of the advertising node.
+var prefixTLVs = map[tlv.Type]bool{
+	tlv.TypeExtIPv4Prefix: true,
+	tlv.TypeIPv6Prefix:    true,
+	tlv.TypeMTIPv4Prefix:  true,
+	tlv.TypeMTIPv6Prefix:  true,
+	tlv.TypeSRv6Locator:   true,
+}
+
+// ignoredTLVs are not used by the decision process.
+var ignoredTLVs = map[tlv.Type]bool{
+	tlv.TypeHostname: true,
+	tlv.TypePadding:  true,
+}
+
+func equalTLVs(a, b []tlv.Data) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// lspChange returns the SPF calculation required by the update of the LSP
+// segment from the old header and TLVs to its current contents. A new
+// segment, a purge or a change of the LSP flags requires a full SPF, a change
+// of the IS reachability of a router (or any other TLV used by the decision
+// process) an incremental SPF and a change of its prefixes only a partial
+// route calculation.
+func (lsp *lspSegment) lspChange(ohdr []byte, otlvs tlv.Map) spfType {
+	if ohdr == nil || pkt.GetUInt16(ohdr[clns.HdrLSPLifetime:]) == 0 ||
+		pkt.GetUInt32(ohdr[clns.HdrLSPSeqNo:]) == 0 ||
+		pkt.GetUInt16(lsp.hdr[clns.HdrLSPLifetime:]) == 0 {
+		return spfFull
+	}
+	if ohdr[clns.HdrLSPFlags]&clns.LSPFlagMask != lsp.hdr[clns.HdrLSPFlags]&clns.LSPFlagMask {
+		return spfFull
+	}
+	stype := spfNone
+	for _, tlvs := range []tlv.Map{otlvs, lsp.tlvs} {
+		for t := range tlvs {
+			if ignoredTLVs[t] || equalTLVs(otlvs[t], lsp.tlvs[t]) {
+				continue
+			}
+			if prefixTLVs[t] {
+				stype = maxSPFType(stype, spfRouteOnly)
+			} else {
+				stype = maxSPFType(stype, spfIncremental)
+			}
+		}
+	}
+	if stype == spfIncremental && lsp.lspid[clns.SysIDLen] != 0 {
+		// Pseudo-nodes are transit nodes.
+		return spfFull
+	}
+	return stype
+}
+
+// transitNodes returns the nodes that are the parent of another node in the
+// shortest path tree.
+func transitNodes(nodes map[clns.NodeID]*spfNode) map[clns.NodeID]bool {
+	transit := make(map[clns.NodeID]bool)
+	for _, n := range nodes {
+		for _, p := range n.parents {
+			transit[p.nodeid] = true
+		}
+	}
+	return transit
+}
+
+// cloneTree returns a copy of the shortest path tree that can be updated
+// without changing the original.
+func cloneTree(nodes map[clns.NodeID]*spfNode) map[clns.NodeID]*spfNode {
+	tree := make(map[clns.NodeID]*spfNode, len(nodes))
+	for id, n := range nodes {
+		nn := *n
+		tree[id] = &nn
+	}
+	for _, n := range tree {
+		if len(n.parents) == 0 {
+			continue
+		}
+		parents := make([]*spfNode, 0, len(n.parents))
+		for _, p := range n.parents {
+			parents = append(parents, tree[p.nodeid])
+		}
+		n.parents = parents
+	}
+	return tree
+}
+
+// incrementalSPF updates the shortest path tree of the previous SPF for the
+// routers whose IS reachability changed. This is only possible if they are
+// leaves of the tree, i.e., no other node is reached through them before or
+// after the change. It returns false if a full SPF is required, the tree may
+// then have been modified and must be discarded, the caller passes a copy.
+func (db *DB) incrementalSPF(root *spfNode, nodes map[clns.NodeID]*spfNode, changed map[clns.NodeID]bool) bool {
+	transit := transitNodes(nodes)
+	for id := range changed {
+		n := nodes[id]
+		if n == nil || n == root || n.isPN() || transit[id] {
+			return false
+		}
+		for _, e := range append(n.edges, n.isReach(tlv.MTIDStandard)...) {
+			if changed[e.nodeid] {
+				return false
+			}
+		}
+	}
+	for id := range changed {
+		n := nodes[id]
+		n.edges = n.isReach(tlv.MTIDStandard)
+		n.dist, n.parents, n.nexthops = maxDist, nil, nil
+		for _, e := range n.edges {
+			u := nodes[e.nodeid]
+			if u == nil || !u.reached() {
+				continue
+			}
+			if u != root && !u.isPN() && u.flags&clns.LSPFOverload != 0 {
+				continue
+			}
+			for _, ue := range u.edges {
+				if ue.nodeid != n.nodeid {
+					continue
+				}
+				d := u.dist + ue.metric
+				nhs := db.spfNextHops(root, u, n, tlv.MTIDStandard)
+				if d < n.dist {
+					n.dist, n.parents, n.nexthops = d, []*spfNode{u}, nhs
+				} else if d == n.dist {
+					n.parents = append(n.parents, u)
+					n.nexthops = mergeNextHops(n.nexthops, nhs)
+				}
+				break
+			}
+		}
+	}
+	// The new links must not shorten or add paths to the other nodes.
+	for id := range changed {
+		n := nodes[id]
+		if !n.reached() || n.flags&clns.LSPFOverload != 0 {
+			continue
+		}
+		for _, e := range n.edges {
+			v := nodes[e.nodeid]
+			if v != nil && v.hasEdge(n.nodeid) && n.dist+e.metric <= v.dist {
+				return false
+			}
+		}
+	}
+	return true
+}
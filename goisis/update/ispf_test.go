@@ -0,0 +1,167 @@
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/tlv"
+	"sort"
+	"testing"
+)
+
+// ispfLinks is a ring of routers 1-6 with router 7 a leaf of router 4 and
+// router 8 a leaf of router 2. Router 7 advertises a link to router 8 that
+// router 8 doesn't.
+var ispfLinks = []testLink{
+	{1, 2, 1}, {2, 3, 1}, {3, 4, 1}, {4, 5, 1}, {5, 6, 1}, {6, 1, 1},
+	{4, 7, 5}, {2, 8, 1},
+}
+
+// ispfTLVs returns the TLVs of router n of ispfLinks with the extra TLVs.
+func ispfTLVs(n byte, extra ...[]byte) [][]byte {
+	tlvs := linkTLVs(n, ispfLinks)
+	if n == 7 {
+		tlvs = append(tlvs, isReach(8, 1))
+	}
+	tlvs = append(tlvs, ipReach(testPrefix(n), 1))
+	return append(tlvs, extra...)
+}
+
+// newISPFDB returns the DB of router 1 for ispfLinks after a full SPF.
+func newISPFDB() *DB {
+	extra := make(map[byte][][]byte)
+	for n := byte(2); n <= 8; n++ {
+		extra[n] = ispfTLVs(n)[len(linkTLVs(n, ispfLinks)):]
+	}
+	db := newTestDB(ispfLinks, extra)
+	db.runSPF()
+	return db
+}
+
+type ispfChange struct {
+	n    byte
+	tlvs [][]byte
+}
+
+// routeHops returns the next hops of a route as sorted strings.
+func routeHops(r *Route) []string {
+	var hops []string
+	for _, nh := range r.NextHops {
+		hops = append(hops, nh.Intf+" "+nh.Sysid.String())
+	}
+	sort.Strings(hops)
+	return hops
+}
+
+// compareSPF checks that the trees and routes of a and b are the same.
+func compareSPF(t *testing.T, a, b *DB) {
+	t.Helper()
+	for id, n := range b.spfNodes {
+		an := a.spfNodes[id]
+		if an == nil || an.reached() != n.reached() || (n.reached() && an.dist != n.dist) {
+			t.Errorf("Node %s: %+v want %+v", id, an, n)
+		}
+	}
+	if len(a.rib) != len(b.rib) {
+		t.Errorf("Got %d routes want %d", len(a.rib), len(b.rib))
+	}
+	for key, r := range b.rib {
+		ar := a.rib[key]
+		if ar == nil || ar.Metric != r.Metric || ar.Type != r.Type {
+			t.Errorf("Route %s: %+v want %+v", key, ar, r)
+			continue
+		}
+		ahops, hops := routeHops(ar), routeHops(r)
+		if len(ahops) != len(hops) {
+			t.Errorf("Route %s next hops %v want %v", key, ahops, hops)
+			continue
+		}
+		for i := range hops {
+			if ahops[i] != hops[i] {
+				t.Errorf("Route %s next hops %v want %v", key, ahops, hops)
+				break
+			}
+		}
+	}
+}
+
+func TestIncrementalSPF(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []ispfChange
+		stypes  []spfType
+		want    string // the SPF run
+	}{
+		{"prefix", []ispfChange{{5, ispfTLVs(5, ipReach("10.5.0.0/16", 1))}},
+			[]spfType{spfRouteOnly}, SPFRouteOnly},
+		{"prefix metric", []ispfChange{{7, append(linkTLVs(7, ispfLinks), isReach(8, 1), ipReach(testPrefix(7), 20))}},
+			[]spfType{spfRouteOnly}, SPFRouteOnly},
+		{"leaf metric", []ispfChange{{7, append(ispfTLVs(7)[1:], isReach(4, 2))}},
+			[]spfType{spfIncremental}, SPFIncremental},
+		{"leaf link removed", []ispfChange{{7, ispfTLVs(7)[1:]}},
+			[]spfType{spfIncremental}, SPFIncremental},
+		{"leaf one way link", []ispfChange{{7, ispfTLVs(7, isReach(5, 1))}},
+			[]spfType{spfIncremental}, SPFIncremental},
+		{"leaves", []ispfChange{{7, ispfTLVs(7, isReach(3, 9))}, {8, ispfTLVs(8, ipReach("10.8.0.0/16", 1))}},
+			[]spfType{spfIncremental, spfRouteOnly}, SPFIncremental},
+		{"transit", []ispfChange{{5, ispfTLVs(5, isReach(7, 1))}},
+			[]spfType{spfIncremental}, SPFFull},
+		{"shorter path", []ispfChange{{8, ispfTLVs(8, isReach(7, 1))}},
+			[]spfType{spfIncremental}, SPFFull},
+		{"adjacent leaves", []ispfChange{{8, ispfTLVs(8, isReach(7, 9))}, {7, append(ispfTLVs(7)[1:], isReach(4, 2))}},
+			[]spfType{spfIncremental, spfIncremental}, SPFFull},
+	}
+	for _, test := range tests {
+		db := newISPFDB()
+		otree := db.spfNodes
+		odist := make(map[clns.NodeID]uint32)
+		for id, n := range otree {
+			odist[id] = n.dist
+		}
+		full := newISPFDB()
+		for i, c := range test.changes {
+			if stype := setLSP(db, c.n, c.tlvs...); stype != test.stypes[i] {
+				t.Errorf("%s: change %d is %s want %s", test.name, i, stype, test.stypes[i])
+			}
+			setLSP(full, c.n, c.tlvs...)
+		}
+		db.runSPF()
+		if typ := db.spflog.entries()[0].Type; typ != test.want {
+			t.Errorf("%s: ran %s SPF want %s", test.name, typ, test.want)
+		}
+		full.spfPend.add(spfFull, nil)
+		full.runSPF()
+		compareSPF(t, db, full)
+
+		// The previous tree is unchanged.
+		for id, n := range otree {
+			if n.dist != odist[id] {
+				t.Errorf("%s: previous tree node %s changed", test.name, id)
+			}
+		}
+	}
+}
+
+func TestLSPChange(t *testing.T) {
+	db := newISPFDB()
+	if stype := setLSP(db, 5, ispfTLVs(5)...); stype != spfNone {
+		t.Errorf("Unchanged LSP is %s", stype)
+	}
+	hostname := []byte{byte(tlv.TypeHostname), 2, 'r', '5'}
+	if stype := setLSP(db, 5, ispfTLVs(5, hostname)...); stype != spfNone {
+		t.Errorf("Hostname change is %s", stype)
+	}
+	lspid := clns.MakeLSPID(testSysID(5), 0, 0)
+	lsp := db.get(lspid[:])
+	ohdr := append([]byte(nil), lsp.hdr...)
+	lsp.hdr[clns.HdrLSPFlags] |= byte(clns.LSPFOverload)
+	if stype := lsp.lspChange(ohdr, lsp.tlvs); stype != spfFull {
+		t.Errorf("Overload change is %s", stype)
+	}
+	if stype := lsp.lspChange(nil, nil); stype != spfFull {
+		t.Errorf("New LSP is %s", stype)
+	}
+	copy(ohdr, lsp.hdr)
+	lsp.hdr[clns.HdrLSPLifetime], lsp.hdr[clns.HdrLSPLifetime+1] = 0, 0
+	if stype := lsp.lspChange(ohdr, lsp.tlvs); stype != spfFull {
+		t.Errorf("Purge is %s", stype)
+	}
+}
@@ -0,0 +1,131 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the leaking of level 2 reachability into level 1
+// (RFC5302 section 3.3, RFC5305 section 4).
+package update
+
+import (
+	"github.com/choppsv1/goisis/policy"
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+)
+
+// LeakPolicy selects the level 2 prefixes leaked into level 1. A prefix is
+// leaked if it is permitted by PrefixList or carries one of Tags, without
+// either all prefixes are leaked. The prefixes are then subject to the
+// PolicyLeak policy.
+type LeakPolicy struct {
+	PrefixList *policy.PrefixList
+	Tags       []uint32
+}
+
+// YangLeaked is a level 2 prefix leaked into level 1 for the yang model.
+type YangLeaked struct {
+	Prefix tlv.IPPrefix `json:"prefix"`
+	Metric uint32       `json:"metric"`
+	Tags   []uint32     `json:"tags,omitempty"`
+}
+
+// permit returns true if the policy permits leaking the prefix.
+func (lp *LeakPolicy) permit(ipi *tlv.IPInfo) bool {
+	if lp.PrefixList == nil && len(lp.Tags) == 0 {
+		return true
+	}
+	if lp.PrefixList.Permit(&ipi.Ipnet) {
+		return true
+	}
+	if len(lp.Tags) == 0 {
+		return false
+	}
+	tags, err := tlv.PrefixTags(ipi.Subtlv)
+	if err != nil {
+		return false
+	}
+	for _, t := range tags {
+		for _, lt := range lp.Tags {
+			if t == lt {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSentPrefix returns true if the prefix is part of the level 1 reachability
+// we sent to level 2, these must not be leaked back into level 1.
+func (db *DB) isSentPrefix(prefix *net.IPNet) bool {
+	for i := range db.sentReach {
+		if samePrefix(&db.sentReach[i].Ipnet, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// leaked returns the level 2 reachability permitted by the leak policy.
+func (db *DB) leaked() []tlv.IPInfo {
+	if db.li != 0 || db.leak == nil {
+		return nil
+	}
+	var out []tlv.IPInfo
+	for _, ipi := range db.otherReach {
+		ipi := ipi
+		if db.isLocalPrefix(&ipi.Ipnet) || db.isSentPrefix(&ipi.Ipnet) {
+			continue
+		}
+		if !db.leak.permit(&ipi) || !db.applyPolicy(db.policies[PolicyLeak], &ipi) {
+			continue
+		}
+		ipi.Updown = true
+		ipi.Readvertised = true
+		out = append(out, ipi)
+	}
+	return out
+}
+
+// leakedReach returns the level 2 reachability to add to our level 1 LSP for
+// the given address family.
+func (db *DB) leakedReach(ipv4 bool) []tlv.IPInfo {
+	var out []tlv.IPInfo
+	for _, ipi := range db.leaked() {
+		if isIPv4(&ipi.Ipnet) == ipv4 {
+			out = append(out, ipi)
+		}
+	}
+	return out
+}
+
+// SetLeakPolicy configures the policy for leaking level 2 prefixes into level
+// 1, nil disables leaking.
+func (db *DB) SetLeakPolicy(lp *LeakPolicy) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.leak = lp
+		return nil
+	})
+	db.SomethingChanged(nil, GenReasonConfig)
+}
+
+// Leaked arranges for the level 2 prefixes leaked into level 1 to be
+// returned.
+func (db *DB) Leaked() ([]*YangLeaked, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		var ys []*YangLeaked
+		for _, ipi := range db.leaked() {
+			tags, _ := tlv.PrefixTags(ipi.Subtlv)
+			tags = append(tags, ipi.Tags...)
+			ys = append(ys, &YangLeaked{
+				Prefix: tlv.IPPrefix(ipi.Ipnet),
+				Metric: ipi.Metric,
+				Tags:   tags,
+			})
+		}
+		return ys
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.([]*YangLeaked), nil
+}
@@ -0,0 +1,96 @@
+package update
+
+import (
+	"github.com/choppsv1/goisis/policy"
+	"github.com/choppsv1/goisis/tlv"
+	"testing"
+)
+
+// taggedIPInfo returns the IP information of the prefix with an
+// administrative tag.
+func taggedIPInfo(prefix string, metric uint32, tag byte) tlv.IPInfo {
+	ipi := testIPInfo(prefix, metric)
+	ipi.Subtlv = []byte{tlv.SubTLVPrefixTag, 4, 0, 0, 0, tag}
+	return ipi
+}
+
+// leakDB returns a level 1 database which received reach from level 2 and
+// sent the prefixes of sent to level 2.
+func leakDB(lp *LeakPolicy, reach []tlv.IPInfo, sent ...tlv.IPInfo) *DB {
+	db := newTestDB(nil, nil)
+	db.li = 0
+	db.leak = lp
+	db.otherReach = reach
+	db.sentReach = sent
+	return db
+}
+
+func TestLeaked(t *testing.T) {
+	pl := &policy.PrefixList{Name: "leak", Entries: []policy.PrefixEntry{
+		{Prefix: policy.Prefix(testIPInfo("10.9.0.0/16", 0).Ipnet), Action: policy.Deny},
+		{Prefix: policy.Prefix(testIPInfo("10.0.0.0/8", 0).Ipnet), Le: 32},
+	}}
+	reach := []tlv.IPInfo{
+		testIPInfo("10.1.0.0/16", 10),
+		testIPInfo("10.2.0.0/16", 20),
+		testIPInfo("10.9.0.0/16", 30),
+		testIPInfo("172.16.0.0/16", 40),
+		taggedIPInfo("192.0.2.0/24", 50, 100),
+		taggedIPInfo("2001:db8::/32", 60, 101),
+	}
+	tests := []struct {
+		name string
+		lp   *LeakPolicy
+		sent []tlv.IPInfo
+		want []tlv.IPInfo
+	}{
+		{"no policy", nil, nil, nil},
+		{"prefix list", &LeakPolicy{PrefixList: pl}, nil, reach[:2]},
+		{"tags", &LeakPolicy{Tags: []uint32{100, 101}}, nil, reach[4:]},
+		{"both", &LeakPolicy{PrefixList: pl, Tags: []uint32{101}}, nil,
+			[]tlv.IPInfo{reach[0], reach[1], reach[5]}},
+		// What we sent to level 2 came from level 1 and isn't leaked back.
+		{"sent", &LeakPolicy{PrefixList: pl}, []tlv.IPInfo{testIPInfo("10.2.0.0/16", 5)}, reach[:1]},
+	}
+	for _, test := range tests {
+		db := leakDB(test.lp, reach, test.sent...)
+		leaked := db.leaked()
+		checkReach(t, test.name, leaked, test.want)
+		for _, ipi := range leaked {
+			if !ipi.Updown {
+				t.Errorf("%s: %s leaked without the down bit", test.name, &ipi.Ipnet)
+			}
+		}
+		var v4 []tlv.IPInfo
+		for _, ipi := range test.want {
+			if isIPv4(&ipi.Ipnet) {
+				v4 = append(v4, ipi)
+			}
+		}
+		checkReach(t, test.name+" ipv4", db.leakedReach(true), v4)
+	}
+
+	// Only level 1 leaks.
+	db := leakDB(&LeakPolicy{PrefixList: pl}, reach)
+	db.li = 1
+	if leaked := db.leaked(); leaked != nil {
+		t.Errorf("Leaked into level 2 %v", leaked)
+	}
+}
+
+func TestLeakedNotPropagated(t *testing.T) {
+	// The leaked prefixes advertised in our level 1 LSP and by another level 1
+	// router are never propagated back to level 2.
+	links := []testLink{{1, 2, 10}}
+	lp := &LeakPolicy{Tags: []uint32{100}}
+	db := leakDB(lp, []tlv.IPInfo{taggedIPInfo("192.0.2.0/24", 50, 100)})
+	tlvs := append(linkTLVs(1, links), ipReach("10.1.0.0/16", 1))
+	for _, ipi := range db.leaked() {
+		tlvs = append(tlvs, downReach(ipi.Ipnet.String(), ipi.Metric))
+	}
+	addLSP(db, testNode(1), 0, tlvs...)
+	addLSP(db, testNode(2), 0, append(linkTLVs(2, links), downReach("198.51.100.0/24", 1))...)
+	db.runSPF()
+	checkReach(t, "level reach", db.levelReach(db.spfNodes[testNode(1)], db.spfNodes),
+		[]tlv.IPInfo{testIPInfo("10.1.0.0/16", 1)})
+}
@@ -0,0 +1,413 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the Loop-Free Alternate (RFC5286) part of the decision
+// process, the SPF rooted at each neighbor and the selection of the per prefix
+// backup next hops.
+package update
+
+import (
+	"bytes"
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"sort"
+)
+
+// Backup types.
+const (
+	BackupLFA       = "lfa"
+	BackupRemoteLFA = "remote-lfa"
+	BackupTILFA     = "ti-lfa"
+)
+
+// Remote LFA PQ node selection policies.
+const (
+	PQSelectLowestMetric = "lowest-metric" // lowest metric of the repair path
+	PQSelectClosest      = "closest"       // closest PQ node to us
+)
+
+// FRRPolicy is the fast reroute configuration of a circuit for a level.
+// Candidate allows the circuit to be used by the alternates of other
+// circuits, the others enable the protection of its primary next hops.
+type FRRPolicy struct {
+	Candidate   bool
+	LFA         bool
+	RemoteLFA   bool
+	PQSelection string
+	TILFA       bool
+}
+
+func (p *FRRPolicy) enabled() bool {
+	return p.LFA || p.RemoteLFA || p.TILFA
+}
+
+// RepairSegment is a segment of the segment list of a repair path.
+type RepairSegment struct {
+	Type  string        `json:"type"`
+	Node  clns.SystemID `json:"node"`
+	Label uint32        `json:"label"`
+}
+
+// Segment types of the repair paths.
+const (
+	SegmentNode   = "node-sid"
+	SegmentAdj    = "adj-sid"
+	SegmentPrefix = "prefix-sid"
+)
+
+// Backup is the repair path protecting a primary next hop. Remote LFAs
+// tunnel to the PQ node, with segment routing the segment list and the
+// resulting outgoing label stack are given.
+type Backup struct {
+	Type        string          `json:"type"`
+	Intf        string          `json:"outgoing-interface"`
+	Addr        net.IP          `json:"next-hop,omitempty"`
+	Sysid       clns.SystemID   `json:"neighbor-sysid"`
+	Metric      uint32          `json:"metric"`
+	NodeProtect bool            `json:"node-protecting"`
+	Downstream  bool            `json:"downstream"`
+	PQNode      *clns.SystemID  `json:"pq-node,omitempty"`
+	Segments    []RepairSegment `json:"segment-list,omitempty"`
+	Labels      []uint32        `json:"outgoing-labels,omitempty"`
+}
+
+// YangFRRCoverage is the fast reroute protection of the routes of a level
+// for the yang model.
+type YangFRRCoverage struct {
+	Level         clns.Level `json:"level"`
+	Prefixes      uint32     `json:"prefixes"`
+	Protected     uint32     `json:"protected-prefixes"`
+	NodeProtected uint32     `json:"node-protected-prefixes"`
+	LFA           uint32     `json:"lfa-prefixes"`
+	RemoteLFA     uint32     `json:"remote-lfa-prefixes"`
+	TILFA         uint32     `json:"ti-lfa-prefixes"`
+	Coverage      float64    `json:"coverage"`
+}
+
+// frrState holds the SPF results shared by the repair path computations.
+type frrState struct {
+	root  *spfNode
+	nodes map[clns.NodeID]*spfNode
+	lnbrs []*lfaNbr
+	advs  map[string][]lfaAdv
+	rdist map[clns.NodeID]map[clns.NodeID]uint32 // reverse distances by node
+	sids  map[string][]prefixSID                 // prefix SIDs, nil without SR
+}
+
+// lfaNbr is a neighbor and the distances from it to the other nodes.
+type lfaNbr struct {
+	c         Circuit
+	sysid     clns.SystemID
+	cost      uint32 // metric of our link to the neighbor
+	candidate bool
+	dist      map[clns.NodeID]uint32
+}
+
+// lfaAdv is a node advertising a prefix.
+type lfaAdv struct {
+	nodeid clns.NodeID
+	metric uint32
+	updown bool
+}
+
+// copyGraph returns a copy of the SPF graph ready for another SPF run.
+func copyGraph(nodes map[clns.NodeID]*spfNode) map[clns.NodeID]*spfNode {
+	graph := make(map[clns.NodeID]*spfNode)
+	for id, n := range nodes {
+		graph[id] = &spfNode{
+			nodeid: n.nodeid,
+			segs:   n.segs,
+			edges:  n.edges,
+			flags:  n.flags,
+			dist:   maxDist,
+			index:  -1,
+		}
+	}
+	return graph
+}
+
+// spfDistances returns the distances from the node src to the reachable
+// nodes. The next hops computed along the way are ours and not used.
+func (db *DB) spfDistances(src clns.NodeID, nodes map[clns.NodeID]*spfNode) map[clns.NodeID]uint32 {
+	graph := copyGraph(nodes)
+	root := graph[src]
+	if root == nil {
+		return nil
+	}
+	db.dijkstra(root, graph, tlv.MTIDStandard)
+	dist := make(map[clns.NodeID]uint32)
+	for id, n := range graph {
+		if n.reached() {
+			dist[id] = n.dist
+		}
+	}
+	return dist
+}
+
+func sysNodeID(sysid clns.SystemID) clns.NodeID {
+	var nodeid clns.NodeID
+	copy(nodeid[:], sysid[:])
+	return nodeid
+}
+
+// linkCost returns the metric of our link to the neighbor on circuit c, on a
+// LAN the link is to the pseudo-node of the circuit.
+func linkCost(root *spfNode, nodes map[clns.NodeID]*spfNode, c Circuit, sysid clns.SystemID) uint32 {
+	nid := sysNodeID(sysid)
+	cost := maxDist
+	for _, e := range root.edges {
+		v := nodes[e.nodeid]
+		if v == nil {
+			continue
+		}
+		if c.IsP2P() && e.nodeid == nid {
+			if e.metric < cost {
+				cost = e.metric
+			}
+		} else if v.isPN() && v.direct == c && v.hasEdge(nid) && e.metric < cost {
+			cost = e.metric
+		}
+	}
+	return cost
+}
+
+// lfaNeighbors returns our neighbors with the distances from them. A neighbor
+// is a candidate alternate if its circuit allows it and it is not overloaded.
+func (db *DB) lfaNeighbors(root *spfNode, nodes map[clns.NodeID]*spfNode) []*lfaNbr {
+	var lnbrs []*lfaNbr
+	dists := make(map[clns.SystemID]map[clns.NodeID]uint32)
+	for name, nbrs := range db.nbrs {
+		c := db.circuits[name]
+		if c == nil {
+			continue
+		}
+		candidate := c.FRR(db.li).Candidate
+		for sysid := range nbrs {
+			n := nodes[sysNodeID(sysid)]
+			if n == nil || !n.reached() {
+				continue
+			}
+			dist, ok := dists[sysid]
+			if !ok {
+				dist = db.spfDistances(n.nodeid, nodes)
+				dists[sysid] = dist
+			}
+			lnbrs = append(lnbrs, &lfaNbr{
+				c:         c,
+				sysid:     sysid,
+				cost:      linkCost(root, nodes, c, sysid),
+				candidate: candidate && n.flags&clns.LSPFOverload == 0,
+				dist:      dist,
+			})
+		}
+	}
+	// Sort for a stable choice between equal alternates.
+	sort.Slice(lnbrs, func(i, j int) bool {
+		if lnbrs[i].c.Name() != lnbrs[j].c.Name() {
+			return lnbrs[i].c.Name() < lnbrs[j].c.Name()
+		}
+		return bytes.Compare(lnbrs[i].sysid[:], lnbrs[j].sysid[:]) < 0
+	})
+	return lnbrs
+}
+
+// lfaAdvertisers returns the nodes advertising each prefix of the RIB. The
+// default routes are advertised by the attached routers.
+func lfaAdvertisers(root *spfNode, nodes map[clns.NodeID]*spfNode) map[string][]lfaAdv {
+	advs := make(map[string][]lfaAdv)
+	for _, n := range nodes {
+		if n == root || n.isPN() {
+			continue
+		}
+		nodeid := n.nodeid
+		n.nodePrefixInfo(func(pfx *tlv.IPPrefixCommon) {
+			key := (*net.IPNet)(&pfx.Prefix).String()
+			advs[key] = append(advs[key], lfaAdv{nodeid, pfx.Metric, pfx.Updown})
+		})
+		if n.flags&clns.LSPFMetDef != 0 && n.flags&clns.LSPFOverload == 0 {
+			for _, d := range []*net.IPNet{&defaultIPv4, &defaultIPv6} {
+				key := d.String()
+				advs[key] = append(advs[key], lfaAdv{nodeid, 0, true})
+			}
+		}
+	}
+	return advs
+}
+
+// prefixDist returns the distance from a node to the prefix using its
+// distances to the advertisers of the route type.
+func prefixDist(dist map[clns.NodeID]uint32, advs []lfaAdv, updown bool) uint32 {
+	best := maxDist
+	for _, a := range advs {
+		if a.updown != updown {
+			continue
+		}
+		if d, ok := dist[a.nodeid]; ok && d+a.metric < best {
+			best = d + a.metric
+		}
+	}
+	return best
+}
+
+// primaryNbr returns the neighbor of the primary next hop.
+func primaryNbr(lnbrs []*lfaNbr, c Circuit, sysid clns.SystemID) *lfaNbr {
+	for _, ln := range lnbrs {
+		if ln.c == c && ln.sysid == sysid {
+			return ln
+		}
+	}
+	return nil
+}
+
+// selectLFA returns the best loop-free alternate for the primary next hop
+// of the route (RFC5286 section 3). Node protecting alternates are preferred
+// then the lowest metric.
+func (db *DB) selectLFA(r *Route, nh *NextHop, lnbrs []*lfaNbr, advs []lfaAdv) *Backup {
+	c := db.circuits[nh.Intf]
+	updown := r.Type == RouteInterArea
+	rootid := sysNodeID(db.sysid)
+	primary := primaryNbr(lnbrs, c, nh.Sysid)
+	var best *Backup
+	var bestnbr *lfaNbr
+	for _, ln := range lnbrs {
+		if !ln.candidate || ln.c == c || ln.cost == maxDist {
+			continue
+		}
+		dNP := prefixDist(ln.dist, advs, updown)
+		dNS, ok := ln.dist[rootid]
+		if dNP == maxDist || !ok {
+			continue
+		}
+		// Inequality 1: loop-free.
+		if dNP >= dNS+r.Metric {
+			continue
+		}
+		b := &Backup{
+			Type:       BackupLFA,
+			Intf:       ln.c.Name(),
+			Sysid:      ln.sysid,
+			Metric:     ln.cost + dNP,
+			Downstream: dNP < r.Metric, // Inequality 2
+		}
+		// Inequality 3: node protecting.
+		if primary != nil && ln.sysid != primary.sysid {
+			dNE, ok := ln.dist[sysNodeID(primary.sysid)]
+			dEP := prefixDist(primary.dist, advs, updown)
+			b.NodeProtect = ok && dEP != maxDist && dNP < dNE+dEP
+		}
+		if best == nil || (b.NodeProtect && !best.NodeProtect) ||
+			(b.NodeProtect == best.NodeProtect && b.Metric < best.Metric) {
+			best, bestnbr = b, ln
+		}
+	}
+	if best != nil {
+		nhs := db.resolveNextHops([]nexthop{{bestnbr.c, bestnbr.sysid}}, r.Prefix.IP.To4() != nil)
+		best.Addr = nhs[0].Addr
+	}
+	return best
+}
+
+// frrEnabled returns true if any of our circuits has fast reroute enabled.
+func (db *DB) frrEnabled() bool {
+	for _, c := range db.circuits {
+		if p := c.FRR(db.li); p.enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// selectBackup returns the repair path for the primary next hop of the route
+// using the protections enabled on its circuit, in order LFA, TI-LFA and
+// remote LFA.
+func (db *DB) selectBackup(fs *frrState, key string, r *Route, nh *NextHop) *Backup {
+	c := db.circuits[nh.Intf]
+	p := c.FRR(db.li)
+	var b *Backup
+	if p.LFA {
+		b = db.selectLFA(r, nh, fs.lnbrs, fs.advs[key])
+	}
+	if b == nil && p.TILFA && fs.sids != nil {
+		b = db.selectTILFA(fs, key, r, nh)
+	}
+	if b == nil && p.RemoteLFA {
+		b = db.selectRemoteLFA(fs, key, r, nh, p.PQSelection)
+	}
+	return b
+}
+
+// newFRRState returns the state of the repair path computation over the
+// shortest path tree rooted at root.
+func (db *DB) newFRRState(root *spfNode, nodes map[clns.NodeID]*spfNode) *frrState {
+	fs := &frrState{
+		root:  root,
+		nodes: nodes,
+		lnbrs: db.lfaNeighbors(root, nodes),
+		advs:  lfaAdvertisers(root, nodes),
+		rdist: make(map[clns.NodeID]map[clns.NodeID]uint32),
+	}
+	if db.srgb() != nil {
+		fs.sids, _ = db.resolveSIDs(prefixSIDs(root, nodes, tlv.SRAlgoSPF))
+	}
+	return fs
+}
+
+// frrRoutes adds the repair paths to the primary next hops of the routes on
+// the circuits with fast reroute enabled and returns the coverage.
+func (db *DB) frrRoutes(root *spfNode, nodes map[clns.NodeID]*spfNode, rib map[string]*Route) *YangFRRCoverage {
+	cov := &YangFRRCoverage{Level: db.li.ToLevel()}
+	var fs *frrState
+	if db.frrEnabled() {
+		fs = db.newFRRState(root, nodes)
+	}
+	for key, r := range rib {
+		cov.Prefixes++
+		protected, nodeProtected := len(r.NextHops) != 0, len(r.NextHops) != 0
+		types := make(map[string]bool)
+		for i := range r.NextHops {
+			nh := &r.NextHops[i]
+			if db.circuits[nh.Intf] != nil && fs != nil {
+				nh.Backup = db.selectBackup(fs, key, r, nh)
+			}
+			if nh.Backup == nil {
+				protected = false
+				continue
+			}
+			types[nh.Backup.Type] = true
+			if !nh.Backup.NodeProtect {
+				nodeProtected = false
+			}
+		}
+		if !protected {
+			continue
+		}
+		cov.Protected++
+		if nodeProtected {
+			cov.NodeProtected++
+		}
+		switch {
+		case types[BackupTILFA]:
+			cov.TILFA++
+		case types[BackupRemoteLFA]:
+			cov.RemoteLFA++
+		default:
+			cov.LFA++
+		}
+	}
+	if cov.Prefixes != 0 {
+		cov.Coverage = float64(cov.Protected) * 100 / float64(cov.Prefixes)
+	}
+	return cov
+}
+
+// FRRCoverage arranges for the fast reroute coverage to be returned.
+func (db *DB) FRRCoverage() (*YangFRRCoverage, error) {
+	i, err := DoRPC(db.rpC, func() interface{} { return db.frr })
+	if err != nil {
+		return nil, err
+	}
+	return i.(*YangFRRCoverage), nil
+}
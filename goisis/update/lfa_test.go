@@ -0,0 +1,137 @@
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	"testing"
+)
+
+// newFRRDB returns the DB of router 1 for the links with the fast reroute
+// policy on its circuits. Router n in dsts advertises testPrefix(n).
+func newFRRDB(links []testLink, frr FRRPolicy, dsts ...byte) *DB {
+	extra := make(map[byte][][]byte)
+	for _, n := range dsts {
+		extra[n] = [][]byte{ipReach(testPrefix(n), 1)}
+	}
+	db := newTestDB(links, extra)
+	for _, c := range db.circuits {
+		c.(*testCircuit).frr = frr
+	}
+	return db
+}
+
+// primaryBackup returns the backup of the only next hop of the route to the
+// prefix of router n.
+func primaryBackup(t *testing.T, db *DB, n byte) *Backup {
+	t.Helper()
+	r := db.rib[testPrefix(n)]
+	if r == nil || len(r.NextHops) != 1 {
+		t.Fatalf("Bad route to %d %+v", n, r)
+	}
+	return r.NextHops[0].Backup
+}
+
+var (
+	lfaSquare = []testLink{{1, 2, 1}, {2, 4, 1}, {1, 3, 2}, {3, 4, 2}}
+	lfaRing5  = []testLink{{1, 2, 1}, {2, 3, 1}, {3, 4, 1}, {4, 5, 1}, {5, 1, 1}}
+	lfaRing6  = []testLink{{1, 2, 1}, {2, 3, 1}, {3, 4, 1}, {4, 5, 1}, {5, 6, 1}, {6, 1, 1}}
+	// Router 3 only protects the link to 2.
+	lfaTriangle = []testLink{{1, 2, 1}, {2, 4, 1}, {1, 3, 1}, {3, 2, 1}}
+	// Router 5 also protects router 2, at a higher metric.
+	lfaTwoAlts = append(append([]testLink(nil), lfaTriangle...), testLink{1, 5, 5}, testLink{5, 4, 1})
+)
+
+func TestSelectLFA(t *testing.T) {
+	tests := []struct {
+		name   string
+		links  []testLink
+		dst    byte
+		noCand byte // a neighbor that isn't a candidate
+		over   byte // an overloaded neighbor
+		want   *Backup
+	}{
+		{"square", lfaSquare, 4, 0, 0,
+			&Backup{Sysid: testSysID(3), Metric: 5, NodeProtect: true}},
+		{"square downstream", []testLink{{1, 2, 1}, {2, 4, 1}, {1, 3, 10}, {3, 4, 1}}, 4, 0, 0,
+			&Backup{Sysid: testSysID(3), Metric: 12, NodeProtect: true, Downstream: true}},
+		{"square not loop-free", lfaSquare, 2, 0, 0, nil},
+		{"link protecting", lfaTriangle, 4, 0, 0,
+			&Backup{Sysid: testSysID(3), Metric: 4}},
+		{"node protecting preferred", lfaTwoAlts, 4, 0, 0,
+			&Backup{Sysid: testSysID(5), Metric: 7, NodeProtect: true, Downstream: true}},
+		{"not candidate", lfaTwoAlts, 4, 5, 0,
+			&Backup{Sysid: testSysID(3), Metric: 4}},
+		{"overloaded", lfaTwoAlts, 4, 0, 5,
+			&Backup{Sysid: testSysID(3), Metric: 4}},
+		{"ring 5", lfaRing5, 3, 0, 0,
+			&Backup{Sysid: testSysID(5), Metric: 4, NodeProtect: true}},
+		{"ring 6", lfaRing6, 3, 0, 0, nil},
+	}
+	for _, test := range tests {
+		db := newFRRDB(test.links, FRRPolicy{Candidate: true, LFA: true}, test.dst)
+		if test.noCand != 0 {
+			db.circuits[testIntf(test.noCand)].(*testCircuit).frr.Candidate = false
+		}
+		if test.over != 0 {
+			lspid := clns.MakeLSPID(testSysID(test.over), 0, 0)
+			db.get(lspid[:]).hdr[clns.HdrLSPFlags] |= byte(clns.LSPFOverload)
+		}
+		db.runSPF()
+		r := db.rib[testPrefix(test.dst)]
+		if r == nil || len(r.NextHops) != 1 || r.NextHops[0].Sysid != testSysID(2) {
+			t.Errorf("%s: bad route %+v", test.name, r)
+			continue
+		}
+		b, want := r.NextHops[0].Backup, test.want
+		if want == nil {
+			if b != nil {
+				t.Errorf("%s: unexpected backup %+v", test.name, b)
+			}
+			continue
+		}
+		n := want.Sysid[5]
+		if b == nil || b.Type != BackupLFA || b.Intf != testIntf(n) || !b.Addr.Equal(testAddr(n)) ||
+			b.Sysid != want.Sysid || b.Metric != want.Metric ||
+			b.NodeProtect != want.NodeProtect || b.Downstream != want.Downstream {
+			t.Errorf("%s: backup %+v want %+v", test.name, b, want)
+		}
+	}
+}
+
+func TestFRRCoverage(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []testLink
+		want  YangFRRCoverage
+	}{
+		// Only the prefix of 4 is protected.
+		{"square", lfaSquare, YangFRRCoverage{Prefixes: 3, Protected: 1, NodeProtected: 1, LFA: 1}},
+		// All are link protected, none node protected.
+		{"triangle", lfaTriangle, YangFRRCoverage{Prefixes: 3, Protected: 3, LFA: 3}},
+		// Only the prefix of 4 with ECMP next hops is protected.
+		{"ring 6", lfaRing6, YangFRRCoverage{Prefixes: 5, Protected: 1, NodeProtected: 1, LFA: 1}},
+	}
+	for _, test := range tests {
+		var dsts []byte
+		for n := byte(2); n <= 6; n++ {
+			if linkTLVs(n, test.links) != nil {
+				dsts = append(dsts, n)
+			}
+		}
+		db := newFRRDB(test.links, FRRPolicy{Candidate: true, LFA: true}, dsts...)
+		db.runSPF()
+		cov := db.frr
+		want := test.want
+		want.Level = db.li.ToLevel()
+		want.Coverage = float64(want.Protected) * 100 / float64(want.Prefixes)
+		if *cov != want {
+			t.Errorf("%s: coverage %+v want %+v", test.name, *cov, want)
+		}
+	}
+
+	// Without fast reroute nothing is protected.
+	db := newFRRDB(lfaSquare, FRRPolicy{Candidate: true}, 2, 3, 4)
+	db.runSPF()
+	if db.frr.Prefixes != 3 || db.frr.Protected != 0 || primaryBackup(t, db, 4) != nil {
+		t.Errorf("Protected without fast reroute %+v", db.frr)
+	}
+}
@@ -0,0 +1,109 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the own LSP generation throttling and the LSP log.
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	xtime "github.com/choppsv1/goisis/time"
+	"strings"
+	"time"
+)
+
+// Default LSP generation back-off timer values.
+const (
+	DefLSPGenInitialWait   = 50 * time.Millisecond
+	DefLSPGenSecondaryWait = 200 * time.Millisecond
+	DefLSPGenMaximumWait   = 5000 * time.Millisecond
+)
+
+// Reasons for own LSP generation recorded in the LSP log.
+const (
+	GenReasonCreate = "create"
+	GenReasonDIS    = "dis-change"
+	GenReasonAdj    = "adjacency-change"
+	GenReasonConfig = "config-change"
+	GenReasonLink   = "link-attribute-change"
+)
+
+// LSPLogSize is the number of entries kept in the LSP log ring.
+const LSPLogSize = 32
+
+// YangLSPLog is an LSP log entry for the yang model.
+type YangLSPLog struct {
+	Level     clns.Level         `json:"level"`
+	Lspid     clns.LSPID         `json:"lsp"`
+	Seqno     uint32             `json:"sequence"`
+	Timestamp time.Time          `json:"timestamp"`
+	Reason    string             `json:"reason"` // comma separated
+	State     xtime.BackoffState `json:"backoff-state"`
+	Wait      uint32             `json:"wait"` // milliseconds
+}
+
+// lspLog is a fixed size ring of LSP generation log entries.
+type lspLog struct {
+	ents  [LSPLogSize]YangLSPLog
+	next  int
+	count int
+}
+
+func (log *lspLog) add(ent YangLSPLog) {
+	log.ents[log.next] = ent
+	log.next = (log.next + 1) % LSPLogSize
+	if log.count < LSPLogSize {
+		log.count++
+	}
+}
+
+// entries returns a copy of the log entries newest first.
+func (log *lspLog) entries() []*YangLSPLog {
+	ents := make([]*YangLSPLog, 0, log.count)
+	for i := 1; i <= log.count; i++ {
+		ent := log.ents[(log.next-i+LSPLogSize)%LSPLogSize]
+		ents = append(ents, &ent)
+	}
+	return ents
+}
+
+// logGeneration records the generation of one of our own LSPs.
+func (db *DB) logGeneration(lsp *ownLSP) {
+	lspid := clns.MakeLSPID(db.sysid, lsp.Pnid, 0)
+	ent := YangLSPLog{
+		Level:     db.li.ToLevel(),
+		Lspid:     lspid,
+		Timestamp: time.Now(),
+		Reason:    strings.Join(lsp.reasons, ","),
+		State:     lsp.backoff.State(),
+		Wait:      uint32(lsp.backoff.Wait() / time.Millisecond),
+	}
+	if dblsp := db.get(lspid[:]); dblsp != nil {
+		ent.Seqno = dblsp.seqNo()
+	}
+	Debug(DbgFLSP, "%s: generated %s reason %s %s", db, lspid, ent.Reason, lsp.backoff)
+	db.lsplog.add(ent)
+	lsp.reasons = nil
+}
+
+// SetLSPGenTimers sets the LSP generation back-off timer values.
+func (db *DB) SetLSPGenTimers(initial, secondary, max time.Duration) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.lspgen.Set(initial, secondary, max)
+		for _, lsp := range db.ownlsp {
+			lsp.backoff.Set(initial, secondary, max)
+		}
+		return nil
+	})
+}
+
+// LSPLog arranges for the LSP log entries to be returned.
+func (db *DB) LSPLog() ([]*YangLSPLog, error) {
+	i, err := DoRPC(db.rpC, func() interface{} { return db.lsplog.entries() })
+	if err != nil {
+		return nil, err
+	}
+	return i.([]*YangLSPLog), nil
+}
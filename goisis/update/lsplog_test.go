@@ -0,0 +1,51 @@
+package update
+
+import (
+	xtime "github.com/choppsv1/goisis/time"
+	"testing"
+)
+
+// newGenDB returns a test DB able to create own LSPs.
+func newGenDB() *DB {
+	db := newTestDB(nil, nil)
+	db.lspgen.Set(DefLSPGenInitialWait, DefLSPGenSecondaryWait, DefLSPGenMaximumWait)
+	db.chgLSPC = make(chan chgLSP, 10)
+	db.ownlsp = make(map[uint8]*ownLSP)
+	return db
+}
+
+func TestOwnLSPCreate(t *testing.T) {
+	db := newGenDB()
+	tests := []struct {
+		pnid  uint8
+		state xtime.BackoffState
+	}{
+		{0, xtime.BackoffQuiet},
+		{1, xtime.BackoffInitial},
+	}
+	for _, test := range tests {
+		lsp := newOwnLSP(test.pnid, db, nil)
+		lsp.regenWait.Stop()
+		if got := lsp.backoff.State(); got != test.state {
+			t.Errorf("pnid %d: back-off %s want %s", test.pnid, got, test.state)
+		}
+	}
+}
+
+func TestGenReasons(t *testing.T) {
+	db := newGenDB()
+	lsp := newOwnLSP(0, db, nil)
+	defer lsp.regenWait.Stop()
+	db.ownlsp[0] = lsp
+	for _, reason := range []string{GenReasonAdj, GenReasonDIS, GenReasonAdj} {
+		db.handleChgLSPC(chgLSP{reason: reason})
+	}
+	db.logGeneration(lsp)
+	want := GenReasonCreate + "," + GenReasonAdj + "," + GenReasonDIS
+	if ents := db.lsplog.entries(); len(ents) != 1 || ents[0].Reason != want {
+		t.Errorf("Bad log %+v want reason %s", ents, want)
+	}
+	if len(lsp.reasons) != 0 {
+		t.Errorf("Reasons %v kept after generation", lsp.reasons)
+	}
+}
@@ -0,0 +1,261 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the Multi-Topology (RFC5120) support, the topologies we
+// advertise and the SPF and routes of the non-standard topologies.
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+)
+
+// GenReasonTopology is the LSP log reason for topology changes.
+const GenReasonTopology = "topology-change"
+
+// inTopology returns true if mtid is one of the topologies mtids. An empty
+// list is a non-MT circuit or neighbor which is only in the standard topology.
+func inTopology(mtids []uint16, mtid uint16) bool {
+	if len(mtids) == 0 {
+		return mtid == tlv.MTIDStandard
+	}
+	for _, id := range mtids {
+		if id == mtid {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTopologies configures the topologies we participate in, an empty list
+// disables multi-topology.
+func (db *DB) SetTopologies(mtids []uint16) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.mtids = mtids
+		db.scheduleSPF()
+		return nil
+	})
+	db.SomethingChanged(nil, GenReasonTopology)
+}
+
+// topologies returns the topologies we participate in, the standard topology
+// if not MT.
+func (db *DB) topologies() []uint16 {
+	if len(db.mtids) == 0 {
+		return []uint16{tlv.MTIDStandard}
+	}
+	return db.mtids
+}
+
+// ownMTs returns the topologies to add to our LSP Multi-Topology TLV. The
+// overload and attached bits of the standard topology are in the LSP header.
+func (db *DB) ownMTs() []tlv.MT {
+	var mts []tlv.MT
+	for _, mtid := range db.mtids {
+		mt := tlv.MT{ID: mtid}
+		if mtid != tlv.MTIDStandard {
+			mt.Overload = db.isOverloaded()
+			mt.Attached = db.setAttached()
+		}
+		mts = append(mts, mt)
+	}
+	return mts
+}
+
+// ipv6Topology returns the topology of our IPv6 reachability.
+func (db *DB) ipv6Topology() uint16 {
+	if inTopology(db.mtids, tlv.MTIDIPv6) {
+		return tlv.MTIDIPv6
+	}
+	return tlv.MTIDStandard
+}
+
+// mtCircuits returns our circuits in the topology.
+func (db *DB) mtCircuits(mtid uint16) []Circuit {
+	var circuits []Circuit
+	for _, c := range db.circuits {
+		if inTopology(c.Topologies(), mtid) {
+			circuits = append(circuits, c)
+		}
+	}
+	return circuits
+}
+
+// mtExcluded returns the point-to-point neighbors of the circuits in the
+// topology that are not themselves in the topology.
+func (db *DB) mtExcluded(mtid uint16) map[clns.NodeID]bool {
+	excluded := make(map[clns.NodeID]bool)
+	for name, nbrs := range db.nbrs {
+		c := db.circuits[name]
+		if c == nil || !c.IsP2P() {
+			continue
+		}
+		for sysid, nbr := range nbrs {
+			if !inTopology(nbr.MTIDs, mtid) {
+				var nodeid clns.NodeID
+				copy(nodeid[:], sysid[:])
+				excluded[nodeid] = true
+			}
+		}
+	}
+	return excluded
+}
+
+// filterAdjs forwards the adjacencies from circuits on 'in' to 'out' dropping
+// the excluded neighbors. It returns after forwarding 'count' Done values.
+func filterAdjs(excluded map[clns.NodeID]bool, in <-chan interface{}, out chan<- interface{}, count int) {
+	for count > 0 {
+		result := <-in
+		if ai, ok := result.(tlv.AdjInfo); ok {
+			if excluded[ai.Nodeid] {
+				continue
+			}
+		} else {
+			count--
+		}
+		out <- result
+	}
+}
+
+// mtNeighbors returns the IS reachability of the node in the topology.
+// Pseudo-nodes only advertise the standard topology.
+func (n *spfNode) mtNeighbors(mtid uint16) []tlv.ISExtReach {
+	var nbrs []tlv.ISExtReach
+	if mtid == tlv.MTIDStandard || n.isPN() {
+		for _, t := range n.tlvs(tlv.TypeExtIsReach) {
+			tnbrs, err := t.ISExtReachDecode()
+			if err != nil {
+				Debug(DbgFSPF, "%s: bad IS reach TLV: %s", n.nodeid, err)
+				continue
+			}
+			nbrs = append(nbrs, tnbrs...)
+		}
+		return nbrs
+	}
+	for _, t := range n.tlvs(tlv.TypeMTISReach) {
+		mtr, err := t.MTISReachDecode()
+		if err != nil {
+			Debug(DbgFSPF, "%s: bad MT IS reach TLV: %s", n.nodeid, err)
+			continue
+		}
+		if mtr.MTID == mtid {
+			nbrs = append(nbrs, mtr.Nbrs...)
+		}
+	}
+	return nbrs
+}
+
+// mtFlags returns the LSP flags of the node for the topology, for the
+// non-standard topologies the overload and attached bits are those of the
+// Multi-Topology TLV.
+func (n *spfNode) mtFlags(mtid uint16) clns.LSPFlags {
+	if mtid == tlv.MTIDStandard || n.isPN() {
+		return n.flags
+	}
+	flags := n.flags &^ (clns.LSPFOverload | clns.LSPFMetDef)
+	for _, t := range n.tlvs(tlv.TypeMT) {
+		mts, err := t.MTDecode()
+		if err != nil {
+			Debug(DbgFSPF, "%s: bad MT TLV: %s", n.nodeid, err)
+			continue
+		}
+		for _, mt := range mts {
+			if mt.ID != mtid {
+				continue
+			}
+			if mt.Overload {
+				flags |= clns.LSPFOverload
+			}
+			if mt.Attached {
+				flags |= clns.LSPFMetDef
+			}
+		}
+	}
+	return flags
+}
+
+// mtPrefixInfo calls F for each of the prefixes the node advertises in the
+// non-standard topology.
+func (n *spfNode) mtPrefixInfo(mtid uint16, F func(pfx *tlv.IPPrefixCommon)) {
+	for _, t := range n.tlvs(tlv.TypeMTIPv4Prefix) {
+		mtp, err := t.MTIPv4PrefixDecode()
+		if err != nil {
+			Debug(DbgFSPF, "%s: bad MT IPv4 prefix TLV: %s", n.nodeid, err)
+			continue
+		}
+		if mtp.MTID != mtid {
+			continue
+		}
+		for i := range mtp.Prefixes {
+			F(&mtp.Prefixes[i].IPPrefixCommon)
+		}
+	}
+	for _, t := range n.tlvs(tlv.TypeMTIPv6Prefix) {
+		mtp, err := t.MTIPv6PrefixDecode()
+		if err != nil {
+			Debug(DbgFSPF, "%s: bad MT IPv6 prefix TLV: %s", n.nodeid, err)
+			continue
+		}
+		if mtp.MTID != mtid {
+			continue
+		}
+		for i := range mtp.Prefixes {
+			F(&mtp.Prefixes[i].IPPrefixCommon)
+		}
+	}
+}
+
+// mtRoutes computes the routes of the non-standard topology from its shortest
+// path tree. A level 1 only router adds an IPv6 default route to the nearest
+// routers attached in the topology.
+func (db *DB) mtRoutes(root *spfNode, nodes map[clns.NodeID]*spfNode, mtid uint16) map[string]*Route {
+	rib := make(map[string]*Route)
+	for _, n := range nodes {
+		if n == root || n.isPN() || !n.reached() {
+			continue
+		}
+		n.mtPrefixInfo(mtid, func(pfx *tlv.IPPrefixCommon) {
+			if pfx.Metric > tlv.ExtIPMaxMetric {
+				return
+			}
+			db.addRoute(rib, (*net.IPNet)(&pfx.Prefix), n.dist+pfx.Metric, pfx.Updown, n)
+		})
+	}
+	if db.istype == clns.L1Flag && !db.att.ignore {
+		for _, n := range nearestAttached(root, nodes) {
+			db.addRoute(rib, &defaultIPv6, n.dist, true, n)
+		}
+	}
+	for _, r := range rib {
+		r.MTID = mtid
+	}
+	return rib
+}
+
+// mtSPF runs the SPF of each of the non-standard topologies we participate in
+// and returns their routes.
+func (db *DB) mtSPF(rootid clns.NodeID) map[uint16]map[string]*Route {
+	if len(db.mtids) == 0 {
+		return nil
+	}
+	ribs := make(map[uint16]map[string]*Route)
+	for _, mtid := range db.mtids {
+		if mtid == tlv.MTIDStandard {
+			continue
+		}
+		nodes := db.spfGraph(mtid)
+		root := nodes[rootid]
+		if root == nil {
+			continue
+		}
+		db.dijkstra(root, nodes, mtid)
+		ribs[mtid] = db.mtRoutes(root, nodes, mtid)
+		Debug(DbgFSPF, "%s: MT %d SPF complete %d nodes %d routes", db, mtid,
+			len(nodes), len(ribs[mtid]))
+	}
+	return ribs
+}
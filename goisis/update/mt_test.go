@@ -0,0 +1,181 @@
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"testing"
+)
+
+// mtTLV returns a Multi-Topology TLV with the topologies.
+func mtTLV(mts ...tlv.MT) []byte {
+	b := []byte{byte(tlv.TypeMT), byte(2 * len(mts))}
+	for _, mt := range mts {
+		val := mt.ID
+		if mt.Overload {
+			val |= tlv.MTFlagO
+		}
+		if mt.Attached {
+			val |= tlv.MTFlagA
+		}
+		b = append(b, byte(val>>8), byte(val))
+	}
+	return b
+}
+
+// mtISReach returns an MT IS Reachability TLV of topology mtid with the
+// neighbor.
+func mtISReach(mtid uint16, nbr byte, metric uint32) []byte {
+	b := isReach(nbr, metric)
+	b = append([]byte{byte(tlv.TypeMTISReach), b[1] + 2, byte(mtid >> 8), byte(mtid)}, b[2:]...)
+	return b
+}
+
+// mtIPv6Reach returns an MT IPv6 Reachability TLV of topology mtid with the
+// prefix.
+func mtIPv6Reach(mtid uint16, prefix string, metric uint32) []byte {
+	_, ipnet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		panic(err)
+	}
+	plen, _ := ipnet.Mask.Size()
+	b := []byte{byte(tlv.TypeMTIPv6Prefix), 0, byte(mtid >> 8), byte(mtid),
+		byte(metric >> 24), byte(metric >> 16), byte(metric >> 8), byte(metric), 0, byte(plen)}
+	b = append(b, ipnet.IP[:(plen+7)/8]...)
+	b[1] = byte(len(b) - 2)
+	return b
+}
+
+// newMTDB returns the DB of router 1 of a square of routers in which the link
+// between routers 1 and 2 is IPv4 only. The other links are in the IPv6
+// topology.
+func newMTDB(extra map[byte][][]byte) *DB {
+	links := []testLink{{1, 2, 1}, {2, 4, 1}, {1, 3, 5}, {3, 4, 5}}
+	tlvs := map[byte][][]byte{
+		1: {mtISReach(tlv.MTIDIPv6, 3, 5)},
+		2: {mtISReach(tlv.MTIDIPv6, 4, 1)},
+		3: {mtISReach(tlv.MTIDIPv6, 1, 5), mtISReach(tlv.MTIDIPv6, 4, 5)},
+		4: {mtISReach(tlv.MTIDIPv6, 2, 1), mtISReach(tlv.MTIDIPv6, 3, 5)},
+	}
+	for n := byte(1); n <= 4; n++ {
+		tlvs[n] = append(tlvs[n], ipReach(testPrefix(n), 1),
+			mtIPv6Reach(tlv.MTIDIPv6, testPrefix6(n), 1))
+		tlvs[n] = append(tlvs[n], extra[n]...)
+		if len(extra[n]) == 0 {
+			tlvs[n] = append(tlvs[n], mtTLV(tlv.MT{ID: tlv.MTIDStandard}, tlv.MT{ID: tlv.MTIDIPv6}))
+		}
+	}
+	db := newTestDB(links, tlvs)
+	db.mtids = []uint16{tlv.MTIDStandard, tlv.MTIDIPv6}
+	db.circuits[testIntf(2)].(*testCircuit).mtids = []uint16{tlv.MTIDStandard}
+	db.circuits[testIntf(3)].(*testCircuit).mtids = db.mtids
+	nbr := db.nbrs[testIntf(2)][testSysID(2)]
+	nbr.MTIDs = []uint16{tlv.MTIDStandard}
+	db.nbrs[testIntf(2)][testSysID(2)] = nbr
+	nbr = db.nbrs[testIntf(3)][testSysID(3)]
+	nbr.MTIDs = db.mtids
+	db.nbrs[testIntf(3)][testSysID(3)] = nbr
+	return db
+}
+
+// testPrefix returns the prefix advertised by router n.
+func testPrefix(n byte) string {
+	return "10.0.0." + string('0'+n) + "/32"
+}
+
+// testPrefix6 returns the IPv6 prefix advertised by router n.
+func testPrefix6(n byte) string {
+	return "2001:db8::" + string('0'+n) + "/128"
+}
+
+// checkRoute checks the metric and the only next hop of the route.
+func checkRoute(t *testing.T, name string, r *Route, metric uint32, via byte) {
+	t.Helper()
+	if via == 0 {
+		if r != nil {
+			t.Errorf("%s: unexpected route %+v", name, r)
+		}
+		return
+	}
+	if r == nil || r.Metric != metric || len(r.NextHops) != 1 || r.NextHops[0].Intf != testIntf(via) {
+		t.Errorf("%s: got %+v want metric %d via %d", name, r, metric, via)
+	}
+}
+
+func TestMTRoutes(t *testing.T) {
+	// Without the link between 1 and 2 the IPv6 topology reaches 2 and 4
+	// through 3. The overload and attached bits of the LSP header only apply to
+	// the standard topology, those of the MT TLV to the IPv6 topology.
+	mt := func(o, a bool) []byte {
+		return mtTLV(tlv.MT{ID: tlv.MTIDStandard}, tlv.MT{ID: tlv.MTIDIPv6, Overload: o, Attached: a})
+	}
+	tests := []struct {
+		name     string
+		flags    map[byte]clns.LSPFlags // LSP header flags
+		extra    map[byte][][]byte
+		v4, v6   byte // next hop to router 4
+		m4, m6   uint32
+		via2     byte // IPv6 next hop to router 2
+		default6 byte
+	}{
+		{"routes", nil, nil, 2, 3, 3, 11, 3, 0},
+		{"overload", map[byte]clns.LSPFlags{2: clns.LSPFOverload}, nil, 3, 3, 11, 11, 3, 0},
+		{"mt overload", nil, map[byte][][]byte{3: {mt(true, false)}}, 2, 0, 3, 0, 0, 0},
+		{"attached", map[byte]clns.LSPFlags{2: clns.LSPFMetDef}, map[byte][][]byte{4: {mt(false, true)}},
+			2, 3, 3, 11, 3, 3},
+	}
+	for _, test := range tests {
+		db := newMTDB(test.extra)
+		db.istype, db.li = clns.L1Flag, 0
+		for n, flags := range test.flags {
+			setFlags(db, n, flags)
+		}
+		db.runSPF()
+		checkRoute(t, test.name+" ipv4", db.rib[testPrefix(4)], test.m4, test.v4)
+		checkRoute(t, test.name+" ipv6", db.mtRIBs[tlv.MTIDIPv6][testPrefix6(4)], test.m6, test.v6)
+		checkRoute(t, test.name+" ipv6 3", db.mtRIBs[tlv.MTIDIPv6][testPrefix6(3)], 6, 3)
+		checkRoute(t, test.name+" ipv6 2", db.mtRIBs[tlv.MTIDIPv6][testPrefix6(2)], 12, test.via2)
+		checkRoute(t, test.name+" ipv6 default", db.mtRIBs[tlv.MTIDIPv6][defaultIPv6.String()], 10,
+			test.default6)
+		if r := db.mtRIBs[tlv.MTIDIPv6][testPrefix(4)]; r != nil {
+			t.Errorf("%s: IPv4 prefix in the IPv6 topology %+v", test.name, r)
+		}
+	}
+}
+
+func TestMTExcluded(t *testing.T) {
+	// Router 2 isn't in the IPv6 topology, it is left out of our MT IS
+	// reachability.
+	db := newMTDB(nil)
+	excluded := db.mtExcluded(tlv.MTIDIPv6)
+	if len(excluded) != 1 || !excluded[testNode(2)] {
+		t.Errorf("Bad excluded neighbors %v", excluded)
+	}
+	if excluded := db.mtExcluded(tlv.MTIDStandard); len(excluded) != 0 {
+		t.Errorf("Bad standard topology excluded neighbors %v", excluded)
+	}
+	if circuits := db.mtCircuits(tlv.MTIDIPv6); len(circuits) != 1 || circuits[0].Name() != testIntf(3) {
+		t.Errorf("Bad IPv6 topology circuits %v", circuits)
+	}
+
+	in := make(chan interface{}, 10)
+	out := make(chan interface{}, 10)
+	in <- tlv.AdjInfo{Nodeid: testNode(2)}
+	in <- tlv.AdjInfo{Nodeid: testNode(3)}
+	in <- tlv.Done{}
+	in <- tlv.Done{}
+	filterAdjs(excluded, in, out, 2)
+	close(out)
+	var nodes []clns.NodeID
+	dones := 0
+	for v := range out {
+		if ai, ok := v.(tlv.AdjInfo); ok {
+			nodes = append(nodes, ai.Nodeid)
+		} else {
+			dones++
+		}
+	}
+	if len(nodes) != 1 || nodes[0] != testNode(3) || dones != 2 {
+		t.Errorf("Bad filtered adjacencies %v %d", nodes, dones)
+	}
+}
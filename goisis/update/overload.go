@@ -0,0 +1,224 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the overload bit control.
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"time"
+)
+
+// OverloadReason is the reason we are setting the overload bit.
+type OverloadReason uint8
+
+// OverloadReason values in order of reporting preference.
+const (
+	OverloadNone OverloadReason = iota
+	OverloadAdmin
+	OverloadStartup
+	OverloadWaitAdj
+)
+
+var overloadReasonStrings = map[OverloadReason]string{
+	OverloadNone:    "none",
+	OverloadAdmin:   "admin",
+	OverloadStartup: "on-startup",
+	OverloadWaitAdj: "wait-for-adjacencies",
+}
+
+func (r OverloadReason) String() string {
+	return overloadReasonStrings[r]
+}
+
+// MarshalText converts the overload reason to text (yang value).
+func (r OverloadReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// GenReasonOverload is the LSP log reason for overload changes.
+const GenReasonOverload = "overload-change"
+
+// DefWaitAdjTimeout is the default maximum time the overload bit is set while
+// waiting for the adjacencies.
+const DefWaitAdjTimeout = 10 * time.Minute
+
+// overload tracks the active reasons for setting the overload bit.
+type overload struct {
+	admin   bool
+	startup *time.Timer
+	waitAdj *time.Timer // bounds the wait for adjacencies
+}
+
+// reason returns the most preferred active overload reason or OverloadNone.
+func (o *overload) reason() OverloadReason {
+	switch {
+	case o.admin:
+		return OverloadAdmin
+	case o.startup != nil:
+		return OverloadStartup
+	case o.waitAdj != nil:
+		return OverloadWaitAdj
+	}
+	return OverloadNone
+}
+
+// YangOverload is the overload state for the yang model.
+type YangOverload struct {
+	Level  clns.Level     `json:"level"`
+	Status bool           `json:"status"`
+	Reason OverloadReason `json:"reason"`
+}
+
+type chgAdj struct {
+	c   Circuit
+	up  bool
+	nbr Neighbor
+}
+
+// isOverloaded returns true if the overload bit should be set.
+func (db *DB) isOverloaded() bool {
+	return db.overload.reason() != OverloadNone
+}
+
+// overloadChanged is called in the update go routine when the overload state
+// may have changed, it returns true if the overload bit changed.
+func (db *DB) overloadChanged(was OverloadReason) bool {
+	now := db.overload.reason()
+	if now == was {
+		return false
+	}
+	Debug(DbgFUpd, "%s: Overload changed from %s to %s", db, was, now)
+	return (was == OverloadNone) != (now == OverloadNone)
+}
+
+// changeOverload calls F in the update go routine to change the overload state
+// and regenerates our LSP if the overload bit changed.
+func (db *DB) changeOverload(F func()) {
+	changed, _ := DoRPC(db.rpC, func() interface{} {
+		was := db.overload.reason()
+		F()
+		return db.overloadChanged(was)
+	})
+	if changed.(bool) {
+		db.SomethingChanged(nil, GenReasonOverload)
+	}
+}
+
+// SetOverload administratively sets or clears the overload bit.
+func (db *DB) SetOverload(set bool) {
+	db.changeOverload(func() { db.overload.admin = set })
+}
+
+// SetOverloadOnStartup sets the overload bit for startup. If 'timeout' is
+// non-zero the bit is set until the timeout expires. If 'waitAdj' is non-zero
+// the bit is set until all circuits have Up adjacencies and the LSDB is
+// synchronized, or at most for 'waitAdj'.
+func (db *DB) SetOverloadOnStartup(timeout, waitAdj time.Duration) {
+	db.changeOverload(func() {
+		if timeout != 0 {
+			db.overload.startup = time.AfterFunc(timeout, func() {
+				db.changeOverload(func() { db.overload.startup = nil })
+			})
+		}
+		if waitAdj != 0 {
+			var t *time.Timer
+			t = time.AfterFunc(waitAdj, func() {
+				db.changeOverload(func() {
+					if db.overload.waitAdj == t {
+						Debug(DbgFUpd, "%s: Adjacencies not synchronized after %s", db, waitAdj)
+						db.overload.waitAdj = nil
+					}
+				})
+			})
+			db.overload.waitAdj = t
+		}
+		db.checkWaitAdj()
+	})
+}
+
+// Overload arranges for the overload state to be returned.
+func (db *DB) Overload() (*YangOverload, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		return &YangOverload{
+			Level:  db.li.ToLevel(),
+			Status: db.isOverloaded(),
+			Reason: db.overload.reason(),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.(*YangOverload), nil
+}
+
+// AdjChange informs the update process of an adjacency to nbr going up or down.
+func (db *DB) AdjChange(c Circuit, up bool, nbr Neighbor) {
+	db.chgAdjC <- chgAdj{c, up, nbr}
+}
+
+// isSynchronized returns true if all circuits have Up adjacencies and we have
+// either received a CSNP or are DIS on each of them, passive circuits don't
+// count. Without any circuit there is nothing to synchronize with yet.
+func (db *DB) isSynchronized() bool {
+	active := false
+	for name, c := range db.circuits {
+		if c.IsPassive() {
+			continue
+		}
+		active = true
+		if db.adjUp[name] == 0 {
+			return false
+		}
+		if di, ok := db.dis[c.CID(db.li)]; ok && di.us {
+			continue
+		}
+		if !db.csnpSeen[name] {
+			return false
+		}
+	}
+	return active
+}
+
+// checkWaitAdj clears the wait-for-adjacencies overload once synchronized.
+func (db *DB) checkWaitAdj() {
+	if db.overload.waitAdj == nil || !db.isSynchronized() {
+		return
+	}
+	Debug(DbgFUpd, "%s: Adjacencies Up and LSDB synchronized", db)
+	was := db.overload.reason()
+	db.overload.waitAdj.Stop()
+	db.overload.waitAdj = nil
+	if db.overloadChanged(was) {
+		db.handleChgLSPC(chgLSP{reason: GenReasonOverload})
+	}
+}
+
+// handleChgAdjC handles adjacency up and down events.
+func (db *DB) handleChgAdjC(in chgAdj) {
+	name := in.c.Name()
+	if in.up {
+		db.adjUp[name]++
+		if db.nbrs[name] == nil {
+			db.nbrs[name] = make(map[clns.SystemID]Neighbor)
+		}
+		db.nbrs[name][in.nbr.Sysid] = in.nbr
+	} else if db.adjUp[name] > 0 {
+		db.adjUp[name]--
+		delete(db.nbrs[name], in.nbr.Sysid)
+		db.localLinkDown(name, in.nbr.Sysid)
+		if db.adjUp[name] == 0 {
+			delete(db.csnpSeen, name)
+		}
+	}
+	db.scheduleSPF()
+
+	// Regenerate our LSP and our pseudo-node LSP if we are DIS.
+	db.handleChgLSPC(chgLSP{reason: GenReasonAdj})
+	db.handleChgLSPC(chgLSP{pnid: in.c.CID(db.li), reason: GenReasonAdj})
+
+	db.checkWaitAdj()
+}
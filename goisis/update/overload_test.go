@@ -0,0 +1,90 @@
+package update
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOverloadReason(t *testing.T) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	tests := []struct {
+		o    overload
+		want OverloadReason
+	}{
+		{overload{}, OverloadNone},
+		{overload{waitAdj: timer}, OverloadWaitAdj},
+		{overload{startup: timer, waitAdj: timer}, OverloadStartup},
+		{overload{admin: true, startup: timer, waitAdj: timer}, OverloadAdmin},
+		{overload{admin: true}, OverloadAdmin},
+	}
+	for _, test := range tests {
+		if got := test.o.reason(); got != test.want {
+			t.Errorf("%+v: reason %s want %s", test.o, got, test.want)
+		}
+	}
+}
+
+func TestIsSynchronized(t *testing.T) {
+	tests := []struct {
+		name     string
+		adjUp    map[string]int
+		csnpSeen map[string]bool
+		dis      bool // DIS on the circuits
+		passive  bool // the circuit to router 3 is passive
+		want     bool
+	}{
+		{"down", nil, nil, false, false, false},
+		{"one up", map[string]int{"eth2": 1}, map[string]bool{"eth2": true}, false, false, false},
+		{"no csnp", map[string]int{"eth2": 1, "eth3": 2}, map[string]bool{"eth2": true}, false, false, false},
+		{"synchronized", map[string]int{"eth2": 1, "eth3": 2}, map[string]bool{"eth2": true, "eth3": true},
+			false, false, true},
+		{"dis", map[string]int{"eth2": 1, "eth3": 2}, nil, true, false, true},
+		{"passive", map[string]int{"eth2": 1}, map[string]bool{"eth2": true}, false, true, true},
+	}
+	for _, test := range tests {
+		db := newTestDB([]testLink{{1, 2, 10}, {1, 3, 10}}, nil)
+		db.adjUp, db.csnpSeen = test.adjUp, test.csnpSeen
+		if test.dis {
+			db.dis[0] = disInfo{us: true}
+		}
+		db.circuits[testIntf(3)].(*testCircuit).passive = test.passive
+		if got := db.isSynchronized(); got != test.want {
+			t.Errorf("%s: synchronized %v want %v", test.name, got, test.want)
+		}
+	}
+
+	// Without circuits the LSDB isn't synchronized.
+	if db := newTestDB(nil, nil); db.isSynchronized() {
+		t.Errorf("Synchronized without circuits")
+	}
+}
+
+func TestWaitAdj(t *testing.T) {
+	db := newTestDB([]testLink{{1, 2, 10}}, nil)
+	db.adjUp, db.csnpSeen = make(map[string]int), make(map[string]bool)
+	db.overload.waitAdj = time.NewTimer(time.Hour)
+	db.checkWaitAdj()
+	if db.overload.reason() != OverloadWaitAdj {
+		t.Errorf("Overload %s before synchronization", db.overload.reason())
+	}
+	db.adjUp[testIntf(2)], db.csnpSeen[testIntf(2)] = 1, true
+	db.checkWaitAdj()
+	if db.overload.reason() != OverloadNone {
+		t.Errorf("Overload %s after synchronization", db.overload.reason())
+	}
+
+	// The wait is bounded.
+	db = newTestDB([]testLink{{1, 2, 10}}, nil)
+	db.rpC, db.chgLSPC = make(chan RPC), make(chan chgLSP, 10)
+	go func() {
+		for rpc := range db.rpC {
+			rpc.Result <- rpc.F()
+		}
+	}()
+	db.SetOverloadOnStartup(0, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if r, _ := DoRPC(db.rpC, func() interface{} { return db.overload.reason() }); r != OverloadNone {
+		t.Errorf("Overload %s after the maximum wait", r)
+	}
+}
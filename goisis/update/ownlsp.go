@@ -10,6 +10,7 @@ import (
 	"github.com/choppsv1/goisis/clns"
 	. "github.com/choppsv1/goisis/logging" // nolint
 	"github.com/choppsv1/goisis/pkt"
+	xtime "github.com/choppsv1/goisis/time"
 	"github.com/choppsv1/goisis/tlv"
 	"time"
 )
@@ -21,6 +22,8 @@ type ownLSP struct {
 	c         Circuit
 	segments  map[uint8][]byte
 	regenWait *time.Timer
+	backoff   xtime.Backoff
+	reasons   []string // since the last generation
 }
 
 // NewOwnLSP creates a new OwnLSP for the router.
@@ -31,6 +34,8 @@ func newOwnLSP(pnid byte, db *DB, c Circuit) *ownLSP {
 		db:       db,
 		c:        c,
 		segments: make(map[uint8][]byte),
+		backoff:  db.lspgen,
+		reasons:  []string{GenReasonCreate},
 	}
 
 	// Just use this code when we need nodeid
@@ -38,10 +43,11 @@ func newOwnLSP(pnid byte, db *DB, c Circuit) *ownLSP {
 	// nodeid[clns.SysIDLen] = pnid
 
 	// We delay generating non-pnode a short time to gather startup changes.
-	delay := LSPGenDelay
-	if pnid == 0 {
-		// We only long delay for our own (non-pnode) ownLSP.
-		delay = LSPCreateGenDelay
+	// We only long delay for our own (non-pnode) ownLSP, without moving the
+	// back-off out of quiet.
+	delay := LSPCreateGenDelay
+	if pnid != 0 {
+		delay = lsp.backoff.Next()
 	}
 	lsp.regenWait = time.AfterFunc(delay,
 		func() { db.chgLSPC <- chgLSP{timer: true, pnid: pnid} })
@@ -51,6 +57,16 @@ func newOwnLSP(pnid byte, db *DB, c Circuit) *ownLSP {
 	return lsp
 }
 
+// addReason records a reason for the next generation of the LSP.
+func (lsp *ownLSP) addReason(reason string) {
+	for _, r := range lsp.reasons {
+		if r == reason {
+			return
+		}
+	}
+	lsp.reasons = append(lsp.reasons, reason)
+}
+
 // finishSegment update the LSP segment header prior to pushing out.
 // send previous buffer to the update process.
 func (lsp *ownLSP) finishSegment(payload []byte, i uint8) error {
@@ -72,12 +88,14 @@ func (lsp *ownLSP) finishSegment(payload []byte, i uint8) error {
 	pkt.PutUInt16(hdr[clns.HdrLSPPDULen:], uint16(len(payload)))
 	copy(hdr[clns.HdrLSPLSPID:], lspid[:])
 
-	if lsp.Pnid == 0 {
-		// For now we mark ourselves Overload since we don't implement // Decision/SPF
-		hdr[clns.HdrLSPFlags] = clns.MakeLSPFlags(clns.LSPFOverload, lsp.db.istype)
-	} else {
-		hdr[clns.HdrLSPFlags] = clns.MakeLSPFlags(0, lsp.db.istype)
+	var flags clns.LSPFlags
+	if lsp.Pnid == 0 && lsp.db.isOverloaded() {
+		flags |= clns.LSPFOverload
+	}
+	if lsp.Pnid == 0 && lsp.db.setAttached() {
+		flags |= clns.LSPFMetDef
 	}
+	hdr[clns.HdrLSPFlags] = clns.MakeLSPFlags(flags, lsp.db.istype)
 
 	lsp.db.incSeqNo(payload, seqno)
 
@@ -95,7 +113,7 @@ func (db *DB) purgeOwn(pnid, segid uint8) {
 	db.initiatePurgeLSP(lsp, false)
 }
 
-func (db *DB) addExtISReach(bt *tlv.BufferTrack, c Circuit) error {
+func (db *DB) addExtISReach(bt *tlv.BufferTrack, c Circuit, mtid uint16) error {
 	C := make(chan interface{}, 10)
 	defer func() {
 		// XXX do we need to drain this too?
@@ -109,32 +127,63 @@ func (db *DB) addExtISReach(bt *tlv.BufferTrack, c Circuit) error {
 		c.Adjacencies(C, db.li, true)
 		count++
 	} else {
-		// Request adjacencies from all circuits
-		for _, c := range db.circuits {
-			c.Adjacencies(C, db.li, false)
+		// Request adjacencies from all circuits in the topology
+		circuits := db.mtCircuits(mtid)
+		IC := make(chan interface{}, 10)
+		go filterAdjs(db.mtExcluded(mtid), IC, C, len(circuits))
+		for _, c := range circuits {
+			c.Adjacencies(IC, db.li, false)
 			count++
 		}
 	}
+	if mtid != tlv.MTIDStandard {
+		return bt.AddMTISReach(mtid, C, count)
+	}
 	return bt.AddExtISReach(C, count)
 }
 
-func (db *DB) addExtIPReach(ipv4 bool, bt *tlv.BufferTrack) error {
+func (db *DB) addExtIPReach(ipv4 bool, bt *tlv.BufferTrack, mtid uint16) error {
 	C := make(chan interface{}, 10)
 	defer func() {
 		// XXX do we need to drain this too?
 		close(C)
 	}()
 
-	// Request adjacencies from all circuits or a given circuit.
+	// Request reachability from all circuits in the topology, through the
+	// interface policy.
+	circuits := db.mtCircuits(mtid)
+	IC := make(chan interface{}, 10)
+	go db.filterIPReach(db.policies[PolicyInterface], IC, C, len(circuits))
 	count := 0
-	// Request adjacencies from all circuits
-	for _, c := range db.circuits {
-		c.IPReach(ipv4, C, db.li)
+	for _, c := range circuits {
+		c.IPReach(ipv4, IC, db.li)
 		count++
 	}
+
+	// Add reachability from other sources.
+	ext := db.extIPReach(ipv4)
+	go func() {
+		for _, ipi := range ext {
+			db.stampIPInfo(&ipi)
+			C <- ipi
+		}
+		C <- tlv.Done{}
+	}()
+	count++
+
+	if mtid != tlv.MTIDStandard {
+		return bt.AddMTIPReach(mtid, ipv4, C, count)
+	}
 	return bt.AddExtIPReach(ipv4, C, count)
 }
 
+// extIPReach returns the IP reachability we advertise that doesn't come from
+// our circuits.
+func (db *DB) extIPReach(ipv4 bool) []tlv.IPInfo {
+	reach := append(db.propagatedReach(ipv4), db.leakedReach(ipv4)...)
+	return append(reach, db.redistReach(ipv4)...)
+}
+
 // regenerate non-pnode ownLSP
 // nolint: gocyclo
 func (lsp *ownLSP) regenNonPNodeLSP() error {
@@ -159,6 +208,10 @@ func (lsp *ownLSP) regenNonPNodeLSP() error {
 		return err
 	}
 
+	if err := bt.AddMT(lsp.db.ownMTs()); err != nil {
+		return err
+	}
+
 	// Add Hostname, ignore error.
 	if err := bt.AddHostname(lsp.db.hostname); err != nil {
 		Info("ERROR: adding Hostname TLV: %s", err)
@@ -172,21 +225,37 @@ func (lsp *ownLSP) regenNonPNodeLSP() error {
 		return err
 	}
 
-	// IS Reach (don't use)
+	if err := bt.AddRouterID(lsp.db.routerID); err != nil {
+		return err
+	}
+
+	if err := bt.AddIPv6RouterID(lsp.db.routerIDv6); err != nil {
+		return err
+	}
 
-	if err := lsp.db.addExtISReach(bt, nil); err != nil {
+	if err := lsp.db.addRouterCaps(bt); err != nil {
 		return err
 	}
 
-	if err := lsp.db.addExtIPReach(true, bt); err != nil {
+	// IS Reach (don't use)
+
+	for _, mtid := range lsp.db.topologies() {
+		if err := lsp.db.addExtISReach(bt, nil, mtid); err != nil {
+			return err
+		}
+	}
+
+	if err := lsp.db.addExtIPReach(true, bt, tlv.MTIDStandard); err != nil {
 		return err
 	}
 
-	if err := lsp.db.addExtIPReach(false, bt); err != nil {
+	if err := lsp.db.addExtIPReach(false, bt, lsp.db.ipv6Topology()); err != nil {
 		return err
 	}
 
-	// External Gen App
+	if err := bt.AddSRv6Locators(lsp.db.ipv6Topology(), lsp.db.srv6Locs); err != nil {
+		return err
+	}
 
 	if err := bt.Close(); err != nil {
 		return err
@@ -223,7 +292,7 @@ func (lsp *ownLSP) regenPNodeLSP() error {
 		})
 
 	// Ext Reach
-	if err := lsp.db.addExtISReach(bt, lsp.c); err != nil {
+	if err := lsp.db.addExtISReach(bt, lsp.c, tlv.MTIDStandard); err != nil {
 		return err
 	}
 
@@ -0,0 +1,73 @@
+package update
+
+import (
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"testing"
+)
+
+// ownIPv4Reach returns the IPv4 prefixes of our LSP reachability.
+func ownIPv4Reach(t *testing.T, db *DB) []string {
+	t.Helper()
+	var b []byte
+	bt := tlv.NewBufferTrack(1492, 0, 1, func(d tlv.Data, i uint8) error {
+		b = append(b, d...)
+		return nil
+	})
+	if err := db.addExtIPReach(true, bt, tlv.MTIDStandard); err != nil {
+		t.Fatalf("Adding IP reachability: %s", err)
+	}
+	if err := bt.Close(); err != nil {
+		t.Fatalf("Closing buffer: %s", err)
+	}
+	m, err := tlv.Data(b).ParseTLV()
+	if err != nil {
+		t.Fatalf("Parsing TLVs: %s", err)
+	}
+	var prefixes []string
+	for _, d := range m[tlv.TypeExtIPv4Prefix] {
+		pfxs, err := d.IPv4PrefixDecode()
+		if err != nil {
+			t.Fatalf("Decoding prefixes: %s", err)
+		}
+		for _, p := range pfxs {
+			prefixes = append(prefixes, (*net.IPNet)(&p.Prefix).String())
+		}
+	}
+	return prefixes
+}
+
+func TestPassive(t *testing.T) {
+	// Router 1 has an adjacency to router 2 on eth2 and a passive loopback
+	// with a small MTU.
+	db := newTestDB([]testLink{{1, 2, 10}}, nil)
+	db.adjUp = map[string]int{testIntf(2): 1}
+	db.csnpSeen = map[string]bool{testIntf(2): true}
+	lo := &testCircuit{
+		name:    "lo0",
+		passive: true,
+		addrs:   []net.IPNet{{IP: net.IPv4(10, 0, 0, 1).To4(), Mask: net.CIDRMask(32, 32)}},
+		mtu:     576,
+	}
+	db.handleChgCircuit(chgCircuit{c: lo, name: lo.name})
+
+	if prefixes := ownIPv4Reach(t, db); len(prefixes) != 1 || prefixes[0] != testPrefix(1) {
+		t.Errorf("Bad advertised prefixes %v", prefixes)
+	}
+	if !db.isSynchronized() {
+		t.Errorf("Not synchronized with a passive circuit")
+	}
+	if db.cache.mtu != 1500 {
+		t.Errorf("MTU %d with a passive circuit", db.cache.mtu)
+	}
+
+	// The same circuit when not passive.
+	lo.passive = false
+	db.handleChgCircuit(chgCircuit{c: lo, name: lo.name})
+	if db.isSynchronized() {
+		t.Errorf("Synchronized without an adjacency")
+	}
+	if db.cache.mtu != 576 {
+		t.Errorf("MTU %d without a passive circuit", db.cache.mtu)
+	}
+}
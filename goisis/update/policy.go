@@ -0,0 +1,83 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the application of routing policy to the prefixes added
+// to our LSPs.
+package update
+
+import (
+	"github.com/choppsv1/goisis/policy"
+	"github.com/choppsv1/goisis/tlv"
+)
+
+// Policy points, these are where prefixes enter our LSPs. Redistributed
+// prefixes carry their own policy.
+const (
+	PolicyInterface = "interface" // circuit prefixes
+	PolicyPropagate = "propagate" // level 1 reachability into level 2
+	PolicyLeak      = "leak"      // level 2 reachability into level 1
+)
+
+// applyPolicy evaluates the policy for the prefix updating its metric and tags.
+// It returns false if the prefix is rejected.
+func (db *DB) applyPolicy(p *policy.Policy, ipi *tlv.IPInfo) bool {
+	if p == nil {
+		return true
+	}
+	tags := append([]uint32(nil), ipi.Tags...)
+	if subtags, err := tlv.PrefixTags(ipi.Subtlv); err == nil {
+		tags = append(tags, subtags...)
+	}
+	r := &policy.Route{
+		Prefix: &ipi.Ipnet,
+		Metric: ipi.Metric,
+		Tags:   tags,
+		Level:  db.li.ToLevel(),
+	}
+	if !p.Apply(r) {
+		return false
+	}
+	ipi.Metric = r.Metric
+	if ipi.Metric > tlv.ExtIPMaxMetric {
+		ipi.Metric = tlv.ExtIPMaxMetric
+	}
+	if added := r.Tags[len(tags):]; len(added) > 0 {
+		ipi.Tags = append(append([]uint32(nil), ipi.Tags...), added...)
+	}
+	return true
+}
+
+// filterIPReach forwards the prefixes from circuits on 'in' to 'out' applying
+// the policy and adding our source router IDs. It returns after forwarding
+// 'count' Done values.
+func (db *DB) filterIPReach(p *policy.Policy, in <-chan interface{}, out chan<- interface{}, count int) {
+	for count > 0 {
+		result := <-in
+		if ipi, ok := result.(tlv.IPInfo); ok {
+			if !db.applyPolicy(p, &ipi) {
+				continue
+			}
+			db.stampIPInfo(&ipi)
+			result = ipi
+		} else {
+			count--
+		}
+		out <- result
+	}
+}
+
+// SetPolicy configures the policy applied at the policy point, nil removes
+// the policy.
+func (db *DB) SetPolicy(point string, p *policy.Policy) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		if p == nil {
+			delete(db.policies, point)
+		} else {
+			db.policies[point] = p
+		}
+		return nil
+	})
+	db.SomethingChanged(nil, GenReasonConfig)
+}
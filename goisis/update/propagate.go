@@ -0,0 +1,267 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the propagation of reachability between the levels of an
+// L1/L2 router (RFC5302, RFC5305) including summarization from level 1 into level
+// 2.
+package update
+
+import (
+	"bytes"
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"sort"
+)
+
+// GenReasonReach is the LSP log reason for changes to reachability from the
+// other level.
+const GenReasonReach = "reachability-change"
+
+// Summary is a summary address range advertised in place of the more
+// specific level 1 prefixes it covers. If Metric is zero the lowest metric of
+// the covered prefixes is used.
+type Summary struct {
+	Prefix net.IPNet
+	Metric uint32
+}
+
+// YangSummary is the summary address state for the yang model.
+type YangSummary struct {
+	Prefix tlv.IPPrefix `json:"prefix"`
+	Metric uint32       `json:"metric,omitempty"`
+	Active bool         `json:"active"`
+	Count  int          `json:"suppressed-count"`
+	Used   uint32       `json:"advertised-metric,omitempty"`
+}
+
+// levelPrefixes is sent between the level 1 and level 2 update processes with
+// the current reachability of the sending level.
+type levelPrefixes []tlv.IPInfo
+
+func isIPv4(ipnet *net.IPNet) bool {
+	return ipnet.IP.To4() != nil
+}
+
+// samePrefix returns true if the two prefixes are the same network.
+func samePrefix(a, b *net.IPNet) bool {
+	if isIPv4(a) != isIPv4(b) {
+		return false
+	}
+	alen, _ := a.Mask.Size()
+	blen, _ := b.Mask.Size()
+	return alen == blen && a.IP.Mask(a.Mask).Equal(b.IP.Mask(b.Mask))
+}
+
+// covers returns true if the prefix 'outer' contains the prefix 'inner'.
+func covers(outer, inner *net.IPNet) bool {
+	if isIPv4(outer) != isIPv4(inner) {
+		return false
+	}
+	olen, _ := outer.Mask.Size()
+	ilen, _ := inner.Mask.Size()
+	return olen <= ilen && outer.Contains(inner.IP)
+}
+
+func sortIPInfo(ipis []tlv.IPInfo) {
+	sort.Slice(ipis, func(i, j int) bool {
+		a, b := ipis[i].Ipnet, ipis[j].Ipnet
+		if c := bytes.Compare(a.IP, b.IP); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(a.Mask, b.Mask) < 0
+	})
+}
+
+func equalIPInfo(a, b []tlv.IPInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Metric != b[i].Metric || !samePrefix(&a[i].Ipnet, &b[i].Ipnet) ||
+			!bytes.Equal(a[i].Subtlv, b[i].Subtlv) {
+			return false
+		}
+	}
+	return true
+}
+
+// levelReach returns the reachability computed by SPF that is eligible for
+// propagation to the other level. Prefixes with the up/down bit set are never
+// propagated back up (RFC5302). Level 1 includes our own prefixes, level 2
+// does not as our level 2 LSP contains what was propagated from level 1.
+func (db *DB) levelReach(root *spfNode, nodes map[clns.NodeID]*spfNode) []tlv.IPInfo {
+	best := make(map[string]tlv.IPInfo)
+	for _, n := range nodes {
+		if n.isPN() || !n.reached() || (db.li == 1 && n == root) {
+			continue
+		}
+		n.nodePrefixInfo(func(pfx *tlv.IPPrefixCommon) {
+			if pfx.Updown || pfx.Metric > tlv.ExtIPMaxMetric {
+				return
+			}
+			metric := pfx.Metric + n.dist
+			if metric > tlv.ExtIPMaxMetric {
+				metric = tlv.ExtIPMaxMetric
+			}
+			prefix := (*net.IPNet)(&pfx.Prefix)
+			key := prefix.String()
+			if ipi, ok := best[key]; ok && ipi.Metric <= metric {
+				return
+			}
+			best[key] = tlv.IPInfo{
+				Metric:   metric,
+				Ipnet:    *prefix,
+				Subtlv:   pfx.Subtlv,
+				External: pfx.XFlag,
+				SIDs:     readvertisedSIDs(pfx.SIDs),
+			}
+		})
+	}
+	reach := make([]tlv.IPInfo, 0, len(best))
+	for _, ipi := range best {
+		reach = append(reach, ipi)
+	}
+	sortIPInfo(reach)
+	return reach
+}
+
+// propagateReach sends our reachability to the other level's update process
+// if it changed.
+func (db *DB) propagateReach(root *spfNode, nodes map[clns.NodeID]*spfNode) {
+	if db.other == nil {
+		return
+	}
+	reach := db.levelReach(root, nodes)
+	if equalIPInfo(reach, db.sentReach) {
+		return
+	}
+	db.sentReach = reach
+	db.sendOther(levelPrefixes(reach))
+}
+
+// handleLevelPrefixes records the other level's reachability and regenerates
+// our LSP if we advertise any of it.
+func (db *DB) handleLevelPrefixes(in levelPrefixes) {
+	Debug(DbgFUpd, "%s: %d prefixes received from other level", db, len(in))
+	db.otherReach = []tlv.IPInfo(in)
+	if db.li == 1 || db.leak != nil {
+		db.handleChgLSPC(chgLSP{reason: GenReasonReach})
+	}
+}
+
+// isLocalPrefix returns true if the prefix is one of our circuit prefixes
+// which are already advertised.
+func (db *DB) isLocalPrefix(prefix *net.IPNet) bool {
+	for _, a := range db.addrs(isIPv4(prefix)) {
+		if samePrefix(&a, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// summaryFor returns the index of the most specific summary covering the
+// prefix or -1.
+func (db *DB) summaryFor(prefix *net.IPNet) int {
+	best, bestlen := -1, -1
+	for i := range db.summaries {
+		s := &db.summaries[i].Prefix
+		if !covers(s, prefix) {
+			continue
+		}
+		if slen, _ := s.Mask.Size(); slen > bestlen {
+			best, bestlen = i, slen
+		}
+	}
+	return best
+}
+
+// summarize returns the level 1 reachability to advertise in our level 2 LSP
+// with more specifics replaced by the summaries covering them. It also returns
+// the count of suppressed prefixes and lowest metric for each summary.
+func (db *DB) summarize() ([]tlv.IPInfo, []int, []uint32) {
+	counts := make([]int, len(db.summaries))
+	metrics := make([]uint32, len(db.summaries))
+	var out []tlv.IPInfo
+	for _, ipi := range db.otherReach {
+		ipi := ipi
+		if db.isLocalPrefix(&ipi.Ipnet) || !db.applyPolicy(db.policies[PolicyPropagate], &ipi) {
+			continue
+		}
+		i := db.summaryFor(&ipi.Ipnet)
+		if i < 0 {
+			ipi.Readvertised = true
+			out = append(out, ipi)
+			continue
+		}
+		if counts[i] == 0 || ipi.Metric < metrics[i] {
+			metrics[i] = ipi.Metric
+		}
+		counts[i]++
+	}
+	for i, s := range db.summaries {
+		if counts[i] == 0 {
+			continue
+		}
+		if s.Metric != 0 {
+			metrics[i] = s.Metric
+		}
+		out = append(out, tlv.IPInfo{Metric: metrics[i], Ipnet: s.Prefix})
+	}
+	return out, counts, metrics
+}
+
+// propagatedReach returns the level 1 reachability to add to our level 2 LSP
+// for the given address family.
+func (db *DB) propagatedReach(ipv4 bool) []tlv.IPInfo {
+	if db.li != 1 {
+		return nil
+	}
+	all, _, _ := db.summarize()
+	var out []tlv.IPInfo
+	for _, ipi := range all {
+		if isIPv4(&ipi.Ipnet) == ipv4 {
+			out = append(out, ipi)
+		}
+	}
+	return out
+}
+
+// SetSummaries configures the summary address ranges used when propagating
+// level 1 reachability into level 2.
+func (db *DB) SetSummaries(summaries []Summary) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.summaries = summaries
+		return nil
+	})
+	db.SomethingChanged(nil, GenReasonConfig)
+}
+
+// Summaries arranges for the summary address state to be returned.
+func (db *DB) Summaries() ([]*YangSummary, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		_, counts, metrics := db.summarize()
+		ys := make([]*YangSummary, 0, len(db.summaries))
+		for i, s := range db.summaries {
+			y := &YangSummary{
+				Prefix: tlv.IPPrefix(s.Prefix),
+				Metric: s.Metric,
+				Active: counts[i] != 0,
+				Count:  counts[i],
+			}
+			if y.Active {
+				y.Used = metrics[i]
+			}
+			ys = append(ys, y)
+		}
+		return ys
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.([]*YangSummary), nil
+}
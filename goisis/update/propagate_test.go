@@ -0,0 +1,136 @@
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"testing"
+)
+
+// downReach returns an Extended IP Reachability TLV with the IPv4 prefix and
+// the up/down bit set.
+func downReach(prefix string, metric uint32) []byte {
+	b := ipReach(prefix, metric)
+	b[6] |= 0x80
+	return b
+}
+
+// testIPInfo returns the IP information of the prefix.
+func testIPInfo(prefix string, metric uint32) tlv.IPInfo {
+	_, ipnet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		panic(err)
+	}
+	return tlv.IPInfo{Metric: metric, Ipnet: *ipnet}
+}
+
+// testSummary returns the summary of the prefix.
+func testSummary(prefix string, metric uint32) Summary {
+	return Summary{Prefix: testIPInfo(prefix, 0).Ipnet, Metric: metric}
+}
+
+// checkReach checks the prefixes and metrics of the reachability.
+func checkReach(t *testing.T, name string, reach []tlv.IPInfo, want []tlv.IPInfo) {
+	t.Helper()
+	if len(reach) != len(want) {
+		t.Errorf("%s: got %v want %v", name, reach, want)
+		return
+	}
+	for i := range want {
+		if !samePrefix(&reach[i].Ipnet, &want[i].Ipnet) || reach[i].Metric != want[i].Metric {
+			t.Errorf("%s: got %v want %v", name, reach, want)
+			return
+		}
+	}
+}
+
+func TestLevelReach(t *testing.T) {
+	links := []testLink{{1, 2, 10}, {2, 3, 5}}
+	db := newTestDB(links, map[byte][][]byte{
+		1: {ipReach("10.1.0.0/16", 1)},
+		// The best metric of a prefix is used.
+		2: {ipReach("10.2.0.0/16", 10), ipReach("10.23.0.0/16", 1)},
+		3: {ipReach("10.2.0.0/16", 1), ipReach("10.23.0.0/16", 1),
+			// Leaked prefixes are never propagated back up.
+			downReach("10.3.0.0/16", 1),
+			// The metric is capped to the maximum.
+			ipReach("10.4.0.0/16", tlv.ExtIPMaxMetric-1),
+			ipReach("10.5.0.0/16", tlv.ExtIPMaxMetric+1)},
+	})
+	db.li, db.istype = 0, clns.L1Flag|clns.L2Flag
+	addLSP(db, testNode(1), 0, append(linkTLVs(1, links), ipReach("10.1.0.0/16", 1))...)
+	db.runSPF()
+	want := []tlv.IPInfo{
+		testIPInfo("10.1.0.0/16", 1),
+		testIPInfo("10.2.0.0/16", 16),
+		testIPInfo("10.4.0.0/16", tlv.ExtIPMaxMetric),
+		testIPInfo("10.23.0.0/16", 11),
+	}
+	root := db.spfNodes[testNode(1)]
+	checkReach(t, "level 1", db.levelReach(root, db.spfNodes), want)
+
+	// Our level 2 prefixes were propagated from level 1.
+	db.li = 1
+	checkReach(t, "level 2", db.levelReach(root, db.spfNodes), want[1:])
+}
+
+func TestSummarize(t *testing.T) {
+	reach := []tlv.IPInfo{
+		testIPInfo("10.1.1.0/24", 20),
+		testIPInfo("10.1.2.0/24", 10),
+		testIPInfo("10.2.0.0/16", 30),
+		testIPInfo("192.0.2.0/24", 5),
+		testIPInfo("2001:db8:1::/48", 7),
+	}
+	tests := []struct {
+		name      string
+		summaries []Summary
+		want      []tlv.IPInfo
+		counts    []int
+	}{
+		{"none", nil, reach, nil},
+		// Without a metric the lowest metric of the covered prefixes is used.
+		{"lowest metric", []Summary{testSummary("10.1.0.0/16", 0)}, []tlv.IPInfo{
+			reach[2], reach[3], reach[4], testIPInfo("10.1.0.0/16", 10),
+		}, []int{2}},
+		{"configured metric", []Summary{testSummary("10.1.0.0/16", 50)}, []tlv.IPInfo{
+			reach[2], reach[3], reach[4], testIPInfo("10.1.0.0/16", 50),
+		}, []int{2}},
+		// The most specific summary suppresses the prefix, an IPv4 summary
+		// doesn't cover IPv6 prefixes and a summary without prefixes isn't
+		// advertised.
+		{"most specific", []Summary{testSummary("10.0.0.0/8", 0), testSummary("10.1.0.0/16", 0),
+			testSummary("0.0.0.0/0", 0), testSummary("172.16.0.0/12", 1)}, []tlv.IPInfo{
+			reach[4], testIPInfo("10.0.0.0/8", 30), testIPInfo("10.1.0.0/16", 10), testIPInfo("0.0.0.0/0", 5),
+		}, []int{1, 2, 1, 0}},
+		{"ipv6", []Summary{testSummary("2001:db8::/32", 0)}, []tlv.IPInfo{
+			reach[0], reach[1], reach[2], reach[3], testIPInfo("2001:db8::/32", 7),
+		}, []int{1}},
+	}
+	for _, test := range tests {
+		db := newTestDB(nil, nil)
+		db.otherReach = reach
+		db.summaries = test.summaries
+		out, counts, _ := db.summarize()
+		checkReach(t, test.name, out, test.want)
+		for i := range test.counts {
+			if counts[i] != test.counts[i] {
+				t.Errorf("%s: counts %v want %v", test.name, counts, test.counts)
+				break
+			}
+		}
+	}
+
+	// Only the level 2 process advertises the level 1 reachability, per address
+	// family.
+	db := newTestDB(nil, nil)
+	db.otherReach = reach
+	db.summaries = []Summary{testSummary("10.1.0.0/16", 0)}
+	db.li = 0
+	if out := db.propagatedReach(true); len(out) != 0 {
+		t.Errorf("Level 1 reachability propagated at level 1 %v", out)
+	}
+	db.li = 1
+	checkReach(t, "ipv4", db.propagatedReach(true), []tlv.IPInfo{reach[2], reach[3], testIPInfo("10.1.0.0/16", 10)})
+	checkReach(t, "ipv6", db.propagatedReach(false), []tlv.IPInfo{reach[4]})
+}
@@ -0,0 +1,132 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the advertisement of prefixes redistributed into IS-IS
+// from other sources.
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/policy"
+	"github.com/choppsv1/goisis/tlv"
+	"sort"
+)
+
+// GenReasonRedist is the LSP log reason for changes to redistributed prefixes.
+const GenReasonRedist = "redistribution-change"
+
+// Redist is a prefix redistributed into IS-IS from another source subject to
+// the source's policy.
+type Redist struct {
+	Source string
+	Policy *policy.Policy
+	tlv.IPInfo
+}
+
+// YangRedist is a redistributed prefix for the yang model.
+type YangRedist struct {
+	Level  clns.Level   `json:"level"`
+	Prefix tlv.IPPrefix `json:"prefix"`
+	Metric uint32       `json:"metric"`
+	Tags   []uint32     `json:"tags,omitempty"`
+	Source string       `json:"source"`
+	Denied bool         `json:"denied,omitempty"`
+}
+
+func equalRedist(a, b []Redist) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Source != b[i].Source || a[i].Policy != b[i].Policy || a[i].Metric != b[i].Metric ||
+			!samePrefix(&a[i].Ipnet, &b[i].Ipnet) || len(a[i].Tags) != len(b[i].Tags) {
+			return false
+		}
+		for j := range a[i].Tags {
+			if a[i].Tags[j] != b[i].Tags[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sortRedist sorts the prefixes and removes duplicates keeping the lowest
+// metric.
+func sortRedist(rs []Redist) []Redist {
+	sort.SliceStable(rs, func(i, j int) bool {
+		a, b := &rs[i].Ipnet, &rs[j].Ipnet
+		if !samePrefix(a, b) {
+			return a.String() < b.String()
+		}
+		return rs[i].Metric < rs[j].Metric
+	})
+	var out []Redist
+	for _, r := range rs {
+		if len(out) > 0 && samePrefix(&out[len(out)-1].Ipnet, &r.Ipnet) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// redistReach returns the redistributed prefixes to add to our LSP for the
+// given address family.
+func (db *DB) redistReach(ipv4 bool) []tlv.IPInfo {
+	var out []tlv.IPInfo
+	for _, r := range db.redist {
+		if isIPv4(&r.Ipnet) != ipv4 || db.isLocalPrefix(&r.Ipnet) {
+			continue
+		}
+		ipi := r.IPInfo
+		ipi.External = true
+		if db.applyPolicy(r.Policy, &ipi) {
+			out = append(out, ipi)
+		}
+	}
+	return out
+}
+
+// SetRedistributed sets the prefixes redistributed into this level,
+// regenerating our LSP if they changed.
+func (db *DB) SetRedistributed(rs []Redist) {
+	rs = sortRedist(rs)
+	changed, _ := DoRPC(db.rpC, func() interface{} {
+		if equalRedist(rs, db.redist) {
+			return false
+		}
+		db.redist = rs
+		return true
+	})
+	if changed.(bool) {
+		db.SomethingChanged(nil, GenReasonRedist)
+	}
+}
+
+// Redistributed arranges for the prefixes redistributed into this level to be
+// returned.
+func (db *DB) Redistributed() ([]*YangRedist, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		ys := make([]*YangRedist, 0, len(db.redist))
+		for _, r := range db.redist {
+			ipi := r.IPInfo
+			denied := !db.applyPolicy(r.Policy, &ipi)
+			ys = append(ys, &YangRedist{
+				Level:  db.li.ToLevel(),
+				Prefix: tlv.IPPrefix(ipi.Ipnet),
+				Metric: ipi.Metric,
+				Tags:   ipi.Tags,
+				Source: r.Source,
+				Denied: denied,
+			})
+		}
+		return ys
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.([]*YangRedist), nil
+}
@@ -0,0 +1,92 @@
+package update
+
+import (
+	"testing"
+)
+
+// testRedist returns the redistributed prefix.
+func testRedist(source, prefix string, metric uint32, tags ...uint32) Redist {
+	r := Redist{Source: source, IPInfo: testIPInfo(prefix, metric)}
+	r.Tags = tags
+	return r
+}
+
+func TestEqualRedist(t *testing.T) {
+	a := []Redist{testRedist("static", "10.1.0.0/16", 10, 1, 2), testRedist("kernel", "10.2.0.0/16", 20)}
+	tests := []struct {
+		name  string
+		b     []Redist
+		equal bool
+	}{
+		{"equal", []Redist{testRedist("static", "10.1.0.0/16", 10, 1, 2), testRedist("kernel", "10.2.0.0/16", 20)}, true},
+		{"length", a[:1], false},
+		{"source", []Redist{testRedist("connected", "10.1.0.0/16", 10, 1, 2), a[1]}, false},
+		{"metric", []Redist{testRedist("static", "10.1.0.0/16", 11, 1, 2), a[1]}, false},
+		{"prefix", []Redist{testRedist("static", "10.1.0.0/24", 10, 1, 2), a[1]}, false},
+		{"tag count", []Redist{testRedist("static", "10.1.0.0/16", 10, 1), a[1]}, false},
+		{"tag", []Redist{testRedist("static", "10.1.0.0/16", 10, 1, 3), a[1]}, false},
+	}
+	for _, test := range tests {
+		if got := equalRedist(a, test.b); got != test.equal {
+			t.Errorf("%s: equal %v want %v", test.name, got, test.equal)
+		}
+	}
+}
+
+func TestSortRedist(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []Redist
+		want []Redist
+	}{
+		{"empty", nil, nil},
+		{"sorted", []Redist{testRedist("static", "10.2.0.0/16", 1), testRedist("static", "10.1.0.0/16", 1)},
+			[]Redist{testRedist("static", "10.1.0.0/16", 1), testRedist("static", "10.2.0.0/16", 1)}},
+		// The lowest metric of a duplicate prefix is kept with its source.
+		{"duplicates", []Redist{
+			testRedist("static", "10.1.0.0/16", 20),
+			testRedist("kernel", "10.1.0.0/16", 10),
+			testRedist("connected", "10.1.0.0/16", 30),
+			testRedist("static", "10.1.0.0/24", 5),
+		}, []Redist{testRedist("kernel", "10.1.0.0/16", 10), testRedist("static", "10.1.0.0/24", 5)}},
+		// The first of equal metrics is kept.
+		{"equal metrics", []Redist{testRedist("static", "10.1.0.0/16", 10), testRedist("kernel", "10.1.0.0/16", 10)},
+			[]Redist{testRedist("static", "10.1.0.0/16", 10)}},
+	}
+	for _, test := range tests {
+		if got := sortRedist(test.in); !equalRedist(got, test.want) {
+			t.Errorf("%s: got %v want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRedistReach(t *testing.T) {
+	db := newTestDB(nil, nil)
+	db.redist = sortRedist([]Redist{
+		testRedist("static", "10.1.0.0/16", 10, 7),
+		testRedist("kernel", "2001:db8::/32", 20),
+		testRedist("static", "10.2.0.0/16", 30),
+	})
+	tests := []struct {
+		ipv4 bool
+		want []string
+	}{
+		{true, []string{"10.1.0.0/16", "10.2.0.0/16"}},
+		{false, []string{"2001:db8::/32"}},
+	}
+	for _, test := range tests {
+		reach := db.redistReach(test.ipv4)
+		if len(reach) != len(test.want) {
+			t.Errorf("ipv4 %v: got %v want %v", test.ipv4, reach, test.want)
+			continue
+		}
+		for i, ipi := range reach {
+			if ipi.Ipnet.String() != test.want[i] || !ipi.External {
+				t.Errorf("ipv4 %v: got %v want %v external", test.ipv4, reach, test.want)
+			}
+		}
+	}
+	if reach := db.redistReach(true); reach[0].Metric != 10 || len(reach[0].Tags) != 1 || reach[0].Tags[0] != 7 {
+		t.Errorf("Bad redistributed prefix %+v", reach[0])
+	}
+}
@@ -0,0 +1,242 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the Remote LFA (RFC7490) part of the decision process,
+// the P-space and Q-space computation and the PQ node selection, and the
+// segment routing label stacks of the repair paths.
+package update
+
+import (
+	"bytes"
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"sort"
+)
+
+// reverseGraph returns a copy of the SPF graph with the edges reversed, an
+// SPF over it gives the distances to its root.
+func reverseGraph(nodes map[clns.NodeID]*spfNode) map[clns.NodeID]*spfNode {
+	graph := make(map[clns.NodeID]*spfNode)
+	for id, n := range nodes {
+		graph[id] = &spfNode{
+			nodeid: n.nodeid,
+			segs:   n.segs,
+			flags:  n.flags,
+			dist:   maxDist,
+			index:  -1,
+		}
+	}
+	for _, n := range nodes {
+		for _, e := range n.edges {
+			if m := graph[e.nodeid]; m != nil {
+				m.edges = append(m.edges, spfEdge{nodeid: n.nodeid, metric: e.metric})
+			}
+		}
+	}
+	return graph
+}
+
+// reverseDist returns the distances from the nodes to dst.
+func (db *DB) reverseDist(fs *frrState, dst clns.NodeID) map[clns.NodeID]uint32 {
+	if dist, ok := fs.rdist[dst]; ok {
+		return dist
+	}
+	graph := reverseGraph(fs.nodes)
+	dist := make(map[clns.NodeID]uint32)
+	if root := graph[dst]; root != nil {
+		db.dijkstra(root, graph, tlv.MTIDStandard)
+		for id, n := range graph {
+			if n.reached() {
+				dist[id] = n.dist
+			}
+		}
+	}
+	fs.rdist[dst] = dist
+	return dist
+}
+
+// pSpaceNbr returns the neighbor, not on circuit c, with the lowest cost path
+// to y that doesn't go through us, nil if y is not in our extended P-space
+// with respect to the link on c (RFC7490 section 4.2).
+func pSpaceNbr(fs *frrState, c Circuit, y clns.NodeID) (*lfaNbr, uint32) {
+	rootid := fs.root.nodeid
+	var best *lfaNbr
+	bestd := maxDist
+	n := fs.nodes[y]
+	if n == nil || !n.reached() {
+		return nil, maxDist
+	}
+	for _, ln := range fs.lnbrs {
+		if !ln.candidate || ln.c == c || ln.cost == maxDist {
+			continue
+		}
+		dNY, ok := ln.dist[y]
+		dNS, ok2 := ln.dist[rootid]
+		if !ok || !ok2 || dNY >= dNS+n.dist {
+			continue
+		}
+		if ln.cost+dNY < bestd {
+			best, bestd = ln, ln.cost+dNY
+		}
+	}
+	return best, bestd
+}
+
+// inQSpace returns true if the shortest paths from y to dst don't go through
+// us, rdst and rroot are the distances to dst and to us.
+func inQSpace(rdst, rroot map[clns.NodeID]uint32, y clns.NodeID, dSD uint32) bool {
+	dYD, ok := rdst[y]
+	if !ok {
+		return false
+	}
+	dYS, ok := rroot[y]
+	return !ok || dYD < dYS+dSD
+}
+
+// nodePrefixDist returns the distance from y to the prefix using the
+// distances to the advertisers of the route type.
+func (db *DB) nodePrefixDist(fs *frrState, y clns.NodeID, advs []lfaAdv, updown bool) uint32 {
+	best := maxDist
+	for _, a := range advs {
+		if a.updown != updown {
+			continue
+		}
+		if d, ok := db.reverseDist(fs, a.nodeid)[y]; ok && d+a.metric < best {
+			best = d + a.metric
+		}
+	}
+	return best
+}
+
+// nodeSID returns the node SID index of the router for the address family,
+// the SID of its lowest prefix with the N flag.
+func nodeSID(sids map[string][]prefixSID, sysid clns.SystemID, ipv4 bool) (uint32, bool) {
+	var best *prefixSID
+	for _, pss := range sids {
+		for i := range pss {
+			ps := &pss[i]
+			if ps.sysid != sysid || !ps.sid.Node || isIPv4(&ps.prefix) != ipv4 {
+				continue
+			}
+			if best == nil || comparePrefix(&ps.prefix, &best.prefix) < 0 {
+				best = ps
+			}
+		}
+	}
+	if best == nil {
+		return 0, false
+	}
+	return best.sid.SID, true
+}
+
+// repairLabels returns the outgoing label stack for the segment list sent to
+// neighbor nbr followed by the Prefix-SID of the prefix if it has one. The
+// labels of node segments are from the SRGB of the router reading them, ok is
+// false if a label can't be computed.
+func (db *DB) repairLabels(fs *frrState, key string, nbr clns.SystemID, segs []RepairSegment, ipv4 bool) ([]RepairSegment, []uint32, bool) {
+	var labels []uint32
+	reader := nbr
+	for i := range segs {
+		seg := &segs[i]
+		switch seg.Type {
+		case SegmentNode:
+			index, ok := nodeSID(fs.sids, seg.Node, ipv4)
+			if !ok {
+				return nil, nil, false
+			}
+			if seg.Label, ok = tlv.SRGBLabel(db.nodeSRGB(reader), index); !ok {
+				return nil, nil, false
+			}
+		case SegmentAdj:
+			// The label of the adjacency segment is already set.
+		}
+		labels = append(labels, seg.Label)
+		reader = seg.Node
+	}
+	if pss := fs.sids[key]; len(pss) != 0 {
+		label, ok := db.outLabel(pss, reader, ipv4)
+		if !ok {
+			return nil, nil, false
+		}
+		segs = append(segs, RepairSegment{Type: SegmentPrefix, Node: reader, Label: label})
+		if label != tlv.LabelImplicitNull {
+			labels = append(labels, label)
+		}
+	}
+	return segs, labels, true
+}
+
+// selectRemoteLFA returns the remote LFA protecting the link of the primary
+// next hop of the route (RFC7490). The PQ nodes are in our extended P-space
+// and the Q-space of the primary neighbor with respect to the link. With
+// PQSelectClosest the PQ node closest to us is used, otherwise the one with
+// the lowest repair path metric.
+func (db *DB) selectRemoteLFA(fs *frrState, key string, r *Route, nh *NextHop, policy string) *Backup {
+	c := db.circuits[nh.Intf]
+	primary := primaryNbr(fs.lnbrs, c, nh.Sysid)
+	if primary == nil || primary.cost == maxDist {
+		return nil
+	}
+	updown := r.Type == RouteInterArea
+	rE := db.reverseDist(fs, sysNodeID(primary.sysid))
+	rS := db.reverseDist(fs, fs.root.nodeid)
+
+	var ids []clns.NodeID
+	for id, n := range fs.nodes {
+		if n != fs.root && !n.isPN() && n.reached() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+
+	var best *Backup
+	var bestnbr *lfaNbr
+	var bestd uint32
+	for _, y := range ids {
+		if !inQSpace(rE, rS, y, primary.cost) {
+			continue
+		}
+		ln, dNY := pSpaceNbr(fs, c, y)
+		if ln == nil {
+			continue
+		}
+		dYP := db.nodePrefixDist(fs, y, fs.advs[key], updown)
+		if dYP == maxDist {
+			continue
+		}
+		metric := dNY + dYP
+		pq := fs.nodes[y].sysid()
+		b := &Backup{
+			Type:   BackupRemoteLFA,
+			Intf:   ln.c.Name(),
+			Sysid:  ln.sysid,
+			Metric: metric,
+			PQNode: &pq,
+		}
+		d := metric
+		if policy == PQSelectClosest {
+			d = dNY
+		}
+		if best == nil || d < bestd {
+			best, bestnbr, bestd = b, ln, d
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	ipv4 := isIPv4((*net.IPNet)(&r.Prefix))
+	if fs.sids != nil {
+		segs := []RepairSegment{{Type: SegmentNode, Node: *best.PQNode}}
+		if *best.PQNode == bestnbr.sysid {
+			segs = nil
+		}
+		if segs, labels, ok := db.repairLabels(fs, key, bestnbr.sysid, segs, ipv4); ok {
+			best.Segments, best.Labels = segs, labels
+		}
+	}
+	best.Addr = db.resolveNextHops([]nexthop{{bestnbr.c, bestnbr.sysid}}, ipv4)[0].Addr
+	return best
+}
@@ -0,0 +1,162 @@
+package update
+
+import "testing"
+
+// ring returns the links of a ring of routers 1-n with metric 1.
+func ring(n byte) []testLink {
+	var links []testLink
+	for i := byte(1); i < n; i++ {
+		links = append(links, testLink{i, i + 1, 1})
+	}
+	return append(links, testLink{n, 1, 1})
+}
+
+// newSRDB returns the DB of router 1 for the links with the fast reroute
+// policy on its circuits and segment routing enabled. The routers advertise
+// testPrefix(n) with node SID index n.
+func newSRDB(links []testLink, frr FRRPolicy) *DB {
+	extra := make(map[byte][][]byte)
+	for _, l := range links {
+		for _, n := range []byte{l.a, l.b} {
+			extra[n] = [][]byte{capTLVs(srCap(n)), sidReach(testPrefix(n), 1, uint32(n))}
+		}
+	}
+	db := newTestDB(links, extra)
+	for _, c := range db.circuits {
+		c.(*testCircuit).frr = frr
+	}
+	db.routerCap = srCap(1)
+	return db
+}
+
+func TestPQSpace(t *testing.T) {
+	// The link to 2 is protected, the P-space is reached through 7.
+	db := newFRRDB(ring(7), FRRPolicy{Candidate: true, RemoteLFA: true}, 3)
+	db.runSPF()
+	fs := db.newFRRState(db.spfNodes[testNode(1)], db.spfNodes)
+	c := db.circuits[testIntf(2)]
+	rE := db.reverseDist(fs, testNode(2))
+	rS := db.reverseDist(fs, testNode(1))
+	pspace := map[byte]bool{4: true, 5: true, 6: true, 7: true}
+	qspace := map[byte]bool{2: true, 3: true, 4: true, 5: true}
+	for n := byte(2); n <= 7; n++ {
+		ln, _ := pSpaceNbr(fs, c, testNode(n))
+		if (ln != nil) != pspace[n] || (ln != nil && ln.sysid != testSysID(7)) {
+			t.Errorf("Router %d in P-space through %+v", n, ln)
+		}
+		if inQSpace(rE, rS, testNode(n), 1) != qspace[n] {
+			t.Errorf("Router %d in Q-space %v", n, !qspace[n])
+		}
+	}
+}
+
+func TestRemoteLFA(t *testing.T) {
+	// The prefix of 3 has no LFA, routers 4 and 5 are PQ nodes with the same
+	// repair path metric, 5 is closer.
+	tests := []struct {
+		policy string
+		sr     bool
+		pq     byte
+		labels []uint32
+	}{
+		{PQSelectLowestMetric, false, 4, nil},
+		{PQSelectClosest, false, 5, nil},
+		{PQSelectLowestMetric, true, 4, []uint32{16004, 16003}},
+		{PQSelectClosest, true, 5, []uint32{16005, 16003}},
+	}
+	for _, test := range tests {
+		frr := FRRPolicy{Candidate: true, LFA: true, RemoteLFA: true, PQSelection: test.policy}
+		var db *DB
+		if test.sr {
+			db = newSRDB(ring(7), frr)
+		} else {
+			db = newFRRDB(ring(7), frr, 3)
+		}
+		db.runSPF()
+		b := primaryBackup(t, db, 3)
+		if b == nil || b.Type != BackupRemoteLFA || b.Intf != testIntf(7) || !b.Addr.Equal(testAddr(7)) ||
+			b.Sysid != testSysID(7) || b.Metric != 6 || b.PQNode == nil || *b.PQNode != testSysID(test.pq) {
+			t.Errorf("%s: bad remote LFA %+v", test.policy, b)
+			continue
+		}
+		if len(b.Labels) != len(test.labels) {
+			t.Errorf("%s: labels %v want %v", test.policy, b.Labels, test.labels)
+			continue
+		}
+		for i := range b.Labels {
+			if b.Labels[i] != test.labels[i] {
+				t.Errorf("%s: labels %v want %v", test.policy, b.Labels, test.labels)
+				break
+			}
+		}
+		if test.sr && (len(b.Segments) != 2 || b.Segments[0].Type != SegmentNode ||
+			b.Segments[0].Node != testSysID(test.pq) || b.Segments[1].Type != SegmentPrefix) {
+			t.Errorf("%s: bad segments %+v", test.policy, b.Segments)
+		}
+	}
+
+	// No PQ node in a ring of 4 with a heavy link.
+	db := newFRRDB([]testLink{{1, 2, 1}, {2, 3, 1}, {3, 4, 10}, {4, 1, 1}},
+		FRRPolicy{Candidate: true, RemoteLFA: true}, 3)
+	db.runSPF()
+	if b := primaryBackup(t, db, 3); b != nil {
+		t.Errorf("Unexpected remote LFA %+v", b)
+	}
+}
+
+func TestTILFA(t *testing.T) {
+	// The post-convergence path to 3 is 6-5-4-3. In the ring the PQ node 5 is
+	// reached with its node SID. With the heavy link between 4 and 5 the last
+	// P-space node is 5 and the first Q-space node 4, the path uses the
+	// Adj-SID of 5 to 4.
+	heavy := ring(6)
+	heavy[3].metric = 3
+	tests := []struct {
+		name   string
+		links  []testLink
+		metric uint32
+		segs   []RepairSegment
+	}{
+		{"ring", ring(6), 5, []RepairSegment{
+			{Type: SegmentNode, Node: testSysID(5), Label: 16005},
+			{Type: SegmentPrefix, Node: testSysID(5), Label: 16003},
+		}},
+		{"adjacency", heavy, 7, []RepairSegment{
+			{Type: SegmentNode, Node: testSysID(5), Label: 16005},
+			{Type: SegmentAdj, Node: testSysID(4), Label: 24004},
+			{Type: SegmentPrefix, Node: testSysID(4), Label: 16003},
+		}},
+	}
+	for _, test := range tests {
+		db := newSRDB(test.links, FRRPolicy{Candidate: true, LFA: true, TILFA: true})
+		addLSP(db, testNode(5), 0, isReach(4, test.links[3].metric, adjSIDSub(24004)...), isReach(6, 1),
+			capTLVs(srCap(5)), sidReach(testPrefix(5), 1, 5))
+		db.runSPF()
+		b := primaryBackup(t, db, 3)
+		if b == nil || b.Type != BackupTILFA || b.Intf != testIntf(6) || !b.Addr.Equal(testAddr(6)) ||
+			b.Sysid != testSysID(6) || b.Metric != test.metric {
+			t.Errorf("%s: bad TI-LFA %+v", test.name, b)
+			continue
+		}
+		if len(b.Segments) != len(test.segs) || len(b.Labels) != len(test.segs) {
+			t.Errorf("%s: segments %+v labels %v want %+v", test.name, b.Segments, b.Labels, test.segs)
+			continue
+		}
+		for i := range test.segs {
+			if b.Segments[i] != test.segs[i] || b.Labels[i] != test.segs[i].Label {
+				t.Errorf("%s: segments %+v labels %v want %+v", test.name, b.Segments, b.Labels, test.segs)
+				break
+			}
+		}
+		if db.frr.TILFA == 0 || db.frr.TILFA+db.frr.LFA != db.frr.Protected {
+			t.Errorf("%s: bad coverage %+v", test.name, db.frr)
+		}
+	}
+
+	// Without segment routing there is no TI-LFA.
+	db := newFRRDB(ring(6), FRRPolicy{Candidate: true, TILFA: true}, 3)
+	db.runSPF()
+	if b := primaryBackup(t, db, 3); b != nil {
+		t.Errorf("TI-LFA without segment routing %+v", b)
+	}
+}
@@ -0,0 +1,243 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the Router Capability (RFC7981) origination, the
+// capabilities received from other routers and the leaking of domain wide
+// capabilities between the levels.
+package update
+
+import (
+	"bytes"
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"sort"
+)
+
+// GenReasonRouterCap is the LSP log reason for changes to the domain wide
+// capabilities from the other level.
+const GenReasonRouterCap = "router-capability-change"
+
+// YangRouterCap are the capabilities advertised by a router for the yang model.
+type YangRouterCap struct {
+	Level        clns.Level       `json:"level"`
+	Sysid        clns.SystemID    `json:"system-id"`
+	Capabilities []*tlv.RouterCap `json:"capabilities"`
+}
+
+// levelCaps is sent between the level 1 and level 2 update processes with the
+// domain wide capabilities of the sending level.
+type levelCaps []*tlv.RouterCap
+
+// decodeRouterCaps decodes the Router Capability TLVs of a segment.
+func decodeRouterCaps(tlvs tlv.Map) []*tlv.RouterCap {
+	var caps []*tlv.RouterCap
+	for _, t := range tlvs[tlv.TypeRouterCap] {
+		rc, err := t.RouterCapDecode()
+		if err != nil {
+			Debug(DbgFUpd, "Bad Router Capability TLV: %s", err)
+			continue
+		}
+		caps = append(caps, rc)
+	}
+	return caps
+}
+
+// originatorCaps returns the capabilities of the router itself out of those
+// advertised in its LSP segments (segment 0 first), the ones with its router
+// ID. The router ID is the TE Router ID of the LSP, otherwise the one of its
+// first capability. The others were leaked from the other level and carry the
+// router ID of the router they describe.
+func originatorCaps(segs []*lspSegment) []*tlv.RouterCap {
+	var rid net.IP
+	if len(segs) != 0 {
+		if ts := segs[0].tlvs[tlv.TypeRouterID]; len(ts) != 0 {
+			rid, _ = ts[0].RouterIDDecode()
+		}
+	}
+	var caps []*tlv.RouterCap
+	for _, lsp := range segs {
+		for _, rc := range lsp.caps {
+			if rid == nil {
+				rid = rc.RouterID
+			}
+			if rc.RouterID.Equal(rid) {
+				caps = append(caps, rc)
+			}
+		}
+	}
+	return caps
+}
+
+// nodeRouterCaps returns the capabilities of the router advertised in its LSP
+// segments.
+func (db *DB) nodeRouterCaps(sysid clns.SystemID) []*tlv.RouterCap {
+	var segs []*lspSegment
+	for segid := 0; segid < 256; segid++ {
+		lspid := clns.MakeLSPID(sysid, 0, uint8(segid))
+		lsp := db.get(lspid[:])
+		if lsp != nil && lsp.checkLifetime() != 0 {
+			segs = append(segs, lsp)
+		}
+	}
+	return originatorCaps(segs)
+}
+
+// routerCaps returns the capabilities of the node itself.
+func (n *spfNode) routerCaps() []*tlv.RouterCap {
+	return originatorCaps(n.segs)
+}
+
+// allRouterCaps returns all the capabilities advertised by the node including
+// the ones it leaked from the other level.
+func (n *spfNode) allRouterCaps() []*tlv.RouterCap {
+	var caps []*tlv.RouterCap
+	for _, lsp := range n.segs {
+		caps = append(caps, lsp.caps...)
+	}
+	return caps
+}
+
+// leakedCap returns the part of a domain wide capability leaked into the other
+// level, nil if nothing is. The SR, SRv6, MSD and flexible algorithm
+// sub-TLVs describe the forwarding of the router advertising them in its
+// level, they aren't leaked (RFC9350 section 5.1 requires the FAD to be area
+// scoped). The router ID identifies the router they came from.
+func leakedCap(rc *tlv.RouterCap) *tlv.RouterCap {
+	if len(rc.NodeTags) == 0 {
+		return nil
+	}
+	return &tlv.RouterCap{
+		RouterID: rc.RouterID,
+		Flood:    rc.Flood,
+		Down:     rc.Down,
+		NodeTags: rc.NodeTags,
+	}
+}
+
+// ownRouterCaps returns the capabilities to advertise in our LSP, our own
+// followed by the domain wide ones from the other level.
+func (db *DB) ownRouterCaps() []*tlv.RouterCap {
+	var caps []*tlv.RouterCap
+	if db.routerCap != nil {
+		rc := *db.routerCap
+		rc.RouterID = db.routerID
+		caps = append(caps, &rc)
+	}
+	for _, orc := range db.otherCaps {
+		rc := *orc
+		rc.Down = rc.Down || db.li == 0
+		caps = append(caps, &rc)
+	}
+	return caps
+}
+
+// addRouterCaps adds our Router Capability TLVs to the LSP.
+func (db *DB) addRouterCaps(bt *tlv.BufferTrack) error {
+	for _, rc := range db.ownRouterCaps() {
+		if err := bt.AddRouterCap(rc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// domainCaps returns the leaked part of the domain wide capabilities of the
+// other routers reachable in this level. Capabilities leaked into level 1 are
+// not leaked back into level 2 (RFC7981 section 3).
+func (db *DB) domainCaps(root *spfNode, nodes map[clns.NodeID]*spfNode) []*tlv.RouterCap {
+	var ids []clns.NodeID
+	for id, n := range nodes {
+		if n != root && !n.isPN() && n.reached() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+	var caps []*tlv.RouterCap
+	for _, id := range ids {
+		for _, rc := range nodes[id].allRouterCaps() {
+			if !rc.Flood || (db.li == 0 && rc.Down) {
+				continue
+			}
+			if lrc := leakedCap(rc); lrc != nil {
+				caps = append(caps, lrc)
+			}
+		}
+	}
+	return caps
+}
+
+func encodeRouterCaps(caps []*tlv.RouterCap) []byte {
+	var b []byte
+	for _, rc := range caps {
+		subs, _ := rc.SubTLVs()
+		b = append(b, rc.RouterID.To4()...)
+		for _, sub := range subs {
+			b = append(b, sub...)
+		}
+	}
+	return b
+}
+
+// propagateCaps sends our domain wide capabilities to the other level's
+// update process if they changed.
+func (db *DB) propagateCaps(root *spfNode, nodes map[clns.NodeID]*spfNode) {
+	if db.other == nil {
+		return
+	}
+	caps := db.domainCaps(root, nodes)
+	if bytes.Equal(encodeRouterCaps(caps), encodeRouterCaps(db.sentCaps)) {
+		return
+	}
+	db.sentCaps = caps
+	db.sendOther(levelCaps(caps))
+}
+
+// handleLevelCaps records the other level's domain wide capabilities and
+// regenerates our LSP.
+func (db *DB) handleLevelCaps(in levelCaps) {
+	Debug(DbgFUpd, "%s: %d capabilities received from other level", db, len(in))
+	db.otherCaps = []*tlv.RouterCap(in)
+	db.handleChgLSPC(chgLSP{reason: GenReasonRouterCap})
+}
+
+// SetRouterCap configures the capabilities we advertise, nil advertises none.
+// The router ID is our TE router ID.
+func (db *DB) SetRouterCap(rc *tlv.RouterCap) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.routerCap = rc
+		return nil
+	})
+	db.SomethingChanged(nil, GenReasonConfig)
+}
+
+// RouterCaps arranges for the capabilities advertised by all routers
+// including us to be returned.
+func (db *DB) RouterCaps() ([]*YangRouterCap, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		var ys []*YangRouterCap
+		var last *YangRouterCap
+		for it := db.db.Iterator(); it.HasNext(); {
+			dbnode, _ := it.Next()
+			lsp := dbnode.Value().(*lspSegment)
+			if len(lsp.caps) == 0 || lsp.checkLifetime() == 0 {
+				continue
+			}
+			var sysid clns.SystemID
+			copy(sysid[:], lsp.lspid[:clns.SysIDLen])
+			if last == nil || last.Sysid != sysid {
+				last = &YangRouterCap{Level: db.li.ToLevel(), Sysid: sysid}
+				ys = append(ys, last)
+			}
+			last.Capabilities = append(last.Capabilities, lsp.caps...)
+		}
+		return ys
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.([]*YangRouterCap), nil
+}
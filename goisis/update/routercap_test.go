@@ -0,0 +1,95 @@
+package update
+
+import (
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"testing"
+)
+
+func TestRouterCapOriginator(t *testing.T) {
+	// Router 2 is an L1/L2 router leaking the capabilities of router 9 from
+	// level 1.
+	own := srCap(2, tlv.SRAlgoSPF)
+	own.Flood = true
+	leaked := &tlv.RouterCap{
+		RouterID: net.IPv4(9, 9, 9, 9).To4(),
+		Flood:    true,
+		SR:       &tlv.SRCap{MPLSIPv4: true, SRGB: []tlv.SIDRange{{Range: 1000, Start: 20000}}},
+		SRAlgos:  []uint8{tlv.SRAlgoSPF, 128},
+		NodeTags: []uint32{7},
+		FADs:     []tlv.FAD{{Algo: 128}},
+	}
+	db := newTestDB([]testLink{{1, 2, 10}}, map[byte][][]byte{
+		2: {capTLVs(own, leaked)},
+	})
+	db.runSPF()
+
+	caps := db.nodeRouterCaps(testSysID(2))
+	if len(caps) != 1 || !caps[0].RouterID.Equal(own.RouterID) {
+		t.Errorf("Bad originator capabilities %+v", caps)
+	}
+	if srgb := db.nodeSRGB(testSysID(2)); len(srgb) != 1 || srgb[0] != testSRGB[0] {
+		t.Errorf("Bad SRGB %+v", srgb)
+	}
+	n := db.spfNodes[testNode(2)]
+	if n.hasAlgo(128) || !n.hasAlgo(tlv.SRAlgoSPF) {
+		t.Errorf("Leaked algorithm credited to the leaking router")
+	}
+	if fad, _ := selectFAD(db.spfNodes[testNode(1)], db.spfNodes, 128); fad != nil {
+		t.Errorf("Leaked FAD used %+v", fad)
+	}
+
+	// Without a TE router ID the router ID of the first capability is used.
+	setLSP(db, 2, isReach(1, 10), capTLVs(leaked))
+	if caps := db.nodeRouterCaps(testSysID(2)); len(caps) != 1 || !caps[0].RouterID.Equal(leaked.RouterID) {
+		t.Errorf("Bad capabilities without router ID %+v", caps)
+	}
+	setLSP(db, 2, isReach(1, 10), []byte{byte(tlv.TypeRouterID), 4, 2, 2, 2, 2}, capTLVs(leaked, own))
+	if caps := db.nodeRouterCaps(testSysID(2)); len(caps) != 1 || !caps[0].RouterID.Equal(own.RouterID) {
+		t.Errorf("Bad capabilities with router ID %+v", caps)
+	}
+}
+
+func TestRouterCapLeak(t *testing.T) {
+	rc := srCap(2, tlv.SRAlgoSPF, 128)
+	rc.Flood = true
+	rc.NodeTags = []uint32{5}
+	rc.FADs = []tlv.FAD{{Algo: 128}}
+	local := &tlv.RouterCap{RouterID: rc.RouterID, NodeTags: []uint32{6}}
+	untagged := srCap(3)
+	untagged.Flood = true
+	db := newTestDB([]testLink{{1, 2, 10}, {1, 3, 10}}, map[byte][][]byte{
+		2: {capTLVs(rc, local)},
+		3: {capTLVs(untagged)},
+	})
+	db.runSPF()
+
+	// Only the node tags of the domain wide capabilities are leaked.
+	caps := db.domainCaps(db.spfNodes[testNode(1)], db.spfNodes)
+	if len(caps) != 1 {
+		t.Fatalf("Bad leaked capabilities %+v", caps)
+	}
+	lrc := caps[0]
+	if !lrc.RouterID.Equal(rc.RouterID) || !lrc.Flood || len(lrc.NodeTags) != 1 || lrc.NodeTags[0] != 5 ||
+		lrc.SR != nil || lrc.SRAlgos != nil || lrc.FADs != nil {
+		t.Errorf("Bad leaked capability %+v", lrc)
+	}
+
+	// Capabilities leaked into level 1 have the D bit set and aren't leaked
+	// back.
+	db.li = 0
+	db.otherCaps = caps
+	db.routerCap = &tlv.RouterCap{NodeTags: []uint32{9}}
+	db.routerID = net.IPv4(1, 1, 1, 1).To4()
+	own := db.ownRouterCaps()
+	if len(own) != 2 || own[0].Down || !own[0].RouterID.Equal(db.routerID) || !own[1].Down || caps[0].Down {
+		t.Errorf("Bad own capabilities %+v", own)
+	}
+	down := *rc
+	down.Down = true
+	setLSP(db, 2, isReach(1, 10), capTLVs(&down))
+	db.runSPF()
+	if caps := db.domainCaps(db.spfNodes[testNode(1)], db.spfNodes); len(caps) != 0 {
+		t.Errorf("Down capability leaked %+v", caps)
+	}
+}
@@ -0,0 +1,36 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the router ID handling.
+package update
+
+import (
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+)
+
+// stampIPInfo adds our router IDs as the source router IDs (RFC7794) of a
+// prefix we originate. Re-advertised prefixes keep those of the originator.
+func (db *DB) stampIPInfo(ipi *tlv.IPInfo) {
+	if ipi.Readvertised {
+		return
+	}
+	ipi.SourceIPv4 = db.routerID
+	ipi.SourceIPv6 = db.routerIDv6
+}
+
+// SetRouterID configures the IPv4 and IPv6 router IDs, nil for none. An IPv4
+// address isn't used as the IPv6 router ID.
+func (db *DB) SetRouterID(v4, v6 net.IP) {
+	if v6.To4() != nil {
+		v6 = nil
+	}
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.routerID = v4.To4()
+		db.routerIDv6 = v6
+		return nil
+	})
+	db.SomethingChanged(nil, GenReasonConfig)
+}
@@ -0,0 +1,593 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the decision process (SPF). It runs in the update process
+// go routine so that it may use the LSP DB directly.
+package update
+
+import (
+	"bytes"
+	"container/heap"
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"sort"
+	"time"
+)
+
+// MaxLinkMetric is the RFC5305 link metric that excludes a link from SPF.
+const MaxLinkMetric = uint32(0xFFFFFF)
+
+const maxDist = ^uint32(0)
+
+// Neighbor holds the adjacency information the decision process requires to
+// resolve next hops.
+type Neighbor struct {
+	Sysid   clns.SystemID
+	SNPA    clns.SNPA
+	V4Addrs []net.IP
+	V6Addrs []net.IP
+	AdjSIDs []tlv.AdjSID
+	EndXSID []tlv.SRv6EndXSID
+	MTIDs   []uint16 // topologies of the neighbor's IIH, nil if not MT
+}
+
+// NextHop is a route next hop. Labels is the outgoing label stack when
+// segment routing is used, Backup the repair path protecting the next hop.
+type NextHop struct {
+	Intf   string        `json:"outgoing-interface"`
+	Addr   net.IP        `json:"next-hop,omitempty"`
+	Sysid  clns.SystemID `json:"neighbor-sysid"`
+	Labels []uint32      `json:"outgoing-labels,omitempty"`
+	Backup *Backup       `json:"backup,omitempty"`
+}
+
+// Route types in order of preference (RFC5302 section 3.3).
+const (
+	RouteIntraArea = "intra-area"
+	RouteInterArea = "inter-area"
+)
+
+// Route is a route computed by the decision process. MTID is the topology of
+// the route (RFC5120).
+type Route struct {
+	Prefix   tlv.IPPrefix `json:"prefix"`
+	Metric   uint32       `json:"metric"`
+	Level    clns.Level   `json:"level"`
+	MTID     uint16       `json:"mt-id,omitempty"`
+	Type     string       `json:"route-type"`
+	NextHops []NextHop    `json:"next-hops"`
+}
+
+func routeType(updown bool) string {
+	if updown {
+		return RouteInterArea
+	}
+	return RouteIntraArea
+}
+
+// nexthop identifies a first hop neighbor on a circuit.
+type nexthop struct {
+	c     Circuit
+	sysid clns.SystemID
+}
+
+// spfEdge is a link from one node to another in the SPF graph.
+type spfEdge struct {
+	nodeid clns.NodeID
+	metric uint32
+	srlg   []uint32    // shared risk link groups of the link
+	te     *tlv.LinkTE // traffic engineering attributes of the link
+}
+
+// spfNode is a vertex in the SPF graph made up of all of an IS's (or pseudo
+// node's) LSP segments.
+type spfNode struct {
+	nodeid   clns.NodeID
+	segs     []*lspSegment
+	edges    []spfEdge
+	flags    clns.LSPFlags
+	dist     uint32
+	parents  []*spfNode
+	nexthops []nexthop
+	direct   Circuit // circuit for pseudo-nodes adjacent to us.
+	index    int     // index in the TENT heap, -1 if not present.
+	done     bool
+}
+
+func (n *spfNode) isPN() bool {
+	return n.nodeid[clns.SysIDLen] != 0
+}
+
+func (n *spfNode) sysid() clns.SystemID {
+	var sysid clns.SystemID
+	copy(sysid[:], n.nodeid[:clns.SysIDLen])
+	return sysid
+}
+
+func (n *spfNode) reached() bool {
+	return n.dist != maxDist
+}
+
+// hasEdge returns true if the node has an edge to nodeid, used for the two-way
+// connectivity check.
+func (n *spfNode) hasEdge(nodeid clns.NodeID) bool {
+	for _, e := range n.edges {
+		if e.nodeid == nodeid {
+			return true
+		}
+	}
+	return false
+}
+
+// tlvs returns all the TLVs of a given type from the node's segments.
+func (n *spfNode) tlvs(typ tlv.Type) []tlv.Data {
+	var tlvs []tlv.Data
+	for _, lsp := range n.segs {
+		tlvs = append(tlvs, lsp.tlvs[typ]...)
+	}
+	return tlvs
+}
+
+// areas returns the area addresses advertised by the node.
+func (n *spfNode) areas() []clns.Area {
+	var areas []clns.Area
+	for _, t := range n.tlvs(tlv.TypeAreaAddrs) {
+		a, err := t.AreaAddrsDecode()
+		if err != nil {
+			Debug(DbgFSPF, "%s: bad area TLV: %s", n.nodeid, err)
+			continue
+		}
+		areas = append(areas, a...)
+	}
+	return areas
+}
+
+// srlgs returns the shared risk link groups (RFC5307, RFC6119) the node
+// advertises for its links by neighbor.
+func (n *spfNode) srlgs() map[clns.NodeID][]uint32 {
+	srlgs := make(map[clns.NodeID][]uint32)
+	for _, typ := range []tlv.Type{tlv.TypeIPv4SRLG, tlv.TypeIPv6SRLG} {
+		for _, t := range n.tlvs(typ) {
+			s, err := t.SRLGDecode()
+			if err != nil {
+				Debug(DbgFSPF, "%s: bad SRLG TLV: %s", n.nodeid, err)
+				continue
+			}
+			for _, v := range s.Values {
+				if !hasSRLG(srlgs[s.Nodeid], v) {
+					srlgs[s.Nodeid] = append(srlgs[s.Nodeid], v)
+				}
+			}
+		}
+	}
+	return srlgs
+}
+
+func hasSRLG(srlg []uint32, v uint32) bool {
+	for _, s := range srlg {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// isReach decodes the IS reachability of the node in topology mtid into
+// edges. Pseudo-nodes only advertise the standard topology (RFC5120).
+func (n *spfNode) isReach(mtid uint16) []spfEdge {
+	var edges []spfEdge
+	srlgs := n.srlgs()
+	for _, nbr := range n.mtNeighbors(mtid) {
+		if nbr.Metric == MaxLinkMetric {
+			continue
+		}
+		edges = append(edges, spfEdge{nbr.Nodeid, nbr.Metric, srlgs[nbr.Nodeid], nbr.TE})
+	}
+	return edges
+}
+
+// spfQueue is the TENT list ordered by distance. Pseudo-nodes are ordered
+// first so that equal cost paths through a LAN are found.
+type spfQueue []*spfNode
+
+func (q spfQueue) Len() int { return len(q) }
+
+func (q spfQueue) Less(i, j int) bool {
+	if q[i].dist != q[j].dist {
+		return q[i].dist < q[j].dist
+	}
+	return q[i].isPN() && !q[j].isPN()
+}
+
+func (q spfQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *spfQueue) Push(x interface{}) {
+	n := x.(*spfNode)
+	n.index = len(*q)
+	*q = append(*q, n)
+}
+
+func (q *spfQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	n.index = -1
+	*q = old[:len(old)-1]
+	return n
+}
+
+// mergeNextHops returns the union of two sets of next hops.
+func mergeNextHops(a, b []nexthop) []nexthop {
+	rv := make([]nexthop, 0, len(a)+len(b))
+	rv = append(rv, a...)
+	for _, nh := range b {
+		found := false
+		for _, onh := range a {
+			if onh.c == nh.c && onh.sysid == nh.sysid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			rv = append(rv, nh)
+		}
+	}
+	return rv
+}
+
+// scheduleSPF arranges for a full SPF to run after the SPF back-off delay.
+func (db *DB) scheduleSPF() {
+	db.scheduleSPFType(spfFull, nil)
+}
+
+// scheduleSPFType arranges for the SPF calculation stype required by the
+// change of lsp, nil if not an LSP change, to run after the SPF back-off
+// (RFC8405) delay.
+func (db *DB) scheduleSPFType(stype spfType, lsp *lspSegment) {
+	if stype == spfNone {
+		return
+	}
+	db.spfPend.add(stype, lsp)
+	delay, start := db.spfDelay.Event()
+	if !start {
+		return
+	}
+	db.spfWait = time.AfterFunc(delay, func() { db.spfC <- true })
+}
+
+// handleSpfC runs the scheduled SPF.
+func (db *DB) handleSpfC() {
+	db.spfWait = nil
+	db.spfDelay.SPFStarted()
+	db.runSPF()
+}
+
+// spfGraph builds the SPF graph of topology mtid from the LSP DB. Nodes
+// without a valid segment 0 are not included.
+func (db *DB) spfGraph(mtid uint16) map[clns.NodeID]*spfNode {
+	nodes := make(map[clns.NodeID]*spfNode)
+	for it := db.db.Iterator(); it.HasNext(); {
+		dbnode, _ := it.Next()
+		lsp := dbnode.Value().(*lspSegment)
+		if lsp.tlvs == nil || lsp.seqNo() == 0 || lsp.checkLifetime() == 0 {
+			continue
+		}
+		var nodeid clns.NodeID
+		copy(nodeid[:], lsp.lspid[:clns.NodeIDLen])
+		n := nodes[nodeid]
+		if n == nil {
+			// Segments are iterated in order, segment 0 is first.
+			if lsp.lspid[clns.NodeIDLen] != 0 {
+				continue
+			}
+			n = &spfNode{
+				nodeid: nodeid,
+				flags:  lsp.flags(),
+				dist:   maxDist,
+				index:  -1,
+			}
+			nodes[nodeid] = n
+		}
+		n.segs = append(n.segs, lsp)
+	}
+	for _, n := range nodes {
+		n.flags = n.mtFlags(mtid)
+		n.edges = n.isReach(mtid)
+	}
+	return nodes
+}
+
+// pnCircuit returns the circuit on which the pseudo-node is our LAN ID.
+func (db *DB) pnCircuit(lanid clns.NodeID) Circuit {
+	for _, di := range db.dis {
+		if di.lanid == lanid {
+			return di.c
+		}
+	}
+	return nil
+}
+
+// nbrNextHops returns the next hops to neighbor sysid in topology mtid on
+// circuit c, or on any circuit if c is nil.
+func (db *DB) nbrNextHops(c Circuit, sysid clns.SystemID, mtid uint16) []nexthop {
+	var nhs []nexthop
+	for name, nbrs := range db.nbrs {
+		if c != nil && c.Name() != name {
+			continue
+		}
+		nc := db.circuits[name]
+		if nbr, ok := nbrs[sysid]; ok && nc != nil && inTopology(nc.Topologies(), mtid) && inTopology(nbr.MTIDs, mtid) {
+			nhs = append(nhs, nexthop{nc, sysid})
+		}
+	}
+	return nhs
+}
+
+// spfNextHops returns the next hops to use for v when reached through u.
+func (db *DB) spfNextHops(root, u, v *spfNode, mtid uint16) []nexthop {
+	if u == root {
+		if v.isPN() {
+			return nil
+		}
+		return db.nbrNextHops(nil, v.sysid(), mtid)
+	}
+	if u.direct != nil {
+		return mergeNextHops(u.nexthops, db.nbrNextHops(u.direct, v.sysid(), mtid))
+	}
+	return u.nexthops
+}
+
+// runSPF computes the shortest path tree from our LSP DB and the routes. The
+// tree of the previous run is kept for partial route calculations and updated
+// by an incremental SPF when possible, otherwise it is recomputed. The other
+// topologies and the flexible algorithms always run a full SPF.
+// nolint: gocyclo
+func (db *DB) runSPF() {
+	start := time.Now()
+	pend := db.spfPend
+	db.spfPend = spfPending{}
+
+	var rootid clns.NodeID
+	copy(rootid[:], db.sysid[:])
+	stype := pend.stype
+	nodes := db.spfNodes
+	root := nodes[rootid]
+	oroot, orib := root, db.rib
+	if root != nil && stype == spfIncremental {
+		// The previous tree is kept for the microloop avoidance.
+		nodes = cloneTree(nodes)
+		root = nodes[rootid]
+		if !db.incrementalSPF(root, nodes, pend.changed) {
+			stype = spfFull
+		}
+	}
+	if root == nil || stype == spfNone || stype == spfFull {
+		stype = spfFull
+		nodes = db.spfGraph(tlv.MTIDStandard)
+		root = nodes[rootid]
+	}
+	if root == nil {
+		Debug(DbgFSPF, "%s: No own LSP, skipping SPF", db)
+		db.spfNodes = nodes
+		db.rib = make(map[string]*Route)
+		db.lfib = make(map[uint32]*LabelRoute)
+		db.conflicts = nil
+		db.flexAlgos = nil
+		db.frr = nil
+		db.mtRIBs = nil
+		db.uloop.down = nil
+		db.stopULoop()
+		db.notifyLFIB()
+		db.srv6Routes, db.localSIDs = nil, nil
+		db.notifySRv6()
+		return
+	}
+
+	if stype == spfFull {
+		db.dijkstra(root, nodes, tlv.MTIDStandard)
+	}
+
+	onodes := db.spfNodes
+	db.spfNodes = nodes
+	db.rib = db.spfRoutes(root, nodes)
+	db.lfib, db.conflicts = db.srRoutes(root, nodes, db.rib)
+	db.frr = db.frrRoutes(root, nodes, db.rib)
+	db.flexAlgos = db.flexAlgoSPF(root, nodes)
+	db.srv6Routes = db.locatorRoutes(root, nodes, db.rib)
+	db.localSIDs = db.srv6LocalSIDs()
+	db.uloopRoutes(oroot, onodes, orib)
+	db.applyULoop(root, nodes)
+	db.notifyLFIB()
+	db.notifySRv6()
+	db.mtRIBs = db.mtSPF(rootid)
+	db.spfCount++
+	db.spfLast = time.Now()
+
+	Debug(DbgFSPF, "%s: %s SPF complete %d nodes %d routes in %s", db,
+		stype, len(nodes), len(db.rib), time.Since(start))
+	db.logSPF(stype, &pend, start)
+
+	db.spfComplete(root, nodes)
+}
+
+// dijkstra computes the shortest path tree rooted at root over the graph of
+// topology mtid.
+func (db *DB) dijkstra(root *spfNode, nodes map[clns.NodeID]*spfNode, mtid uint16) {
+	root.dist = 0
+	tent := spfQueue{}
+	heap.Push(&tent, root)
+	for tent.Len() > 0 {
+		u := heap.Pop(&tent).(*spfNode)
+		u.done = true
+
+		// Overloaded IS are not used for transit.
+		if u != root && !u.isPN() && u.flags&clns.LSPFOverload != 0 {
+			continue
+		}
+
+		for _, e := range u.edges {
+			v := nodes[e.nodeid]
+			if v == nil || v.done || !v.hasEdge(u.nodeid) {
+				continue
+			}
+			d := u.dist + e.metric
+			if d > v.dist {
+				continue
+			}
+			if u == root && v.isPN() {
+				v.direct = db.pnCircuit(v.nodeid)
+			}
+			nhs := db.spfNextHops(root, u, v, mtid)
+			if d < v.dist {
+				v.dist = d
+				v.parents = []*spfNode{u}
+				v.nexthops = nhs
+			} else {
+				v.parents = append(v.parents, u)
+				v.nexthops = mergeNextHops(v.nexthops, nhs)
+			}
+			if v.index < 0 {
+				heap.Push(&tent, v)
+			} else {
+				heap.Fix(&tent, v.index)
+			}
+		}
+	}
+}
+
+// resolveNextHops converts SPF next hops into address family specific route
+// next hops.
+func (db *DB) resolveNextHops(nhs []nexthop, ipv4 bool) []NextHop {
+	rv := make([]NextHop, 0, len(nhs))
+	for _, nh := range nhs {
+		rnh := NextHop{Intf: nh.c.Name(), Sysid: nh.sysid}
+		if nbr, ok := db.nbrs[nh.c.Name()][nh.sysid]; ok {
+			if ipv4 && len(nbr.V4Addrs) > 0 {
+				rnh.Addr = nbr.V4Addrs[0]
+			} else if !ipv4 && len(nbr.V6Addrs) > 0 {
+				rnh.Addr = nbr.V6Addrs[0]
+			}
+		}
+		rv = append(rv, rnh)
+	}
+	return rv
+}
+
+// addRoute adds or updates a route in the RIB keeping the best metric and
+// merging the next hops of equal cost routes.
+func (db *DB) addRoute(rib map[string]*Route, prefix *net.IPNet, metric uint32, updown bool, n *spfNode) {
+	if len(n.nexthops) == 0 {
+		return
+	}
+	key := prefix.String()
+	rtype := routeType(updown)
+	r := rib[key]
+	// Intra-area routes are preferred over inter-area (leaked) routes.
+	if r != nil && ((r.Type == RouteIntraArea && updown) || (r.Type == rtype && r.Metric < metric)) {
+		return
+	}
+	nhs := db.resolveNextHops(n.nexthops, prefix.IP.To4() != nil)
+	if r == nil || r.Type != rtype || r.Metric > metric {
+		rib[key] = &Route{
+			Prefix:   tlv.IPPrefix(*prefix),
+			Metric:   metric,
+			Level:    db.li.ToLevel(),
+			Type:     rtype,
+			NextHops: nhs,
+		}
+		return
+	}
+	for _, nh := range nhs {
+		found := false
+		for _, onh := range r.NextHops {
+			if onh.Intf == nh.Intf && onh.Sysid == nh.Sysid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			r.NextHops = append(r.NextHops, nh)
+		}
+	}
+}
+
+// nodePrefixInfo calls F for each IP prefix advertised by the node.
+func (n *spfNode) nodePrefixInfo(F func(pfx *tlv.IPPrefixCommon)) {
+	for _, t := range n.tlvs(tlv.TypeExtIPv4Prefix) {
+		pfxs, err := t.IPv4PrefixDecode()
+		if err != nil {
+			Debug(DbgFSPF, "%s: bad IPv4 prefix TLV: %s", n.nodeid, err)
+			continue
+		}
+		for i := range pfxs {
+			F(&pfxs[i].IPPrefixCommon)
+		}
+	}
+	for _, t := range n.tlvs(tlv.TypeIPv6Prefix) {
+		pfxs, err := t.IPv6PrefixDecode()
+		if err != nil {
+			Debug(DbgFSPF, "%s: bad IPv6 prefix TLV: %s", n.nodeid, err)
+			continue
+		}
+		for i := range pfxs {
+			F(&pfxs[i].IPPrefixCommon)
+		}
+	}
+}
+
+// spfRoutes computes the routes from the shortest path tree.
+func (db *DB) spfRoutes(root *spfNode, nodes map[clns.NodeID]*spfNode) map[string]*Route {
+	rib := make(map[string]*Route)
+	for _, n := range nodes {
+		if n == root || n.isPN() || !n.reached() {
+			continue
+		}
+		n.nodePrefixInfo(func(pfx *tlv.IPPrefixCommon) {
+			if pfx.Metric > tlv.ExtIPMaxMetric {
+				return
+			}
+			db.addRoute(rib, (*net.IPNet)(&pfx.Prefix), n.dist+pfx.Metric, pfx.Updown, n)
+		})
+	}
+	db.addDefaultRoutes(rib, root, nodes)
+	return rib
+}
+
+// sortedRoutes returns the routes of the RIB sorted by prefix.
+func sortedRoutes(rib map[string]*Route) []*Route {
+	routes := make([]*Route, 0, len(rib))
+	for _, r := range rib {
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		a, b := routes[i].Prefix, routes[j].Prefix
+		if c := bytes.Compare(a.IP, b.IP); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(a.Mask, b.Mask) < 0
+	})
+	return routes
+}
+
+// LocalRIB arranges for the routes computed by the decision process to be
+// returned.
+func (db *DB) LocalRIB() ([]*Route, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		routes := sortedRoutes(db.rib)
+		for _, mtid := range db.mtids {
+			routes = append(routes, sortedRoutes(db.mtRIBs[mtid])...)
+		}
+		return routes
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.([]*Route), nil
+}
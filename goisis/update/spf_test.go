@@ -0,0 +1,329 @@
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/pkt"
+	xtime "github.com/choppsv1/goisis/time"
+	"github.com/choppsv1/goisis/tlv"
+	"github.com/plar/go-adaptive-radix-tree"
+	"net"
+	"testing"
+)
+
+// testCircuit is a point-to-point circuit of router 1 of a test topology.
+type testCircuit struct {
+	name    string
+	mtids   []uint16
+	frr     FRRPolicy
+	passive bool
+	addrs   []net.IPNet
+	mtu     uint
+}
+
+func (c *testCircuit) Adjacencies(C chan<- interface{}, li clns.Lindex, pn bool) {}
+func (c *testCircuit) ChgFlag(SxxFlag, *clns.LSPID, bool, clns.Lindex)           {}
+func (c *testCircuit) CID(li clns.Lindex) uint8                                  { return 0 }
+func (c *testCircuit) IsP2P() bool                                               { return true }
+func (c *testCircuit) IsPassive() bool                                           { return c.passive }
+func (c *testCircuit) Name() string                                              { return c.name }
+func (c *testCircuit) Send(pdu []byte, li clns.Lindex)                           {}
+func (c *testCircuit) Topologies() []uint16                                      { return c.mtids }
+func (c *testCircuit) FRR(li clns.Lindex) FRRPolicy                              { return c.frr }
+
+func (c *testCircuit) Addrs(v4, linklocal bool) []net.IPNet {
+	var addrs []net.IPNet
+	for _, a := range c.addrs {
+		if (a.IP.To4() != nil) == v4 {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
+
+func (c *testCircuit) IPReach(v4 bool, C chan<- interface{}, li clns.Lindex) {
+	go func() {
+		for _, a := range c.Addrs(v4, false) {
+			C <- tlv.IPInfo{Metric: clns.DefExtIPMetric, Ipnet: a}
+		}
+		C <- tlv.Done{}
+	}()
+}
+
+func (c *testCircuit) MTU() uint {
+	if c.mtu == 0 {
+		return 1500
+	}
+	return c.mtu
+}
+
+// testLink is a bidirectional point-to-point link between two routers.
+type testLink struct {
+	a, b   byte
+	metric uint32
+}
+
+// testNode returns the node ID of router n.
+func testNode(n byte) clns.NodeID {
+	return clns.NodeID{0, 0, 0, 0, 0, n, 0}
+}
+
+// testSysID returns the system ID of router n.
+func testSysID(n byte) clns.SystemID {
+	return clns.SystemID{0, 0, 0, 0, 0, n}
+}
+
+// testIntf returns the name of the circuit of router 1 to router n.
+func testIntf(n byte) string {
+	return "eth" + string('0'+n)
+}
+
+// testAddr returns the interface address of router n on its link to router 1.
+func testAddr(n byte) net.IP {
+	return net.IPv4(10, 0, n, n).To4()
+}
+
+// putUint24 appends a 3 byte value.
+func putUint24(b []byte, v uint32) []byte {
+	return append(b, byte(v>>16), byte(v>>8), byte(v))
+}
+
+// isReach returns an Extended IS Reachability TLV with the neighbor.
+func isReach(nbr byte, metric uint32, subtlvs ...byte) []byte {
+	nodeid := testNode(nbr)
+	b := append([]byte{byte(tlv.TypeExtIsReach), 0}, nodeid[:]...)
+	b = putUint24(b, metric)
+	b = append(append(b, byte(len(subtlvs))), subtlvs...)
+	b[1] = byte(len(b) - 2)
+	return b
+}
+
+// ipReach returns an Extended IP Reachability TLV with the IPv4 prefix.
+func ipReach(prefix string, metric uint32) []byte {
+	_, ipnet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		panic(err)
+	}
+	plen, _ := ipnet.Mask.Size()
+	b := []byte{byte(tlv.TypeExtIPv4Prefix), 0, byte(metric >> 24), byte(metric >> 16), byte(metric >> 8), byte(metric), byte(plen)}
+	b = append(b, ipnet.IP.To4()[:(plen+7)/8]...)
+	b[1] = byte(len(b) - 2)
+	return b
+}
+
+// adjSIDSub returns an Adj-SID sub-TLV with the IPv4 label.
+func adjSIDSub(label uint32) []byte {
+	return putUint24([]byte{tlv.SubTLVAdjSID, 5, tlv.AdjSIDFlagV | tlv.AdjSIDFlagL, 0}, label)
+}
+
+// sidReach returns an Extended IP Reachability TLV with the IPv4 prefix and a
+// node Prefix-SID with the index.
+func sidReach(prefix string, metric uint32, sid uint32) []byte {
+	b := ipReach(prefix, metric)
+	b[6] |= 0x40
+	b = append(b, 8, tlv.SubTLVPrefixSID, 6, 0x40, 0, 0, byte(sid>>16), byte(sid>>8), byte(sid))
+	b[1] = byte(len(b) - 2)
+	return b
+}
+
+// capTLVs returns the Router Capability TLVs of the capabilities.
+func capTLVs(caps ...*tlv.RouterCap) []byte {
+	var b []byte
+	bt := tlv.NewBufferTrack(1492, 0, 1, func(d tlv.Data, i uint8) error {
+		b = append(b, d...)
+		return nil
+	})
+	for _, rc := range caps {
+		if err := bt.AddRouterCap(rc); err != nil {
+			panic(err)
+		}
+	}
+	if err := bt.Close(); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// testSRGB is the SRGB of the test routers.
+var testSRGB = []tlv.SIDRange{{Range: 8000, Start: 16000}}
+
+// srCap returns the segment routing capabilities of router n with the
+// algorithms.
+func srCap(n byte, algos ...uint8) *tlv.RouterCap {
+	return &tlv.RouterCap{
+		RouterID: net.IPv4(n, n, n, n).To4(),
+		SR:       &tlv.SRCap{MPLSIPv4: true, SRGB: testSRGB},
+		SRAlgos:  algos,
+	}
+}
+
+// addLSP adds segment 0 of the LSP of the node with the TLVs to the DB.
+func addLSP(db *DB, nodeid clns.NodeID, flags clns.LSPFlags, tlvs ...[]byte) *lspSegment {
+	var tlvb []byte
+	for _, t := range tlvs {
+		tlvb = append(tlvb, t...)
+	}
+	hdr := make([]byte, clns.HdrLSPSize)
+	copy(hdr[clns.HdrLSPLSPID:], nodeid[:])
+	pkt.PutUInt32(hdr[clns.HdrLSPSeqNo:], 1)
+	pkt.PutUInt16(hdr[clns.HdrLSPLifetime:], 1200)
+	hdr[clns.HdrLSPFlags] = byte(flags) | byte(clns.L1Flag)
+	m, err := tlv.Data(tlvb).ParseTLV()
+	if err != nil {
+		panic(err)
+	}
+	lsp := &lspSegment{hdr: hdr, tlvs: m, caps: decodeRouterCaps(m), life: xtime.NewHoldTimer(1200, func() {})}
+	copy(lsp.lspid[:], hdr[clns.HdrLSPLSPID:])
+	db.db.Insert(lsp.lspid[:], lsp)
+	return lsp
+}
+
+// setFlags sets the LSP flags of segment 0 of the LSP of router n.
+func setFlags(db *DB, n byte, flags clns.LSPFlags) {
+	lspid := clns.MakeLSPID(testSysID(n), 0, 0)
+	db.get(lspid[:]).hdr[clns.HdrLSPFlags] |= byte(flags)
+}
+
+// setLSP replaces the TLVs of segment 0 of the LSP of router n with a new
+// sequence number and schedules the SPF for the change.
+func setLSP(db *DB, n byte, tlvs ...[]byte) spfType {
+	var tlvb []byte
+	for _, t := range tlvs {
+		tlvb = append(tlvb, t...)
+	}
+	lspid := clns.MakeLSPID(testSysID(n), 0, 0)
+	lsp := db.get(lspid[:])
+	ohdr := append([]byte(nil), lsp.hdr...)
+	otlvs := lsp.tlvs
+	m, err := tlv.Data(tlvb).ParseTLV()
+	if err != nil {
+		panic(err)
+	}
+	lsp.tlvs = m
+	lsp.caps = decodeRouterCaps(m)
+	pkt.PutUInt32(lsp.hdr[clns.HdrLSPSeqNo:], lsp.seqNo()+1)
+	stype := lsp.lspChange(ohdr, otlvs)
+	db.spfPend.add(stype, lsp)
+	return stype
+}
+
+// linkTLVs returns the IS reachability TLVs of router n over the links.
+func linkTLVs(n byte, links []testLink) [][]byte {
+	var tlvs [][]byte
+	for _, l := range links {
+		if l.a == n {
+			tlvs = append(tlvs, isReach(l.b, l.metric))
+		} else if l.b == n {
+			tlvs = append(tlvs, isReach(l.a, l.metric))
+		}
+	}
+	return tlvs
+}
+
+// newTestDB returns the level 2 DB of router 1 with the LSPs of the routers of
+// the links, followed by their extra TLVs. Router 1 has a circuit and an
+// adjacency to each of its neighbors.
+func newTestDB(links []testLink, extra map[byte][][]byte) *DB {
+	db := &DB{
+		sysid:    testSysID(1),
+		istype:   clns.L2Flag,
+		li:       1,
+		db:       art.New(),
+		circuits: make(map[string]Circuit),
+		dis:      make(map[uint8]disInfo),
+		nbrs:     make(map[string]map[clns.SystemID]Neighbor),
+	}
+	routers := make(map[byte]bool)
+	for _, l := range links {
+		routers[l.a], routers[l.b] = true, true
+		n := l.b
+		if l.b == 1 {
+			n = l.a
+		} else if l.a != 1 {
+			continue
+		}
+		name := testIntf(n)
+		db.circuits[name] = &testCircuit{name: name}
+		db.nbrs[name] = map[clns.SystemID]Neighbor{
+			testSysID(n): {Sysid: testSysID(n), V4Addrs: []net.IP{testAddr(n)}},
+		}
+	}
+	for n := range routers {
+		addLSP(db, testNode(n), 0, append(linkTLVs(n, links), extra[n]...)...)
+	}
+	return db
+}
+
+func TestSPFRoutes(t *testing.T) {
+	// Router 4 is reached through 2 (10+10) and through 3 (5+15).
+	links := []testLink{{1, 2, 10}, {2, 4, 10}, {1, 3, 5}, {3, 4, 15}}
+	db := newTestDB(links, map[byte][][]byte{
+		4: {ipReach("10.4.0.0/16", 1)},
+		3: {ipReach("10.3.0.0/16", 1)},
+	})
+	db.runSPF()
+	r := db.rib["10.4.0.0/16"]
+	if r == nil || r.Metric != 21 || len(r.NextHops) != 2 {
+		t.Fatalf("Bad ECMP route %+v", r)
+	}
+	for _, nh := range r.NextHops {
+		n := nh.Sysid[5]
+		if (n != 2 && n != 3) || nh.Intf != testIntf(n) || !nh.Addr.Equal(testAddr(n)) {
+			t.Errorf("Bad next hop %+v", nh)
+		}
+	}
+	if r := db.rib["10.3.0.0/16"]; r == nil || r.Metric != 6 || len(r.NextHops) != 1 {
+		t.Errorf("Bad route %+v", r)
+	}
+
+	// An overloaded router is not used for transit.
+	setFlags(db, 3, clns.LSPFOverload)
+	db.runSPF()
+	if r := db.rib["10.4.0.0/16"]; r == nil || r.Metric != 21 || len(r.NextHops) != 1 || r.NextHops[0].Intf != testIntf(2) {
+		t.Errorf("Bad route with overload %+v", r)
+	}
+	if r := db.rib["10.3.0.0/16"]; r == nil || r.Metric != 6 {
+		t.Errorf("Bad route to overloaded router %+v", r)
+	}
+}
+
+func TestDefaultRoutes(t *testing.T) {
+	// Routers 3 and 4 are attached, 4 is the nearest.
+	links := []testLink{{1, 2, 10}, {1, 3, 20}, {2, 4, 5}}
+	tests := []struct {
+		name     string
+		istype   clns.LevelFlag
+		ignore   bool
+		overload bool
+		nearest  byte
+		metric   uint32
+	}{
+		{"nearest", clns.L1Flag, false, false, 4, 15},
+		{"overloaded", clns.L1Flag, false, true, 3, 20},
+		{"ignored", clns.L1Flag, true, false, 0, 0},
+		{"level 1 and 2", clns.L1Flag | clns.L2Flag, false, false, 0, 0},
+	}
+	for _, test := range tests {
+		db := newTestDB(links, nil)
+		db.li, db.istype, db.att.ignore = 0, test.istype, test.ignore
+		setFlags(db, 3, clns.LSPFMetDef)
+		setFlags(db, 4, clns.LSPFMetDef)
+		if test.overload {
+			setFlags(db, 4, clns.LSPFOverload)
+		}
+		db.runSPF()
+		r4, r6 := db.rib[defaultIPv4.String()], db.rib[defaultIPv6.String()]
+		if test.nearest == 0 {
+			if r4 != nil || r6 != nil || db.att.nearest != nil {
+				t.Errorf("%s: unexpected default routes %+v %+v", test.name, r4, r6)
+			}
+			continue
+		}
+		if r4 == nil || r6 == nil || r4.Metric != test.metric || r6.Metric != test.metric {
+			t.Errorf("%s: bad default routes %+v %+v", test.name, r4, r6)
+			continue
+		}
+		if len(db.att.nearest) != 1 || db.att.nearest[0] != testSysID(test.nearest) {
+			t.Errorf("%s: bad nearest attached %v", test.name, db.att.nearest)
+		}
+	}
+}
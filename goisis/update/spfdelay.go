@@ -0,0 +1,84 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the SPF back-off (RFC8405) configuration and state.
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	xtime "github.com/choppsv1/goisis/time"
+	"time"
+)
+
+// Default SPF back-off timer values (RFC8405 section 6).
+const (
+	DefSPFInitialDelay = 50 * time.Millisecond
+	DefSPFShortDelay   = 200 * time.Millisecond
+	DefSPFLongDelay    = 5000 * time.Millisecond
+	DefSPFHoldDown     = 10000 * time.Millisecond
+	DefSPFTimeToLearn  = 500 * time.Millisecond
+)
+
+// YangSPFDelay is the SPF back-off configuration and state of a level for the
+// yang model, durations are in milliseconds.
+type YangSPFDelay struct {
+	Level             clns.Level          `json:"level"`
+	InitialDelay      uint32              `json:"initial-delay"`
+	ShortDelay        uint32              `json:"short-delay"`
+	LongDelay         uint32              `json:"long-delay"`
+	HoldDown          uint32              `json:"hold-down"`
+	TimeToLearn       uint32              `json:"time-to-learn"`
+	State             xtime.SPFDelayState `json:"current-state"`
+	RemainingLearn    uint32              `json:"remaining-time-to-learn"`
+	RemainingHoldDown uint32              `json:"remaining-hold-down"`
+	LastEvent         *time.Time          `json:"last-event-received,omitempty"`
+	NextSPF           *time.Time          `json:"next-spf-time,omitempty"`
+	LastSPF           *time.Time          `json:"last-spf-time,omitempty"`
+}
+
+func msec(d time.Duration) uint32 {
+	return uint32(d / time.Millisecond)
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// SetSPFDelayTimers sets the SPF back-off timer values.
+func (db *DB) SetSPFDelayTimers(initial, short, long, holddown, learn time.Duration) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.spfDelay.Set(initial, short, long, holddown, learn)
+		return nil
+	})
+}
+
+// SPFDelay arranges for the SPF back-off state to be returned.
+func (db *DB) SPFDelay() (*YangSPFDelay, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		d := &db.spfDelay
+		learn, holddown := d.Remaining()
+		return &YangSPFDelay{
+			Level:             db.li.ToLevel(),
+			InitialDelay:      msec(d.Initial),
+			ShortDelay:        msec(d.Short),
+			LongDelay:         msec(d.Long),
+			HoldDown:          msec(d.HoldDown),
+			TimeToLearn:       msec(d.TimeToLearn),
+			State:             d.State(),
+			RemainingLearn:    msec(learn),
+			RemainingHoldDown: msec(holddown),
+			LastEvent:         timeOrNil(d.LastEvent()),
+			NextSPF:           timeOrNil(d.NextSPF()),
+			LastSPF:           timeOrNil(d.LastSPF()),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.(*YangSPFDelay), nil
+}
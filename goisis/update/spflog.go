@@ -0,0 +1,160 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the SPF types, the pending SPF state and the SPF log.
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"time"
+)
+
+// SPF types recorded in the SPF log.
+const (
+	SPFFull        = "full"
+	SPFIncremental = "incremental"
+	SPFRouteOnly   = "route-only"
+)
+
+// spfType is the SPF calculation required by the LSP DB changes, ordered by
+// cost.
+type spfType int
+
+const (
+	spfNone spfType = iota
+	spfRouteOnly
+	spfIncremental
+	spfFull
+)
+
+func (t spfType) String() string {
+	switch t {
+	case spfRouteOnly:
+		return SPFRouteOnly
+	case spfIncremental:
+		return SPFIncremental
+	case spfFull:
+		return SPFFull
+	}
+	return "none"
+}
+
+func maxSPFType(a, b spfType) spfType {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// SPFLogSize is the number of entries kept in the SPF log ring.
+const SPFLogSize = 32
+
+// SPFLogMaxTriggers is the maximum number of trigger LSPs kept for an SPF.
+const SPFLogMaxTriggers = 16
+
+// YangSPFTrigger is an LSP that triggered an SPF for the yang model.
+type YangSPFTrigger struct {
+	Lspid clns.LSPID `json:"lsp"`
+	Seqno uint32     `json:"sequence"`
+}
+
+// YangSPFLog is an SPF log entry for the yang model.
+type YangSPFLog struct {
+	ID       uint32           `json:"id"`
+	Level    clns.Level       `json:"level"`
+	Type     string           `json:"spf-type"`
+	Schedule time.Time        `json:"schedule-timestamp"`
+	Start    time.Time        `json:"start-timestamp"`
+	End      time.Time        `json:"end-timestamp"`
+	Duration uint32           `json:"duration"` // microseconds
+	Triggers []YangSPFTrigger `json:"trigger-lsp,omitempty"`
+}
+
+// spfLog is a fixed size ring of SPF log entries.
+type spfLog struct {
+	ents  [SPFLogSize]YangSPFLog
+	next  int
+	count int
+}
+
+func (log *spfLog) add(ent YangSPFLog) {
+	log.ents[log.next] = ent
+	log.next = (log.next + 1) % SPFLogSize
+	if log.count < SPFLogSize {
+		log.count++
+	}
+}
+
+// entries returns a copy of the log entries newest first.
+func (log *spfLog) entries() []*YangSPFLog {
+	ents := make([]*YangSPFLog, 0, log.count)
+	for i := 1; i <= log.count; i++ {
+		ent := log.ents[(log.next-i+SPFLogSize)%SPFLogSize]
+		ents = append(ents, &ent)
+	}
+	return ents
+}
+
+// spfPending is the scheduled SPF, the calculation required by the changes
+// since the last run and the LSPs that triggered it.
+type spfPending struct {
+	stype    spfType
+	changed  map[clns.NodeID]bool // routers whose IS reachability changed
+	triggers []YangSPFTrigger
+	sched    time.Time
+}
+
+// add records a change requiring stype, lsp is the changed LSP segment or nil
+// if not triggered by an LSP.
+func (p *spfPending) add(stype spfType, lsp *lspSegment) {
+	if p.sched.IsZero() {
+		p.sched = time.Now()
+	}
+	p.stype = maxSPFType(p.stype, stype)
+	if lsp == nil {
+		return
+	}
+	if stype == spfIncremental {
+		if p.changed == nil {
+			p.changed = make(map[clns.NodeID]bool)
+		}
+		var nodeid clns.NodeID
+		copy(nodeid[:], lsp.lspid[:clns.NodeIDLen])
+		p.changed[nodeid] = true
+	}
+	if len(p.triggers) < SPFLogMaxTriggers {
+		p.triggers = append(p.triggers, YangSPFTrigger{lsp.lspid, lsp.seqNo()})
+	}
+}
+
+// logSPF records an SPF run in the SPF log.
+func (db *DB) logSPF(stype spfType, pend *spfPending, start time.Time) {
+	end := time.Now()
+	ent := YangSPFLog{
+		ID:       db.spfCount,
+		Level:    db.li.ToLevel(),
+		Type:     stype.String(),
+		Schedule: pend.sched,
+		Start:    start,
+		End:      end,
+		Duration: uint32(end.Sub(start) / time.Microsecond),
+		Triggers: pend.triggers,
+	}
+	if ent.Schedule.IsZero() {
+		ent.Schedule = start
+	}
+	Debug(DbgFSPF, "%s: %s SPF %d triggers in %s", db, stype, len(pend.triggers), end.Sub(start))
+	db.spflog.add(ent)
+}
+
+// SPFLog arranges for the SPF log entries to be returned.
+func (db *DB) SPFLog() ([]*YangSPFLog, error) {
+	i, err := DoRPC(db.rpC, func() interface{} { return db.spflog.entries() })
+	if err != nil {
+		return nil, err
+	}
+	return i.([]*YangSPFLog), nil
+}
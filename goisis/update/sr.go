@@ -0,0 +1,356 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the segment routing MPLS (RFC8667) part of the decision
+// process, the labelled next hops of the routes, the label forwarding table
+// and the resolution of prefix SID conflicts.
+package update
+
+import (
+	"bytes"
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"sort"
+)
+
+// Label forwarding table entry types.
+const (
+	LabelTypePrefixSID = "prefix-sid"
+	LabelTypeAdjSID    = "adj-sid"
+)
+
+// Prefix SID conflict types.
+const (
+	SIDConflictPrefix = "prefix-conflict" // the prefix has different SIDs
+	SIDConflictSID    = "sid-conflict"    // the SID is used for different prefixes
+	SIDOutOfRange     = "out-of-range"    // the SID is outside our SRGB
+)
+
+// LabelRoute is an MPLS label forwarding table entry computed by the decision
+// process. The next hop labels replace the incoming label, the implicit null
+// label pops it.
+type LabelRoute struct {
+	Label    uint32        `json:"in-label"`
+	Type     string        `json:"type"`
+	Prefix   *tlv.IPPrefix `json:"prefix,omitempty"`
+	Level    clns.Level    `json:"level"`
+	NextHops []NextHop     `json:"next-hops"`
+}
+
+// YangSIDConflict is a prefix SID advertisement not used due to a conflict.
+type YangSIDConflict struct {
+	Level     clns.Level    `json:"level"`
+	Algo      uint8         `json:"algorithm"`
+	Type      string        `json:"conflict-type"`
+	Prefix    tlv.IPPrefix  `json:"prefix"`
+	SID       uint32        `json:"sid"`
+	Sysid     clns.SystemID `json:"originator"`
+	PrefPfx   *tlv.IPPrefix `json:"preferred-prefix,omitempty"`
+	PrefIndex *uint32       `json:"preferred-sid,omitempty"`
+}
+
+// prefixSID is a Prefix-SID of a prefix advertised by a node.
+type prefixSID struct {
+	prefix net.IPNet
+	sid    tlv.PrefixSID
+	sysid  clns.SystemID
+}
+
+// readvertisedSIDs returns the Prefix-SIDs to advertise with a prefix
+// propagated from another level, with the R and P flags set and the E flag
+// cleared (RFC8667 section 2.1).
+func readvertisedSIDs(sids []tlv.PrefixSID) []tlv.PrefixSID {
+	var out []tlv.PrefixSID
+	for _, ps := range sids {
+		ps.Readvertised = true
+		ps.NoPHP = true
+		ps.ExplicitNull = false
+		out = append(out, ps)
+	}
+	return out
+}
+
+// srgb returns our SRGB, nil if segment routing is not enabled.
+func (db *DB) srgb() []tlv.SIDRange {
+	if db.routerCap == nil || db.routerCap.SR == nil {
+		return nil
+	}
+	return db.routerCap.SR.SRGB
+}
+
+// nodeSRGB returns the SRGB advertised by the router, nil if it doesn't
+// support segment routing.
+func (db *DB) nodeSRGB(sysid clns.SystemID) []tlv.SIDRange {
+	for _, rc := range db.nodeRouterCaps(sysid) {
+		if rc.SR != nil && !rc.Down {
+			return rc.SR.SRGB
+		}
+	}
+	return nil
+}
+
+// prefixSIDs returns the Prefix-SIDs of algorithm algo that are SRGB indices
+// advertised by the reachable nodes.
+func prefixSIDs(root *spfNode, nodes map[clns.NodeID]*spfNode, algo uint8) []prefixSID {
+	var sids []prefixSID
+	for _, n := range nodes {
+		if n.isPN() || (n != root && !n.reached()) {
+			continue
+		}
+		sysid := n.sysid()
+		n.nodePrefixInfo(func(pfx *tlv.IPPrefixCommon) {
+			for _, ps := range pfx.SIDs {
+				if ps.Algo != algo || ps.Value || ps.Local {
+					continue
+				}
+				sids = append(sids, prefixSID{*(*net.IPNet)(&pfx.Prefix), ps, sysid})
+			}
+		})
+	}
+	// Sort for stable conflict resolution and reporting.
+	sort.Slice(sids, func(i, j int) bool {
+		a, b := &sids[i], &sids[j]
+		if c := comparePrefix(&a.prefix, &b.prefix); c != 0 {
+			return c < 0
+		}
+		if a.sid.SID != b.sid.SID {
+			return a.sid.SID < b.sid.SID
+		}
+		return bytes.Compare(a.sysid[:], b.sysid[:]) < 0
+	})
+	return sids
+}
+
+// comparePrefix orders prefixes longest first then by address.
+func comparePrefix(a, b *net.IPNet) int {
+	alen, _ := a.Mask.Size()
+	blen, _ := b.Mask.Size()
+	if alen != blen {
+		return blen - alen
+	}
+	if len(a.IP) != len(b.IP) {
+		return len(a.IP) - len(b.IP)
+	}
+	return bytes.Compare(a.IP, b.IP)
+}
+
+// resolveSIDs resolves the Prefix-SID conflicts. A prefix with different SIDs
+// uses the lowest SID, a SID used for different prefixes is used for the
+// longest then lowest prefix. The returned SIDs are by prefix, each prefix
+// may have several advertisements (anycast) of the same SID.
+func (db *DB) resolveSIDs(sids []prefixSID) (map[string][]prefixSID, []*YangSIDConflict) {
+	var conflicts []*YangSIDConflict
+	conflict := func(typ string, ps *prefixSID, pref *prefixSID) {
+		yc := &YangSIDConflict{
+			Level:  db.li.ToLevel(),
+			Type:   typ,
+			Algo:   ps.sid.Algo,
+			Prefix: tlv.IPPrefix(ps.prefix),
+			SID:    ps.sid.SID,
+			Sysid:  ps.sysid,
+		}
+		if pref != nil && typ == SIDConflictPrefix {
+			index := pref.sid.SID
+			yc.PrefIndex = &index
+		} else if pref != nil {
+			p := tlv.IPPrefix(pref.prefix)
+			yc.PrefPfx = &p
+		}
+		conflicts = append(conflicts, yc)
+	}
+
+	// Prefix conflicts, sids is sorted by prefix then SID.
+	byPrefix := make(map[string][]prefixSID)
+	for i := range sids {
+		ps := &sids[i]
+		key := ps.prefix.String()
+		if cur := byPrefix[key]; len(cur) != 0 && cur[0].sid.SID != ps.sid.SID {
+			conflict(SIDConflictPrefix, ps, &cur[0])
+			continue
+		}
+		byPrefix[key] = append(byPrefix[key], *ps)
+	}
+
+	// SID conflicts, the first prefix in sorted order wins.
+	bySID := make(map[uint32]*prefixSID)
+	for i := range sids {
+		ps := &sids[i]
+		key := ps.prefix.String()
+		if pss := byPrefix[key]; len(pss) == 0 || pss[0].sid.SID != ps.sid.SID {
+			continue
+		}
+		if pref := bySID[ps.sid.SID]; pref != nil && !samePrefix(&pref.prefix, &ps.prefix) {
+			conflict(SIDConflictSID, ps, pref)
+			delete(byPrefix, key)
+			continue
+		}
+		bySID[ps.sid.SID] = ps
+	}
+
+	// Out of range of our SRGB.
+	srgb := db.srgb()
+	for key, pss := range byPrefix {
+		if _, ok := tlv.SRGBLabel(srgb, pss[0].sid.SID); !ok {
+			for i := range pss {
+				conflict(SIDOutOfRange, &pss[i], nil)
+			}
+			delete(byPrefix, key)
+		}
+	}
+	return byPrefix, conflicts
+}
+
+// outLabel returns the label to send to neighbor sysid for the prefix SID,
+// ok is false if the neighbor doesn't support segment routing or the SID is
+// outside its SRGB.
+func (db *DB) outLabel(pss []prefixSID, nbr clns.SystemID, ipv4 bool) (uint32, bool) {
+	for i := range pss {
+		ps := &pss[i]
+		if ps.sysid != nbr {
+			continue
+		}
+		// The neighbor is the originator.
+		switch {
+		case ps.sid.ExplicitNull && ipv4:
+			return tlv.LabelIPv4ExplicitNull, true
+		case ps.sid.ExplicitNull:
+			return tlv.LabelIPv6ExplicitNull, true
+		case !ps.sid.NoPHP:
+			return tlv.LabelImplicitNull, true
+		}
+	}
+	return tlv.SRGBLabel(db.nodeSRGB(nbr), pss[0].sid.SID)
+}
+
+// prefixLabels adds the label forwarding table entries of the algorithm algo
+// Prefix-SIDs to lfib and the labelled next hops to the algorithm's routes. It
+// returns the prefix SID conflicts.
+func (db *DB) prefixLabels(lfib map[uint32]*LabelRoute, root *spfNode, nodes map[clns.NodeID]*spfNode, rib map[string]*Route, algo uint8) []*YangSIDConflict {
+	srgb := db.srgb()
+	byPrefix, conflicts := db.resolveSIDs(prefixSIDs(root, nodes, algo))
+	for key, pss := range byPrefix {
+		r := rib[key]
+		if r == nil {
+			continue
+		}
+		prefix := r.Prefix
+		ipv4 := isIPv4((*net.IPNet)(&prefix))
+		in, _ := tlv.SRGBLabel(srgb, pss[0].sid.SID)
+		lr := &LabelRoute{Label: in, Type: LabelTypePrefixSID, Prefix: &prefix, Level: db.li.ToLevel()}
+		for i := range r.NextHops {
+			nh := &r.NextHops[i]
+			out, ok := db.outLabel(pss, nh.Sysid, ipv4)
+			if !ok {
+				continue
+			}
+			nh.Labels = []uint32{out}
+			if nh.Addr != nil {
+				lr.NextHops = append(lr.NextHops, *nh)
+			}
+		}
+		if len(lr.NextHops) == 0 {
+			continue
+		}
+		// The same index may be used by another algorithm.
+		if olr := lfib[in]; olr != nil {
+			conflicts = append(conflicts, &YangSIDConflict{
+				Level:   db.li.ToLevel(),
+				Algo:    algo,
+				Type:    SIDConflictSID,
+				Prefix:  prefix,
+				SID:     pss[0].sid.SID,
+				Sysid:   pss[0].sysid,
+				PrefPfx: olr.Prefix,
+			})
+			continue
+		}
+		lfib[in] = lr
+	}
+	return conflicts
+}
+
+// srRoutes adds the labelled next hops to the routes of the RIB and returns
+// the label forwarding table and prefix SID conflicts.
+func (db *DB) srRoutes(root *spfNode, nodes map[clns.NodeID]*spfNode, rib map[string]*Route) (map[uint32]*LabelRoute, []*YangSIDConflict) {
+	lfib := make(map[uint32]*LabelRoute)
+	srgb := db.srgb()
+	if srgb == nil {
+		return lfib, nil
+	}
+	conflicts := db.prefixLabels(lfib, root, nodes, rib, tlv.SRAlgoSPF)
+	for name, nbrs := range db.nbrs {
+		for _, nbr := range nbrs {
+			for _, as := range nbr.AdjSIDs {
+				nh := NextHop{Intf: name, Sysid: nbr.Sysid, Labels: []uint32{tlv.LabelImplicitNull}}
+				if as.IPv6 && len(nbr.V6Addrs) > 0 {
+					nh.Addr = nbr.V6Addrs[0]
+				} else if !as.IPv6 && len(nbr.V4Addrs) > 0 {
+					nh.Addr = nbr.V4Addrs[0]
+				} else {
+					continue
+				}
+				lfib[as.SID] = &LabelRoute{
+					Label:    as.SID,
+					Type:     LabelTypeAdjSID,
+					Level:    db.li.ToLevel(),
+					NextHops: []NextHop{nh},
+				}
+			}
+		}
+	}
+	for _, c := range conflicts {
+		Debug(DbgFSPF, "%s: prefix SID %s %d from %s: %s", db, (*net.IPNet)(&c.Prefix), c.SID, c.Sysid, c.Type)
+	}
+	return lfib, conflicts
+}
+
+// notifyLFIB lets the LFIB listener know the table was recomputed.
+func (db *DB) notifyLFIB() {
+	if db.lfibC == nil {
+		return
+	}
+	select {
+	case db.lfibC <- true:
+	default:
+	}
+}
+
+// SetLFIBNotify arranges for a value to be sent on C, without blocking, each
+// time the label forwarding table is recomputed.
+func (db *DB) SetLFIBNotify(C chan<- bool) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.lfibC = C
+		return nil
+	})
+}
+
+// LFIB arranges for the label forwarding table computed by the decision
+// process to be returned sorted by label.
+func (db *DB) LFIB() ([]*LabelRoute, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		lrs := make([]*LabelRoute, 0, len(db.lfib))
+		for _, lr := range db.lfib {
+			lrs = append(lrs, lr)
+		}
+		sort.Slice(lrs, func(i, j int) bool { return lrs[i].Label < lrs[j].Label })
+		return lrs
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.([]*LabelRoute), nil
+}
+
+// SIDConflicts arranges for the prefix SID advertisements not used due to
+// conflicts to be returned.
+func (db *DB) SIDConflicts() ([]*YangSIDConflict, error) {
+	i, err := DoRPC(db.rpC, func() interface{} { return db.conflicts })
+	if err != nil {
+		return nil, err
+	}
+	return i.([]*YangSIDConflict), nil
+}
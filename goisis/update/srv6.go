@@ -0,0 +1,151 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the segment routing over IPv6 (RFC9352) part of the
+// decision process, the routes to the remote locators and our local SIDs.
+package update
+
+import (
+	"bytes"
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+	"sort"
+)
+
+// SRv6SID is one of our SRv6 SIDs. An End SID has the locator it belongs to,
+// an End.X SID the next hop to the neighbor of the adjacency.
+type SRv6SID struct {
+	SID      net.IP        `json:"sid"`
+	Behavior uint16        `json:"endpoint-behavior"`
+	Level    clns.Level    `json:"level"`
+	Locator  *tlv.IPPrefix `json:"locator,omitempty"`
+	NextHop  *NextHop      `json:"next-hop,omitempty"`
+}
+
+// YangSRv6 is the SRv6 state of a level.
+type YangSRv6 struct {
+	Locators  []*Route   `json:"locator-routes"`
+	LocalSIDs []*SRv6SID `json:"local-sids"`
+}
+
+// srv6Locators returns the SPF algorithm locators advertised by the node in
+// the standard or IPv6 unicast topology.
+func (n *spfNode) srv6Locators() []tlv.SRv6Locator {
+	var locs []tlv.SRv6Locator
+	for _, t := range n.tlvs(tlv.TypeSRv6Locator) {
+		tlocs, err := t.SRv6LocatorDecode()
+		if err != nil {
+			Debug(DbgFSPF, "%s: bad SRv6 Locator TLV: %s", n.nodeid, err)
+			continue
+		}
+		if tlocs.MTID != tlv.MTIDStandard && tlocs.MTID != tlv.MTIDIPv6 {
+			continue
+		}
+		for _, loc := range tlocs.Locators {
+			if loc.Algo == tlv.SRAlgoSPF && loc.Metric <= tlv.ExtIPMaxMetric {
+				locs = append(locs, loc)
+			}
+		}
+	}
+	return locs
+}
+
+// locatorRoutes adds the routes to the locators of the other routers to the
+// RIB and returns them.
+func (db *DB) locatorRoutes(root *spfNode, nodes map[clns.NodeID]*spfNode, rib map[string]*Route) map[string]*Route {
+	routes := make(map[string]*Route)
+	for _, n := range nodes {
+		if n == root || n.isPN() || !n.reached() {
+			continue
+		}
+		for _, loc := range n.srv6Locators() {
+			prefix := (*net.IPNet)(&loc.Locator)
+			db.addRoute(routes, prefix, n.dist+loc.Metric, loc.Down, n)
+			db.addRoute(rib, prefix, n.dist+loc.Metric, loc.Down, n)
+		}
+	}
+	return routes
+}
+
+// srv6LocalSIDs returns the End SIDs of our locators and the End.X SIDs of our
+// adjacencies.
+func (db *DB) srv6LocalSIDs() []*SRv6SID {
+	var sids []*SRv6SID
+	for i := range db.srv6Locs {
+		loc := &db.srv6Locs[i]
+		for _, es := range loc.EndSIDs {
+			sids = append(sids, &SRv6SID{
+				SID:      es.SID,
+				Behavior: es.Behavior,
+				Level:    db.li.ToLevel(),
+				Locator:  &loc.Locator,
+			})
+		}
+	}
+	for name, nbrs := range db.nbrs {
+		for _, nbr := range nbrs {
+			if len(nbr.V6Addrs) == 0 {
+				continue
+			}
+			for _, xs := range nbr.EndXSID {
+				sids = append(sids, &SRv6SID{
+					SID:      xs.SID,
+					Behavior: xs.Behavior,
+					Level:    db.li.ToLevel(),
+					NextHop:  &NextHop{Intf: name, Addr: nbr.V6Addrs[0], Sysid: nbr.Sysid},
+				})
+			}
+		}
+	}
+	sort.Slice(sids, func(i, j int) bool { return bytes.Compare(sids[i].SID, sids[j].SID) < 0 })
+	return sids
+}
+
+// notifySRv6 lets the SRv6 listener know the SRv6 state was recomputed.
+func (db *DB) notifySRv6() {
+	if db.srv6C == nil {
+		return
+	}
+	select {
+	case db.srv6C <- true:
+	default:
+	}
+}
+
+// SetSRv6Notify arranges for a value to be sent on C, without blocking, each
+// time the SRv6 state is recomputed.
+func (db *DB) SetSRv6Notify(C chan<- bool) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.srv6C = C
+		return nil
+	})
+}
+
+// SetSRv6Locators configures the SRv6 locators, with their End SIDs, that we
+// advertise.
+func (db *DB) SetSRv6Locators(locs []tlv.SRv6Locator) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.srv6Locs = locs
+		return nil
+	})
+	db.SomethingChanged(nil, GenReasonConfig)
+}
+
+// SRv6 arranges for the routes to the remote locators and our local SIDs to
+// be returned.
+func (db *DB) SRv6() (*YangSRv6, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		return &YangSRv6{
+			Locators:  sortedRoutes(db.srv6Routes),
+			LocalSIDs: db.localSIDs,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.(*YangSRv6), nil
+}
@@ -0,0 +1,166 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the Topology Independent LFA (RFC9855) part of the
+// decision process, the post-convergence path without the protected link and
+// its encoding as a segment list.
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+)
+
+// postConvergence runs the SPF without our link on circuit c to the neighbor
+// sysid and returns the graph. On a LAN the link to the pseudo-node is
+// removed.
+func (db *DB) postConvergence(fs *frrState, c Circuit, sysid clns.SystemID) (*spfNode, map[clns.NodeID]*spfNode) {
+	graph := copyGraph(fs.nodes)
+	root := graph[fs.root.nodeid]
+	nid := sysNodeID(sysid)
+	var edges []spfEdge
+	for _, e := range root.edges {
+		v := fs.nodes[e.nodeid]
+		if v != nil && ((c.IsP2P() && e.nodeid == nid) || (v.isPN() && v.direct == c)) {
+			continue
+		}
+		edges = append(edges, e)
+	}
+	root.edges = edges
+	db.dijkstra(root, graph, tlv.MTIDStandard)
+	return root, graph
+}
+
+// pcPath returns the nodes of the post-convergence path from the root to n
+// following the first parent, the root excluded.
+func pcPath(root, n *spfNode) []*spfNode {
+	var path []*spfNode
+	for ; n != nil && n != root; n = n.parents[0] {
+		path = append([]*spfNode{n}, path...)
+		if len(n.parents) == 0 {
+			return nil
+		}
+	}
+	return path
+}
+
+// adjSegment returns the Adj-SID segment of the adjacency of p to q, through
+// the pseudo-node pn on a LAN.
+func adjSegment(p *spfNode, pn *spfNode, q clns.SystemID, ipv4 bool) (RepairSegment, bool) {
+	qid := sysNodeID(q)
+	for _, nbr := range p.mtNeighbors(tlv.MTIDStandard) {
+		for _, as := range nbr.AdjSIDs {
+			if as.IPv6 == ipv4 || !as.Value || !as.Local {
+				continue
+			}
+			if (pn == nil && nbr.Nodeid == qid && as.Nbr == nil) ||
+				(pn != nil && nbr.Nodeid == pn.nodeid && as.Nbr != nil && *as.Nbr == q) {
+				return RepairSegment{Type: SegmentAdj, Node: q, Label: as.SID}, true
+			}
+		}
+	}
+	return RepairSegment{}, false
+}
+
+// selectTILFA returns the TI-LFA repair path protecting the link of the
+// primary next hop of the route. The post-convergence path is encoded with the
+// node SID of its last node in our extended P-space followed by the Adj-SID
+// to its first node in the Q-space of the destination, or the node SID of a
+// PQ node.
+func (db *DB) selectTILFA(fs *frrState, key string, r *Route, nh *NextHop) *Backup {
+	c := db.circuits[nh.Intf]
+	updown := r.Type == RouteInterArea
+	ipv4 := isIPv4((*net.IPNet)(&r.Prefix))
+	root, graph := db.postConvergence(fs, c, nh.Sysid)
+
+	// The destination is the advertiser reached with the lowest metric.
+	var dst *spfNode
+	metric := maxDist
+	for _, a := range fs.advs[key] {
+		n := graph[a.nodeid]
+		if a.updown != updown || n == nil || !n.reached() || len(n.nexthops) == 0 {
+			continue
+		}
+		if n.dist+a.metric < metric {
+			dst, metric = n, n.dist+a.metric
+		}
+	}
+	if dst == nil {
+		return nil
+	}
+	path := pcPath(root, dst)
+	if len(path) == 0 || path[0].isPN() && len(path) < 2 {
+		return nil
+	}
+	first := path[0]
+	if first.isPN() {
+		first = path[1]
+	}
+
+	// The last node of the path in P-space and the first in Q-space.
+	rD := db.reverseDist(fs, dst.nodeid)
+	rS := db.reverseDist(fs, fs.root.nodeid)
+	pi, qi := -1, -1
+	for i, n := range path {
+		if n.isPN() {
+			continue
+		}
+		if ln, _ := pSpaceNbr(fs, c, n.nodeid); ln != nil && pi == i-1-pnSkip(path, i) {
+			pi = i
+		}
+		if qi < 0 && inQSpace(rD, rS, n.nodeid, fs.nodes[dst.nodeid].dist) {
+			qi = i
+		}
+	}
+	if qi < 0 {
+		return nil
+	}
+
+	var segs []RepairSegment
+	switch {
+	case path[qi] == first:
+		// The first hop is a loop-free alternate.
+	case pi >= qi:
+		segs = []RepairSegment{{Type: SegmentNode, Node: path[qi].sysid()}}
+	case pi >= 0 && qi == pi+1+pnSkip(path, qi):
+		if path[pi] != first {
+			segs = append(segs, RepairSegment{Type: SegmentNode, Node: path[pi].sysid()})
+		}
+		var pn *spfNode
+		if path[qi-1].isPN() {
+			pn = path[qi-1]
+		}
+		adj, ok := adjSegment(path[pi], pn, path[qi].sysid(), ipv4)
+		if !ok {
+			return nil
+		}
+		segs = append(segs, adj)
+	default:
+		return nil
+	}
+	segs, labels, ok := db.repairLabels(fs, key, first.sysid(), segs, ipv4)
+	if !ok {
+		return nil
+	}
+	nhs := db.resolveNextHops(dst.nexthops[:1], ipv4)
+	return &Backup{
+		Type:     BackupTILFA,
+		Intf:     nhs[0].Intf,
+		Addr:     nhs[0].Addr,
+		Sysid:    nhs[0].Sysid,
+		Metric:   metric,
+		Segments: segs,
+		Labels:   labels,
+	}
+}
+
+// pnSkip returns 1 if the node before index i of the path is a pseudo-node.
+func pnSkip(path []*spfNode, i int) int {
+	if i > 0 && path[i-1].isPN() {
+		return 1
+	}
+	return 0
+}
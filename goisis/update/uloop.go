@@ -0,0 +1,296 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package update implements the update process of the IS-IS routing protocol.
+// This file contains the local microloop avoidance (RFC8333). After a local
+// link goes down the forwarding entries of the destinations that used it keep
+// a loop-free repair path for a while, giving the rest of the network time to
+// converge, before the new routes are installed. Only the forwarding entries
+// we program are held: the label forwarding table and the SRv6 routes. The IP
+// routes aren't installed by us and aren't affected.
+package update
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	. "github.com/choppsv1/goisis/logging" // nolint
+	"github.com/choppsv1/goisis/tlv"
+	"time"
+)
+
+// DefULoopDelay is the default delay of the forwarding updates after a local
+// link down (RFC8333 ULOOP_DELAY_DOWN_TIMER).
+const DefULoopDelay = 5000 * time.Millisecond
+
+// linkDown is an adjacency of ours that went down.
+type linkDown struct {
+	intf  string
+	sysid clns.SystemID
+}
+
+// uloop is the microloop avoidance state. The held routes are installed in
+// place of the converged routes until the timer expires, the saved entries are
+// the converged forwarding entries they replace.
+type uloop struct {
+	delay     time.Duration // zero if disabled
+	down      []linkDown    // local link down events since the last SPF
+	held      map[string]*Route
+	until     time.Time
+	timer     *time.Timer
+	savedLFIB map[uint32]*LabelRoute
+	savedSRv6 map[string]*Route
+}
+
+// YangULoop is the microloop avoidance state of a level for the yang model.
+type YangULoop struct {
+	Level     clns.Level `json:"level"`
+	Enable    bool       `json:"enable"`
+	Delay     uint32     `json:"rib-update-delay"` // milliseconds
+	Active    bool       `json:"active"`
+	Remaining uint32     `json:"remaining-time"` // milliseconds
+	Routes    []*Route   `json:"held-routes,omitempty"`
+}
+
+func (ld linkDown) uses(nh *NextHop) bool {
+	return nh.Intf == ld.intf && nh.Sysid == ld.sysid
+}
+
+func linkDownNextHop(down []linkDown, nh *NextHop) bool {
+	for _, ld := range down {
+		if ld.uses(nh) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasNextHop(nhs []NextHop, nh *NextHop) bool {
+	for i := range nhs {
+		if nhs[i].Intf == nh.Intf && nhs[i].Sysid == nh.Sysid && equalLabels(nhs[i].Labels, nh.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalLabels(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// localLinkDown records the loss of our adjacency to sysid on circuit name.
+func (db *DB) localLinkDown(name string, sysid clns.SystemID) {
+	if db.uloop.delay == 0 {
+		return
+	}
+	db.uloop.down = append(db.uloop.down, linkDown{name, sysid})
+}
+
+// repairNextHop returns the loop-free repair next hop replacing the next hop
+// of the route over a link that went down. With segment routing it follows the
+// post-convergence path of the previous topology encoded as a segment list,
+// otherwise the backup computed by the previous SPF is used.
+func (db *DB) repairNextHop(fs *frrState, key string, r *Route, nh *NextHop) *NextHop {
+	var b *Backup
+	if fs != nil && db.circuits[nh.Intf] != nil {
+		b = db.selectTILFA(fs, key, r, nh)
+	}
+	if b == nil {
+		b = nh.Backup
+	}
+	if b == nil {
+		return nil
+	}
+	return &NextHop{Intf: b.Intf, Addr: b.Addr, Sysid: b.Sysid, Labels: b.Labels}
+}
+
+// uloopRoutes holds the routes of the previous SPF, orib computed over the
+// graph onodes, that used one of our links that went down. The next hops over
+// the links are replaced by their repair paths. Destinations without a repair
+// path or no longer reachable converge immediately.
+func (db *DB) uloopRoutes(oroot *spfNode, onodes map[clns.NodeID]*spfNode, orib map[string]*Route) {
+	down := db.uloop.down
+	db.uloop.down = nil
+	if db.uloop.delay == 0 || oroot == nil || len(down) == 0 {
+		return
+	}
+	var fs *frrState
+	if db.srgb() != nil {
+		fs = db.newFRRState(oroot, onodes)
+	}
+	held := make(map[string]*Route)
+	for key, or := range orib {
+		if db.uloop.held[key] != nil || db.rib[key] == nil {
+			continue
+		}
+		affected := false
+		tr := *or
+		tr.NextHops = nil
+		for i := range or.NextHops {
+			nh := or.NextHops[i]
+			nh.Backup = nil
+			if !linkDownNextHop(down, &nh) {
+				tr.NextHops = append(tr.NextHops, nh)
+				continue
+			}
+			affected = true
+			if rnh := db.repairNextHop(fs, key, or, &or.NextHops[i]); rnh != nil && !hasNextHop(tr.NextHops, rnh) {
+				tr.NextHops = append(tr.NextHops, *rnh)
+			}
+		}
+		if affected && len(tr.NextHops) != 0 {
+			held[key] = &tr
+		}
+	}
+	if len(held) == 0 {
+		return
+	}
+	if db.uloop.held == nil {
+		db.uloop.held = make(map[string]*Route)
+	}
+	for key, r := range held {
+		db.uloop.held[key] = r
+	}
+	if db.uloop.timer != nil {
+		db.uloop.timer.Stop()
+	}
+	db.uloop.until = time.Now().Add(db.uloop.delay)
+	db.uloop.timer = time.AfterFunc(db.uloop.delay, func() { db.uloopC <- true })
+	Debug(DbgFSPF, "%s: microloop avoidance holding %d routes for %s", db, len(held), db.uloop.delay)
+}
+
+// applyULoop installs the held routes in the label forwarding table and the
+// SRv6 routes computed over the graph nodes, saving the entries they replace.
+// The held destinations that are no longer reachable or without a forwarding
+// entry to hold are released.
+func (db *DB) applyULoop(root *spfNode, nodes map[clns.NodeID]*spfNode) {
+	db.uloop.savedLFIB = make(map[uint32]*LabelRoute)
+	db.uloop.savedSRv6 = make(map[string]*Route)
+	for key := range db.uloop.held {
+		if db.rib[key] == nil {
+			delete(db.uloop.held, key)
+		}
+	}
+	if len(db.uloop.held) == 0 {
+		db.stopULoop()
+		return
+	}
+	var byPrefix map[string][]prefixSID
+	srgb := db.srgb()
+	if srgb != nil {
+		byPrefix, _ = db.resolveSIDs(prefixSIDs(root, nodes, tlv.SRAlgoSPF))
+	}
+	for key, hr := range db.uloop.held {
+		held := false
+		if pss := byPrefix[key]; len(pss) != 0 {
+			in, _ := tlv.SRGBLabel(srgb, pss[0].sid.SID)
+			if lr := db.lfib[in]; lr != nil && lr.Type == LabelTypePrefixSID {
+				nlr := *lr
+				nlr.NextHops = nil
+				for _, nh := range hr.NextHops {
+					if nh.Addr != nil && len(nh.Labels) != 0 {
+						nlr.NextHops = append(nlr.NextHops, nh)
+					}
+				}
+				if len(nlr.NextHops) != 0 {
+					db.uloop.savedLFIB[in] = lr
+					db.lfib[in] = &nlr
+					held = true
+				}
+			}
+		}
+		if r := db.srv6Routes[key]; r != nil {
+			// Repair paths requiring a segment list can't be used.
+			nr := *r
+			nr.NextHops = nil
+			for _, nh := range hr.NextHops {
+				if len(nh.Labels) <= 1 {
+					nh.Labels = nil
+					nr.NextHops = append(nr.NextHops, nh)
+				}
+			}
+			if len(nr.NextHops) != 0 {
+				db.uloop.savedSRv6[key] = r
+				db.srv6Routes[key] = &nr
+				held = true
+			}
+		}
+		if !held {
+			delete(db.uloop.held, key)
+		}
+	}
+	if len(db.uloop.held) == 0 {
+		db.stopULoop()
+	}
+}
+
+// stopULoop drops the held routes without restoring the forwarding entries.
+func (db *DB) stopULoop() {
+	if db.uloop.timer != nil {
+		db.uloop.timer.Stop()
+	}
+	db.uloop.timer = nil
+	db.uloop.held = nil
+	db.uloop.savedLFIB, db.uloop.savedSRv6 = nil, nil
+}
+
+// handleULoopC installs the converged forwarding entries of the held routes.
+func (db *DB) handleULoopC() {
+	if db.uloop.timer == nil || time.Now().Before(db.uloop.until) {
+		// Stale timer event after a restart.
+		return
+	}
+	Debug(DbgFSPF, "%s: microloop avoidance releasing %d routes", db, len(db.uloop.held))
+	for label, lr := range db.uloop.savedLFIB {
+		db.lfib[label] = lr
+	}
+	for key, r := range db.uloop.savedSRv6 {
+		db.srv6Routes[key] = r
+	}
+	db.stopULoop()
+	db.notifyLFIB()
+	db.notifySRv6()
+}
+
+// SetMicroloopAvoidance enables the delay of the forwarding updates after a
+// local link down, a zero delay disables it.
+func (db *DB) SetMicroloopAvoidance(delay time.Duration) {
+	_, _ = DoRPC(db.rpC, func() interface{} { // nolint
+		db.uloop.delay = delay
+		if delay == 0 {
+			db.uloop.down = nil
+		}
+		return nil
+	})
+}
+
+// MicroloopAvoidance arranges for the microloop avoidance state to be
+// returned.
+func (db *DB) MicroloopAvoidance() (*YangULoop, error) {
+	i, err := DoRPC(db.rpC, func() interface{} {
+		yd := &YangULoop{
+			Level:  db.li.ToLevel(),
+			Enable: db.uloop.delay != 0,
+			Delay:  msec(db.uloop.delay),
+			Active: db.uloop.timer != nil,
+			Routes: sortedRoutes(db.uloop.held),
+		}
+		if yd.Active {
+			if left := time.Until(db.uloop.until); left > 0 {
+				yd.Remaining = msec(left)
+			}
+		}
+		return yd
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.(*YangULoop), nil
+}
@@ -0,0 +1,94 @@
+package update
+
+import (
+	"testing"
+	"time"
+)
+
+// uloopLinkDown brings down the link between routers 1 and 2 of the links.
+func uloopLinkDown(db *DB, links []testLink, extra map[byte][][]byte) {
+	delete(db.nbrs, testIntf(2))
+	db.localLinkDown(testIntf(2), testSysID(2))
+	for _, n := range []byte{1, 2} {
+		setLSP(db, n, append(linkTLVs(n, links[1:]), extra[n]...)...)
+	}
+}
+
+// lfibLabels returns the out labels of the only next hop of the LFIB entry
+// of label in, nil if there isn't one.
+func lfibLabels(db *DB, in uint32) []uint32 {
+	lr := db.lfib[in]
+	if lr == nil || len(lr.NextHops) != 1 || lr.NextHops[0].Intf != testIntf(6) {
+		return nil
+	}
+	return lr.NextHops[0].Labels
+}
+
+func TestULoop(t *testing.T) {
+	// Router 3 is reached through 2 and after the failure of the link to 2
+	// through 6-5-4. Until the delay expires the previous post-convergence path
+	// is used with the node SID of 5.
+	links := ring(6)
+	extra := make(map[byte][][]byte)
+	for n := byte(1); n <= 6; n++ {
+		extra[n] = [][]byte{capTLVs(srCap(n)), sidReach(testPrefix(n), 1, uint32(n))}
+	}
+	db := newSRDB(links, FRRPolicy{Candidate: true})
+	db.uloop.delay = time.Hour
+	db.runSPF()
+	uloopLinkDown(db, links, extra)
+	db.runSPF()
+
+	r := db.rib[testPrefix(3)]
+	if r == nil || len(r.NextHops) != 1 || r.NextHops[0].Intf != testIntf(6) || r.Metric != 5 {
+		t.Fatalf("Bad converged route %+v", r)
+	}
+	hr := db.uloop.held[testPrefix(3)]
+	if hr == nil || len(hr.NextHops) != 1 || hr.NextHops[0].Intf != testIntf(6) {
+		t.Fatalf("Bad held route %+v", hr)
+	}
+	if db.uloop.held[testPrefix(5)] != nil || db.uloop.timer == nil {
+		t.Errorf("Bad held routes %+v", db.uloop.held)
+	}
+	if labels := lfibLabels(db, 16003); len(labels) != 2 || labels[0] != 16005 || labels[1] != 16003 {
+		t.Errorf("Held LFIB labels %v", labels)
+	}
+
+	// A stale timer event doesn't release the routes.
+	db.handleULoopC()
+	if db.uloop.held == nil || len(lfibLabels(db, 16003)) != 2 {
+		t.Errorf("Routes released before the delay")
+	}
+
+	// The held routes survive an SPF.
+	setLSP(db, 5, append(linkTLVs(5, links), extra[5]...)...)
+	db.runSPF()
+	if labels := lfibLabels(db, 16003); len(labels) != 2 || db.uloop.held[testPrefix(3)] == nil {
+		t.Errorf("Held LFIB labels after SPF %v", labels)
+	}
+
+	db.uloop.until = time.Now()
+	db.handleULoopC()
+	if labels := lfibLabels(db, 16003); len(labels) != 1 || labels[0] != 16003 {
+		t.Errorf("Restored LFIB labels %v", labels)
+	}
+	if db.uloop.held != nil || db.uloop.timer != nil || db.uloop.savedLFIB != nil {
+		t.Errorf("Microloop avoidance still active %+v", db.uloop)
+	}
+}
+
+func TestULoopNoSR(t *testing.T) {
+	// Without segment routing there is no forwarding entry to hold.
+	links := ring(5)
+	db := newFRRDB(links, FRRPolicy{Candidate: true, LFA: true}, 3)
+	db.uloop.delay = time.Hour
+	db.runSPF()
+	uloopLinkDown(db, links, map[byte][][]byte{3: {ipReach(testPrefix(3), 1)}})
+	db.runSPF()
+	if db.uloop.held != nil || db.uloop.timer != nil {
+		t.Errorf("Routes held without segment routing %+v", db.uloop.held)
+	}
+	if r := db.rib[testPrefix(3)]; r == nil || len(r.NextHops) != 1 || r.NextHops[0].Intf != testIntf(5) {
+		t.Errorf("Bad converged route %+v", r)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/choppsv1/goisis/clns"
 	. "github.com/choppsv1/goisis/logging" // nolint
 	"github.com/choppsv1/goisis/pkt"
+	"github.com/choppsv1/goisis/policy"
 	xtime "github.com/choppsv1/goisis/time"
 	"github.com/choppsv1/goisis/tlv"
 	"github.com/plar/go-adaptive-radix-tree"
@@ -24,9 +25,6 @@ import (
 // LSPCreateGenDelay is the initial delay for our non-pnode LSP
 const LSPCreateGenDelay = 10 * time.Second
 
-// LSPGenDelay is the delay used to try and gather multiple chaanges before advertising
-const LSPGenDelay = 100 * time.Millisecond
-
 // ==========
 // Interfaces
 // ==========
@@ -39,9 +37,12 @@ type Circuit interface {
 	ChgFlag(SxxFlag, *clns.LSPID, bool, clns.Lindex)
 	CID(clns.Lindex) uint8
 	IsP2P() bool
+	IsPassive() bool
 	Name() string
 	MTU() uint
 	Send([]byte, clns.Lindex)
+	Topologies() []uint16
+	FRR(clns.Lindex) FRRPolicy
 }
 
 // =====
@@ -50,28 +51,72 @@ type Circuit interface {
 
 // DB holds all LSP for a given level.
 type DB struct {
-	sysid     clns.SystemID  // change to public as immutable
-	istype    clns.LevelFlag // change to public as immutable
-	li        clns.Lindex    // change to public as immutable
-	cache     csnpCache
-	areas     [][]byte
-	nlpid     []byte
-	hostname  string
-	circuits  map[string]Circuit
-	dis       map[uint8]disInfo
-	disCount  uint
-	disTimer  *time.Timer
-	rpC       chan RPC
-	chgCC     chan chgCircuit
-	chgDISC   chan chgDIS
-	chgLSPC   chan chgLSP
-	csnpTickC chan bool
-	expireC   chan clns.LSPID
-	refreshC  chan clns.LSPID
-	dataC     chan interface{}
-	pduC      chan inputPDU
-	db        art.Tree
-	ownlsp    map[uint8]*ownLSP
+	sysid      clns.SystemID  // change to public as immutable
+	istype     clns.LevelFlag // change to public as immutable
+	li         clns.Lindex    // change to public as immutable
+	cache      csnpCache
+	areas      [][]byte
+	nlpid      []byte
+	hostname   string
+	circuits   map[string]Circuit
+	dis        map[uint8]disInfo
+	disCount   uint
+	disTimer   *time.Timer
+	rpC        chan RPC
+	chgCC      chan chgCircuit
+	chgDISC    chan chgDIS
+	chgLSPC    chan chgLSP
+	csnpTickC  chan bool
+	expireC    chan clns.LSPID
+	refreshC   chan clns.LSPID
+	dataC      chan interface{}
+	pduC       chan inputPDU
+	db         art.Tree
+	ownlsp     map[uint8]*ownLSP
+	lspgen     xtime.Backoff
+	lsplog     lspLog
+	overload   overload
+	chgAdjC    chan chgAdj
+	adjUp      map[string]int
+	csnpSeen   map[string]bool
+	nbrs       map[string]map[clns.SystemID]Neighbor
+	spfC       chan bool
+	spfWait    *time.Timer
+	spfDelay   xtime.SPFDelay
+	spfPend    spfPending // the scheduled SPF
+	spflog     spfLog
+	spfNodes   map[clns.NodeID]*spfNode
+	spfCount   uint32
+	spfLast    time.Time
+	rib        map[string]*Route
+	lfib       map[uint32]*LabelRoute
+	lfibC      chan<- bool                  // notified when the LFIB is recomputed
+	conflicts  []*YangSIDConflict           // prefix SID conflicts
+	srv6Locs   []tlv.SRv6Locator            // our SRv6 locators
+	srv6Routes map[string]*Route            // routes to the remote SRv6 locators
+	localSIDs  []*SRv6SID                   // our SRv6 SIDs
+	srv6C      chan<- bool                  // notified when the SRv6 state is recomputed
+	flexAlgos  []*YangFlexAlgo              // per flexible algorithm SPF results
+	mtids      []uint16                     // our topologies, empty if not MT
+	mtRIBs     map[uint16]map[string]*Route // routes of the non-standard topologies
+	frr        *YangFRRCoverage             // fast reroute coverage of the routes
+	uloop      uloop                        // microloop avoidance
+	uloopC     chan bool
+	att        attachedBit
+	other      *DB          // other level for L1/L2 routers
+	levelC     chan bool    // notified when levelQ has messages
+	levelQ     levelQueue   // messages from the other level
+	sentReach  []tlv.IPInfo // reachability sent to the other level
+	otherReach []tlv.IPInfo // reachability received from the other level
+	leak       *LeakPolicy
+	redist     []Redist
+	policies   map[string]*policy.Policy
+	routerID   net.IP
+	routerIDv6 net.IP
+	routerCap  *tlv.RouterCap
+	sentCaps   []*tlv.RouterCap // domain wide capabilities sent to the other level
+	otherCaps  []*tlv.RouterCap // domain wide capabilities from the other level
+	summaries  []Summary
 }
 
 func (db *DB) String() string {
@@ -84,13 +129,15 @@ type chgCircuit struct {
 }
 
 type chgDIS struct {
-	c   Circuit // nil for resign.
-	cid uint8   // circuit ID
+	c     Circuit     // nil for resign.
+	cid   uint8       // circuit ID
+	lanid clns.NodeID // LAN ID (zero if no DIS)
 }
 
 type chgLSP struct {
-	pnid  uint8
-	timer bool
+	pnid   uint8
+	timer  bool
+	reason string
 }
 
 // ErrLSP is a general error in LSP packet processing
@@ -127,6 +174,7 @@ type lspSegment struct {
 	payload  []byte
 	hdr      []byte
 	tlvs     map[tlv.Type][]tlv.Data
+	caps     []*tlv.RouterCap // decoded Router Capability TLVs
 	lspid    clns.LSPID
 	life     *xtime.HoldTimer
 	zeroLife *xtime.HoldTimer
@@ -157,10 +205,11 @@ func (result lspCompareResult) String() string {
 
 // disInfo is used to track state for DIS (CSNP)
 type disInfo struct {
-	c   Circuit // circuit for this info
-	i   uint    // index in csnp cache
-	us  bool    // If we are DIS
-	cid uint8   // DIS circuit ID
+	c     Circuit     // circuit for this info
+	i     uint        // index in csnp cache
+	us    bool        // If we are DIS
+	cid   uint8       // DIS circuit ID
+	lanid clns.NodeID // DIS LAN ID
 }
 
 // NewDB returns a new Update Process LSP database
@@ -176,6 +225,16 @@ func NewDB(sysid clns.SystemID, istype clns.LevelFlag, l clns.Level, areas [][]b
 		chgLSPC:   make(chan chgLSP, 10),
 		chgCC:     make(chan chgCircuit, 10),
 		chgDISC:   make(chan chgDIS, 10),
+		chgAdjC:   make(chan chgAdj, 10),
+		adjUp:     make(map[string]int),
+		csnpSeen:  make(map[string]bool),
+		nbrs:      make(map[string]map[clns.SystemID]Neighbor),
+		spfC:      make(chan bool, 10),
+		uloopC:    make(chan bool, 10),
+		rib:       make(map[string]*Route),
+		lfib:      make(map[uint32]*LabelRoute),
+		levelC:    make(chan bool, 1),
+		policies:  make(map[string]*policy.Policy),
 		dis:       make(map[uint8]disInfo),
 		db:        art.New(),
 		ownlsp:    make(map[uint8]*ownLSP),
@@ -187,6 +246,10 @@ func NewDB(sysid clns.SystemID, istype clns.LevelFlag, l clns.Level, areas [][]b
 		rpC:       make(chan RPC, 10),
 	}
 
+	db.lspgen.Set(DefLSPGenInitialWait, DefLSPGenSecondaryWait, DefLSPGenMaximumWait)
+	db.spfDelay.Set(DefSPFInitialDelay, DefSPFShortDelay, DefSPFLongDelay, DefSPFHoldDown,
+		DefSPFTimeToLearn)
+
 	if h, err := os.Hostname(); err != nil {
 		Debug(DbgFUpd, "WARNING: Error getting hostname: %s", err)
 	} else {
@@ -313,20 +376,22 @@ func (db *DB) RemoveCircuit(c Circuit) {
 	db.chgCC <- chgCircuit{c: nil, name: c.Name()}
 }
 
-// ChangeDIS sets or clears if we are DIS for the circuit ID.
-func (db *DB) ChangeDIS(c Circuit, cid uint8) {
-	db.chgDISC <- chgDIS{c, cid}
-	db.SomethingChanged(nil)
+// ChangeDIS informs the update process of the LAN ID of the DIS for the
+// circuit, this sets or clears if we are DIS for the circuit ID.
+func (db *DB) ChangeDIS(c Circuit, lanid clns.NodeID) {
+	db.chgDISC <- chgDIS{c, lanid[clns.SysIDLen], lanid}
+	db.SomethingChanged(nil, GenReasonDIS)
 }
 
 // SomethingChanged indicate to the update process that something changed, if
-// 'c' is non-nil then it relates to the circuit otherwise the router.
-func (db *DB) SomethingChanged(c Circuit) {
+// 'c' is non-nil then it relates to the circuit otherwise the router. The
+// reason is recorded in the LSP log when the LSP is regenerated.
+func (db *DB) SomethingChanged(c Circuit, reason string) {
 	if c == nil {
-		db.chgLSPC <- chgLSP{}
+		db.chgLSPC <- chgLSP{reason: reason}
 	} else {
 		cid := c.CID(db.li)
-		db.chgLSPC <- chgLSP{pnid: cid}
+		db.chgLSPC <- chgLSP{pnid: cid, reason: reason}
 	}
 }
 
@@ -560,17 +625,21 @@ func (db *DB) handleChgLSPC(in chgLSP) {
 	if in.timer {
 		lsp.regenWait = nil
 		_ = lsp.regenLSP() // nolint
+		db.logGeneration(lsp)
 		return
 	}
 
-	// If we are already waiting we are done.
+	// Record the reason, if we are already waiting we are done.
+	lsp.addReason(in.reason)
 	if lsp.regenWait != nil {
 		return
 	}
 
-	delay := time.Millisecond * 100
+	// Throttle regeneration using exponential back-off.
+	delay := lsp.backoff.Next()
 	lsp.regenWait = time.AfterFunc(delay,
 		func() { db.chgLSPC <- chgLSP{timer: true, pnid: in.pnid} })
+	Debug(DbgFLSP, "%s: OwnLSP pnid %d generation in %s (%s)", db, in.pnid, delay, in.reason)
 }
 
 // inputPDU handles one PDU from our pdu channel
@@ -621,18 +690,20 @@ func (db *DB) handleChgDISC(in chgDIS) {
 	di, wasSet := db.dis[localCid]
 	wasDis := wasSet && di.cid == localCid
 
-	if wasSet && di.cid == in.cid {
-		Debug(DbgFUpd, "%s: same DIS (%d) on %s", db, in.cid, name)
+	if wasSet && di.lanid == in.lanid {
+		Debug(DbgFUpd, "%s: same DIS (%s) on %s", db, in.lanid, name)
 		return
 	}
 
 	// Update DIS info.
 	di = disInfo{
-		c:   in.c,
-		us:  elected,
-		cid: in.cid,
+		c:     in.c,
+		us:    elected,
+		cid:   in.cid,
+		lanid: in.lanid,
 	}
 	db.dis[localCid] = di
+	db.scheduleSPF()
 
 	if !wasDis && elected {
 		Debug(DbgFUpd, "%s: Elected DIS on %s", db, name)
@@ -678,8 +749,14 @@ func (db *DB) handleChgCircuit(in chgCircuit) {
 		db.circuits[in.name] = in.c
 	} else {
 		delete(db.circuits, in.name)
+		delete(db.adjUp, in.name)
+		delete(db.csnpSeen, in.name)
+		delete(db.nbrs, in.name)
 	}
 	for _, c := range db.circuits {
+		if c.IsPassive() {
+			continue
+		}
 		mtu := c.MTU()
 		if mtu < newMtu {
 			newMtu = mtu
@@ -693,6 +770,8 @@ func (db *DB) handleChgCircuit(in chgCircuit) {
 		db.cache.pdus = nil
 	}
 
+	// A removed circuit may have been the last one not synchronized.
+	db.checkWaitAdj()
 }
 
 // Send a CSNP packet on all DIS circuits.
@@ -722,6 +801,14 @@ func (db *DB) run() {
 			db.handleChgDISC(in)
 		case in := <-db.chgLSPC:
 			db.handleChgLSPC(in)
+		case in := <-db.chgAdjC:
+			db.handleChgAdjC(in)
+		case <-db.spfC:
+			db.handleSpfC()
+		case <-db.uloopC:
+			db.handleULoopC()
+		case <-db.levelC:
+			db.handleLevelC()
 		case <-db.csnpTickC:
 			db.handleCsnpTickC()
 		case in := <-db.pduC:
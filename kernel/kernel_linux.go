@@ -0,0 +1,101 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package kernel implements access to the kernel routing tables.
+package kernel
+
+import (
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	"net"
+)
+
+// DefTable is the table used when none is given.
+const DefTable = unix.RT_TABLE_MAIN
+
+// ProtoISIS is the protocol of the routes we install.
+const ProtoISIS = unix.RTPROT_ISIS
+
+// nsHandle returns a netlink handle for the network namespace at 'nsPath'
+// (e.g., /var/run/netns/name), the current namespace if empty.
+func nsHandle(nsPath string) (*netlink.Handle, error) {
+	if nsPath == "" {
+		return netlink.NewHandle(unix.NETLINK_ROUTE)
+	}
+	ns, err := netns.GetFromPath(nsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer ns.Close() // nolint
+	return netlink.NewHandleAt(ns, unix.NETLINK_ROUTE)
+}
+
+// Routes returns the destination prefixes of the unicast routes in the kernel
+// table 'table'. If 'protocol' is non-zero only routes installed by that
+// protocol are returned. The routes we installed are never returned.
+func Routes(table, protocol int) ([]net.IPNet, error) {
+	if table == 0 {
+		table = DefTable
+	}
+	filter := &netlink.Route{Table: table, Protocol: netlink.RouteProtocol(protocol)}
+	mask := netlink.RT_FILTER_TABLE
+	if protocol != 0 {
+		mask |= netlink.RT_FILTER_PROTOCOL
+	}
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, filter, mask)
+	if err != nil {
+		return nil, err
+	}
+	var prefixes []net.IPNet
+	for _, r := range routes {
+		if r.Type != unix.RTN_UNICAST || r.Protocol == unix.RTPROT_ISIS {
+			continue
+		}
+		if r.Dst == nil {
+			// The default route.
+			if r.Family == netlink.FAMILY_V6 {
+				prefixes = append(prefixes, net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)})
+			} else {
+				prefixes = append(prefixes, net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)})
+			}
+			continue
+		}
+		prefixes = append(prefixes, *r.Dst)
+	}
+	return prefixes, nil
+}
+
+// Subscribe returns a channel that is sent a value when the kernel routes or
+// addresses change. The subscription ends when 'done' is closed.
+func Subscribe(done <-chan struct{}) (<-chan bool, error) {
+	routeC := make(chan netlink.RouteUpdate, 16)
+	addrC := make(chan netlink.AddrUpdate, 16)
+	if err := netlink.RouteSubscribe(routeC, done); err != nil {
+		return nil, err
+	}
+	if err := netlink.AddrSubscribe(addrC, done); err != nil {
+		return nil, err
+	}
+	changeC := make(chan bool, 1)
+	go func() {
+		for {
+			select {
+			case _, ok := <-routeC:
+				if !ok {
+					return
+				}
+			case _, ok := <-addrC:
+				if !ok {
+					return
+				}
+			}
+			select {
+			case changeC <- true:
+			default:
+			}
+		}
+	}()
+	return changeC, nil
+}
@@ -0,0 +1,34 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+//go:build !linux
+// +build !linux
+
+// Package kernel implements access to the kernel routing tables.
+package kernel
+
+import (
+	"errors"
+	"net"
+)
+
+// DefTable is the table used when none is given.
+const DefTable = 0
+
+// ProtoISIS is the protocol of the routes we install.
+const ProtoISIS = 187
+
+var errNotSupported = errors.New("kernel routes not supported on this platform")
+
+// Routes returns the destination prefixes of the unicast routes in the kernel
+// table 'table'.
+func Routes(table, protocol int) ([]net.IPNet, error) {
+	return nil, errNotSupported
+}
+
+// Subscribe returns a channel that is sent a value when the kernel routes or
+// addresses change.
+func Subscribe(done <-chan struct{}) (<-chan bool, error) {
+	return nil, errNotSupported
+}
@@ -0,0 +1,53 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package kernel implements access to the kernel routing tables.
+// This file contains the platform independent MPLS forwarding types.
+package kernel
+
+import (
+	"net"
+)
+
+// LabelImplicitNull is the implicit null label, as an outgoing label it pops
+// the incoming label.
+const LabelImplicitNull = 3
+
+// MPLSNextHop is a next hop of an MPLS route. The incoming label is swapped
+// for Labels, Labels empty or the implicit null label pops it.
+type MPLSNextHop struct {
+	Intf    string
+	Gateway net.IP
+	Labels  []uint32
+}
+
+// MPLSRoute is an entry of the MPLS label forwarding table.
+type MPLSRoute struct {
+	Label    uint32
+	NextHops []MPLSNextHop
+}
+
+// pops returns true if the next hop pops the incoming label.
+func (nh *MPLSNextHop) pops() bool {
+	return len(nh.Labels) == 0 || (len(nh.Labels) == 1 && nh.Labels[0] == LabelImplicitNull)
+}
+
+// equal returns true if the routes are the same.
+func (r *MPLSRoute) equal(o *MPLSRoute) bool {
+	if r.Label != o.Label || len(r.NextHops) != len(o.NextHops) {
+		return false
+	}
+	for i := range r.NextHops {
+		a, b := &r.NextHops[i], &o.NextHops[i]
+		if a.Intf != b.Intf || !a.Gateway.Equal(b.Gateway) || len(a.Labels) != len(b.Labels) {
+			return false
+		}
+		for j := range a.Labels {
+			if a.Labels[j] != b.Labels[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
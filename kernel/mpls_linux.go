@@ -0,0 +1,144 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package kernel implements access to the kernel routing tables.
+// This file contains the MPLS label forwarding table programming.
+package kernel
+
+import (
+	"fmt"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"net"
+)
+
+// LFIB programs the kernel MPLS label forwarding table of a network namespace
+// with the routes of the IS-IS protocol. It is not safe for concurrent use.
+type LFIB struct {
+	h         *netlink.Handle
+	installed map[uint32]*MPLSRoute
+}
+
+// NewLFIB returns an LFIB for the network namespace at 'nsPath' (e.g.,
+// /var/run/netns/name), the current namespace if empty. Any IS-IS MPLS routes
+// left from a previous run are removed.
+func NewLFIB(nsPath string) (*LFIB, error) {
+	h, err := nsHandle(nsPath)
+	if err != nil {
+		return nil, err
+	}
+	l := &LFIB{h: h, installed: make(map[uint32]*MPLSRoute)}
+	if err = l.flush(); err != nil {
+		l.h.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Close releases the netlink handle, installed routes are left in place.
+func (l *LFIB) Close() {
+	l.h.Close()
+}
+
+// flush removes all IS-IS MPLS routes.
+func (l *LFIB) flush() error {
+	filter := &netlink.Route{Protocol: unix.RTPROT_ISIS}
+	routes, err := l.h.RouteListFiltered(netlink.FAMILY_MPLS, filter, netlink.RT_FILTER_PROTOCOL)
+	if err != nil {
+		return err
+	}
+	for i := range routes {
+		if err := l.h.RouteDel(&routes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// via returns the netlink gateway for an IPv4 or IPv6 address.
+func via(gw net.IP) *netlink.Via {
+	if ip4 := gw.To4(); ip4 != nil {
+		return &netlink.Via{AddrFamily: netlink.FAMILY_V4, Addr: ip4}
+	}
+	return &netlink.Via{AddrFamily: netlink.FAMILY_V6, Addr: gw}
+}
+
+// nlRoute converts the route to a netlink route.
+func (l *LFIB) nlRoute(r *MPLSRoute) (*netlink.Route, error) {
+	label := int(r.Label)
+	nr := &netlink.Route{MPLSDst: &label, Protocol: unix.RTPROT_ISIS}
+	for _, nh := range r.NextHops {
+		link, err := l.h.LinkByName(nh.Intf)
+		if err != nil {
+			return nil, fmt.Errorf("label %d: %s: %s", r.Label, nh.Intf, err)
+		}
+		nlnh := &netlink.NexthopInfo{LinkIndex: link.Attrs().Index}
+		if nh.Gateway != nil {
+			nlnh.Via = via(nh.Gateway)
+		}
+		if !nh.pops() {
+			dst := &netlink.MPLSDestination{}
+			for _, out := range nh.Labels {
+				dst.Labels = append(dst.Labels, int(out))
+			}
+			nlnh.NewDst = dst
+		}
+		nr.MultiPath = append(nr.MultiPath, nlnh)
+	}
+	if len(nr.MultiPath) == 1 {
+		nh := nr.MultiPath[0]
+		nr.LinkIndex, nr.Via, nr.NewDst, nr.MultiPath = nh.LinkIndex, nh.Via, nh.NewDst, nil
+	}
+	return nr, nil
+}
+
+// Update makes the IS-IS MPLS routes in the kernel match 'routes'. Routes
+// that fail to install are skipped, the first error is returned.
+func (l *LFIB) Update(routes []MPLSRoute) error {
+	var rerr error
+	seterr := func(err error) {
+		if rerr == nil {
+			rerr = err
+		}
+	}
+	want := make(map[uint32]*MPLSRoute)
+	for i := range routes {
+		r := routes[i]
+		want[r.Label] = &r
+	}
+	for label, r := range l.installed {
+		if want[label] != nil {
+			continue
+		}
+		nr, err := l.nlRoute(r)
+		if err == nil {
+			err = l.h.RouteDel(nr)
+		}
+		if err != nil {
+			// Fall back to deleting by label only.
+			lbl := int(label)
+			err = l.h.RouteDel(&netlink.Route{MPLSDst: &lbl, Protocol: unix.RTPROT_ISIS})
+		}
+		if err != nil {
+			seterr(err)
+			continue
+		}
+		delete(l.installed, label)
+	}
+	for label, r := range want {
+		if ir := l.installed[label]; ir != nil && ir.equal(r) {
+			continue
+		}
+		nr, err := l.nlRoute(r)
+		if err == nil {
+			err = l.h.RouteReplace(nr)
+		}
+		if err != nil {
+			seterr(err)
+			continue
+		}
+		l.installed[label] = r
+	}
+	return rerr
+}
@@ -0,0 +1,141 @@
+package kernel
+
+import (
+	"fmt"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// newTestNS creates a named network namespace, with MPLS enabled if 'mpls' is
+// set, and a veth interface test0 with IPv4 and IPv6 addresses. The test is skipped
+// if this isn't possible.
+func newTestNS(t *testing.T, mpls bool) (string, *netlink.Handle) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	orig, err := netns.Get()
+	if err != nil {
+		t.Skipf("no network namespaces: %s", err)
+	}
+	defer orig.Close() // nolint
+	name := fmt.Sprintf("goisis-test-%d", os.Getpid())
+	ns, err := netns.NewNamed(name)
+	if err != nil {
+		t.Skipf("can't create network namespace: %s", err)
+	}
+	defer ns.Close() // nolint
+
+	t.Cleanup(func() { netns.DeleteNamed(name) }) // nolint
+	if mpls {
+		err = ioutil.WriteFile("/proc/sys/net/mpls/platform_labels", []byte("100000"), 0644)
+	}
+	if serr := netns.Set(orig); serr != nil {
+		t.Fatalf("restoring namespace: %s", serr)
+	}
+	if err != nil {
+		t.Skipf("no MPLS support: %s", err)
+	}
+
+	h, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		t.Fatalf("NewHandleAt: %s", err)
+	}
+	t.Cleanup(h.Close)
+	link := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "test0"}, PeerName: "test1"}
+	if err = h.LinkAdd(link); err != nil {
+		t.Skipf("can't add veth interface: %s", err)
+	}
+	lo, err := h.LinkByName("lo")
+	if err != nil {
+		t.Fatalf("LinkByName: %s", err)
+	}
+	if err = h.LinkSetUp(lo); err != nil {
+		t.Fatalf("LinkSetUp: %s", err)
+	}
+	peer, err := h.LinkByName("test1")
+	if err != nil {
+		t.Fatalf("LinkByName: %s", err)
+	}
+	if err = h.LinkSetUp(peer); err != nil {
+		t.Fatalf("LinkSetUp: %s", err)
+	}
+	_, ipnet, _ := net.ParseCIDR("10.0.0.1/24")
+	ipnet.IP = net.IPv4(10, 0, 0, 1)
+	if err = h.AddrAdd(link, &netlink.Addr{IPNet: ipnet}); err != nil {
+		t.Fatalf("AddrAdd: %s", err)
+	}
+	ipnet = &net.IPNet{IP: net.ParseIP("2001:db8::1"), Mask: net.CIDRMask(64, 128)}
+	if err = h.AddrAdd(link, &netlink.Addr{IPNet: ipnet, Flags: unix.IFA_F_NODAD}); err != nil {
+		t.Fatalf("AddrAdd: %s", err)
+	}
+	if err = h.LinkSetUp(link); err != nil {
+		t.Fatalf("LinkSetUp: %s", err)
+	}
+	return "/var/run/netns/" + name, h
+}
+
+func isisMPLSRoutes(t *testing.T, h *netlink.Handle) []netlink.Route {
+	filter := &netlink.Route{Protocol: unix.RTPROT_ISIS}
+	routes, err := h.RouteListFiltered(netlink.FAMILY_MPLS, filter, netlink.RT_FILTER_PROTOCOL)
+	if err != nil {
+		t.Fatalf("RouteListFiltered: %s", err)
+	}
+	return routes
+}
+
+func TestLFIB(t *testing.T) {
+	path, h := newTestNS(t, true)
+	l, err := NewLFIB(path)
+	if err != nil {
+		t.Fatalf("NewLFIB: %s", err)
+	}
+	defer l.Close()
+
+	gw := net.IPv4(10, 0, 0, 2)
+	routes := []MPLSRoute{
+		{Label: 16002, NextHops: []MPLSNextHop{{Intf: "test0", Gateway: gw, Labels: []uint32{17002}}}},
+		{Label: 15001, NextHops: []MPLSNextHop{{Intf: "test0", Gateway: gw, Labels: []uint32{LabelImplicitNull}}}},
+	}
+	if err = l.Update(routes); err != nil {
+		t.Fatalf("Update: %s", err)
+	}
+	got := isisMPLSRoutes(t, h)
+	if len(got) != 2 {
+		t.Fatalf("Got %d routes: %v", len(got), got)
+	}
+	for _, r := range got {
+		swap, ok := r.NewDst.(*netlink.MPLSDestination)
+		if *r.MPLSDst == 16002 && (!ok || len(swap.Labels) != 1 || swap.Labels[0] != 17002) {
+			t.Errorf("Bad swap route %s", r)
+		} else if *r.MPLSDst == 15001 && r.NewDst != nil {
+			t.Errorf("Bad pop route %s", r)
+		}
+	}
+
+	// A new LFIB flushes the routes of the old one.
+	l2, err := NewLFIB(path)
+	if err != nil {
+		t.Fatalf("NewLFIB: %s", err)
+	}
+	defer l2.Close()
+	if got = isisMPLSRoutes(t, h); len(got) != 0 {
+		t.Errorf("Routes not flushed: %v", got)
+	}
+	if err = l2.Update(routes[1:]); err != nil {
+		t.Fatalf("Update: %s", err)
+	}
+	if err = l2.Update(nil); err != nil {
+		t.Fatalf("Update: %s", err)
+	}
+	if got = isisMPLSRoutes(t, h); len(got) != 0 {
+		t.Errorf("Routes not removed: %v", got)
+	}
+}
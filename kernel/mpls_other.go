@@ -0,0 +1,26 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+//go:build !linux
+// +build !linux
+
+// Package kernel implements access to the kernel routing tables.
+// This file contains the MPLS label forwarding table stubs.
+package kernel
+
+// LFIB programs the kernel MPLS label forwarding table.
+type LFIB struct{}
+
+// NewLFIB returns an LFIB for the network namespace at 'nsPath'.
+func NewLFIB(nsPath string) (*LFIB, error) {
+	return nil, errNotSupported
+}
+
+// Close releases the LFIB.
+func (l *LFIB) Close() {}
+
+// Update makes the IS-IS MPLS routes in the kernel match 'routes'.
+func (l *LFIB) Update(routes []MPLSRoute) error {
+	return errNotSupported
+}
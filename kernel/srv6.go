@@ -0,0 +1,38 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package kernel implements access to the kernel routing tables.
+// This file contains the platform independent SRv6 forwarding types.
+package kernel
+
+import (
+	"net"
+)
+
+// SRv6 endpoint behaviors (RFC8986) of local SIDs.
+const (
+	SRv6BehaviorEnd  = 1
+	SRv6BehaviorEndX = 5
+)
+
+// IPNextHop is a next hop of an IP route.
+type IPNextHop struct {
+	Intf    string
+	Gateway net.IP
+}
+
+// IPRoute is a route to an IP prefix.
+type IPRoute struct {
+	Prefix   net.IPNet
+	NextHops []IPNextHop
+}
+
+// SRv6LocalSID is a local SRv6 SID. An End.X SID forwards to Gateway on Intf,
+// an End SID is processed on Intf, the loopback if empty.
+type SRv6LocalSID struct {
+	SID      net.IP
+	Behavior uint16
+	Intf     string
+	Gateway  net.IP
+}
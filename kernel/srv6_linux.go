@@ -0,0 +1,174 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package kernel implements access to the kernel routing tables.
+// This file contains the SRv6 locator route and local SID programming.
+package kernel
+
+import (
+	"fmt"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+	"net"
+)
+
+// SRv6FIB programs the kernel IPv6 routes of a network namespace with the
+// routes to the remote SRv6 locators and the local SIDs (seg6local routes) of
+// the IS-IS protocol. It is not safe for concurrent use.
+type SRv6FIB struct {
+	h         *netlink.Handle
+	installed map[string]*srv6Entry
+}
+
+// srv6Entry is an installed route, desc identifies its contents.
+type srv6Entry struct {
+	nr   *netlink.Route
+	desc string
+}
+
+// NewSRv6FIB returns an SRv6FIB for the network namespace at 'nsPath' (e.g.,
+// /var/run/netns/name), the current namespace if empty. Any IS-IS IPv6 routes
+// left from a previous run are removed.
+func NewSRv6FIB(nsPath string) (*SRv6FIB, error) {
+	h, err := nsHandle(nsPath)
+	if err != nil {
+		return nil, err
+	}
+	f := &SRv6FIB{h: h, installed: make(map[string]*srv6Entry)}
+	if err = f.flush(); err != nil {
+		f.h.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Close releases the netlink handle, installed routes are left in place.
+func (f *SRv6FIB) Close() {
+	f.h.Close()
+}
+
+// flush removes all IS-IS IPv6 routes.
+func (f *SRv6FIB) flush() error {
+	filter := &netlink.Route{Protocol: unix.RTPROT_ISIS}
+	routes, err := f.h.RouteListFiltered(netlink.FAMILY_V6, filter, netlink.RT_FILTER_PROTOCOL)
+	if err != nil {
+		return err
+	}
+	for i := range routes {
+		if err := f.h.RouteDel(&routes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkIndex returns the index of the interface 'name'.
+func (f *SRv6FIB) linkIndex(name string) (int, error) {
+	link, err := f.h.LinkByName(name)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", name, err)
+	}
+	return link.Attrs().Index, nil
+}
+
+// nlIPRoute converts the route to a netlink route.
+func (f *SRv6FIB) nlIPRoute(r *IPRoute) (*netlink.Route, error) {
+	dst := r.Prefix
+	nr := &netlink.Route{Dst: &dst, Protocol: unix.RTPROT_ISIS}
+	for _, nh := range r.NextHops {
+		index, err := f.linkIndex(nh.Intf)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %s", &dst, err)
+		}
+		nr.MultiPath = append(nr.MultiPath, &netlink.NexthopInfo{LinkIndex: index, Gw: nh.Gateway})
+	}
+	if len(nr.MultiPath) == 1 {
+		nh := nr.MultiPath[0]
+		nr.LinkIndex, nr.Gw, nr.MultiPath = nh.LinkIndex, nh.Gw, nil
+	}
+	return nr, nil
+}
+
+// nlLocalSID converts the local SID to a netlink seg6local route.
+func (f *SRv6FIB) nlLocalSID(s *SRv6LocalSID) (*netlink.Route, error) {
+	intf := s.Intf
+	if intf == "" {
+		intf = "lo"
+	}
+	index, err := f.linkIndex(intf)
+	if err != nil {
+		return nil, fmt.Errorf("SID %s: %s", s.SID, err)
+	}
+	encap := &netlink.SEG6LocalEncap{}
+	encap.Flags[nl.SEG6_LOCAL_ACTION] = true
+	switch s.Behavior {
+	case SRv6BehaviorEnd:
+		encap.Action = nl.SEG6_LOCAL_ACTION_END
+	case SRv6BehaviorEndX:
+		encap.Action = nl.SEG6_LOCAL_ACTION_END_X
+		encap.Flags[nl.SEG6_LOCAL_NH6] = true
+		encap.In6Addr = s.Gateway
+	default:
+		return nil, fmt.Errorf("SID %s: unsupported behavior %d", s.SID, s.Behavior)
+	}
+	return &netlink.Route{
+		Dst:       &net.IPNet{IP: s.SID, Mask: net.CIDRMask(128, 128)},
+		LinkIndex: index,
+		Protocol:  unix.RTPROT_ISIS,
+		Encap:     encap,
+	}, nil
+}
+
+// Update makes the IS-IS IPv6 routes in the kernel match the locator 'routes'
+// and the local 'sids'. Routes that fail to install are skipped, the first
+// error is returned.
+func (f *SRv6FIB) Update(routes []IPRoute, sids []SRv6LocalSID) error {
+	var rerr error
+	seterr := func(err error) {
+		if rerr == nil {
+			rerr = err
+		}
+	}
+	want := make(map[string]*srv6Entry)
+	for i := range routes {
+		r := &routes[i]
+		nr, err := f.nlIPRoute(r)
+		if err != nil {
+			seterr(err)
+			continue
+		}
+		want[r.Prefix.String()] = &srv6Entry{nr, fmt.Sprint(*r)}
+	}
+	for i := range sids {
+		s := &sids[i]
+		nr, err := f.nlLocalSID(s)
+		if err != nil {
+			seterr(err)
+			continue
+		}
+		want[nr.Dst.String()] = &srv6Entry{nr, fmt.Sprint(*s)}
+	}
+	for key, e := range f.installed {
+		if want[key] != nil {
+			continue
+		}
+		if err := f.h.RouteDel(e.nr); err != nil {
+			seterr(err)
+			continue
+		}
+		delete(f.installed, key)
+	}
+	for key, e := range want {
+		if ie := f.installed[key]; ie != nil && ie.desc == e.desc {
+			continue
+		}
+		if err := f.h.RouteReplace(e.nr); err != nil {
+			seterr(fmt.Errorf("%s: %s", key, err))
+			continue
+		}
+		f.installed[key] = e
+	}
+	return rerr
+}
@@ -0,0 +1,69 @@
+package kernel
+
+import (
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+	"net"
+	"testing"
+)
+
+func isisIPv6Routes(t *testing.T, h *netlink.Handle) map[string]netlink.Route {
+	filter := &netlink.Route{Protocol: unix.RTPROT_ISIS}
+	routes, err := h.RouteListFiltered(netlink.FAMILY_V6, filter, netlink.RT_FILTER_PROTOCOL)
+	if err != nil {
+		t.Fatalf("RouteListFiltered: %s", err)
+	}
+	rmap := make(map[string]netlink.Route)
+	for _, r := range routes {
+		rmap[r.Dst.String()] = r
+	}
+	return rmap
+}
+
+func TestSRv6FIB(t *testing.T) {
+	path, h := newTestNS(t, false)
+	f, err := NewSRv6FIB(path)
+	if err != nil {
+		t.Fatalf("NewSRv6FIB: %s", err)
+	}
+	defer f.Close()
+
+	gw := net.ParseIP("2001:db8::2")
+	_, loc, _ := net.ParseCIDR("2001:db8:0:2::/64")
+	routes := []IPRoute{{Prefix: *loc, NextHops: []IPNextHop{{Intf: "test0", Gateway: gw}}}}
+	sids := []SRv6LocalSID{
+		{SID: net.ParseIP("2001:db8:0:1::1"), Behavior: SRv6BehaviorEnd},
+		{SID: net.ParseIP("2001:db8:0:1::100"), Behavior: SRv6BehaviorEndX, Intf: "test0", Gateway: gw},
+	}
+	if err = f.Update(routes, sids); err != nil {
+		t.Skipf("Update (no seg6local support?): %s", err)
+	}
+	got := isisIPv6Routes(t, h)
+	if len(got) != 3 {
+		t.Fatalf("Got %d routes: %v", len(got), got)
+	}
+	if r := got["2001:db8:0:2::/64"]; !r.Gw.Equal(gw) {
+		t.Errorf("Bad locator route %s", r)
+	}
+	if r := got["2001:db8:0:1::100/128"]; r.Encap == nil || r.Encap.Type() != nl.LWTUNNEL_ENCAP_SEG6_LOCAL {
+		t.Errorf("Bad End.X route %s", r)
+	}
+
+	if err = f.Update(nil, sids[:1]); err != nil {
+		t.Fatalf("Update: %s", err)
+	}
+	if got = isisIPv6Routes(t, h); len(got) != 1 {
+		t.Errorf("Routes not removed: %v", got)
+	}
+
+	// A new SRv6FIB flushes the routes of the old one.
+	f2, err := NewSRv6FIB(path)
+	if err != nil {
+		t.Fatalf("NewSRv6FIB: %s", err)
+	}
+	defer f2.Close()
+	if got = isisIPv6Routes(t, h); len(got) != 0 {
+		t.Errorf("Routes not flushed: %v", got)
+	}
+}
@@ -0,0 +1,26 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+//go:build !linux
+// +build !linux
+
+// Package kernel implements access to the kernel routing tables.
+// This file contains the SRv6 forwarding table stubs.
+package kernel
+
+// SRv6FIB programs the kernel SRv6 locator routes and local SIDs.
+type SRv6FIB struct{}
+
+// NewSRv6FIB returns an SRv6FIB for the network namespace at 'nsPath'.
+func NewSRv6FIB(nsPath string) (*SRv6FIB, error) {
+	return nil, errNotSupported
+}
+
+// Close releases the SRv6FIB.
+func (f *SRv6FIB) Close() {}
+
+// Update makes the IS-IS IPv6 routes in the kernel match 'routes' and 'sids'.
+func (f *SRv6FIB) Update(routes []IPRoute, sids []SRv6LocalSID) error {
+	return errNotSupported
+}
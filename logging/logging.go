@@ -18,6 +18,7 @@ const (
 	DbgFLSP
 	DbgFUpd
 	DbgFFlags
+	DbgFSPF
 )
 
 // FlagNames map a string name value to the flag bit value
@@ -27,6 +28,7 @@ var FlagNames = map[string]DbgFlags{
 	"flags":  DbgFFlags,
 	"lsp":    DbgFLSP,
 	"packet": DbgFPkt,
+	"spf":    DbgFSPF,
 	"update": DbgFUpd,
 }
 
@@ -38,6 +40,7 @@ var FlagTags = map[DbgFlags]string{
 	DbgFLSP:   "LSPGEN: ",
 	DbgFUpd:   "UPDATE: ",
 	DbgFFlags: "FLAGS: ",
+	DbgFSPF:   "SPF: ",
 }
 
 // GlbDebug are the enabled debugs.
@@ -0,0 +1,133 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package policy implements the routing policy used to select and modify
+// prefixes advertised by IS-IS. This file contains the policies.
+package policy
+
+import (
+	"fmt"
+	"github.com/choppsv1/goisis/clns"
+	"github.com/choppsv1/goisis/tlv"
+	"net"
+)
+
+// Route is the prefix information a policy is evaluated against and
+// modifies.
+type Route struct {
+	Prefix *net.IPNet
+	Metric uint32
+	Tags   []uint32
+	Level  clns.Level
+}
+
+// Match are the conditions of a statement, all present conditions must match.
+// Tags matches if the route carries any of the tags.
+type Match struct {
+	PrefixList string         `json:"prefix-list,omitempty"`
+	Tags       []uint32       `json:"tag,omitempty"`
+	Level      clns.LevelFlag `json:"level,omitempty"`
+
+	prefixList *PrefixList
+}
+
+// Statement is a policy statement. If the statement matches and the action is
+// not deny the route is accepted after setting the metric and adding the tags.
+type Statement struct {
+	Match     Match    `json:"match"`
+	Action    Action   `json:"action,omitempty"`
+	SetMetric *uint32  `json:"set-metric,omitempty"`
+	AddTags   []uint32 `json:"add-tag,omitempty"`
+}
+
+// Policy is a named ordered list of statements. The first matching statement
+// decides, no matching statement rejects the route.
+type Policy struct {
+	Name       string      `json:"name"`
+	Statements []Statement `json:"statements"`
+}
+
+// Resolve validates the policy and resolves the prefix lists it references.
+func (p *Policy) Resolve(lists []*PrefixList) error {
+	for i := range p.Statements {
+		s := &p.Statements[i]
+		if s.Action != "" && s.Action != Permit && s.Action != Deny {
+			return fmt.Errorf("policy %s: invalid action %q", p.Name, s.Action)
+		}
+		if len(s.AddTags) > tlv.MaxPrefixTags {
+			return fmt.Errorf("policy %s: more than %d add-tag values", p.Name, tlv.MaxPrefixTags)
+		}
+		if s.Match.PrefixList == "" {
+			continue
+		}
+		s.Match.prefixList = nil
+		for _, pl := range lists {
+			if pl.Name == s.Match.PrefixList {
+				s.Match.prefixList = pl
+				break
+			}
+		}
+		if s.Match.prefixList == nil {
+			return fmt.Errorf("policy %s: unknown prefix-list %s", p.Name, s.Match.PrefixList)
+		}
+	}
+	return nil
+}
+
+// matches returns true if the match conditions are met by the route.
+func (m *Match) matches(r *Route) bool {
+	if m.PrefixList != "" && !m.prefixList.Permit(r.Prefix) {
+		return false
+	}
+	if m.Level != 0 && !m.Level.IsLevelEnabled(r.Level) {
+		return false
+	}
+	if len(m.Tags) == 0 {
+		return true
+	}
+	for _, t := range r.Tags {
+		for _, mt := range m.Tags {
+			if t == mt {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Apply evaluates the policy for the route modifying it according to the
+// matching statement. It returns true if the route is accepted.
+func (p *Policy) Apply(r *Route) bool {
+	if p == nil {
+		return true
+	}
+	for i := range p.Statements {
+		s := &p.Statements[i]
+		if !s.Match.matches(r) {
+			continue
+		}
+		if s.Action == Deny {
+			return false
+		}
+		if s.SetMetric != nil {
+			r.Metric = *s.SetMetric
+		}
+		for _, t := range s.AddTags {
+			if !hasTag(r.Tags, t) {
+				r.Tags = append(r.Tags, t)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func hasTag(tags []uint32, tag uint32) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
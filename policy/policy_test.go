@@ -0,0 +1,113 @@
+package policy
+
+import (
+	"github.com/choppsv1/goisis/clns"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func uint32p(v uint32) *uint32 {
+	return &v
+}
+
+func testPolicy(t *testing.T) *Policy {
+	lists := []*PrefixList{
+		{
+			Name: "ten",
+			Entries: []PrefixEntry{
+				{Prefix: mustPrefix(t, "10.0.0.0/8"), Le: 24},
+				{Prefix: mustPrefix(t, "2001:db8::/32"), Le: 64},
+			},
+		},
+		{
+			Name: "block",
+			Entries: []PrefixEntry{
+				{Prefix: mustPrefix(t, "10.99.0.0/16"), Le: 32},
+			},
+		},
+	}
+	p := &Policy{
+		Name: "test",
+		Statements: []Statement{
+			{Match: Match{PrefixList: "block"}, Action: Deny},
+			{Match: Match{Tags: []uint32{666}}, Action: Deny},
+			{Match: Match{PrefixList: "ten", Level: clns.L1Flag}, SetMetric: uint32p(100)},
+			{Match: Match{PrefixList: "ten"}, AddTags: []uint32{200}},
+			{Match: Match{Tags: []uint32{1, 2}}, SetMetric: uint32p(5), AddTags: []uint32{2, 3}},
+		},
+	}
+	if err := p.Resolve(lists); err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	return p
+}
+
+func TestPolicyApply(t *testing.T) {
+	p := testPolicy(t)
+	tests := []struct {
+		prefix  string
+		metric  uint32
+		tags    []uint32
+		level   clns.Level
+		accept  bool
+		emetric uint32
+		etags   []uint32
+	}{
+		{"10.1.0.0/16", 10, nil, 1, true, 100, nil},
+		{"10.1.0.0/16", 10, nil, 2, true, 10, []uint32{200}},
+		{"10.1.1.1/32", 10, nil, 2, false, 10, nil},
+		{"10.99.1.0/24", 10, nil, 1, false, 10, nil},
+		{"10.1.0.0/16", 10, []uint32{666}, 1, false, 10, []uint32{666}},
+		{"2001:db8:1::/48", 10, []uint32{7}, 2, true, 10, []uint32{7, 200}},
+		{"2001:db8:1::/48", 10, []uint32{200}, 2, true, 10, []uint32{200}},
+		{"192.168.0.0/16", 10, []uint32{2}, 2, true, 5, []uint32{2, 3}},
+		{"192.168.0.0/16", 10, []uint32{4}, 2, false, 10, []uint32{4}},
+		{"192.168.0.0/16", 10, nil, 1, false, 10, nil},
+	}
+	for _, test := range tests {
+		pfx := mustPrefix(t, test.prefix)
+		r := &Route{
+			Prefix: (*net.IPNet)(&pfx),
+			Metric: test.metric,
+			Tags:   append([]uint32(nil), test.tags...),
+			Level:  test.level,
+		}
+		if got := p.Apply(r); got != test.accept {
+			t.Errorf("%s tags %v level %d: accept %v want %v", test.prefix, test.tags, test.level, got, test.accept)
+			continue
+		}
+		if r.Metric != test.emetric || !reflect.DeepEqual(r.Tags, test.etags) {
+			t.Errorf("%s tags %v level %d: got metric %d tags %v want %d %v",
+				test.prefix, test.tags, test.level, r.Metric, r.Tags, test.emetric, test.etags)
+		}
+	}
+}
+
+func TestPolicyResolve(t *testing.T) {
+	lists := []*PrefixList{{Name: "ten"}}
+	tests := []struct {
+		stmt  Statement
+		valid bool
+	}{
+		{Statement{Match: Match{PrefixList: "ten"}}, true},
+		{Statement{Match: Match{PrefixList: "eleven"}}, false},
+		{Statement{Action: "drop"}, false},
+		{Statement{Action: Deny}, true},
+		{Statement{AddTags: make([]uint32, 64)}, false},
+	}
+	for _, test := range tests {
+		p := &Policy{Name: "test", Statements: []Statement{test.stmt}}
+		if err := p.Resolve(lists); (err == nil) != test.valid {
+			t.Errorf("%v: valid %v got %v", test.stmt, test.valid, err)
+		}
+	}
+}
+
+func TestNilPolicyAccepts(t *testing.T) {
+	var p *Policy
+	pfx := mustPrefix(t, "10.0.0.0/8")
+	if !p.Apply(&Route{Prefix: (*net.IPNet)(&pfx)}) {
+		t.Errorf("nil policy rejects")
+	}
+}
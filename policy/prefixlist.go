@@ -0,0 +1,149 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// Package policy implements the routing policy used to select and modify
+// prefixes advertised by IS-IS. This file contains the prefix lists.
+package policy
+
+import (
+	"fmt"
+	"net"
+)
+
+// Prefix is a net.IPNet that converts to and from CIDR notation text.
+type Prefix net.IPNet
+
+// ParsePrefix parses a prefix in CIDR notation.
+func ParsePrefix(s string) (Prefix, error) {
+	_, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return Prefix{}, err
+	}
+	if ip := ipnet.IP.To4(); ip != nil {
+		ipnet.IP = ip
+	}
+	return Prefix(*ipnet), nil
+}
+
+func (p Prefix) String() string {
+	return (*net.IPNet)(&p).String()
+}
+
+// MarshalText converts the prefix to CIDR notation.
+func (p Prefix) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText parses a prefix in CIDR notation.
+func (p *Prefix) UnmarshalText(text []byte) error {
+	np, err := ParsePrefix(string(text))
+	if err != nil {
+		return err
+	}
+	*p = np
+	return nil
+}
+
+// Len returns the prefix length.
+func (p *Prefix) Len() int {
+	l, _ := p.Mask.Size()
+	return l
+}
+
+// Bits returns the address length in bits of the prefix family.
+func (p *Prefix) Bits() int {
+	_, b := p.Mask.Size()
+	return b
+}
+
+// Action is the action of a prefix list entry.
+type Action string
+
+// Action values.
+const (
+	Permit Action = "permit"
+	Deny   Action = "deny"
+)
+
+// PrefixEntry is an entry in a prefix list. Without Ge or Le the entry matches
+// the prefix exactly, otherwise it matches the prefixes covered by Prefix with
+// a length in the range [Ge, Le]. A missing Ge is the length of Prefix and a
+// missing Le is the address length.
+type PrefixEntry struct {
+	Prefix Prefix `json:"prefix"`
+	Ge     int    `json:"ge,omitempty"`
+	Le     int    `json:"le,omitempty"`
+	Action Action `json:"action,omitempty"`
+}
+
+// PrefixList is a named ordered list of prefix entries. The first matching
+// entry decides, no matching entry denies.
+type PrefixList struct {
+	Name    string        `json:"name"`
+	Entries []PrefixEntry `json:"entries"`
+}
+
+// Validate checks the entry values.
+func (e *PrefixEntry) Validate() error {
+	plen, bits := e.Prefix.Len(), e.Prefix.Bits()
+	if e.Ge != 0 && (e.Ge < plen || e.Ge > bits) {
+		return fmt.Errorf("prefix %s invalid ge %d", e.Prefix, e.Ge)
+	}
+	if e.Le != 0 && (e.Le < plen || e.Le > bits || e.Le < e.Ge) {
+		return fmt.Errorf("prefix %s invalid le %d", e.Prefix, e.Le)
+	}
+	if e.Action != "" && e.Action != Permit && e.Action != Deny {
+		return fmt.Errorf("prefix %s invalid action %q", e.Prefix, e.Action)
+	}
+	return nil
+}
+
+// Validate checks all the entries of the prefix list.
+func (pl *PrefixList) Validate() error {
+	for i := range pl.Entries {
+		if err := pl.Entries[i].Validate(); err != nil {
+			return fmt.Errorf("prefix-list %s: %s", pl.Name, err)
+		}
+	}
+	return nil
+}
+
+// Match returns true if the entry matches the prefix.
+func (e *PrefixEntry) Match(p *net.IPNet) bool {
+	plen, bits := p.Mask.Size()
+	if bits != e.Prefix.Bits() {
+		return false
+	}
+	elen := e.Prefix.Len()
+	if e.Ge == 0 && e.Le == 0 {
+		if plen != elen {
+			return false
+		}
+	} else {
+		ge, le := e.Ge, e.Le
+		if ge == 0 {
+			ge = elen
+		}
+		if le == 0 {
+			le = bits
+		}
+		if plen < ge || plen > le {
+			return false
+		}
+	}
+	return (*net.IPNet)(&e.Prefix).Contains(p.IP)
+}
+
+// Permit returns true if the first matching entry permits the prefix.
+func (pl *PrefixList) Permit(p *net.IPNet) bool {
+	if pl == nil {
+		return false
+	}
+	for i := range pl.Entries {
+		if pl.Entries[i].Match(p) {
+			return pl.Entries[i].Action != Deny
+		}
+	}
+	return false
+}
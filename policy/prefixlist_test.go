@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"net"
+	"testing"
+)
+
+func mustPrefix(t *testing.T, s string) Prefix {
+	p, err := ParsePrefix(s)
+	if err != nil {
+		t.Fatalf("bad prefix %s: %s", s, err)
+	}
+	return p
+}
+
+func TestPrefixEntryMatch(t *testing.T) {
+	tests := []struct {
+		entry  string
+		ge, le int
+		prefix string
+		match  bool
+	}{
+		{"10.0.0.0/8", 0, 0, "10.0.0.0/8", true},
+		{"10.0.0.0/8", 0, 0, "10.1.0.0/16", false},
+		{"10.0.0.0/8", 16, 0, "10.1.0.0/16", true},
+		{"10.0.0.0/8", 16, 0, "10.1.1.0/24", true},
+		{"10.0.0.0/8", 16, 0, "10.0.0.0/8", false},
+		{"10.0.0.0/8", 0, 16, "10.0.0.0/8", true},
+		{"10.0.0.0/8", 0, 16, "10.1.1.0/24", false},
+		{"10.0.0.0/8", 16, 24, "11.1.1.0/24", false},
+		{"10.0.0.0/8", 0, 32, "2001:db8::/32", false},
+		{"2001:db8::/32", 0, 64, "2001:db8:1::/48", true},
+		{"2001:db8::/32", 0, 0, "2001:db9::/32", false},
+		{"0.0.0.0/0", 0, 32, "192.168.1.1/32", true},
+	}
+	for _, test := range tests {
+		e := PrefixEntry{Prefix: mustPrefix(t, test.entry), Ge: test.ge, Le: test.le}
+		if err := e.Validate(); err != nil {
+			t.Errorf("%s ge %d le %d: %s", test.entry, test.ge, test.le, err)
+			continue
+		}
+		p := mustPrefix(t, test.prefix)
+		if got := e.Match((*net.IPNet)(&p)); got != test.match {
+			t.Errorf("%s ge %d le %d match %s: got %v want %v",
+				test.entry, test.ge, test.le, test.prefix, got, test.match)
+		}
+	}
+}
+
+func TestPrefixEntryValidate(t *testing.T) {
+	tests := []struct {
+		entry  string
+		ge, le int
+		action Action
+		valid  bool
+	}{
+		{"10.0.0.0/8", 0, 0, "", true},
+		{"10.0.0.0/8", 4, 0, "", false},
+		{"10.0.0.0/8", 0, 33, "", false},
+		{"10.0.0.0/8", 24, 16, "", false},
+		{"10.0.0.0/8", 0, 0, "drop", false},
+		{"2001:db8::/32", 48, 128, Deny, true},
+	}
+	for _, test := range tests {
+		e := PrefixEntry{Prefix: mustPrefix(t, test.entry), Ge: test.ge, Le: test.le, Action: test.action}
+		if err := e.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s ge %d le %d action %q: valid %v got %v",
+				test.entry, test.ge, test.le, test.action, test.valid, err)
+		}
+	}
+}
+
+func TestPrefixListPermit(t *testing.T) {
+	pl := &PrefixList{
+		Name: "test",
+		Entries: []PrefixEntry{
+			{Prefix: mustPrefix(t, "10.1.0.0/16"), Le: 32, Action: Deny},
+			{Prefix: mustPrefix(t, "10.0.0.0/8"), Le: 32},
+		},
+	}
+	tests := []struct {
+		prefix string
+		permit bool
+	}{
+		{"10.1.2.0/24", false},
+		{"10.2.2.0/24", true},
+		{"11.0.0.0/8", false},
+	}
+	for _, test := range tests {
+		p := mustPrefix(t, test.prefix)
+		if got := pl.Permit((*net.IPNet)(&p)); got != test.permit {
+			t.Errorf("%s: got %v want %v", test.prefix, got, test.permit)
+		}
+	}
+	var nilpl *PrefixList
+	p := mustPrefix(t, "10.2.2.0/24")
+	if nilpl.Permit((*net.IPNet)(&p)) {
+		t.Errorf("nil prefix list permits")
+	}
+}
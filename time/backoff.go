@@ -0,0 +1,132 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+package time
+
+import (
+	"fmt"
+	"time"
+)
+
+// BackoffState is the current state of a Backoff.
+type BackoffState uint8
+
+// BackoffState values.
+const (
+	BackoffQuiet BackoffState = iota
+	BackoffInitial
+	BackoffSecondary
+	BackoffMaximum
+)
+
+var backoffStateStrings = map[BackoffState]string{
+	BackoffQuiet:     "quiet",
+	BackoffInitial:   "initial-wait",
+	BackoffSecondary: "secondary-wait",
+	BackoffMaximum:   "maximum-wait",
+}
+
+func (s BackoffState) String() string {
+	ss, ok := backoffStateStrings[s]
+	if !ok {
+		return fmt.Sprintf("Unknown BackoffState(%d)", s)
+	}
+	return ss
+}
+
+// MarshalText converts the backoff state to text (yang value).
+func (s BackoffState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Backoff computes exponential back-off waits using initial, secondary and
+// maximum wait values (e.g., RFC8405 style throttling for LSP generation).
+// The first event after a quiet period waits Initial, the next event waits
+// Secondary and each following event doubles the wait up to Max. If no event
+// is seen for 2 * Max after the wait the backoff returns to quiet, this is
+// evaluated when the state is used. Clock returns the current time, it may be
+// replaced in tests.
+type Backoff struct {
+	Initial   time.Duration
+	Secondary time.Duration
+	Max       time.Duration
+	Clock     func() time.Time
+
+	state BackoffState
+	wait  time.Duration
+	last  time.Time
+}
+
+// NewBackoff returns a new Backoff in the quiet state.
+func NewBackoff(initial, secondary, max time.Duration) *Backoff {
+	b := &Backoff{}
+	b.Set(initial, secondary, max)
+	return b
+}
+
+func (b *Backoff) String() string {
+	return fmt.Sprintf("Backoff(%s wait:%s init:%s sec:%s max:%s)",
+		b.state, b.wait, b.Initial, b.Secondary, b.Max)
+}
+
+// Set changes the wait values, the current state is left alone.
+func (b *Backoff) Set(initial, secondary, max time.Duration) {
+	if secondary < initial {
+		secondary = initial
+	}
+	if max < secondary {
+		max = secondary
+	}
+	b.Initial = initial
+	b.Secondary = secondary
+	b.Max = max
+}
+
+func (b *Backoff) now() time.Time {
+	if b.Clock == nil {
+		return time.Now()
+	}
+	return b.Clock()
+}
+
+// update returns to quiet if no event was seen for 2 * Max after the wait.
+func (b *Backoff) update(now time.Time) {
+	if b.state != BackoffQuiet && now.Sub(b.last) > 2*b.Max {
+		b.state = BackoffQuiet
+		b.wait = 0
+	}
+}
+
+// Next returns the wait to use for an event occurring now, and advances the
+// backoff state.
+func (b *Backoff) Next() time.Duration {
+	now := b.now()
+	b.update(now)
+	if b.state == BackoffQuiet {
+		b.state = BackoffInitial
+		b.wait = b.Initial
+	} else if b.state == BackoffInitial {
+		b.state = BackoffSecondary
+		b.wait = b.Secondary
+	} else {
+		b.wait *= 2
+		if b.wait >= b.Max {
+			b.state = BackoffMaximum
+			b.wait = b.Max
+		}
+	}
+	b.last = now.Add(b.wait)
+	return b.wait
+}
+
+// State returns the current state of the backoff.
+func (b *Backoff) State() BackoffState {
+	b.update(b.now())
+	return b.state
+}
+
+// Wait returns the most recently computed wait, zero when quiet.
+func (b *Backoff) Wait() time.Duration {
+	b.update(b.now())
+	return b.wait
+}
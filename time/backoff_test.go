@@ -0,0 +1,59 @@
+package time
+
+import (
+	"testing"
+	"time"
+)
+
+func testBackoff() (*Backoff, *testClock) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	b := NewBackoff(50*time.Millisecond, 200*time.Millisecond, time.Second)
+	b.Clock = clock.Now
+	return b, clock
+}
+
+func expectNext(t *testing.T, b *Backoff, wait time.Duration, state BackoffState) {
+	t.Helper()
+	if rwait := b.Next(); rwait != wait {
+		t.Errorf("Next() = %s want %s", rwait, wait)
+	}
+	if b.State() != state || b.Wait() != wait {
+		t.Errorf("State() = %s Wait() = %s want %s %s", b.State(), b.Wait(), state, wait)
+	}
+}
+
+func TestBackoffStates(t *testing.T) {
+	b, clock := testBackoff()
+	if b.State() != BackoffQuiet || b.Wait() != 0 {
+		t.Errorf("initial state %s wait %s", b.State(), b.Wait())
+	}
+	expectNext(t, b, 50*time.Millisecond, BackoffInitial)
+	clock.advance(50 * time.Millisecond)
+	expectNext(t, b, 200*time.Millisecond, BackoffSecondary)
+	clock.advance(200 * time.Millisecond)
+	expectNext(t, b, 400*time.Millisecond, BackoffSecondary)
+	clock.advance(400 * time.Millisecond)
+	expectNext(t, b, 800*time.Millisecond, BackoffSecondary)
+	clock.advance(800 * time.Millisecond)
+	expectNext(t, b, time.Second, BackoffMaximum)
+	clock.advance(time.Second)
+	expectNext(t, b, time.Second, BackoffMaximum)
+
+	// Quiet after 2 * Max following the end of the wait without Next.
+	clock.advance(3 * time.Second)
+	if b.State() != BackoffMaximum {
+		t.Errorf("State() = %s before 2 * Max", b.State())
+	}
+	clock.advance(time.Millisecond)
+	if b.State() != BackoffQuiet || b.Wait() != 0 {
+		t.Errorf("State() = %s Wait() = %s after 2 * Max", b.State(), b.Wait())
+	}
+	expectNext(t, b, 50*time.Millisecond, BackoffInitial)
+}
+
+func TestBackoffSet(t *testing.T) {
+	b := NewBackoff(time.Second, 100*time.Millisecond, 10*time.Millisecond)
+	if b.Initial != time.Second || b.Secondary != time.Second || b.Max != time.Second {
+		t.Errorf("Set() didn't order the waits %s", b)
+	}
+}
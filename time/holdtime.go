@@ -11,12 +11,17 @@ type HoldTimer struct {
 	end time.Time
 }
 
-// NewHoldTimer creates a new hold timer.
+// NewHoldTimer creates a new hold timer, holdtime is in seconds.
 func NewHoldTimer(holdtime uint16, expireF func()) *HoldTimer {
-	ns := time.Second * time.Duration(holdtime)
+	return NewHoldTimerDuration(time.Second*time.Duration(holdtime), expireF)
+}
+
+// NewHoldTimerDuration creates a new hold timer with a sub-second precision
+// hold time.
+func NewHoldTimerDuration(holdtime time.Duration, expireF func()) *HoldTimer {
 	return &HoldTimer{
-		t:   time.AfterFunc(ns, expireF),
-		end: time.Now().Add(ns),
+		t:   time.AfterFunc(holdtime, expireF),
+		end: time.Now().Add(holdtime),
 	}
 }
 
@@ -26,8 +31,14 @@ func (t *HoldTimer) Stop() bool {
 }
 
 // Reset resets the timer if possible, if it has already fired then false is
-// returned.
+// returned. The holdtime is in seconds.
 func (t *HoldTimer) Reset(holdtime uint16) bool {
+	return t.ResetDuration(time.Second * time.Duration(holdtime))
+}
+
+// ResetDuration resets the timer to a sub-second precision hold time, see
+// Reset.
+func (t *HoldTimer) ResetDuration(holdtime time.Duration) bool {
 
 	// XXX It's very important that Stop either be called prior or we know
 	// its expired before resetting here. Just calling t.t.Stop() here will
@@ -39,19 +50,22 @@ func (t *HoldTimer) Reset(holdtime uint16) bool {
 	//      return false
 	// }
 
-	ns := time.Second * time.Duration(holdtime)
-	t.end = time.Now().Add(ns)
-	t.t.Reset(ns)
+	t.end = time.Now().Add(holdtime)
+	t.t.Reset(holdtime)
 	return true
 }
 
-// Until returns the number of seconds until the timer will fire.
+// Until returns the number of whole seconds until the timer will fire.
 func (t *HoldTimer) Until() uint16 {
-	if d := time.Until(t.end); d < 0 {
-		return 0
-	} else {
-		return uint16(d / time.Second)
+	return uint16(t.Remaining() / time.Second)
+}
+
+// Remaining returns the time left until the timer will fire.
+func (t *HoldTimer) Remaining() time.Duration {
+	if d := time.Until(t.end); d > 0 {
+		return d
 	}
+	return 0
 }
 
 //
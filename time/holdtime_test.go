@@ -0,0 +1,47 @@
+package time
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHoldTimer(t *testing.T) {
+	fired := make(chan bool, 1)
+	ht := NewHoldTimerDuration(300*time.Millisecond, func() { fired <- true })
+	if r := ht.Remaining(); r <= 200*time.Millisecond || r > 300*time.Millisecond {
+		t.Errorf("remaining %s", r)
+	}
+	if ht.Until() != 0 {
+		t.Errorf("until %d want 0", ht.Until())
+	}
+	if !ht.Stop() {
+		t.Fatalf("failed to stop")
+	}
+	ht.ResetDuration(20 * time.Millisecond)
+	select {
+	case <-fired:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("hold timer didn't fire")
+	}
+	if ht.Remaining() != 0 {
+		t.Errorf("remaining %s after firing", ht.Remaining())
+	}
+
+	ht = NewHoldTimer(30, func() {})
+	defer ht.Stop()
+	if ht.Until() != 29 {
+		t.Errorf("until %d want 29", ht.Until())
+	}
+}
+
+func TestJitter(t *testing.T) {
+	ival := 333 * time.Millisecond
+	for i := 0; i < 1000; i++ {
+		if j := Jitter(ival, DefJitter); j < ival*3/4 || j > ival {
+			t.Fatalf("jittered %s out of range", j)
+		}
+	}
+	if Jitter(ival, 0) != ival {
+		t.Errorf("zero jitter changed interval")
+	}
+}
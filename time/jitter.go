@@ -0,0 +1,22 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+package time
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefJitter is the percentage periodic timers are jittered by (ISO10589
+// section 10.1).
+const DefJitter = 25
+
+// Jitter returns the interval reduced by a random amount of up to percent
+// percent, so that periodic events of different systems don't synchronize.
+func Jitter(ival time.Duration, percent int) time.Duration {
+	if percent <= 0 || ival <= 0 {
+		return ival
+	}
+	return ival - time.Duration(rand.Int63n(int64(ival)*int64(percent)/100+1))
+}
@@ -0,0 +1,182 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+package time
+
+import (
+	"fmt"
+	"time"
+)
+
+// SPFDelayState is the current state of an SPFDelay.
+type SPFDelayState uint8
+
+// SPFDelayState values (RFC8405 section 5).
+const (
+	SPFDelayQuiet SPFDelayState = iota
+	SPFDelayShortWait
+	SPFDelayLongWait
+)
+
+var spfDelayStateStrings = map[SPFDelayState]string{
+	SPFDelayQuiet:     "quiet",
+	SPFDelayShortWait: "short-wait",
+	SPFDelayLongWait:  "long-wait",
+}
+
+func (s SPFDelayState) String() string {
+	ss, ok := spfDelayStateStrings[s]
+	if !ok {
+		return fmt.Sprintf("Unknown SPFDelayState(%d)", s)
+	}
+	return ss
+}
+
+// MarshalText converts the SPF delay state to text (yang value).
+func (s SPFDelayState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// SPFDelay is the RFC8405 SPF back-off state machine. The first IGP event in
+// the quiet state schedules SPF after Initial and starts the time to learn
+// and hold-down intervals. Until TimeToLearn has passed SPF is scheduled after
+// Short, after that after Long. The state returns to quiet when no event is
+// seen for HoldDown.
+//
+// The hold-down and time to learn timers are evaluated when the state is
+// used, the SPF timer is run by the owner: Event returns the delay of the SPF
+// timer to start and SPFStarted is called when SPF runs. Clock returns the
+// current time, it may be replaced in tests.
+type SPFDelay struct {
+	Initial     time.Duration
+	Short       time.Duration
+	Long        time.Duration
+	HoldDown    time.Duration
+	TimeToLearn time.Duration
+	Clock       func() time.Time
+
+	state   SPFDelayState
+	pending bool      // SPF timer running
+	first   time.Time // first event since quiet
+	last    time.Time // last event
+	nextSPF time.Time
+	lastSPF time.Time
+}
+
+// NewSPFDelay returns a new SPFDelay in the quiet state.
+func NewSPFDelay(initial, short, long, holddown, learn time.Duration) *SPFDelay {
+	d := &SPFDelay{}
+	d.Set(initial, short, long, holddown, learn)
+	return d
+}
+
+func (d *SPFDelay) String() string {
+	return fmt.Sprintf("SPFDelay(%s init:%s short:%s long:%s hold:%s learn:%s)",
+		d.state, d.Initial, d.Short, d.Long, d.HoldDown, d.TimeToLearn)
+}
+
+// Set changes the timer values, the current state is left alone.
+func (d *SPFDelay) Set(initial, short, long, holddown, learn time.Duration) {
+	if short < initial {
+		short = initial
+	}
+	if long < short {
+		long = short
+	}
+	d.Initial = initial
+	d.Short = short
+	d.Long = long
+	d.HoldDown = holddown
+	d.TimeToLearn = learn
+}
+
+func (d *SPFDelay) now() time.Time {
+	if d.Clock == nil {
+		return time.Now()
+	}
+	return d.Clock()
+}
+
+// update applies the expirations of the time to learn and hold-down timers.
+func (d *SPFDelay) update(now time.Time) {
+	if d.state == SPFDelayQuiet {
+		return
+	}
+	holddown := d.last.Add(d.HoldDown)
+	learn := d.first.Add(d.TimeToLearn)
+	if d.state == SPFDelayShortWait && !now.Before(learn) && learn.Before(holddown) {
+		d.state = SPFDelayLongWait
+	}
+	if !now.Before(holddown) {
+		d.state = SPFDelayQuiet
+	}
+}
+
+// Event records an IGP event. If the SPF timer isn't running it returns the
+// delay to start it with and true.
+func (d *SPFDelay) Event() (time.Duration, bool) {
+	now := d.now()
+	d.update(now)
+	var delay time.Duration
+	switch d.state {
+	case SPFDelayQuiet:
+		d.state = SPFDelayShortWait
+		d.first = now
+		delay = d.Initial
+	case SPFDelayShortWait:
+		delay = d.Short
+	case SPFDelayLongWait:
+		delay = d.Long
+	}
+	d.last = now
+	if d.pending {
+		return 0, false
+	}
+	d.pending = true
+	d.nextSPF = now.Add(delay)
+	return delay, true
+}
+
+// SPFStarted records the expiration of the SPF timer.
+func (d *SPFDelay) SPFStarted() {
+	d.pending = false
+	d.lastSPF = d.now()
+}
+
+// State returns the current state.
+func (d *SPFDelay) State() SPFDelayState {
+	d.update(d.now())
+	return d.state
+}
+
+// Remaining returns the time left until the time to learn and hold-down
+// timers expire, zero if not running.
+func (d *SPFDelay) Remaining() (learn, holddown time.Duration) {
+	now := d.now()
+	d.update(now)
+	if d.state == SPFDelayQuiet {
+		return 0, 0
+	}
+	if d.state == SPFDelayShortWait {
+		learn = d.first.Add(d.TimeToLearn).Sub(now)
+	}
+	return learn, d.last.Add(d.HoldDown).Sub(now)
+}
+
+// LastEvent returns the time of the last event, zero if none.
+func (d *SPFDelay) LastEvent() time.Time {
+	return d.last
+}
+
+// NextSPF returns the time the SPF timer expires, zero if not running.
+func (d *SPFDelay) NextSPF() time.Time {
+	if !d.pending {
+		return time.Time{}
+	}
+	return d.nextSPF
+}
+
+// LastSPF returns the time of the last SPF, zero if none.
+func (d *SPFDelay) LastSPF() time.Time {
+	return d.lastSPF
+}
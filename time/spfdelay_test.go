@@ -0,0 +1,120 @@
+package time
+
+import (
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func testSPFDelay() (*SPFDelay, *testClock) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	d := NewSPFDelay(50*time.Millisecond, 200*time.Millisecond, 5*time.Second,
+		10*time.Second, 500*time.Millisecond)
+	d.Clock = clock.Now
+	return d, clock
+}
+
+func expectEvent(t *testing.T, d *SPFDelay, delay time.Duration, start bool, state SPFDelayState) {
+	t.Helper()
+	rdelay, rstart := d.Event()
+	if rdelay != delay || rstart != start {
+		t.Errorf("Event() = %s, %v want %s, %v", rdelay, rstart, delay, start)
+	}
+	if d.State() != state {
+		t.Errorf("State() = %s want %s", d.State(), state)
+	}
+}
+
+func TestSPFDelayStates(t *testing.T) {
+	d, clock := testSPFDelay()
+	if d.State() != SPFDelayQuiet {
+		t.Errorf("initial state %s", d.State())
+	}
+
+	// QUIET -> SHORT_WAIT with the initial delay.
+	expectEvent(t, d, 50*time.Millisecond, true, SPFDelayShortWait)
+	// SPF timer is running.
+	clock.advance(10 * time.Millisecond)
+	expectEvent(t, d, 0, false, SPFDelayShortWait)
+	clock.advance(40 * time.Millisecond)
+	d.SPFStarted()
+
+	// Short delay until the time to learn expires.
+	clock.advance(100 * time.Millisecond)
+	expectEvent(t, d, 200*time.Millisecond, true, SPFDelayShortWait)
+	clock.advance(200 * time.Millisecond)
+	d.SPFStarted()
+	if learn, hold := d.Remaining(); learn != 150*time.Millisecond || hold != 9800*time.Millisecond {
+		t.Errorf("Remaining() = %s, %s", learn, hold)
+	}
+
+	// SHORT_WAIT -> LONG_WAIT on time to learn expiry.
+	clock.advance(150 * time.Millisecond)
+	if d.State() != SPFDelayLongWait {
+		t.Errorf("state %s want long-wait", d.State())
+	}
+	expectEvent(t, d, 5*time.Second, true, SPFDelayLongWait)
+	if next := d.NextSPF(); !next.Equal(clock.now.Add(5 * time.Second)) {
+		t.Errorf("NextSPF() = %s", next)
+	}
+	clock.advance(5 * time.Second)
+	d.SPFStarted()
+	if !d.NextSPF().IsZero() || !d.LastSPF().Equal(clock.now) {
+		t.Errorf("NextSPF() %s LastSPF() %s", d.NextSPF(), d.LastSPF())
+	}
+
+	// LONG_WAIT -> QUIET on hold-down expiry.
+	clock.advance(10 * time.Second)
+	if d.State() != SPFDelayQuiet {
+		t.Errorf("state %s want quiet", d.State())
+	}
+	expectEvent(t, d, 50*time.Millisecond, true, SPFDelayShortWait)
+}
+
+func TestSPFDelayHoldDown(t *testing.T) {
+	d, clock := testSPFDelay()
+	d.Set(50*time.Millisecond, 200*time.Millisecond, 5*time.Second,
+		100*time.Millisecond, 500*time.Millisecond)
+
+	// The hold-down expires before the time to learn: back to quiet.
+	expectEvent(t, d, 50*time.Millisecond, true, SPFDelayShortWait)
+	clock.advance(50 * time.Millisecond)
+	d.SPFStarted()
+	clock.advance(600 * time.Millisecond)
+	if d.State() != SPFDelayQuiet {
+		t.Errorf("state %s want quiet", d.State())
+	}
+	if learn, hold := d.Remaining(); learn != 0 || hold != 0 {
+		t.Errorf("Remaining() = %s, %s", learn, hold)
+	}
+
+	// Events keep resetting the hold-down.
+	expectEvent(t, d, 50*time.Millisecond, true, SPFDelayShortWait)
+	for i := 0; i < 10; i++ {
+		clock.advance(80 * time.Millisecond)
+		d.SPFStarted()
+		if i < 6 {
+			expectEvent(t, d, 200*time.Millisecond, true, SPFDelayShortWait)
+		} else {
+			expectEvent(t, d, 5*time.Second, true, SPFDelayLongWait)
+		}
+	}
+}
+
+func TestSPFDelaySet(t *testing.T) {
+	d := NewSPFDelay(100*time.Millisecond, 50*time.Millisecond, 10*time.Millisecond, time.Second, time.Second)
+	if d.Short != 100*time.Millisecond || d.Long != 100*time.Millisecond {
+		t.Errorf("Set did not order delays: %s", d)
+	}
+}
@@ -0,0 +1,62 @@
+// -*- coding: utf-8 -*-
+//
+// October 18 2026, Christian Hopps <chopps@gmail.com>
+
+// ===================================================
+// BFD-Enabled TLV (RFC6213)
+// ===================================================
+
+package tlv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/choppsv1/goisis/clns"
+)
+
+// bfdEnabledLen is the length of a BFD-Enabled TLV entry.
+const bfdEnabledLen = 3
+
+// BFDEnabled is an entry of the BFD-Enabled TLV, the topology and network
+// layer protocol for which BFD is enabled on the interface.
+type BFDEnabled struct {
+	MTID  uint16     `json:"mt-id"`
+	NLPID clns.NLPID `json:"nlpid"`
+}
+
+// BFDEnabledDecode returns the entries of the BFD-Enabled TLV.
+func (tlv Data) BFDEnabledDecode() ([]BFDEnabled, error) {
+	_, l, v, err := GetTLV(tlv)
+	if err != nil {
+		return nil, err
+	}
+	if l%bfdEnabledLen != 0 {
+		return nil, fmt.Errorf("Length of BFD-Enabled TLV %d not a multiple of %d", l, bfdEnabledLen)
+	}
+	ents := make([]BFDEnabled, 0, l/bfdEnabledLen)
+	for ; len(v) >= bfdEnabledLen; v = v[bfdEnabledLen:] {
+		ents = append(ents, BFDEnabled{
+			MTID:  binary.BigEndian.Uint16(v) & MTIDMask,
+			NLPID: clns.NLPID(v[2]),
+		})
+	}
+	return ents, nil
+}
+
+// AddBFDEnabled adds the BFD-Enabled TLV with the entries.
+func (bt *BufferTrack) AddBFDEnabled(ents []BFDEnabled) error {
+	if len(ents) == 0 {
+		return nil
+	}
+	if err := bt.OpenTLV(TypeBFDEnabled, nil); err != nil {
+		return err
+	}
+	for _, e := range ents {
+		mtid := e.MTID & MTIDMask
+		if err := bt.Add([]byte{byte(mtid >> 8), byte(mtid), byte(e.NLPID)}); err != nil {
+			return err
+		}
+	}
+	bt.CloseTLV(true)
+	return nil
+}
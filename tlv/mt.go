@@ -0,0 +1,181 @@
+// ===================================================
+// Multi-Topology TLVs (RFC5120)
+// ===================================================
+
+package tlv
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Multi-topology IDs (RFC5120).
+const (
+	MTIDStandard = 0 // IPv4 unicast and non-MT routers
+	MTIDIPv6     = 2 // IPv6 unicast
+	MTIDMask     = 0xFFF
+)
+
+// Multi-topology TLV flags (RFC5120).
+const (
+	MTFlagO = 0x8000 // Overload
+	MTFlagA = 0x4000 // Attached
+)
+
+// MT is a topology entry of the Multi-Topology TLV. The overload and attached
+// bits are only used for non-zero topologies in LSPs.
+type MT struct {
+	ID       uint16 `json:"mt-id"`
+	Overload bool   `json:"overload,omitempty"`
+	Attached bool   `json:"attached,omitempty"`
+}
+
+// MTISReach is an MT IS Reachability TLV.
+type MTISReach struct {
+	MTID uint16       `json:"mt-id"`
+	Nbrs []ISExtReach `json:"neighbors"`
+}
+
+// MTIPv4Prefixes is an MT IPv4 Reachability TLV.
+type MTIPv4Prefixes struct {
+	MTID     uint16          `json:"mt-id"`
+	Prefixes []ExtIPv4Prefix `json:"prefixes"`
+}
+
+// MTIPv6Prefixes is an MT IPv6 Reachability TLV.
+type MTIPv6Prefixes struct {
+	MTID     uint16       `json:"mt-id"`
+	Prefixes []IPv6Prefix `json:"prefixes"`
+}
+
+// MTDecode returns the topologies found in the Multi-Topology TLV.
+func (tlv Data) MTDecode() ([]MT, error) {
+	_, l, v, err := GetTLV(tlv)
+	if err != nil {
+		return nil, err
+	}
+	if l%2 != 0 {
+		return nil, fmt.Errorf("Length of MT TLV %d not a multiple of 2", l)
+	}
+	mts := make([]MT, 0, l/2)
+	for ; len(v) > 0; v = v[2:] {
+		val := binary.BigEndian.Uint16(v)
+		mts = append(mts, MT{
+			ID:       val & MTIDMask,
+			Overload: val&MTFlagO != 0,
+			Attached: val&MTFlagA != 0,
+		})
+	}
+	return mts, nil
+}
+
+// mtValue returns the MT ID of an MT reachability TLV and the TLV re-encoded
+// as the non-MT TLV of type typ for the existing decoders.
+func (tlv Data) mtValue(typ Type) (uint16, Data, error) {
+	_, l, v, err := GetTLV(tlv)
+	if err != nil {
+		return 0, nil, err
+	}
+	if l < 2 {
+		return 0, nil, fmt.Errorf("Length of MT TLV %d too short", l)
+	}
+	t := make(Data, l)
+	t[0], t[1] = byte(typ), byte(l-2)
+	copy(t[2:], v[2:])
+	return binary.BigEndian.Uint16(v) & MTIDMask, t, nil
+}
+
+// MTISReachDecode returns the MT ID and neighbors of an MT IS Reachability
+// TLV.
+func (tlv Data) MTISReachDecode() (*MTISReach, error) {
+	mtid, t, err := tlv.mtValue(TypeExtIsReach)
+	if err != nil {
+		return nil, err
+	}
+	nbrs, err := t.ISExtReachDecode()
+	if err != nil {
+		return nil, err
+	}
+	return &MTISReach{MTID: mtid, Nbrs: nbrs}, nil
+}
+
+// MTIPv4PrefixDecode returns the MT ID and prefixes of an MT IPv4
+// Reachability TLV.
+func (tlv Data) MTIPv4PrefixDecode() (*MTIPv4Prefixes, error) {
+	mtid, t, err := tlv.mtValue(TypeExtIPv4Prefix)
+	if err != nil {
+		return nil, err
+	}
+	pfxs, err := t.IPv4PrefixDecode()
+	if err != nil {
+		return nil, err
+	}
+	return &MTIPv4Prefixes{MTID: mtid, Prefixes: pfxs}, nil
+}
+
+// MTIPv6PrefixDecode returns the MT ID and prefixes of an MT IPv6
+// Reachability TLV.
+func (tlv Data) MTIPv6PrefixDecode() (*MTIPv6Prefixes, error) {
+	mtid, t, err := tlv.mtValue(TypeIPv6Prefix)
+	if err != nil {
+		return nil, err
+	}
+	pfxs, err := t.IPv6PrefixDecode()
+	if err != nil {
+		return nil, err
+	}
+	return &MTIPv6Prefixes{MTID: mtid, Prefixes: pfxs}, nil
+}
+
+// AddMT adds the Multi-Topology TLV with the topologies.
+func (bt *BufferTrack) AddMT(mts []MT) error {
+	if len(mts) == 0 {
+		return nil
+	}
+	if err := bt.OpenTLV(TypeMT, nil); err != nil {
+		return err
+	}
+	for _, mt := range mts {
+		val := mt.ID & MTIDMask
+		if mt.Overload {
+			val |= MTFlagO
+		}
+		if mt.Attached {
+			val |= MTFlagA
+		}
+		if err := bt.Add([]byte{byte(val >> 8), byte(val)}); err != nil {
+			return err
+		}
+	}
+	bt.CloseTLV(true)
+	return nil
+}
+
+// mtHeader returns an addheader function adding the MT ID to each TLV.
+func mtHeader(mtid uint16) func(Type, Data) (Data, error) {
+	return func(t Type, p Data) (Data, error) {
+		if len(p) < 2 {
+			return nil, ErrNoSpace{2, uint(len(p))}
+		}
+		binary.BigEndian.PutUint16(p, mtid&MTIDMask)
+		return p[2:], nil
+	}
+}
+
+// AddMTISReach reads AdjInfo from the channel C adding the information to MT
+// IS Reachability TLV[s] of topology mtid. It stops reading from the channel
+// after it has read count AdjDone values.
+func (bt *BufferTrack) AddMTISReach(mtid uint16, c <-chan interface{}, count int) error {
+	return bt.addISReach(TypeMTISReach, mtHeader(mtid), c, count)
+}
+
+// AddMTIPReach reads IPInfo from the channel C adding the information to MT
+// IPv4 or IPv6 Reachability TLV[s] of topology mtid. It stops reading from the
+// channel after it has read count AdjDone values.
+func (bt *BufferTrack) AddMTIPReach(mtid uint16, ipv4 bool, c <-chan interface{}, count int) error {
+	typ := TypeMTIPv4Prefix
+	if !ipv4 {
+		typ = TypeMTIPv6Prefix
+	}
+	return bt.addIPReach(typ, mtHeader(mtid), c, count)
+}
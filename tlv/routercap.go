@@ -0,0 +1,470 @@
+// ===================================================
+// Router Capability TLV (RFC7981) and its sub-TLVs
+// ===================================================
+
+package tlv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Router Capability flags (RFC7981).
+const (
+	RouterCapFlagS = 0x01 // flood domain wide
+	RouterCapFlagD = 0x02 // leaked from level 2 into level 1
+)
+
+// Router Capability sub-TLV types.
+const (
+	SubTLVCapSR       = 2  // RFC8667 SR-Capabilities
+	SubTLVCapSRAlgo   = 19 // RFC8667 SR-Algorithm
+	SubTLVCapNodeTags = 21 // RFC7917 Node Administrative Tags
+	SubTLVCapSRLB     = 22 // RFC8667 SR Local Block
+	SubTLVCapNodeMSD  = 23 // RFC8491 Node MSD
+	SubTLVCapFAD      = 26 // RFC9350 Flexible Algorithm Definition
+)
+
+// SubTLVSIDLabel is the SID/Label sub-TLV of the SRGB and SRLB range
+// descriptors (RFC8667).
+const SubTLVSIDLabel = 1
+
+// SR-Capabilities flags (RFC8667).
+const (
+	SRCapFlagI = 0x80 // MPLS IPv4
+	SRCapFlagV = 0x40 // MPLS IPv6
+)
+
+// SR algorithms (RFC8665, RFC9350).
+const (
+	SRAlgoSPF       = 0
+	SRAlgoStrictSPF = 1
+	FlexAlgoMin     = 128
+	FlexAlgoMax     = 255
+)
+
+// MSDTypeBaseMPLS is the Base MPLS Imposition MSD type (RFC8491).
+const MSDTypeBaseMPLS = 1
+
+// Flexible Algorithm Definition metric types (RFC9350).
+const (
+	FlexAlgoMetricIGP   = 0
+	FlexAlgoMetricDelay = 1 // min unidirectional link delay (RFC8570)
+	FlexAlgoMetricTE    = 2 // TE default metric (RFC5305)
+)
+
+// Flexible Algorithm Definition sub-TLV types (RFC9350).
+const (
+	SubTLVFADExcludeAG    = 1
+	SubTLVFADIncludeAnyAG = 2
+	SubTLVFADIncludeAllAG = 3
+	SubTLVFADFlags        = 4
+	SubTLVFADExcludeSRLG  = 5
+)
+
+// FADFlagM is the flexible algorithm prefix metric flag (RFC9350).
+const FADFlagM = 0x80
+
+// maxSubTLVLen is the largest sub-TLV value that fits in a Router Capability
+// TLV with the router ID, flags and sub-TLV header.
+const maxSubTLVLen = 255 - 5 - 2
+
+// SIDRange is an SRGB or SRLB range descriptor (RFC8667).
+type SIDRange struct {
+	Range uint32 `json:"range-size"`
+	Start uint32 `json:"start-label"`
+}
+
+// SRCap is the SR-Capabilities sub-TLV (RFC8667).
+type SRCap struct {
+	MPLSIPv4 bool       `json:"mpls-ipv4"`
+	MPLSIPv6 bool       `json:"mpls-ipv6"`
+	SRGB     []SIDRange `json:"srgb"`
+}
+
+// MSD is a maximum SID depth type and value (RFC8491).
+type MSD struct {
+	Type  uint8 `json:"msd-type"`
+	Value uint8 `json:"msd-value"`
+}
+
+// FAD is a Flexible Algorithm Definition (RFC9350). The admin groups are
+// extended admin group bit masks (RFC7308).
+type FAD struct {
+	Algo        uint8    `json:"flex-algo"`
+	MetricType  uint8    `json:"metric-type"`
+	CalcType    uint8    `json:"calc-type"`
+	Priority    uint8    `json:"priority"`
+	ExcludeAny  []uint32 `json:"exclude-any,omitempty"`
+	IncludeAny  []uint32 `json:"include-any,omitempty"`
+	IncludeAll  []uint32 `json:"include-all,omitempty"`
+	MFlag       bool     `json:"prefix-metric,omitempty"`
+	ExcludeSRLG []uint32 `json:"exclude-srlg,omitempty"`
+}
+
+// RouterCap is the Router Capability TLV (RFC7981) with its known sub-TLVs
+// decoded. Unknown sub-TLV types are recorded in Unknown.
+type RouterCap struct {
+	RouterID net.IP     `json:"router-id"`
+	Flood    bool       `json:"flood-domain-wide,omitempty"` // S bit
+	Down     bool       `json:"down,omitempty"`              // D bit
+	SR       *SRCap     `json:"sr-capabilities,omitempty"`
+	SRAlgos  []uint8    `json:"sr-algorithms,omitempty"`
+	SRLB     []SIDRange `json:"srlb,omitempty"`
+	SRv6     *SRv6Cap   `json:"srv6-capabilities,omitempty"`
+	NodeMSD  []MSD      `json:"node-msd,omitempty"`
+	NodeTags []uint32   `json:"node-tags,omitempty"`
+	FADs     []FAD      `json:"flex-algo-definitions,omitempty"`
+	Unknown  []uint8    `json:"unknown-sub-tlvs,omitempty"`
+}
+
+// routerCapSubTLV is a registry entry for a Router Capability sub-TLV. encode
+// returns the values of the sub-TLVs to add, decode merges a received value
+// into the capabilities.
+type routerCapSubTLV struct {
+	encode func(rc *RouterCap) [][]byte
+	decode func(rc *RouterCap, v []byte) error
+}
+
+// routerCapSubTLVs is the registry of the known sub-TLVs.
+var routerCapSubTLVs = map[uint8]routerCapSubTLV{
+	SubTLVCapSR:       {encodeSRCap, decodeSRCap},
+	SubTLVCapSRAlgo:   {encodeSRAlgos, decodeSRAlgos},
+	SubTLVCapSRLB:     {encodeSRLB, decodeSRLB},
+	SubTLVCapNodeMSD:  {encodeNodeMSD, decodeNodeMSD},
+	SubTLVCapNodeTags: {encodeNodeTags, decodeNodeTags},
+	SubTLVCapFAD:      {encodeFADs, decodeFAD},
+}
+
+// routerCapOrder is the order in which the sub-TLVs are encoded.
+var routerCapOrder = []uint8{
+	SubTLVCapSR,
+	SubTLVCapSRAlgo,
+	SubTLVCapSRLB,
+	SubTLVCapNodeMSD,
+	SubTLVCapNodeTags,
+	SubTLVCapFAD,
+}
+
+// RegisterRouterCapSubTLV adds a sub-TLV to the registry, it must be called
+// during initialization.
+func RegisterRouterCapSubTLV(typ uint8, encode func(rc *RouterCap) [][]byte, decode func(rc *RouterCap, v []byte) error) {
+	if _, ok := routerCapSubTLVs[typ]; !ok {
+		routerCapOrder = append(routerCapOrder, typ)
+	}
+	routerCapSubTLVs[typ] = routerCapSubTLV{encode, decode}
+}
+
+// SubTLVs returns the encoded sub-TLVs of the capabilities.
+func (rc *RouterCap) SubTLVs() ([][]byte, error) {
+	var subs [][]byte
+	for _, typ := range routerCapOrder {
+		for _, v := range routerCapSubTLVs[typ].encode(rc) {
+			if len(v) > maxSubTLVLen {
+				return nil, fmt.Errorf("Router Capability sub-TLV %d length %d too long", typ, len(v))
+			}
+			subs = append(subs, append([]byte{typ, byte(len(v))}, v...))
+		}
+	}
+	return subs, nil
+}
+
+// header returns the router ID and flags that start each TLV.
+func (rc *RouterCap) header() []byte {
+	b := make([]byte, 5)
+	if ip := rc.RouterID.To4(); ip != nil {
+		copy(b, ip)
+	}
+	if rc.Flood {
+		b[4] |= RouterCapFlagS
+	}
+	if rc.Down {
+		b[4] |= RouterCapFlagD
+	}
+	return b
+}
+
+// RouterCapDecode returns the Router Capability found in the TLV.
+func (tlv Data) RouterCapDecode() (*RouterCap, error) {
+	_, l, v, err := GetTLV(tlv)
+	if err != nil {
+		return nil, err
+	}
+	if l < 5 {
+		return nil, fmt.Errorf("Length of Router Capability %d too short", l)
+	}
+	rc := &RouterCap{
+		RouterID: net.IP(v[:4]),
+		Flood:    v[4]&RouterCapFlagS != 0,
+		Down:     v[4]&RouterCapFlagD != 0,
+	}
+	for sub := v[5:]; len(sub) > 0; {
+		if len(sub) < 2 || int(sub[1]) > len(sub)-2 {
+			return nil, fmt.Errorf("Router Capability sub-TLV overruns TLV")
+		}
+		t, sv := sub[0], sub[2:2+int(sub[1])]
+		sub = sub[2+int(sub[1]):]
+		st, ok := routerCapSubTLVs[t]
+		if !ok {
+			rc.Unknown = append(rc.Unknown, t)
+			continue
+		}
+		if err := st.decode(rc, sv); err != nil {
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// AddRouterCap adds the Router Capability TLV[s], sub-TLVs that don't fit are
+// continued in another TLV. Nothing is added without sub-TLVs.
+func (bt *BufferTrack) AddRouterCap(rc *RouterCap) error {
+	if rc == nil {
+		return nil
+	}
+	subs, err := rc.SubTLVs()
+	if err != nil {
+		return err
+	}
+	hdr := rc.header()
+	for len(subs) > 0 {
+		b := append([]byte(nil), hdr...)
+		for len(subs) > 0 && len(b)+len(subs[0]) <= 255 {
+			b = append(b, subs[0]...)
+			subs = subs[1:]
+		}
+		if err := bt.OpenWithAdd(b, TypeRouterCap, nil); err != nil {
+			return err
+		}
+		bt.CloseTLV(true)
+	}
+	return nil
+}
+
+// ----------------------------
+// Sub-TLV encoding and decoding
+// ----------------------------
+
+func appendUint24(b []byte, v uint32) []byte {
+	return append(b, byte(v>>16), byte(v>>8), byte(v))
+}
+
+func getUint24(b []byte) uint32 {
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+func appendSIDRanges(b []byte, ranges []SIDRange) []byte {
+	for _, r := range ranges {
+		b = appendUint24(b, r.Range)
+		b = appendUint24(append(b, SubTLVSIDLabel, 3), r.Start&0xFFFFF)
+	}
+	return b
+}
+
+func decodeSIDRanges(v []byte) ([]SIDRange, error) {
+	var ranges []SIDRange
+	for len(v) > 0 {
+		if len(v) < 5 || int(v[4]) > len(v)-5 {
+			return nil, fmt.Errorf("SID range descriptor overruns sub-TLV")
+		}
+		r := SIDRange{Range: getUint24(v)}
+		t, sv := v[3], v[5:5+int(v[4])]
+		v = v[5+int(v[4]):]
+		switch {
+		case t == SubTLVSIDLabel && len(sv) == 3:
+			r.Start = getUint24(sv) & 0xFFFFF
+		case t == SubTLVSIDLabel && len(sv) == 4:
+			r.Start = binary.BigEndian.Uint32(sv)
+		default:
+			return nil, fmt.Errorf("SID range descriptor without SID/Label")
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func encodeSRCap(rc *RouterCap) [][]byte {
+	if rc.SR == nil {
+		return nil
+	}
+	var flags byte
+	if rc.SR.MPLSIPv4 {
+		flags |= SRCapFlagI
+	}
+	if rc.SR.MPLSIPv6 {
+		flags |= SRCapFlagV
+	}
+	return [][]byte{appendSIDRanges([]byte{flags}, rc.SR.SRGB)}
+}
+
+func decodeSRCap(rc *RouterCap, v []byte) error {
+	if len(v) < 1 {
+		return fmt.Errorf("SR-Capabilities sub-TLV too short")
+	}
+	srgb, err := decodeSIDRanges(v[1:])
+	if err != nil {
+		return err
+	}
+	rc.SR = &SRCap{
+		MPLSIPv4: v[0]&SRCapFlagI != 0,
+		MPLSIPv6: v[0]&SRCapFlagV != 0,
+		SRGB:     srgb,
+	}
+	return nil
+}
+
+func encodeSRAlgos(rc *RouterCap) [][]byte {
+	if len(rc.SRAlgos) == 0 {
+		return nil
+	}
+	return [][]byte{append([]byte(nil), rc.SRAlgos...)}
+}
+
+func decodeSRAlgos(rc *RouterCap, v []byte) error {
+	rc.SRAlgos = append(rc.SRAlgos, v...)
+	return nil
+}
+
+func encodeSRLB(rc *RouterCap) [][]byte {
+	if len(rc.SRLB) == 0 {
+		return nil
+	}
+	return [][]byte{appendSIDRanges([]byte{0}, rc.SRLB)}
+}
+
+func decodeSRLB(rc *RouterCap, v []byte) error {
+	if len(v) < 1 {
+		return fmt.Errorf("SRLB sub-TLV too short")
+	}
+	srlb, err := decodeSIDRanges(v[1:])
+	if err != nil {
+		return err
+	}
+	rc.SRLB = append(rc.SRLB, srlb...)
+	return nil
+}
+
+func encodeNodeMSD(rc *RouterCap) [][]byte {
+	if len(rc.NodeMSD) == 0 {
+		return nil
+	}
+	var b []byte
+	for _, m := range rc.NodeMSD {
+		b = append(b, m.Type, m.Value)
+	}
+	return [][]byte{b}
+}
+
+func decodeNodeMSD(rc *RouterCap, v []byte) error {
+	if len(v)%2 != 0 {
+		return fmt.Errorf("Node MSD sub-TLV length %d not a multiple of 2", len(v))
+	}
+	for ; len(v) > 0; v = v[2:] {
+		rc.NodeMSD = append(rc.NodeMSD, MSD{v[0], v[1]})
+	}
+	return nil
+}
+
+// splitUint32s encodes the values splitting them into chunks that fit in a
+// sub-TLV.
+func splitUint32s(values []uint32) [][]byte {
+	var out [][]byte
+	for len(values) > 0 {
+		n := len(values)
+		if n > maxSubTLVLen/4 {
+			n = maxSubTLVLen / 4
+		}
+		var b []byte
+		for _, v := range values[:n] {
+			b = appendUint32(b, v)
+		}
+		out = append(out, b)
+		values = values[n:]
+	}
+	return out
+}
+
+func decodeUint32s(v []byte) ([]uint32, error) {
+	if len(v)%4 != 0 {
+		return nil, fmt.Errorf("length %d not a multiple of 4", len(v))
+	}
+	var values []uint32
+	for ; len(v) > 0; v = v[4:] {
+		values = append(values, binary.BigEndian.Uint32(v))
+	}
+	return values, nil
+}
+
+func encodeNodeTags(rc *RouterCap) [][]byte {
+	return splitUint32s(rc.NodeTags)
+}
+
+func decodeNodeTags(rc *RouterCap, v []byte) error {
+	tags, err := decodeUint32s(v)
+	if err != nil {
+		return fmt.Errorf("Node admin tag sub-TLV %s", err)
+	}
+	rc.NodeTags = append(rc.NodeTags, tags...)
+	return nil
+}
+
+func appendFADSub(b []byte, typ byte, values []uint32) []byte {
+	if len(values) == 0 {
+		return b
+	}
+	b = append(b, typ, byte(4*len(values)))
+	for _, v := range values {
+		b = appendUint32(b, v)
+	}
+	return b
+}
+
+func encodeFADs(rc *RouterCap) [][]byte {
+	var out [][]byte
+	for i := range rc.FADs {
+		fad := &rc.FADs[i]
+		b := []byte{fad.Algo, fad.MetricType, fad.CalcType, fad.Priority}
+		b = appendFADSub(b, SubTLVFADExcludeAG, fad.ExcludeAny)
+		b = appendFADSub(b, SubTLVFADIncludeAnyAG, fad.IncludeAny)
+		b = appendFADSub(b, SubTLVFADIncludeAllAG, fad.IncludeAll)
+		if fad.MFlag {
+			b = append(b, SubTLVFADFlags, 1, FADFlagM)
+		}
+		b = appendFADSub(b, SubTLVFADExcludeSRLG, fad.ExcludeSRLG)
+		out = append(out, b)
+	}
+	return out
+}
+
+func decodeFAD(rc *RouterCap, v []byte) error {
+	if len(v) < 4 {
+		return fmt.Errorf("Flex-Algo definition sub-TLV too short")
+	}
+	fad := FAD{Algo: v[0], MetricType: v[1], CalcType: v[2], Priority: v[3]}
+	for sub := v[4:]; len(sub) > 0; {
+		if len(sub) < 2 || int(sub[1]) > len(sub)-2 {
+			return fmt.Errorf("Flex-Algo definition sub-TLV overruns sub-TLV")
+		}
+		t, sv := sub[0], sub[2:2+int(sub[1])]
+		sub = sub[2+int(sub[1]):]
+		var err error
+		switch t {
+		case SubTLVFADExcludeAG:
+			fad.ExcludeAny, err = decodeUint32s(sv)
+		case SubTLVFADIncludeAnyAG:
+			fad.IncludeAny, err = decodeUint32s(sv)
+		case SubTLVFADIncludeAllAG:
+			fad.IncludeAll, err = decodeUint32s(sv)
+		case SubTLVFADFlags:
+			fad.MFlag = len(sv) > 0 && sv[0]&FADFlagM != 0
+		case SubTLVFADExcludeSRLG:
+			fad.ExcludeSRLG, err = decodeUint32s(sv)
+		}
+		if err != nil {
+			return fmt.Errorf("Flex-Algo definition %s", err)
+		}
+	}
+	rc.FADs = append(rc.FADs, fad)
+	return nil
+}
@@ -0,0 +1,235 @@
+// ===================================================
+// Segment Routing MPLS sub-TLVs (RFC8667)
+// ===================================================
+
+package tlv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/choppsv1/goisis/clns"
+)
+
+// Segment routing prefix and adjacency sub-TLV types (RFC8667).
+const (
+	SubTLVPrefixSID = 3  // Prefix-SID of TLV 135, 235, 236 and 237
+	SubTLVAdjSID    = 31 // Adj-SID of TLV 22, 23, 222, 223 and 141
+	SubTLVLANAdjSID = 32 // LAN-Adj-SID of TLV 22, 23, 222, 223 and 141
+)
+
+// Prefix-SID flags (RFC8667).
+const (
+	PrefixSIDFlagR = 0x80 // Re-advertisement
+	PrefixSIDFlagN = 0x40 // Node-SID
+	PrefixSIDFlagP = 0x20 // no-PHP
+	PrefixSIDFlagE = 0x10 // Explicit-Null
+	PrefixSIDFlagV = 0x08 // Value
+	PrefixSIDFlagL = 0x04 // Local
+)
+
+// Adj-SID and LAN-Adj-SID flags (RFC8667).
+const (
+	AdjSIDFlagF = 0x80 // Address-Family IPv6
+	AdjSIDFlagB = 0x40 // Backup
+	AdjSIDFlagV = 0x20 // Value
+	AdjSIDFlagL = 0x10 // Local
+	AdjSIDFlagS = 0x08 // Set
+	AdjSIDFlagP = 0x04 // Persistent
+)
+
+// MPLS reserved labels used by segment routing.
+const (
+	LabelIPv4ExplicitNull = 0
+	LabelIPv6ExplicitNull = 2
+	LabelImplicitNull     = 3
+	LabelMax              = 0xFFFFF
+)
+
+// PrefixSID is a Prefix-SID sub-TLV (RFC8667). SID is an index into the SRGB
+// unless Value is set in which case it is a label.
+type PrefixSID struct {
+	Readvertised bool   `json:"readvertisement,omitempty"`
+	Node         bool   `json:"node,omitempty"`
+	NoPHP        bool   `json:"no-php,omitempty"`
+	ExplicitNull bool   `json:"explicit-null,omitempty"`
+	Value        bool   `json:"value,omitempty"`
+	Local        bool   `json:"local,omitempty"`
+	Algo         uint8  `json:"algorithm"`
+	SID          uint32 `json:"sid"`
+}
+
+// Flags returns the encoded flags of the Prefix-SID.
+func (ps *PrefixSID) Flags() byte {
+	var flags byte
+	for _, f := range []struct {
+		set  bool
+		flag byte
+	}{
+		{ps.Readvertised, PrefixSIDFlagR},
+		{ps.Node, PrefixSIDFlagN},
+		{ps.NoPHP, PrefixSIDFlagP},
+		{ps.ExplicitNull, PrefixSIDFlagE},
+		{ps.Value, PrefixSIDFlagV},
+		{ps.Local, PrefixSIDFlagL},
+	} {
+		if f.set {
+			flags |= f.flag
+		}
+	}
+	return flags
+}
+
+// encode returns the Prefix-SID encoded as a sub-TLV.
+func (ps *PrefixSID) encode() []byte {
+	b := []byte{SubTLVPrefixSID, 0, ps.Flags(), ps.Algo}
+	if ps.Value {
+		b = appendUint24(b, ps.SID&LabelMax)
+	} else {
+		b = appendUint32(b, ps.SID)
+	}
+	b[1] = byte(len(b) - 2)
+	return b
+}
+
+// decodePrefixSID decodes the value of a Prefix-SID sub-TLV.
+func decodePrefixSID(v []byte) (PrefixSID, error) {
+	if len(v) < 2 {
+		return PrefixSID{}, fmt.Errorf("Prefix-SID sub-TLV too short")
+	}
+	ps := PrefixSID{
+		Readvertised: v[0]&PrefixSIDFlagR != 0,
+		Node:         v[0]&PrefixSIDFlagN != 0,
+		NoPHP:        v[0]&PrefixSIDFlagP != 0,
+		ExplicitNull: v[0]&PrefixSIDFlagE != 0,
+		Value:        v[0]&PrefixSIDFlagV != 0,
+		Local:        v[0]&PrefixSIDFlagL != 0,
+		Algo:         v[1],
+	}
+	switch {
+	case ps.Value && ps.Local && len(v) == 5:
+		ps.SID = getUint24(v[2:]) & LabelMax
+	case !ps.Value && !ps.Local && len(v) == 6:
+		ps.SID = binary.BigEndian.Uint32(v[2:])
+	default:
+		return PrefixSID{}, fmt.Errorf("Prefix-SID sub-TLV bad flags 0x%x for length %d", v[0], len(v))
+	}
+	return ps, nil
+}
+
+// AdjSID is an Adj-SID or, with a neighbor system ID, a LAN-Adj-SID sub-TLV
+// (RFC8667). SID is a label when Value and Local are set (the normal case),
+// otherwise an index into the SRGB.
+type AdjSID struct {
+	IPv6       bool           `json:"ipv6,omitempty"`
+	Backup     bool           `json:"backup,omitempty"`
+	Value      bool           `json:"value,omitempty"`
+	Local      bool           `json:"local,omitempty"`
+	Set        bool           `json:"set,omitempty"`
+	Persistent bool           `json:"persistent,omitempty"`
+	Weight     uint8          `json:"weight"`
+	Nbr        *clns.SystemID `json:"neighbor-id,omitempty"`
+	SID        uint32         `json:"sid"`
+}
+
+// Flags returns the encoded flags of the Adj-SID.
+func (as *AdjSID) Flags() byte {
+	var flags byte
+	for _, f := range []struct {
+		set  bool
+		flag byte
+	}{
+		{as.IPv6, AdjSIDFlagF},
+		{as.Backup, AdjSIDFlagB},
+		{as.Value, AdjSIDFlagV},
+		{as.Local, AdjSIDFlagL},
+		{as.Set, AdjSIDFlagS},
+		{as.Persistent, AdjSIDFlagP},
+	} {
+		if f.set {
+			flags |= f.flag
+		}
+	}
+	return flags
+}
+
+// encode returns the Adj-SID encoded as a sub-TLV.
+func (as *AdjSID) encode() []byte {
+	typ := byte(SubTLVAdjSID)
+	if as.Nbr != nil {
+		typ = SubTLVLANAdjSID
+	}
+	b := []byte{typ, 0, as.Flags(), as.Weight}
+	if as.Nbr != nil {
+		b = append(b, as.Nbr[:]...)
+	}
+	if as.Value {
+		b = appendUint24(b, as.SID&LabelMax)
+	} else {
+		b = appendUint32(b, as.SID)
+	}
+	b[1] = byte(len(b) - 2)
+	return b
+}
+
+// decodeAdjSID decodes the value of an Adj-SID or LAN-Adj-SID sub-TLV.
+func decodeAdjSID(t byte, v []byte) (AdjSID, error) {
+	if len(v) < 2 {
+		return AdjSID{}, fmt.Errorf("Adj-SID sub-TLV too short")
+	}
+	flags := v[0]
+	as := AdjSID{
+		IPv6:       v[0]&AdjSIDFlagF != 0,
+		Backup:     v[0]&AdjSIDFlagB != 0,
+		Value:      v[0]&AdjSIDFlagV != 0,
+		Local:      v[0]&AdjSIDFlagL != 0,
+		Set:        v[0]&AdjSIDFlagS != 0,
+		Persistent: v[0]&AdjSIDFlagP != 0,
+		Weight:     v[1],
+	}
+	v = v[2:]
+	if t == SubTLVLANAdjSID {
+		if len(v) < clns.SysIDLen {
+			return AdjSID{}, fmt.Errorf("LAN-Adj-SID sub-TLV too short")
+		}
+		as.Nbr = new(clns.SystemID)
+		copy(as.Nbr[:], v)
+		v = v[clns.SysIDLen:]
+	}
+	switch {
+	case as.Value && as.Local && len(v) == 3:
+		as.SID = getUint24(v) & LabelMax
+	case !as.Value && !as.Local && len(v) == 4:
+		as.SID = binary.BigEndian.Uint32(v)
+	default:
+		return AdjSID{}, fmt.Errorf("Adj-SID sub-TLV bad flags 0x%x for SID length %d", flags, len(v))
+	}
+	return as, nil
+}
+
+// decodeAdjSIDs returns the Adj-SIDs found in the sub-TLVs of an IS
+// reachability entry, malformed sub-TLVs are ignored.
+func decodeAdjSIDs(sub Data) []AdjSID {
+	var sids []AdjSID
+	for ; len(sub) >= 2 && int(sub[1]) <= len(sub)-2; sub = sub[2+int(sub[1]):] {
+		t, v := sub[0], sub[2:2+int(sub[1])]
+		if t != SubTLVAdjSID && t != SubTLVLANAdjSID {
+			continue
+		}
+		if as, err := decodeAdjSID(t, v); err == nil {
+			sids = append(sids, as)
+		}
+	}
+	return sids
+}
+
+// SRGBLabel returns the label for index in the SRGB ranges, ok is false if the
+// index is outside the SRGB.
+func SRGBLabel(srgb []SIDRange, index uint32) (label uint32, ok bool) {
+	for _, r := range srgb {
+		if index < r.Range {
+			return r.Start + index, true
+		}
+		index -= r.Range
+	}
+	return 0, false
+}
@@ -0,0 +1,341 @@
+// ===================================================
+// Segment Routing over IPv6 TLVs and sub-TLVs (RFC9352)
+// ===================================================
+
+package tlv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/choppsv1/goisis/clns"
+	"net"
+)
+
+// SRv6 sub-TLV types (RFC9352).
+const (
+	SubTLVCapSRv6        = 25 // SRv6 Capabilities of TLV 242
+	SubTLVSRv6EndSID     = 5  // SRv6 End SID of TLV 27
+	SubTLVSRv6EndXSID    = 43 // SRv6 End.X SID of TLV 22, 23, 222, 223 and 141
+	SubTLVSRv6LANEndXSID = 44 // SRv6 LAN End.X SID of TLV 22, 23, 222, 223 and 141
+	SubSubTLVSIDStruct   = 1  // SRv6 SID Structure of the SID sub-TLVs
+)
+
+// SRv6 flags (RFC9352).
+const (
+	SRv6CapFlagO     = 0x4000 // OAM, supports the O-bit
+	SRv6LocFlagD     = 0x80   // Locator leaked from level 2 into level 1
+	SRv6LocFlagA     = 0x40   // Anycast locator
+	SRv6EndXSIDFlagB = 0x80   // Backup
+	SRv6EndXSIDFlagS = 0x40   // Set
+	SRv6EndXSIDFlagP = 0x20   // Persistent
+)
+
+// SRv6 endpoint behaviors (RFC8986) used in IS-IS.
+const (
+	SRv6BehaviorEnd     = 1
+	SRv6BehaviorEndPSP  = 2
+	SRv6BehaviorEndX    = 5
+	SRv6BehaviorEndXPSP = 6
+)
+
+// SRv6Cap is the SRv6 Capabilities sub-TLV (RFC9352).
+type SRv6Cap struct {
+	OAM bool `json:"oam,omitempty"`
+}
+
+// SRv6SIDStructure is the SRv6 SID Structure sub-sub-TLV (RFC9352), the lengths
+// are in bits.
+type SRv6SIDStructure struct {
+	LBLen  uint8 `json:"locator-block-length"`
+	LNLen  uint8 `json:"locator-node-length"`
+	FunLen uint8 `json:"function-length"`
+	ArgLen uint8 `json:"argument-length"`
+}
+
+// SRv6EndSID is an SRv6 End SID sub-TLV of a locator.
+type SRv6EndSID struct {
+	Behavior  uint16            `json:"endpoint-behavior"`
+	SID       net.IP            `json:"sid"`
+	Structure *SRv6SIDStructure `json:"sid-structure,omitempty"`
+}
+
+// SRv6EndXSID is an SRv6 End.X SID or, with a neighbor system ID, an SRv6 LAN
+// End.X SID sub-TLV (RFC9352).
+type SRv6EndXSID struct {
+	Backup     bool              `json:"backup,omitempty"`
+	Set        bool              `json:"set,omitempty"`
+	Persistent bool              `json:"persistent,omitempty"`
+	Algo       uint8             `json:"algorithm"`
+	Weight     uint8             `json:"weight"`
+	Behavior   uint16            `json:"endpoint-behavior"`
+	Nbr        *clns.SystemID    `json:"neighbor-id,omitempty"`
+	SID        net.IP            `json:"sid"`
+	Structure  *SRv6SIDStructure `json:"sid-structure,omitempty"`
+}
+
+// SRv6Locator is a locator entry of the SRv6 Locator TLV. Subtlv holds the
+// sub-TLVs other than the End SIDs.
+type SRv6Locator struct {
+	Metric  uint32       `json:"metric"`
+	Down    bool         `json:"down,omitempty"`
+	Anycast bool         `json:"anycast,omitempty"`
+	Algo    uint8        `json:"algorithm"`
+	Locator IPPrefix     `json:"locator"`
+	EndSIDs []SRv6EndSID `json:"end-sids,omitempty"`
+	Subtlv  Data         `json:"subtlv,omitempty"`
+}
+
+// SRv6Locators is the decoded SRv6 Locator TLV.
+type SRv6Locators struct {
+	MTID     uint16        `json:"mt-id"`
+	Locators []SRv6Locator `json:"locators"`
+}
+
+func init() {
+	RegisterRouterCapSubTLV(SubTLVCapSRv6, encodeSRv6Cap, decodeSRv6Cap)
+}
+
+func encodeSRv6Cap(rc *RouterCap) [][]byte {
+	if rc.SRv6 == nil {
+		return nil
+	}
+	var flags uint16
+	if rc.SRv6.OAM {
+		flags |= SRv6CapFlagO
+	}
+	return [][]byte{{byte(flags >> 8), byte(flags)}}
+}
+
+func decodeSRv6Cap(rc *RouterCap, v []byte) error {
+	if len(v) < 2 {
+		return fmt.Errorf("SRv6 Capabilities sub-TLV too short")
+	}
+	rc.SRv6 = &SRv6Cap{OAM: binary.BigEndian.Uint16(v)&SRv6CapFlagO != 0}
+	return nil
+}
+
+// appendSIDSubSubTLVs appends the sub-sub-TLV length and sub-sub-TLVs of an
+// SRv6 SID sub-TLV.
+func appendSIDSubSubTLVs(b []byte, st *SRv6SIDStructure) []byte {
+	if st == nil {
+		return append(b, 0)
+	}
+	return append(b, 6, SubSubTLVSIDStruct, 4, st.LBLen, st.LNLen, st.FunLen, st.ArgLen)
+}
+
+// decodeSIDSubSubTLVs decodes the sub-sub-TLVs of an SRv6 SID sub-TLV
+// returning the SID structure if present.
+func decodeSIDSubSubTLVs(v []byte) (*SRv6SIDStructure, error) {
+	if len(v) < 1 || int(v[0]) != len(v)-1 {
+		return nil, fmt.Errorf("SRv6 SID sub-sub-TLV length mismatch")
+	}
+	var st *SRv6SIDStructure
+	for sub := v[1:]; len(sub) > 0; sub = sub[2+int(sub[1]):] {
+		if len(sub) < 2 || int(sub[1]) > len(sub)-2 {
+			return nil, fmt.Errorf("SRv6 SID sub-sub-TLV overruns sub-TLV")
+		}
+		if sub[0] == SubSubTLVSIDStruct && sub[1] == 4 {
+			st = &SRv6SIDStructure{sub[2], sub[3], sub[4], sub[5]}
+		}
+	}
+	return st, nil
+}
+
+// encode returns the End SID encoded as a sub-TLV.
+func (es *SRv6EndSID) encode() []byte {
+	b := []byte{SubTLVSRv6EndSID, 0, 0, byte(es.Behavior >> 8), byte(es.Behavior)}
+	b = append(b, es.SID.To16()...)
+	b = appendSIDSubSubTLVs(b, es.Structure)
+	b[1] = byte(len(b) - 2)
+	return b
+}
+
+// decodeSRv6EndSID decodes the value of an End SID sub-TLV.
+func decodeSRv6EndSID(v []byte) (SRv6EndSID, error) {
+	if len(v) < 3+net.IPv6len+1 {
+		return SRv6EndSID{}, fmt.Errorf("SRv6 End SID sub-TLV too short")
+	}
+	st, err := decodeSIDSubSubTLVs(v[3+net.IPv6len:])
+	if err != nil {
+		return SRv6EndSID{}, err
+	}
+	return SRv6EndSID{
+		Behavior:  binary.BigEndian.Uint16(v[1:]),
+		SID:       net.IP(append([]byte(nil), v[3:3+net.IPv6len]...)),
+		Structure: st,
+	}, nil
+}
+
+// Flags returns the encoded flags of the End.X SID.
+func (xs *SRv6EndXSID) Flags() byte {
+	var flags byte
+	if xs.Backup {
+		flags |= SRv6EndXSIDFlagB
+	}
+	if xs.Set {
+		flags |= SRv6EndXSIDFlagS
+	}
+	if xs.Persistent {
+		flags |= SRv6EndXSIDFlagP
+	}
+	return flags
+}
+
+// encode returns the End.X SID encoded as a sub-TLV.
+func (xs *SRv6EndXSID) encode() []byte {
+	typ := byte(SubTLVSRv6EndXSID)
+	if xs.Nbr != nil {
+		typ = SubTLVSRv6LANEndXSID
+	}
+	b := []byte{typ, 0}
+	if xs.Nbr != nil {
+		b = append(b, xs.Nbr[:]...)
+	}
+	b = append(b, xs.Flags(), xs.Algo, xs.Weight, byte(xs.Behavior>>8), byte(xs.Behavior))
+	b = append(b, xs.SID.To16()...)
+	b = appendSIDSubSubTLVs(b, xs.Structure)
+	b[1] = byte(len(b) - 2)
+	return b
+}
+
+// decodeSRv6EndXSID decodes the value of an End.X SID or LAN End.X SID
+// sub-TLV.
+func decodeSRv6EndXSID(t byte, v []byte) (SRv6EndXSID, error) {
+	var xs SRv6EndXSID
+	if t == SubTLVSRv6LANEndXSID {
+		if len(v) < clns.SysIDLen {
+			return xs, fmt.Errorf("SRv6 LAN End.X SID sub-TLV too short")
+		}
+		xs.Nbr = new(clns.SystemID)
+		copy(xs.Nbr[:], v)
+		v = v[clns.SysIDLen:]
+	}
+	if len(v) < 5+net.IPv6len+1 {
+		return SRv6EndXSID{}, fmt.Errorf("SRv6 End.X SID sub-TLV too short")
+	}
+	st, err := decodeSIDSubSubTLVs(v[5+net.IPv6len:])
+	if err != nil {
+		return SRv6EndXSID{}, err
+	}
+	xs.Backup = v[0]&SRv6EndXSIDFlagB != 0
+	xs.Set = v[0]&SRv6EndXSIDFlagS != 0
+	xs.Persistent = v[0]&SRv6EndXSIDFlagP != 0
+	xs.Algo = v[1]
+	xs.Weight = v[2]
+	xs.Behavior = binary.BigEndian.Uint16(v[3:])
+	xs.SID = net.IP(append([]byte(nil), v[5:5+net.IPv6len]...))
+	xs.Structure = st
+	return xs, nil
+}
+
+// decodeSRv6EndXSIDs returns the End.X SIDs found in the sub-TLVs of an IS
+// reachability entry, malformed sub-TLVs are ignored.
+func decodeSRv6EndXSIDs(sub Data) []SRv6EndXSID {
+	var sids []SRv6EndXSID
+	for ; len(sub) >= 2 && int(sub[1]) <= len(sub)-2; sub = sub[2+int(sub[1]):] {
+		t, v := sub[0], sub[2:2+int(sub[1])]
+		if t != SubTLVSRv6EndXSID && t != SubTLVSRv6LANEndXSID {
+			continue
+		}
+		if xs, err := decodeSRv6EndXSID(t, v); err == nil {
+			sids = append(sids, xs)
+		}
+	}
+	return sids
+}
+
+// encode returns the locator entry, nil if its sub-TLVs don't fit in a TLV
+// with the MT ID.
+func (loc *SRv6Locator) encode() []byte {
+	var flags byte
+	if loc.Down {
+		flags |= SRv6LocFlagD
+	}
+	if loc.Anycast {
+		flags |= SRv6LocFlagA
+	}
+	pfxlen, _ := loc.Locator.Mask.Size()
+	b := appendUint32(nil, loc.Metric)
+	b = append(b, flags, loc.Algo, byte(pfxlen))
+	b = append(b, loc.Locator.IP.To16()[:(pfxlen+7)/8]...)
+	sub := append([]byte(nil), loc.Subtlv...)
+	for i := range loc.EndSIDs {
+		sub = append(sub, loc.EndSIDs[i].encode()...)
+	}
+	if len(b)+1+len(sub) > 255-2 {
+		return nil
+	}
+	b = append(b, byte(len(sub)))
+	return append(b, sub...)
+}
+
+// SRv6LocatorDecode returns the locators found in the SRv6 Locator TLV.
+func (tlv Data) SRv6LocatorDecode() (*SRv6Locators, error) {
+	_, l, v, err := GetTLV(tlv)
+	if err != nil {
+		return nil, err
+	}
+	if l < 2 {
+		return nil, fmt.Errorf("Length of SRv6 Locator %d too short", l)
+	}
+	locs := &SRv6Locators{MTID: binary.BigEndian.Uint16(v) & MTIDMask}
+	for v = v[2:]; len(v) > 0; {
+		if len(v) < 7 {
+			return nil, fmt.Errorf("SRv6 locator entry overruns TLV")
+		}
+		pfxlen := int(v[6])
+		if pfxlen > 128 {
+			return nil, fmt.Errorf("SRv6 locator length %d too long", pfxlen)
+		}
+		pfxblen := (pfxlen + 7) / 8
+		if len(v) < 7+pfxblen+1 || int(v[7+pfxblen]) > len(v)-8-pfxblen {
+			return nil, fmt.Errorf("SRv6 locator entry overruns TLV")
+		}
+		loc := SRv6Locator{
+			Metric:  binary.BigEndian.Uint32(v),
+			Down:    v[4]&SRv6LocFlagD != 0,
+			Anycast: v[4]&SRv6LocFlagA != 0,
+			Algo:    v[5],
+		}
+		loc.Locator.IP = make(net.IP, net.IPv6len)
+		loc.Locator.Mask = net.CIDRMask(pfxlen, 128)
+		copy(loc.Locator.IP, v[7:7+pfxblen])
+		loc.Locator.IP = loc.Locator.IP.Mask(loc.Locator.Mask)
+		sub := v[8+pfxblen : 8+pfxblen+int(v[7+pfxblen])]
+		v = v[8+pfxblen+len(sub):]
+		for ; len(sub) >= 2 && int(sub[1]) <= len(sub)-2; sub = sub[2+int(sub[1]):] {
+			st := sub[:2+int(sub[1])]
+			if st[0] == SubTLVSRv6EndSID {
+				if es, err := decodeSRv6EndSID(st[2:]); err == nil {
+					loc.EndSIDs = append(loc.EndSIDs, es)
+					continue
+				}
+			}
+			loc.Subtlv = append(loc.Subtlv, st...)
+		}
+		locs.Locators = append(locs.Locators, loc)
+	}
+	return locs, nil
+}
+
+// AddSRv6Locators adds the SRv6 Locator TLV[s] for the multi-topology mtid.
+// Locators whose sub-TLVs don't fit are not added.
+func (bt *BufferTrack) AddSRv6Locators(mtid uint16, locs []SRv6Locator) error {
+	if len(locs) == 0 {
+		return nil
+	}
+	if err := bt.OpenTLV(TypeSRv6Locator, mtHeader(mtid)); err != nil {
+		return err
+	}
+	for i := range locs {
+		b := locs[i].encode()
+		if b == nil {
+			continue
+		}
+		if err := bt.Add(b); err != nil {
+			return err
+		}
+	}
+	bt.CloseTLV(true)
+	return nil
+}
@@ -2,7 +2,9 @@ package tlv
 
 import (
 	"fmt"
+	"github.com/choppsv1/goisis/clns"
 	"net"
+	"reflect"
 	"testing"
 )
 
@@ -76,6 +78,213 @@ func TestISNeighborsValue(t *testing.T) {
 	// Output: sysids: [0102.0304.0506]
 }
 
+func TestIPv6PrefixDecode(t *testing.T) {
+	b := Data{byte(TypeIPv6Prefix),
+		20,
+		0, 0, 0, 10, 0, 64, 0xfc, 0x00, 0, 0, 0, 0, 0, 1,
+		0, 0, 0, 20, 0x80, 0,
+	}
+	pfxs, err := b.IPv6PrefixDecode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(pfxs) != 2 {
+		t.Fatalf("Returned prefix count %d not 2", len(pfxs))
+	}
+	ipnet := (*net.IPNet)(&pfxs[0].Prefix)
+	if ipnet.String() != "fc00:0:0:1::/64" {
+		t.Errorf("Returned prefix %s not fc00:0:0:1::/64", ipnet)
+	}
+	if pfxs[0].Metric != 10 || pfxs[0].Updown {
+		t.Errorf("Bad metric or up/down: %v", pfxs[0])
+	}
+	ipnet = (*net.IPNet)(&pfxs[1].Prefix)
+	if ipnet.String() != "::/0" || !pfxs[1].Updown || pfxs[1].Metric != 20 {
+		t.Errorf("Bad default prefix: %v", pfxs[1])
+	}
+}
+
+func TestExtIPEncodeExternal(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("2001:db8::/32")
+	ipi := IPInfo{Metric: 10, Ipnet: *ipnet, External: true, Tags: []uint32{100}, Tags64: []uint64{1 << 40}}
+	l := lenExtIP(&ipi)
+	b := make(Data, 2+l)
+	b[0], b[1] = byte(TypeIPv6Prefix), byte(l)
+	encodeExtIP(b[2:], &ipi)
+	pfxs, err := b.IPv6PrefixDecode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(pfxs) != 1 || !pfxs[0].External || pfxs[0].Metric != 10 {
+		t.Fatalf("Bad prefix %v", pfxs)
+	}
+	tags, err := PrefixTags(pfxs[0].Subtlv)
+	if err != nil || len(tags) != 1 || tags[0] != 100 {
+		t.Errorf("Bad tags %v: %v", tags, err)
+	}
+	if len(pfxs[0].Tags) != 1 || pfxs[0].Tags[0] != 100 {
+		t.Errorf("Bad decoded tags %v", pfxs[0].Tags)
+	}
+	if len(pfxs[0].Tags64) != 1 || pfxs[0].Tags64[0] != 1<<40 {
+		t.Errorf("Bad decoded 64 bit tags %v", pfxs[0].Tags64)
+	}
+}
+
+func TestExtIPTags(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("2001:db8::/32")
+	many := make([]uint32, 100)
+	for i := range many {
+		many[i] = uint32(i)
+	}
+	tests := []struct {
+		name   string
+		tags   []uint32
+		tags64 []uint64
+		subtlv []byte
+		ntags  int
+		ntag64 int
+	}{
+		// Raw tags are merged with ours without duplicates.
+		{"merge", []uint32{100, 200}, []uint64{1}, []byte{
+			SubTLVPrefixTag, 8, 0, 0, 0, 100, 0, 0, 0, 50,
+			SubTLVPrefixTag64, 8, 0, 0, 0, 0, 0, 0, 0, 1}, 3, 1},
+		// The prefix leaves room for 59 tags or 29 64 bit tags with the flags.
+		{"max tags", many, nil, nil, 59, 0},
+		{"room", many, []uint64{1, 2}, nil, 59, 0},
+		{"max tags64", nil, make([]uint64, 40), nil, 0, 29},
+		{"few tags", many[:50], []uint64{1, 2}, nil, 50, 2},
+	}
+	for _, test := range tests {
+		ipi := IPInfo{Metric: 10, Ipnet: *ipnet, Tags: test.tags, Tags64: test.tags64, Subtlv: test.subtlv}
+		l := lenExtIP(&ipi)
+		if l > 253 {
+			t.Errorf("%s: prefix length %d", test.name, l)
+			continue
+		}
+		b := make(Data, 2+l)
+		b[0], b[1] = byte(TypeIPv6Prefix), byte(l)
+		encodeExtIP(b[2:], &ipi)
+		pfxs, err := b.IPv6PrefixDecode()
+		if err != nil || len(pfxs) != 1 {
+			t.Errorf("%s: bad prefix %v: %v", test.name, pfxs, err)
+			continue
+		}
+		p := &pfxs[0]
+		if len(p.Tags) != test.ntags || len(p.Tags64) != test.ntag64 {
+			t.Errorf("%s: got %d %d tags want %d %d", test.name, len(p.Tags), len(p.Tags64), test.ntags, test.ntag64)
+		}
+	}
+}
+
+func TestExtIPAttrFlags(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("10.0.0.1/32")
+	ipnet.IP = ipnet.IP.To4()
+	ipi := IPInfo{
+		Metric:       10,
+		Ipnet:        *ipnet,
+		Readvertised: true,
+		Node:         true,
+		SourceIPv6:   net.ParseIP("2001:db8::1"),
+		// Raw flags are replaced, raw IPv4 source router ID is kept.
+		Subtlv: []byte{SubTLVPrefixAttrFlags, 1, PrefixAttrFlagX, SubTLVPrefixSrcIPv4, 4, 1, 1, 1, 1},
+	}
+	l := lenExtIP(&ipi)
+	b := make(Data, 2+l)
+	b[0], b[1] = byte(TypeExtIPv4Prefix), byte(l)
+	encodeExtIP(b[2:], &ipi)
+	pfxs, err := b.IPv4PrefixDecode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(pfxs) != 1 {
+		t.Fatalf("Bad prefix count %d", len(pfxs))
+	}
+	p := &pfxs[0]
+	if p.XFlag || !p.RFlag || !p.NFlag {
+		t.Errorf("Bad flags X %v R %v N %v", p.XFlag, p.RFlag, p.NFlag)
+	}
+	if !p.SrcV4.Equal(net.IPv4(1, 1, 1, 1)) || !p.SrcV6.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("Bad source router IDs %s %s", p.SrcV4, p.SrcV6)
+	}
+
+	// An IPv4 address isn't an IPv6 source router ID.
+	ipi.SourceIPv6 = net.ParseIP("1.1.1.1")
+	encodeExtIP(b[2:], &ipi)
+	b = b[:2+lenExtIP(&ipi)]
+	b[1] = byte(len(b) - 2)
+	if pfxs, err = b.IPv4PrefixDecode(); err != nil || len(pfxs) != 1 || pfxs[0].SrcV6 != nil {
+		t.Errorf("Bad IPv4 mapped source router ID %+v %v", pfxs, err)
+	}
+}
+
+func TestLinkTE(t *testing.T) {
+	ag, tem := uint32(0x5), uint32(100)
+	te := &LinkTE{
+		AdminGroup: &ag,
+		LocalAddr:  net.IPv4(10, 0, 0, 1),
+		NbrAddr:    net.IPv4(10, 0, 0, 2),
+		MaxBW:      1.25e9,
+		MaxResvBW:  1e9,
+		UnresvBW:   []float32{1e9, 5e8},
+		TEMetric:   &tem,
+		LocalAddr6: net.ParseIP("2001:db8::1"),
+		NbrAddr6:   net.ParseIP("2001:db8::2"),
+		// RFC7308
+		ExtAdminGroup: []uint32{0x5, 0, 0x80000000},
+	}
+	dte := decodeLinkTE(te.Encode())
+	if dte == nil {
+		t.Fatalf("No TE decoded")
+	}
+	if *dte.AdminGroup != ag || *dte.TEMetric != tem || dte.MaxBW != te.MaxBW || dte.MaxResvBW != te.MaxResvBW {
+		t.Errorf("Bad TE %+v", dte)
+	}
+	if !dte.LocalAddr.Equal(te.LocalAddr) || !dte.NbrAddr.Equal(te.NbrAddr) {
+		t.Errorf("Bad TE addresses %s %s", dte.LocalAddr, dte.NbrAddr)
+	}
+	if !dte.LocalAddr6.Equal(te.LocalAddr6) || !dte.NbrAddr6.Equal(te.NbrAddr6) {
+		t.Errorf("Bad TE IPv6 addresses %s %s", dte.LocalAddr6, dte.NbrAddr6)
+	}
+	if len(dte.UnresvBW) != TEPriorities || dte.UnresvBW[1] != 5e8 || dte.UnresvBW[7] != 0 {
+		t.Errorf("Bad unreserved bandwidth %v", dte.UnresvBW)
+	}
+	if !reflect.DeepEqual(dte.ExtAdminGroup, te.ExtAdminGroup) {
+		t.Errorf("Bad extended admin group %v", dte.ExtAdminGroup)
+	}
+	if dte := decodeLinkTE(Data{SubTLVExtAdminGroup, 3, 0, 0, 1}); dte != nil {
+		t.Errorf("Bad length extended admin group decoded %+v", dte)
+	}
+	if decodeLinkTE(Data{200, 0}) != nil {
+		t.Errorf("Unknown sub-TLV decoded as TE")
+	}
+}
+
+func TestLinkTEMeasured(t *testing.T) {
+	dv, bw := uint32(12), float32(1e8)
+	te := &LinkTE{
+		Delay:       &TEValue{Anomalous: true, Value: 1500},
+		MinMaxDelay: &TEMinMax{Min: 1000, Max: 0x1FFFFFF},
+		DelayVar:    &dv,
+		Loss:        &TEValue{Value: 3333},
+		ResidualBW:  &bw,
+		AvailableBW: &bw,
+		UtilizedBW:  &bw,
+	}
+	dte := decodeLinkTE(te.Encode())
+	if dte == nil {
+		t.Fatalf("No TE decoded")
+	}
+	if *dte.Delay != *te.Delay || *dte.Loss != *te.Loss || *dte.DelayVar != dv {
+		t.Errorf("Bad delay/loss %+v %+v %d", dte.Delay, dte.Loss, *dte.DelayVar)
+	}
+	if dte.MinMaxDelay.Min != 1000 || dte.MinMaxDelay.Max != TEMaxValue || dte.MinMaxDelay.Anomalous {
+		t.Errorf("Bad min/max delay %+v", dte.MinMaxDelay)
+	}
+	if *dte.ResidualBW != bw || *dte.AvailableBW != bw || *dte.UtilizedBW != bw {
+		t.Errorf("Bad bandwidth %+v", dte)
+	}
+}
+
 // func BenchmarkTLV(b *testing.B) {
 // 	for j := 0; j < 255; j++ {
 // 		var buf []byte = make([]byte, 0, 255)
@@ -89,3 +298,353 @@ func TestISNeighborsValue(t *testing.T) {
 // 	//fmt.Printf("len %d cap %d\n", len(elm.Value), cap(elm.Value))
 // 	fmt.Printf("Done\n")
 // }
+
+func TestSRLG(t *testing.T) {
+	var segs []Data
+	bt := NewBufferTrack(1492, 0, 1, func(b Data, i uint8) error {
+		segs = append(segs, b)
+		return nil
+	})
+	values := make([]uint32, 70)
+	for i := range values {
+		values[i] = uint32(i + 1)
+	}
+	v4 := &SRLG{Nodeid: clns.NodeID{1, 2, 3, 4, 5, 6, 1}, Numbered: true,
+		LocalAddr: net.IPv4(10, 0, 0, 1), Values: values}
+	v6 := &SRLG{Nodeid: clns.NodeID{1, 2, 3, 4, 5, 6, 0},
+		LocalAddr: net.ParseIP("2001:db8::1"), NbrAddr: net.ParseIP("2001:db8::2"), Values: []uint32{7}}
+	if err := bt.AddSRLG(v4); err != nil {
+		t.Fatalf("AddSRLG: %s", err)
+	}
+	if err := bt.AddSRLG(v6); err != nil {
+		t.Fatalf("AddSRLG: %s", err)
+	}
+	if err := bt.AddIPv6RouterID(net.ParseIP("2001:db8::99")); err != nil {
+		t.Fatalf("AddIPv6RouterID: %s", err)
+	}
+	if err := bt.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	if len(segs) != 1 {
+		t.Fatalf("Got %d segments", len(segs))
+	}
+	tlvs, err := segs[0].ParseTLV()
+	if err != nil {
+		t.Fatalf("ParseTLV: %s", err)
+	}
+	if len(tlvs[TypeIPv4SRLG]) != 2 || len(tlvs[TypeIPv6SRLG]) != 1 {
+		t.Fatalf("Bad SRLG TLV count %d %d", len(tlvs[TypeIPv4SRLG]), len(tlvs[TypeIPv6SRLG]))
+	}
+	var got []uint32
+	for _, d := range tlvs[TypeIPv4SRLG] {
+		s, err := d.SRLGDecode()
+		if err != nil {
+			t.Fatalf("SRLGDecode: %s", err)
+		}
+		if s.Nodeid != v4.Nodeid || !s.Numbered || !s.LocalAddr.Equal(v4.LocalAddr) || !s.NbrAddr.Equal(net.IPv4zero) {
+			t.Errorf("Bad IPv4 SRLG %+v", s)
+		}
+		got = append(got, s.Values...)
+	}
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("Bad IPv4 SRLG values %v", got)
+	}
+	s, err := tlvs[TypeIPv6SRLG][0].SRLGDecode()
+	if err != nil || !s.IsIPv6() || !s.NbrAddr.Equal(v6.NbrAddr) || !reflect.DeepEqual(s.Values, v6.Values) {
+		t.Errorf("Bad IPv6 SRLG %+v %v", s, err)
+	}
+	rid, err := tlvs[TypeIPv6RouterID][0].IPv6RouterIDDecode()
+	if err != nil || !rid.Equal(net.ParseIP("2001:db8::99")) {
+		t.Errorf("Bad IPv6 router ID %s %v", rid, err)
+	}
+}
+
+func TestRouterCap(t *testing.T) {
+	tags := make([]uint32, 100)
+	for i := range tags {
+		tags[i] = uint32(i)
+	}
+	rc := &RouterCap{
+		RouterID: net.IPv4(192, 0, 2, 1).To4(),
+		Flood:    true,
+		SR: &SRCap{
+			MPLSIPv4: true,
+			SRGB:     []SIDRange{{Range: 8000, Start: 16000}},
+		},
+		SRAlgos:  []uint8{SRAlgoSPF, SRAlgoStrictSPF, 128},
+		SRLB:     []SIDRange{{Range: 1000, Start: 15000}},
+		NodeMSD:  []MSD{{Type: MSDTypeBaseMPLS, Value: 10}},
+		NodeTags: tags,
+		FADs: []FAD{{Algo: 128, MetricType: FlexAlgoMetricDelay, Priority: 100,
+			ExcludeAny: []uint32{0x1}, IncludeAll: []uint32{0x2, 0x4}, MFlag: true, ExcludeSRLG: []uint32{7}}},
+	}
+	var segs []Data
+	bt := NewBufferTrack(1492, 0, 1, func(b Data, i uint8) error {
+		segs = append(segs, b)
+		return nil
+	})
+	if err := bt.AddRouterCap(rc); err != nil {
+		t.Fatalf("AddRouterCap: %s", err)
+	}
+	if err := bt.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	tlvs, err := segs[0].ParseTLV()
+	if err != nil {
+		t.Fatalf("ParseTLV: %s", err)
+	}
+	if len(tlvs[TypeRouterCap]) != 3 {
+		t.Fatalf("Got %d Router Capability TLVs", len(tlvs[TypeRouterCap]))
+	}
+	var got RouterCap
+	for _, d := range tlvs[TypeRouterCap] {
+		drc, err := d.RouterCapDecode()
+		if err != nil {
+			t.Fatalf("RouterCapDecode: %s", err)
+		}
+		if !drc.RouterID.Equal(rc.RouterID) || !drc.Flood || drc.Down {
+			t.Errorf("Bad Router Capability header %+v", drc)
+		}
+		got.RouterID, got.Flood = drc.RouterID, drc.Flood
+		if drc.SR != nil {
+			got.SR = drc.SR
+		}
+		got.SRAlgos = append(got.SRAlgos, drc.SRAlgos...)
+		got.SRLB = append(got.SRLB, drc.SRLB...)
+		got.NodeMSD = append(got.NodeMSD, drc.NodeMSD...)
+		got.NodeTags = append(got.NodeTags, drc.NodeTags...)
+		got.FADs = append(got.FADs, drc.FADs...)
+	}
+	if !reflect.DeepEqual(&got, rc) {
+		t.Errorf("Router Capability\ngot  %+v\nwant %+v", got, *rc)
+	}
+	bad := Data{byte(TypeRouterCap), 7, 192, 0, 2, 1, 0, 2, 5}
+	if _, err := bad.RouterCapDecode(); err == nil {
+		t.Errorf("Overrun sub-TLV decoded")
+	}
+}
+
+func TestPrefixSID(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("2001:db8::1/128")
+	ipi := IPInfo{
+		Metric: 10,
+		Ipnet:  *ipnet,
+		Node:   true,
+		SIDs:   []PrefixSID{{Node: true, NoPHP: true, SID: 101}, {Value: true, Local: true, Algo: 128, SID: 24001}},
+		// Raw Prefix-SID is replaced.
+		Subtlv: []byte{SubTLVPrefixSID, 6, 0, 0, 0, 0, 0, 9},
+	}
+	l := lenExtIP(&ipi)
+	b := make(Data, 2+l)
+	b[0], b[1] = byte(TypeIPv6Prefix), byte(l)
+	encodeExtIP(b[2:], &ipi)
+	pfxs, err := b.IPv6PrefixDecode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(pfxs) != 1 || !reflect.DeepEqual(pfxs[0].SIDs, ipi.SIDs) {
+		t.Errorf("Bad prefix SIDs %+v", pfxs)
+	}
+	if _, err := decodePrefixSID([]byte{PrefixSIDFlagV, 0, 0, 0, 0, 1}); err == nil {
+		t.Errorf("Index with V flag decoded")
+	}
+}
+
+func TestAdjSID(t *testing.T) {
+	var segs []Data
+	bt := NewBufferTrack(1492, 0, 1, func(b Data, i uint8) error {
+		segs = append(segs, b)
+		return nil
+	})
+	nbr := clns.SystemID{1, 2, 3, 4, 5, 6}
+	sids := []AdjSID{
+		{Value: true, Local: true, SID: 15001},
+		{Value: true, Local: true, Backup: true, Weight: 1, Nbr: &nbr, SID: 15002},
+	}
+	c := make(chan interface{}, 2)
+	c <- AdjInfo{Metric: 10, Nodeid: clns.NodeID{1, 2, 3, 4, 5, 6, 1}, AdjSIDs: sids}
+	c <- Done{}
+	if err := bt.AddExtISReach(c, 1); err != nil {
+		t.Fatalf("AddExtISReach: %s", err)
+	}
+	if err := bt.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	tlvs, err := segs[0].ParseTLV()
+	if err != nil {
+		t.Fatalf("ParseTLV: %s", err)
+	}
+	isr, err := tlvs[TypeExtIsReach][0].ISExtReachDecode()
+	if err != nil {
+		t.Fatalf("ISExtReachDecode: %s", err)
+	}
+	if len(isr) != 1 || !reflect.DeepEqual(isr[0].AdjSIDs, sids) {
+		t.Errorf("Bad Adj-SIDs %+v", isr)
+	}
+	if label, ok := SRGBLabel([]SIDRange{{100, 16000}, {100, 20000}}, 150); !ok || label != 20050 {
+		t.Errorf("Bad SRGB label %d %v", label, ok)
+	}
+}
+
+func TestSRv6(t *testing.T) {
+	var segs []Data
+	bt := NewBufferTrack(1492, 0, 1, func(b Data, i uint8) error {
+		segs = append(segs, b)
+		return nil
+	})
+	st := &SRv6SIDStructure{LBLen: 32, LNLen: 16, FunLen: 16}
+	_, pfx, _ := net.ParseCIDR("2001:db8:0:1::/64")
+	locs := []SRv6Locator{{
+		Metric:  10,
+		Anycast: true,
+		Locator: IPPrefix(*pfx),
+		EndSIDs: []SRv6EndSID{{Behavior: SRv6BehaviorEnd, SID: net.ParseIP("2001:db8:0:1::1"), Structure: st}},
+		Subtlv:  Data{SubTLVPrefixTag, 4, 0, 0, 0, 7},
+	}}
+	nbr := clns.SystemID{1, 2, 3, 4, 5, 6}
+	xsids := []SRv6EndXSID{
+		{Behavior: SRv6BehaviorEndX, SID: net.ParseIP("2001:db8:0:1::100"), Structure: st},
+		{Backup: true, Weight: 1, Behavior: SRv6BehaviorEndX, Nbr: &nbr, SID: net.ParseIP("2001:db8:0:1::101")},
+	}
+	if err := bt.AddSRv6Locators(2, locs); err != nil {
+		t.Fatalf("AddSRv6Locators: %s", err)
+	}
+	c := make(chan interface{}, 2)
+	c <- AdjInfo{Metric: 10, Nodeid: clns.NodeID{1, 2, 3, 4, 5, 6, 1}, EndXSID: xsids}
+	c <- Done{}
+	if err := bt.AddExtISReach(c, 1); err != nil {
+		t.Fatalf("AddExtISReach: %s", err)
+	}
+	if err := bt.AddRouterCap(&RouterCap{SRv6: &SRv6Cap{OAM: true}}); err != nil {
+		t.Fatalf("AddRouterCap: %s", err)
+	}
+	if err := bt.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	tlvs, err := segs[0].ParseTLV()
+	if err != nil {
+		t.Fatalf("ParseTLV: %s", err)
+	}
+	got, err := tlvs[TypeSRv6Locator][0].SRv6LocatorDecode()
+	if err != nil {
+		t.Fatalf("SRv6LocatorDecode: %s", err)
+	}
+	if got.MTID != 2 || !reflect.DeepEqual(got.Locators, locs) {
+		t.Errorf("Bad locators %+v", got)
+	}
+	isr, err := tlvs[TypeExtIsReach][0].ISExtReachDecode()
+	if err != nil {
+		t.Fatalf("ISExtReachDecode: %s", err)
+	}
+	if len(isr) != 1 || !reflect.DeepEqual(isr[0].EndXSID, xsids) {
+		t.Errorf("Bad End.X SIDs %+v", isr)
+	}
+	rc, err := tlvs[TypeRouterCap][0].RouterCapDecode()
+	if err != nil || rc.SRv6 == nil || !rc.SRv6.OAM {
+		t.Errorf("Bad SRv6 capabilities %+v %v", rc, err)
+	}
+	if _, err := tlvs.MarshalJSON(); err != nil {
+		t.Errorf("MarshalJSON: %s", err)
+	}
+}
+
+func TestMT(t *testing.T) {
+	var segs []Data
+	bt := NewBufferTrack(1492, 0, 1, func(b Data, i uint8) error {
+		segs = append(segs, b)
+		return nil
+	})
+	mts := []MT{{ID: MTIDStandard}, {ID: MTIDIPv6, Overload: true, Attached: true}}
+	if err := bt.AddMT(mts); err != nil {
+		t.Fatalf("AddMT: %s", err)
+	}
+	te := uint32(20)
+	c := make(chan interface{}, 2)
+	c <- AdjInfo{Metric: 10, Nodeid: clns.NodeID{1, 2, 3, 4, 5, 6, 1}, TE: &LinkTE{TEMetric: &te},
+		SRLG: []SRLG{{Nodeid: clns.NodeID{1, 2, 3, 4, 5, 6, 1}, Values: []uint32{1}}}}
+	c <- Done{}
+	if err := bt.AddMTISReach(MTIDIPv6, c, 1); err != nil {
+		t.Fatalf("AddMTISReach: %s", err)
+	}
+	_, pfx, _ := net.ParseCIDR("2001:db8:1::/48")
+	c <- IPInfo{Metric: 5, Ipnet: *pfx, Tags: []uint32{7}}
+	c <- Done{}
+	if err := bt.AddMTIPReach(MTIDIPv6, false, c, 1); err != nil {
+		t.Fatalf("AddMTIPReach: %s", err)
+	}
+	_, pfx4, _ := net.ParseCIDR("192.0.2.0/24")
+	c <- IPInfo{Metric: 6, Ipnet: *pfx4}
+	c <- Done{}
+	if err := bt.AddMTIPReach(3, true, c, 1); err != nil {
+		t.Fatalf("AddMTIPReach: %s", err)
+	}
+	if err := bt.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	tlvs, err := segs[0].ParseTLV()
+	if err != nil {
+		t.Fatalf("ParseTLV: %s", err)
+	}
+	if len(tlvs[TypeIPv4SRLG]) != 0 {
+		t.Errorf("SRLG added with MT IS reachability")
+	}
+	gotmts, err := tlvs[TypeMT][0].MTDecode()
+	if err != nil || !reflect.DeepEqual(gotmts, mts) {
+		t.Errorf("Bad MT %+v %v", gotmts, err)
+	}
+	isr, err := tlvs[TypeMTISReach][0].MTISReachDecode()
+	if err != nil {
+		t.Fatalf("MTISReachDecode: %s", err)
+	}
+	if isr.MTID != MTIDIPv6 || len(isr.Nbrs) != 1 || isr.Nbrs[0].Metric != 10 ||
+		isr.Nbrs[0].TE == nil || *isr.Nbrs[0].TE.TEMetric != te {
+		t.Errorf("Bad MT IS reach %+v", isr)
+	}
+	p6, err := tlvs[TypeMTIPv6Prefix][0].MTIPv6PrefixDecode()
+	if err != nil {
+		t.Fatalf("MTIPv6PrefixDecode: %s", err)
+	}
+	if p6.MTID != MTIDIPv6 || len(p6.Prefixes) != 1 || p6.Prefixes[0].Metric != 5 ||
+		(*net.IPNet)(&p6.Prefixes[0].Prefix).String() != pfx.String() || p6.Prefixes[0].Tags[0] != 7 {
+		t.Errorf("Bad MT IPv6 prefixes %+v", p6)
+	}
+	p4, err := tlvs[TypeMTIPv4Prefix][0].MTIPv4PrefixDecode()
+	if err != nil {
+		t.Fatalf("MTIPv4PrefixDecode: %s", err)
+	}
+	if p4.MTID != 3 || len(p4.Prefixes) != 1 || (*net.IPNet)(&p4.Prefixes[0].Prefix).String() != pfx4.String() {
+		t.Errorf("Bad MT IPv4 prefixes %+v", p4)
+	}
+	if _, err := tlvs.MarshalJSON(); err != nil {
+		t.Errorf("MarshalJSON: %s", err)
+	}
+}
+
+func TestBFDEnabled(t *testing.T) {
+	var segs []Data
+	bt := NewBufferTrack(1492, 0, 1, func(b Data, i uint8) error {
+		segs = append(segs, b)
+		return nil
+	})
+	ents := []BFDEnabled{{MTIDStandard, clns.NLPIDIPv4}, {MTIDIPv6, clns.NLPIDIPv6}}
+	if err := bt.AddBFDEnabled(ents); err != nil {
+		t.Fatalf("AddBFDEnabled: %s", err)
+	}
+	if err := bt.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	tlvs, err := segs[0].ParseTLV()
+	if err != nil {
+		t.Fatalf("ParseTLV: %s", err)
+	}
+	got, err := tlvs[TypeBFDEnabled][0].BFDEnabledDecode()
+	if err != nil || !reflect.DeepEqual(got, ents) {
+		t.Errorf("Bad BFD-Enabled %+v %v", got, err)
+	}
+	if _, err := Data([]byte{148, 2, 0, 0}).BFDEnabledDecode(); err == nil {
+		t.Errorf("BFDEnabledDecode accepted a short entry")
+	}
+	if _, err := tlvs.MarshalJSON(); err != nil {
+		t.Errorf("MarshalJSON: %s", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"github.com/choppsv1/goisis/clns"
 	// "golang.org/x/net/idna" for hostname
+	"math"
 	"net"
 	"reflect"
 	"sort"
@@ -55,7 +56,8 @@ const (
 	TypePurge      Type = 13 // RFC6232 (marshaled)
 	TypeLspBufSize Type = 14 // ISO10590 (marshaled)
 
-	TypeExtIsReach Type = 22 // RFC5305
+	TypeExtIsReach  Type = 22 // RFC5305
+	TypeSRv6Locator Type = 27 // RFC9352
 
 	TypeIPv4Iprefix   Type = 128 // RFC1195
 	TypeNLPID         Type = 129 // RFC1195 (marshaled)
@@ -64,8 +66,16 @@ const (
 	TypeRouterID      Type = 134 // (marshaled)
 	TypeExtIPv4Prefix Type = 135 // RFC5305
 	TypeHostname      Type = 137 // RFC5301 (marshaled)
+	TypeIPv4SRLG      Type = 138 // RFC5307 (marshaled)
+	TypeIPv6SRLG      Type = 139 // RFC6119 (marshaled)
+	TypeIPv6RouterID  Type = 140 // RFC6119 (marshaled)
+	TypeBFDEnabled    Type = 148 // RFC6213 (marshaled)
+	TypeMTISReach     Type = 222 // RFC5120
+	TypeMT            Type = 229 // RFC5120 (marshaled)
 	TypeIPv6IntfAddrs Type = 232 // RFC5308 (marshaled)
+	TypeMTIPv4Prefix  Type = 235 // RFC5120
 	TypeIPv6Prefix    Type = 236 // RFC5308
+	TypeMTIPv6Prefix  Type = 237 // RFC5120
 	TypeRouterCap     Type = 242 // RFC7981
 	TypeGenInfo       Type = 251 // RFC6823
 )
@@ -82,6 +92,7 @@ var TypeNameMap = map[Type]string{
 	TypeAuth:          "TypeAuth",
 	TypeLspBufSize:    "TypeLspBufSize",
 	TypeExtIsReach:    "TypeExtIsReach",
+	TypeSRv6Locator:   "TypeSRv6Locator",
 	TypeIPv4Iprefix:   "TypeIPv4Iprefix",
 	TypeNLPID:         "TypeNLPID",
 	TypeIPv4Eprefix:   "TypeIPv4Eprefix",
@@ -89,8 +100,16 @@ var TypeNameMap = map[Type]string{
 	TypeRouterID:      "TypeRouterID",
 	TypeExtIPv4Prefix: "TypeExtIPv4Prefix",
 	TypeHostname:      "TypeHostname",
+	TypeIPv4SRLG:      "TypeIPv4SRLG",
+	TypeIPv6SRLG:      "TypeIPv6SRLG",
+	TypeIPv6RouterID:  "TypeIPv6RouterID",
+	TypeBFDEnabled:    "TypeBFDEnabled",
+	TypeMTISReach:     "TypeMTISReach",
+	TypeMT:            "TypeMT",
 	TypeIPv6IntfAddrs: "TypeIPv6IntfAddrs",
+	TypeMTIPv4Prefix:  "TypeMTIPv4Prefix",
 	TypeIPv6Prefix:    "TypeIPv6Prefix",
+	TypeMTIPv6Prefix:  "TypeMTIPv6Prefix",
 	TypeRouterCap:     "TypeRouterCap",
 	TypeGenInfo:       "TypeGenInfo",
 }
@@ -109,7 +128,9 @@ var TypeNoPurge = map[int]struct{}{
 	16: {},
 	// 17-21: unassigned
 	22: {}, 23: {}, 24: {}, 25: {},
-	// 26-41: unassigned
+	// 26: unassigned
+	27: {},
+	// 28-41: unassigned
 	42: {},
 	// 43-65: unassigned
 	66: {},
@@ -333,6 +354,83 @@ func (tlv Data) RouterIDDecode() (net.IP, error) {
 	return net.IP(v), nil
 }
 
+// IPv6RouterIDDecode returns the IPv6 TE Router ID found in the TLV.
+func (tlv Data) IPv6RouterIDDecode() (net.IP, error) {
+	_, l, v, err := GetTLV(tlv)
+	if err != nil {
+		return nil, err
+	}
+	if l != net.IPv6len {
+		return nil, fmt.Errorf("Length of data %d is not %d", l, net.IPv6len)
+	}
+	return net.IP(v), nil
+}
+
+// SRLG flags.
+const (
+	SRLGFlagNumbered = 0x1 // RFC5307 numbered link (IPv4)
+	SRLGFlagNbrAddr  = 0x1 // RFC6119 neighbor address included (IPv6)
+)
+
+// SRLG are the shared risk link groups of a link (RFC5307, RFC6119). The
+// address family of LocalAddr selects the TLV. For an unnumbered IPv4 link the
+// addresses hold the local and remote link identifiers.
+type SRLG struct {
+	Nodeid    clns.NodeID `json:"neighbor-id"`
+	Numbered  bool        `json:"numbered,omitempty"`
+	LocalAddr net.IP      `json:"interface-address,omitempty"`
+	NbrAddr   net.IP      `json:"neighbor-address,omitempty"`
+	Values    []uint32    `json:"srlg"`
+}
+
+// IsIPv6 returns true if the SRLG is for an IPv6 link (TLV 139).
+func (s *SRLG) IsIPv6() bool {
+	return s.LocalAddr != nil && s.LocalAddr.To4() == nil
+}
+
+// SRLGDecode returns the shared risk link groups found in an IPv4 or IPv6 SRLG
+// TLV.
+func (tlv Data) SRLGDecode() (*SRLG, error) {
+	t, l, v, err := GetTLV(tlv)
+	if err != nil {
+		return nil, err
+	}
+	if l < clns.NodeIDLen+1 {
+		return nil, fmt.Errorf("Length of SRLG TLV %d too short", l)
+	}
+	s := &SRLG{}
+	copy(s.Nodeid[:], v)
+	flags := v[clns.NodeIDLen]
+	v = v[clns.NodeIDLen+1:]
+	alen := net.IPv4len
+	nbr := true
+	if Type(t) == TypeIPv6SRLG {
+		alen = net.IPv6len
+		nbr = flags&SRLGFlagNbrAddr != 0
+	} else {
+		s.Numbered = flags&SRLGFlagNumbered != 0
+	}
+	if len(v) < alen {
+		return nil, fmt.Errorf("SRLG TLV missing interface address")
+	}
+	s.LocalAddr = net.IP(v[:alen])
+	v = v[alen:]
+	if nbr {
+		if len(v) < alen {
+			return nil, fmt.Errorf("SRLG TLV missing neighbor address")
+		}
+		s.NbrAddr = net.IP(v[:alen])
+		v = v[alen:]
+	}
+	if len(v)%4 != 0 {
+		return nil, fmt.Errorf("SRLG TLV values length %d not a multiple of 4", len(v))
+	}
+	for ; len(v) > 0; v = v[4:] {
+		s.Values = append(s.Values, binary.BigEndian.Uint32(v))
+	}
+	return s, nil
+}
+
 // NLPIDValues returns a slice of NLPID values.
 func (tlv Data) NLPIDDecode() ([]clns.NLPID, error) {
 	_, l, v, err := GetTLV(tlv)
@@ -458,9 +556,249 @@ func (tlv Data) SNPEntryDecode() ([]SNPEntry, error) {
 }
 
 type ISExtReach struct {
-	Metric uint32      `json:"metric"`
-	Nodeid clns.NodeID `json:"nodeid"`
-	Subtlv Data        `json:"subtlv,omitempty"`
+	Metric  uint32        `json:"metric"`
+	Nodeid  clns.NodeID   `json:"nodeid"`
+	Subtlv  Data          `json:"subtlv,omitempty"`
+	TE      *LinkTE       `json:"te,omitempty"`
+	AdjSIDs []AdjSID      `json:"adj-sids,omitempty"`
+	EndXSID []SRv6EndXSID `json:"srv6-endx-sids,omitempty"`
+}
+
+// Extended IS Reachability sub-TLV types (RFC5305)
+const (
+	SubTLVAdminGroup   = 3
+	SubTLVIPv4IntfAddr = 6
+	SubTLVIPv4NbrAddr  = 8
+	SubTLVMaxBW        = 9
+	SubTLVMaxResvBW    = 10
+	SubTLVUnresvBW     = 11
+	SubTLVTEMetric     = 18
+	// RFC6119 IPv6 TE
+	SubTLVIPv6IntfAddr = 12
+	SubTLVIPv6NbrAddr  = 13
+	// RFC7308 extended administrative groups
+	SubTLVExtAdminGroup = 14
+	// RFC8570 TE metric extensions
+	SubTLVLinkDelay   = 33
+	SubTLVMinMaxDelay = 34
+	SubTLVDelayVar    = 35
+	SubTLVLinkLoss    = 36
+	SubTLVResidualBW  = 37
+	SubTLVAvailableBW = 38
+	SubTLVUtilizedBW  = 39
+)
+
+// MaxExtAdminGroup is the maximum number of 32 bit words of an extended admin
+// group sub-TLV.
+const MaxExtAdminGroup = 255 / 4
+
+// TEAnomalousFlag is the A flag of the RFC8570 delay and loss sub-TLVs.
+const TEAnomalousFlag = byte(1 << 7)
+
+// TEMaxValue is the maximum of the 24 bit RFC8570 delay and loss values.
+const TEMaxValue = 0xFFFFFF
+
+// TEValue is an RFC8570 24 bit delay (microseconds) or loss (0.000003%) value
+// with the anomalous flag.
+type TEValue struct {
+	Anomalous bool   `json:"anomalous"`
+	Value     uint32 `json:"value"`
+}
+
+// TEMinMax is the RFC8570 minimum and maximum delay (microseconds).
+type TEMinMax struct {
+	Anomalous bool   `json:"anomalous"`
+	Min       uint32 `json:"min"`
+	Max       uint32 `json:"max"`
+}
+
+// TEPriorities is the number of priorities of the unreserved bandwidth.
+const TEPriorities = 8
+
+// LinkTE are the traffic engineering attributes of a link (RFC5305).
+// Bandwidths are in bytes per second as IEEE floating point. Nil or zero values
+// are not advertised.
+type LinkTE struct {
+	AdminGroup *uint32   `json:"admin-group,omitempty"`
+	LocalAddr  net.IP    `json:"ipv4-interface-address,omitempty"`
+	NbrAddr    net.IP    `json:"ipv4-neighbor-address,omitempty"`
+	MaxBW      float32   `json:"max-link-bandwidth,omitempty"`
+	MaxResvBW  float32   `json:"max-reservable-bandwidth,omitempty"`
+	UnresvBW   []float32 `json:"unreserved-bandwidth,omitempty"`
+	TEMetric   *uint32   `json:"te-default-metric,omitempty"`
+	LocalAddr6 net.IP    `json:"ipv6-interface-address,omitempty"`
+	NbrAddr6   net.IP    `json:"ipv6-neighbor-address,omitempty"`
+	// RFC7308 extended admin group, its first word is the admin group.
+	ExtAdminGroup []uint32 `json:"extended-admin-group,omitempty"`
+	// RFC8570 values
+	Delay       *TEValue  `json:"unidirectional-link-delay,omitempty"`
+	MinMaxDelay *TEMinMax `json:"min-max-unidirectional-link-delay,omitempty"`
+	DelayVar    *uint32   `json:"unidirectional-delay-variation,omitempty"`
+	Loss        *TEValue  `json:"unidirectional-link-loss,omitempty"`
+	ResidualBW  *float32  `json:"unidirectional-residual-bandwidth,omitempty"`
+	AvailableBW *float32  `json:"unidirectional-available-bandwidth,omitempty"`
+	UtilizedBW  *float32  `json:"unidirectional-utilized-bandwidth,omitempty"`
+}
+
+func appendTEValue(b []byte, a bool, v uint32) []byte {
+	if v > TEMaxValue {
+		v = TEMaxValue
+	}
+	if a {
+		v |= uint32(TEAnomalousFlag) << 24
+	}
+	return appendUint32(b, v)
+}
+
+func decodeTEValue(v []byte) (bool, uint32) {
+	return v[0]&TEAnomalousFlag != 0, binary.BigEndian.Uint32(v) & TEMaxValue
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendFloat32(b []byte, v float32) []byte {
+	return appendUint32(b, math.Float32bits(v))
+}
+
+// Encode returns the link attributes encoded as sub-TLVs.
+func (te *LinkTE) Encode() []byte {
+	var sub []byte
+	if te.AdminGroup != nil {
+		sub = appendUint32(append(sub, SubTLVAdminGroup, 4), *te.AdminGroup)
+	}
+	if n := len(te.ExtAdminGroup); n != 0 && n <= MaxExtAdminGroup {
+		sub = append(sub, SubTLVExtAdminGroup, byte(4*n))
+		for _, w := range te.ExtAdminGroup {
+			sub = appendUint32(sub, w)
+		}
+	}
+	if ip := te.LocalAddr.To4(); ip != nil {
+		sub = append(append(sub, SubTLVIPv4IntfAddr, 4), ip...)
+	}
+	if ip := te.NbrAddr.To4(); ip != nil {
+		sub = append(append(sub, SubTLVIPv4NbrAddr, 4), ip...)
+	}
+	if ip := te.LocalAddr6; len(ip) == net.IPv6len && ip.To4() == nil {
+		sub = append(append(sub, SubTLVIPv6IntfAddr, net.IPv6len), ip...)
+	}
+	if ip := te.NbrAddr6; len(ip) == net.IPv6len && ip.To4() == nil {
+		sub = append(append(sub, SubTLVIPv6NbrAddr, net.IPv6len), ip...)
+	}
+	if te.MaxBW != 0 {
+		sub = appendFloat32(append(sub, SubTLVMaxBW, 4), te.MaxBW)
+	}
+	if te.MaxResvBW != 0 {
+		sub = appendFloat32(append(sub, SubTLVMaxResvBW, 4), te.MaxResvBW)
+	}
+	if len(te.UnresvBW) != 0 {
+		sub = append(sub, SubTLVUnresvBW, 4*TEPriorities)
+		for i := 0; i < TEPriorities; i++ {
+			var bw float32
+			if i < len(te.UnresvBW) {
+				bw = te.UnresvBW[i]
+			}
+			sub = appendFloat32(sub, bw)
+		}
+	}
+	if te.TEMetric != nil {
+		m := *te.TEMetric
+		sub = append(sub, SubTLVTEMetric, 3, byte(m>>16), byte(m>>8), byte(m))
+	}
+	if te.Delay != nil {
+		sub = appendTEValue(append(sub, SubTLVLinkDelay, 4), te.Delay.Anomalous, te.Delay.Value)
+	}
+	if mm := te.MinMaxDelay; mm != nil {
+		sub = appendTEValue(append(sub, SubTLVMinMaxDelay, 8), mm.Anomalous, mm.Min)
+		sub = appendTEValue(sub, false, mm.Max)
+	}
+	if te.DelayVar != nil {
+		sub = appendTEValue(append(sub, SubTLVDelayVar, 4), false, *te.DelayVar)
+	}
+	if te.Loss != nil {
+		sub = appendTEValue(append(sub, SubTLVLinkLoss, 4), te.Loss.Anomalous, te.Loss.Value)
+	}
+	if te.ResidualBW != nil {
+		sub = appendFloat32(append(sub, SubTLVResidualBW, 4), *te.ResidualBW)
+	}
+	if te.AvailableBW != nil {
+		sub = appendFloat32(append(sub, SubTLVAvailableBW, 4), *te.AvailableBW)
+	}
+	if te.UtilizedBW != nil {
+		sub = appendFloat32(append(sub, SubTLVUtilizedBW, 4), *te.UtilizedBW)
+	}
+	return sub
+}
+
+// decodeLinkTE decodes the known link attribute sub-TLVs, it returns nil if
+// there are none.
+func decodeLinkTE(sub Data) *LinkTE {
+	te := &LinkTE{}
+	found := false
+	for ; len(sub) >= 2 && int(sub[1]) <= len(sub)-2; sub = sub[2+int(sub[1]):] {
+		t, v := sub[0], sub[2:2+int(sub[1])]
+		switch {
+		case t == SubTLVAdminGroup && len(v) == 4:
+			ag := binary.BigEndian.Uint32(v)
+			te.AdminGroup = &ag
+		case t == SubTLVExtAdminGroup && len(v) != 0 && len(v)%4 == 0:
+			te.ExtAdminGroup = make([]uint32, len(v)/4)
+			for i := range te.ExtAdminGroup {
+				te.ExtAdminGroup[i] = binary.BigEndian.Uint32(v[4*i:])
+			}
+		case t == SubTLVIPv4IntfAddr && len(v) == 4:
+			te.LocalAddr = net.IP(append([]byte(nil), v...))
+		case t == SubTLVIPv4NbrAddr && len(v) == 4:
+			te.NbrAddr = net.IP(append([]byte(nil), v...))
+		case t == SubTLVIPv6IntfAddr && len(v) == net.IPv6len:
+			te.LocalAddr6 = net.IP(append([]byte(nil), v...))
+		case t == SubTLVIPv6NbrAddr && len(v) == net.IPv6len:
+			te.NbrAddr6 = net.IP(append([]byte(nil), v...))
+		case t == SubTLVMaxBW && len(v) == 4:
+			te.MaxBW = math.Float32frombits(binary.BigEndian.Uint32(v))
+		case t == SubTLVMaxResvBW && len(v) == 4:
+			te.MaxResvBW = math.Float32frombits(binary.BigEndian.Uint32(v))
+		case t == SubTLVUnresvBW && len(v) == 4*TEPriorities:
+			te.UnresvBW = make([]float32, TEPriorities)
+			for i := range te.UnresvBW {
+				te.UnresvBW[i] = math.Float32frombits(binary.BigEndian.Uint32(v[4*i:]))
+			}
+		case t == SubTLVTEMetric && len(v) == 3:
+			m := uint32(v[0])<<16 | uint32(v[1])<<8 | uint32(v[2])
+			te.TEMetric = &m
+		case t == SubTLVLinkDelay && len(v) == 4:
+			a, d := decodeTEValue(v)
+			te.Delay = &TEValue{a, d}
+		case t == SubTLVMinMaxDelay && len(v) == 8:
+			a, min := decodeTEValue(v)
+			_, max := decodeTEValue(v[4:])
+			te.MinMaxDelay = &TEMinMax{a, min, max}
+		case t == SubTLVDelayVar && len(v) == 4:
+			_, dv := decodeTEValue(v)
+			te.DelayVar = &dv
+		case t == SubTLVLinkLoss && len(v) == 4:
+			a, l := decodeTEValue(v)
+			te.Loss = &TEValue{a, l}
+		case (t == SubTLVResidualBW || t == SubTLVAvailableBW || t == SubTLVUtilizedBW) && len(v) == 4:
+			bw := math.Float32frombits(binary.BigEndian.Uint32(v))
+			switch t {
+			case SubTLVResidualBW:
+				te.ResidualBW = &bw
+			case SubTLVAvailableBW:
+				te.AvailableBW = &bw
+			default:
+				te.UtilizedBW = &bw
+			}
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return te
 }
 
 func (tlv Data) ISExtReachDecode() ([]ISExtReach, error) {
@@ -476,8 +814,8 @@ func (tlv Data) ISExtReachDecode() ([]ISExtReach, error) {
 		sublen := int(v[clns.NodeIDLen+3])
 		if sublen != 0 {
 			sub := v[clns.NodeIDLen+4:]
-			if len(sub) != sublen {
-				return nil, fmt.Errorf("subtlv length %d not equal to actual len %d", sublen, len(sub))
+			if sublen > len(sub) {
+				return nil, fmt.Errorf("subtlv length %d > remaining len %d", sublen, len(sub))
 			}
 		}
 		nlen := 11 + sublen
@@ -495,6 +833,9 @@ func (tlv Data) ISExtReachDecode() ([]ISExtReach, error) {
 		if sublen != 0 {
 			rv[i].Subtlv = make(Data, sublen)
 			copy(rv[i].Subtlv, v[clns.NodeIDLen+4:])
+			rv[i].TE = decodeLinkTE(rv[i].Subtlv)
+			rv[i].AdjSIDs = decodeAdjSIDs(rv[i].Subtlv)
+			rv[i].EndXSID = decodeSRv6EndXSIDs(rv[i].Subtlv)
 		}
 		nlen := 11 + sublen
 		v = v[nlen:]
@@ -522,10 +863,51 @@ func (p IPPrefix) MarshalText() ([]byte, error) {
 }
 
 type IPPrefixCommon struct {
-	Metric uint32   `json:"metric"`
-	Prefix IPPrefix `json:"prefix"`
-	Subtlv Data     `json:"subtlv,omitempty"`
-	Updown bool     `json:"updown"`
+	Metric uint32      `json:"metric"`
+	Prefix IPPrefix    `json:"prefix"`
+	Subtlv Data        `json:"subtlv,omitempty"`
+	Updown bool        `json:"updown"`
+	Tags   []uint32    `json:"tag,omitempty"`
+	Tags64 []uint64    `json:"tag64,omitempty"`
+	XFlag  bool        `json:"external-flag,omitempty"`
+	RFlag  bool        `json:"readvertisement-flag,omitempty"`
+	NFlag  bool        `json:"node-flag,omitempty"`
+	SrcV4  net.IP      `json:"ipv4-source-router-id,omitempty"`
+	SrcV6  net.IP      `json:"ipv6-source-router-id,omitempty"`
+	SIDs   []PrefixSID `json:"prefix-sid,omitempty"`
+}
+
+// decodeSubTLVs decodes the known prefix sub-TLVs, unknown or malformed
+// sub-TLVs are left only in Subtlv.
+func (pc *IPPrefixCommon) decodeSubTLVs() {
+	for sub := pc.Subtlv; len(sub) >= 2; sub = sub[2+int(sub[1]):] {
+		if int(sub[1]) > len(sub)-2 {
+			return
+		}
+		t, v := sub[0], sub[2:2+int(sub[1])]
+		switch {
+		case t == SubTLVPrefixTag && len(v)%4 == 0:
+			for ; len(v) > 0; v = v[4:] {
+				pc.Tags = append(pc.Tags, binary.BigEndian.Uint32(v))
+			}
+		case t == SubTLVPrefixTag64 && len(v)%8 == 0:
+			for ; len(v) > 0; v = v[8:] {
+				pc.Tags64 = append(pc.Tags64, binary.BigEndian.Uint64(v))
+			}
+		case t == SubTLVPrefixAttrFlags && len(v) >= 1:
+			pc.XFlag = v[0]&PrefixAttrFlagX != 0
+			pc.RFlag = v[0]&PrefixAttrFlagR != 0
+			pc.NFlag = v[0]&PrefixAttrFlagN != 0
+		case t == SubTLVPrefixSrcIPv4 && len(v) == net.IPv4len:
+			pc.SrcV4 = net.IP(append([]byte(nil), v...))
+		case t == SubTLVPrefixSrcIPv6 && len(v) == net.IPv6len:
+			pc.SrcV6 = net.IP(append([]byte(nil), v...))
+		case t == SubTLVPrefixSID:
+			if ps, err := decodePrefixSID(v); err == nil {
+				pc.SIDs = append(pc.SIDs, ps)
+			}
+		}
+	}
 }
 
 type ExtIPv4Prefix struct {
@@ -539,6 +921,51 @@ type IPv6Prefix struct {
 	ipbytes  [16]byte
 }
 
+// Prefix sub-TLV types (RFC5130, RFC7794)
+const (
+	SubTLVPrefixTag       = 1
+	SubTLVPrefixTag64     = 2
+	SubTLVPrefixAttrFlags = 4
+	SubTLVPrefixSrcIPv4   = 11
+	SubTLVPrefixSrcIPv6   = 12
+)
+
+// MaxPrefixTags and MaxPrefixTags64 are the maximum number of tags of a prefix
+// tag sub-TLV.
+const (
+	MaxPrefixTags   = 255 / 4
+	MaxPrefixTags64 = 255 / 8
+)
+
+// Prefix attribute flags (RFC7794)
+const (
+	PrefixAttrFlagX = byte(1 << 7) // External
+	PrefixAttrFlagR = byte(1 << 6) // Re-advertisement
+	PrefixAttrFlagN = byte(1 << 5) // Node
+)
+
+// PrefixTags returns the 32 bit administrative tags found in the prefix
+// sub-TLVs.
+func PrefixTags(subtlv Data) ([]uint32, error) {
+	var tags []uint32
+	for len(subtlv) > 0 {
+		if len(subtlv) < 2 || int(subtlv[1]) > len(subtlv)-2 {
+			return nil, fmt.Errorf("short prefix sub-TLV space %d", len(subtlv))
+		}
+		t, v := subtlv[0], subtlv[2:2+subtlv[1]]
+		if t == SubTLVPrefixTag {
+			if len(v)%4 != 0 {
+				return nil, fmt.Errorf("bad tag sub-TLV length %d", len(v))
+			}
+			for ; len(v) > 0; v = v[4:] {
+				tags = append(tags, binary.BigEndian.Uint32(v))
+			}
+		}
+		subtlv = subtlv[2+subtlv[1]:]
+	}
+	return tags, nil
+}
+
 func (tlv Data) IPPrefixCount() (int, error) {
 	t, l, v, err := GetTLV(tlv)
 	if err != nil {
@@ -554,10 +981,10 @@ func (tlv Data) IPPrefixCount() (int, error) {
 		var pfxlen int
 		nlen := 5
 		if ipv4 {
-			gotsub = (ExtIPv4FlagSubTLV & v[4]) == 1
+			gotsub = (ExtIPv4FlagSubTLV & v[4]) != 0
 			pfxlen = int(ExtIPv4FlagLenMask & v[4])
 		} else {
-			gotsub = (ExtIPv6FlagSubTLV & v[4]) == 1
+			gotsub = (ExtIPv6FlagSubTLV & v[4]) != 0
 			pfxlen = int(v[5])
 			nlen++
 		}
@@ -597,21 +1024,23 @@ func (tlv Data) IPv4PrefixDecode() ([]ExtIPv4Prefix, error) {
 	rv := make([]ExtIPv4Prefix, count)
 	for i := 0; i < count; i++ {
 		rv[i].Metric = binary.BigEndian.Uint32(v)
-		gotsub := (ExtIPv4FlagSubTLV & v[4]) == 1
+		gotsub := (ExtIPv4FlagSubTLV & v[4]) != 0
 		pfxlen := int(ExtIPv4FlagLenMask & v[4])
 		pfxblen := (pfxlen + 7) / 8
 		nlen := 5 + pfxblen
 
-		rv[i].Updown = (ExtIPFlagDown & v[4]) == 1
+		rv[i].Updown = (ExtIPFlagDown & v[4]) != 0
 		rv[i].Prefix.IP = rv[i].ipbytes[:]
 		rv[i].Prefix.Mask = net.CIDRMask(pfxlen, 32)
-		copy(rv[i].Prefix.IP, v[5:])
+		copy(rv[i].Prefix.IP, v[5:5+pfxblen])
+		rv[i].Prefix.IP = rv[i].Prefix.IP.Mask(rv[i].Prefix.Mask)
 
 		if gotsub {
 			sublen := int(v[nlen])
 			if sublen != 0 {
 				rv[i].Subtlv = make(Data, sublen)
 				copy(rv[i].Subtlv, v[nlen+1:])
+				rv[i].decodeSubTLVs()
 			}
 			nlen += 1 + sublen
 		}
@@ -630,22 +1059,24 @@ func (tlv Data) IPv6PrefixDecode() ([]IPv6Prefix, error) {
 	rv := make([]IPv6Prefix, count)
 	for i := 0; i < count; i++ {
 		rv[i].Metric = binary.BigEndian.Uint32(v)
-		gotsub := (ExtIPv6FlagSubTLV & v[4]) == 1
+		gotsub := (ExtIPv6FlagSubTLV & v[4]) != 0
 		pfxlen := int(v[5])
 		pfxblen := (pfxlen + 7) / 8
 		nlen := 6 + pfxblen
 
-		rv[i].Updown = (ExtIPFlagDown & v[4]) == 1
-		rv[i].External = (ExtIPv6FlagExternal & v[4]) == 1
+		rv[i].Updown = (ExtIPFlagDown & v[4]) != 0
+		rv[i].External = (ExtIPv6FlagExternal & v[4]) != 0
 		rv[i].Prefix.IP = rv[i].ipbytes[:]
 		rv[i].Prefix.Mask = net.CIDRMask(pfxlen, 128)
-		copy(rv[i].Prefix.IP, v[5:])
+		copy(rv[i].Prefix.IP, v[6:6+pfxblen])
+		rv[i].Prefix.IP = rv[i].Prefix.IP.Mask(rv[i].Prefix.Mask)
 
 		if gotsub {
 			sublen := int(v[nlen])
 			if sublen != 0 {
 				rv[i].Subtlv = make(Data, sublen)
 				copy(rv[i].Subtlv, v[nlen+1:])
+				rv[i].decodeSubTLVs()
 			}
 			nlen += 1 + sublen
 		}
@@ -724,12 +1155,30 @@ func (tlvs Map) MarshalJSON() ([]byte, error) {
 				value, err = tlv.Hostname()
 			case TypeRouterID:
 				value, err = tlv.RouterIDDecode()
+			case TypeIPv6RouterID:
+				value, err = tlv.IPv6RouterIDDecode()
+			case TypeIPv4SRLG, TypeIPv6SRLG:
+				value, err = tlv.SRLGDecode()
+			case TypeRouterCap:
+				value, err = tlv.RouterCapDecode()
 			case TypeExtIsReach:
 				value, err = tlv.ISExtReachDecode()
+			case TypeSRv6Locator:
+				value, err = tlv.SRv6LocatorDecode()
 			case TypeExtIPv4Prefix:
 				value, err = tlv.IPv4PrefixDecode()
 			case TypeIPv6Prefix:
 				value, err = tlv.IPv6PrefixDecode()
+			case TypeMT:
+				value, err = tlv.MTDecode()
+			case TypeBFDEnabled:
+				value, err = tlv.BFDEnabledDecode()
+			case TypeMTISReach:
+				value, err = tlv.MTISReachDecode()
+			case TypeMTIPv4Prefix:
+				value, err = tlv.MTIPv4PrefixDecode()
+			case TypeMTIPv6Prefix:
+				value, err = tlv.MTIPv6PrefixDecode()
 			case TypeInstanceID:
 				value, err = tlv.InstanceIDDecode()
 			// Non-LSP
@@ -1137,22 +1586,36 @@ func (bt *BufferTrack) AddAdjSNPA(addrs []net.HardwareAddr) error {
 	return nil
 }
 
-// AdjInfo is received on a channel to describe adjacencies.
+// AdjInfo is received on a channel to describe adjacencies. TE and AdjSIDs
+// are encoded as sub-TLVs following any raw Subtlv.
 type AdjInfo struct {
-	Metric uint32
-	Nodeid clns.NodeID
-	Subtlv []byte
+	Metric  uint32
+	Nodeid  clns.NodeID
+	Subtlv  []byte
+	TE      *LinkTE
+	SRLG    []SRLG
+	AdjSIDs []AdjSID
+	EndXSID []SRv6EndXSID
 }
 
 // AddExtISReach reads AdjInfo from the channel C adding the information to
 // Extended IS Reachability TLV[s] (RFC5305). It stops reading from the channel
-// after it has read count AdjDone values.
+// after it has read count AdjDone values. The SRLG of the adjacencies are
+// added after the reachability.
 func (bt *BufferTrack) AddExtISReach(c <-chan interface{}, count int) error {
+	return bt.addISReach(TypeExtIsReach, nil, c, count)
+}
+
+// addISReach adds the adjacencies read from the channel C to IS reachability
+// TLV[s] of type typ, with addhdr adding the MT ID of MT IS reachability. The
+// SRLG are only added with the standard topology.
+func (bt *BufferTrack) addISReach(typ Type, addhdr func(Type, Data) (Data, error), c <-chan interface{}, count int) error {
 	defer drainChannel(c, &count)
 
-	if err := bt.OpenTLV(TypeExtIsReach, nil); err != nil {
+	if err := bt.OpenTLV(typ, addhdr); err != nil {
 		return err
 	}
+	var srlgs []SRLG
 	for count > 0 {
 		result, ok := <-c
 		if !ok {
@@ -1164,6 +1627,21 @@ func (bt *BufferTrack) AddExtISReach(c <-chan interface{}, count int) error {
 		}
 
 		adj := result.(AdjInfo)
+		if typ == TypeExtIsReach {
+			srlgs = append(srlgs, adj.SRLG...)
+		}
+		if adj.TE != nil || len(adj.AdjSIDs) > 0 || len(adj.EndXSID) > 0 {
+			adj.Subtlv = append([]byte(nil), adj.Subtlv...)
+		}
+		if adj.TE != nil {
+			adj.Subtlv = append(adj.Subtlv, adj.TE.Encode()...)
+		}
+		for i := range adj.AdjSIDs {
+			adj.Subtlv = append(adj.Subtlv, adj.AdjSIDs[i].encode()...)
+		}
+		for i := range adj.EndXSID {
+			adj.Subtlv = append(adj.Subtlv, adj.EndXSID[i].encode()...)
+		}
 		tlvp, err := bt.Alloc(clns.NodeIDLen + uint(4) + uint(len(adj.Subtlv)))
 		if err != nil {
 			return err
@@ -1185,6 +1663,53 @@ func (bt *BufferTrack) AddExtISReach(c <-chan interface{}, count int) error {
 	}
 
 	bt.CloseTLV(true)
+
+	for i := range srlgs {
+		if err := bt.AddSRLG(&srlgs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddSRLG adds the IPv4 or IPv6 SRLG TLV[s] for a link if it has any SRLG
+// values. Values that don't fit are continued in another TLV for the link.
+func (bt *BufferTrack) AddSRLG(s *SRLG) error {
+	typ, flags := TypeIPv4SRLG, byte(0)
+	local, nbr := s.LocalAddr.To4(), s.NbrAddr.To4()
+	if s.IsIPv6() {
+		typ, local, nbr = TypeIPv6SRLG, s.LocalAddr.To16(), s.NbrAddr.To16()
+		if nbr != nil {
+			flags |= SRLGFlagNbrAddr
+		}
+	} else {
+		if local == nil {
+			local = net.IPv4zero.To4()
+		}
+		if nbr == nil {
+			nbr = net.IPv4zero.To4()
+		}
+		if s.Numbered {
+			flags |= SRLGFlagNumbered
+		}
+	}
+	hdr := append(append(append(append([]byte(nil), s.Nodeid[:]...), flags), local...), nbr...)
+	room := (255 - len(hdr)) / 4
+	for values := s.Values; len(values) > 0; {
+		n := len(values)
+		if n > room {
+			n = room
+		}
+		b := append([]byte(nil), hdr...)
+		for _, v := range values[:n] {
+			b = appendUint32(b, v)
+		}
+		if err := bt.OpenWithAdd(b, typ, nil); err != nil {
+			return err
+		}
+		bt.CloseTLV(true)
+		values = values[n:]
+	}
 	return nil
 }
 
@@ -1192,11 +1717,157 @@ func (bt *BufferTrack) AddExtISReach(c <-chan interface{}, count int) error {
 // Extended IPv4 Reachability
 // --------------------------
 
-// IPInfo is received on a channel to describe adjacencies.
+// IPInfo is received on a channel to describe adjacencies. Tags, Tags64, the
+// prefix attribute flags, source router IDs and prefix SIDs are encoded as
+// sub-TLVs ahead of any raw Subtlv, raw sub-TLVs of the same type are
+// replaced.
 type IPInfo struct {
-	Metric uint32
-	Ipnet  net.IPNet
-	Subtlv []byte
+	Metric       uint32
+	Ipnet        net.IPNet
+	Subtlv       []byte
+	Updown       bool
+	External     bool
+	Readvertised bool
+	Node         bool
+	Tags         []uint32
+	Tags64       []uint64
+	SourceIPv4   net.IP
+	SourceIPv6   net.IP
+	SIDs         []PrefixSID
+}
+
+// AttrFlags returns the RFC7794 prefix attribute flags for the prefix.
+func (ipi *IPInfo) AttrFlags() byte {
+	var flags byte
+	if ipi.External {
+		flags |= PrefixAttrFlagX
+	}
+	if ipi.Readvertised {
+		flags |= PrefixAttrFlagR
+	}
+	if ipi.Node {
+		flags |= PrefixAttrFlagN
+	}
+	return flags
+}
+
+// subTLVs returns the encoded sub-TLVs for the prefix. The tags of raw tag
+// sub-TLVs are merged with ours. Sub-TLVs that don't fit in the TLV are
+// dropped, the raw ones first then the tags.
+func (ipi *IPInfo) subTLVs() []byte {
+	var fixed, other []byte
+	fixed = append(fixed, SubTLVPrefixAttrFlags, 1, ipi.AttrFlags())
+	if ip := ipi.SourceIPv4.To4(); ip != nil {
+		fixed = append(append(fixed, SubTLVPrefixSrcIPv4, net.IPv4len), ip...)
+	}
+	if ip := ipi.SourceIPv6; ip.To4() == nil && ip.To16() != nil {
+		fixed = append(append(fixed, SubTLVPrefixSrcIPv6, net.IPv6len), ip...)
+	}
+	for i := range ipi.SIDs {
+		fixed = append(fixed, ipi.SIDs[i].encode()...)
+	}
+	tags := append([]uint32(nil), ipi.Tags...)
+	tags64 := append([]uint64(nil), ipi.Tags64...)
+	for raw := ipi.Subtlv; len(raw) >= 2 && int(raw[1]) <= len(raw)-2; raw = raw[2+int(raw[1]):] {
+		v := raw[2 : 2+int(raw[1])]
+		switch raw[0] {
+		case SubTLVPrefixAttrFlags:
+			continue
+		case SubTLVPrefixTag:
+			if len(v)%4 == 0 {
+				for ; len(v) > 0; v = v[4:] {
+					tags = addTag(tags, binary.BigEndian.Uint32(v))
+				}
+				continue
+			}
+		case SubTLVPrefixTag64:
+			if len(v)%8 == 0 {
+				for ; len(v) > 0; v = v[8:] {
+					tags64 = addTag64(tags64, binary.BigEndian.Uint64(v))
+				}
+				continue
+			}
+		case SubTLVPrefixSrcIPv4:
+			if ipi.SourceIPv4 != nil {
+				continue
+			}
+		case SubTLVPrefixSrcIPv6:
+			if ipi.SourceIPv6 != nil {
+				continue
+			}
+		case SubTLVPrefixSID:
+			if len(ipi.SIDs) > 0 {
+				continue
+			}
+		}
+		other = append(other, raw[:2+int(raw[1])]...)
+	}
+
+	room := ipi.maxSubTLVLen() - len(fixed)
+	var sub []byte
+	if n := tagCount(len(tags), MaxPrefixTags, 4, room); n > 0 {
+		sub = append(sub, SubTLVPrefixTag, byte(4*n))
+		for _, tag := range tags[:n] {
+			sub = appendUint32(sub, tag)
+		}
+	}
+	if n := tagCount(len(tags64), MaxPrefixTags64, 8, room-len(sub)); n > 0 {
+		sub = append(sub, SubTLVPrefixTag64, byte(8*n))
+		for _, tag := range tags64[:n] {
+			sub = append(sub, 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.BigEndian.PutUint64(sub[len(sub)-8:], tag)
+		}
+	}
+	room -= len(sub)
+	sub = append(sub, fixed...)
+	for ; len(other) != 0; other = other[2+int(other[1]):] {
+		if l := 2 + int(other[1]); l <= room {
+			sub = append(sub, other[:l]...)
+			room -= l
+		}
+	}
+	return sub
+}
+
+// maxSubTLVLen returns the room for the sub-TLVs of the prefix in a TLV, an MT
+// ID included.
+func (ipi *IPInfo) maxSubTLVLen() int {
+	clen := 5
+	if ipi.Ipnet.IP.To4() == nil {
+		clen++
+	}
+	mlen, _ := ipi.Ipnet.Mask.Size()
+	return 255 - 2 - clen - (mlen+7)/8 - 1
+}
+
+// tagCount returns the number of count tags of size bytes fitting in a tag
+// sub-TLV within room bytes.
+func tagCount(count, max, size, room int) int {
+	if n := (room - 2) / size; n < max {
+		max = n
+	}
+	if count > max {
+		return max
+	}
+	return count
+}
+
+func addTag(tags []uint32, tag uint32) []uint32 {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+func addTag64(tags []uint64, tag uint64) []uint64 {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
 }
 
 func lenExtIP(ipi *IPInfo) uint {
@@ -1209,7 +1880,7 @@ func lenExtIP(ipi *IPInfo) uint {
 
 	mlen, _ := ipi.Ipnet.Mask.Size()
 	blen := (mlen + 7) / 8
-	sublen := len(ipi.Subtlv)
+	sublen := len(ipi.subTLVs())
 
 	tlen := uint(clen + blen + sublen)
 	if sublen > 0 {
@@ -1226,11 +1897,15 @@ func encodeExtIP(tlvp []byte, ipi *IPInfo) {
 	isv4 := ipi.Ipnet.IP.To4() != nil
 	mlen, _ := ipi.Ipnet.Mask.Size()
 	blen := uint(mlen+7) / 8
-	sublen := uint(len(ipi.Subtlv))
+	subtlv := ipi.subTLVs()
+	sublen := uint(len(subtlv))
 
-	// if !up {
-	//      tlvp[0] |= ExtIPFlagDown
-	// }
+	if ipi.Updown {
+		tlvp[0] |= ExtIPFlagDown
+	}
+	if ipi.External && !isv4 {
+		tlvp[0] |= ExtIPv6FlagExternal
+	}
 	if sublen > 0 {
 		if isv4 {
 			tlvp[0] |= ExtIPv4FlagSubTLV
@@ -1248,7 +1923,7 @@ func encodeExtIP(tlvp []byte, ipi *IPInfo) {
 	copy(tlvp, ipi.Ipnet.IP[:blen])
 	if sublen > 0 {
 		tlvp[blen] = byte(sublen)
-		copy(tlvp[1+blen:], ipi.Subtlv)
+		copy(tlvp[1+blen:], subtlv)
 	}
 }
 
@@ -1256,13 +1931,19 @@ func encodeExtIP(tlvp []byte, ipi *IPInfo) {
 // Extended Reachability TLV[s]. It stops reading from the channel after it has
 // read count AdjDone values.
 func (bt *BufferTrack) AddExtIPReach(ipv4 bool, c <-chan interface{}, count int) error {
-	defer drainChannel(c, &count)
-
 	typ := TypeExtIPv4Prefix
 	if !ipv4 {
 		typ = TypeIPv6Prefix
 	}
-	if err := bt.OpenTLV(typ, nil); err != nil {
+	return bt.addIPReach(typ, nil, c, count)
+}
+
+// addIPReach adds the prefixes read from the channel C to IP reachability
+// TLV[s] of type typ, with addhdr adding the MT ID of MT IP reachability.
+func (bt *BufferTrack) addIPReach(typ Type, addhdr func(Type, Data) (Data, error), c <-chan interface{}, count int) error {
+	defer drainChannel(c, &count)
+
+	if err := bt.OpenTLV(typ, addhdr); err != nil {
 		return err
 	}
 	for count > 0 {
@@ -1287,6 +1968,32 @@ func (bt *BufferTrack) AddExtIPReach(ipv4 bool, c <-chan interface{}, count int)
 	return nil
 }
 
+// AddRouterID adds the TE router ID TLV if routerID is an IPv4 address.
+func (bt *BufferTrack) AddRouterID(routerID net.IP) error {
+	ip := routerID.To4()
+	if ip == nil {
+		return nil
+	}
+	err := bt.OpenWithAdd(ip, TypeRouterID, nil)
+	if err == nil {
+		bt.CloseTLV(true)
+	}
+	return err
+}
+
+// AddIPv6RouterID adds the IPv6 TE router ID TLV (RFC6119) if routerID is an
+// IPv6 address.
+func (bt *BufferTrack) AddIPv6RouterID(routerID net.IP) error {
+	if len(routerID) != net.IPv6len || routerID.To4() != nil {
+		return nil
+	}
+	err := bt.OpenWithAdd(routerID, TypeIPv6RouterID, nil)
+	if err == nil {
+		bt.CloseTLV(true)
+	}
+	return err
+}
+
 // AddHostname adds hostname TLV if hostname is not nil.
 func (bt *BufferTrack) AddHostname(hostname string) error {
 	if len(hostname) == 0 {